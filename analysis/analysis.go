@@ -0,0 +1,64 @@
+// Package analysis inspects the source before encoding to derive settings
+// that must be identical for every chunk, such as the crop rectangle or
+// whether the source needs deinterlacing.
+//
+// Each analysis samples short windows spread across the input with an ffmpeg
+// detection filter and reduces the per-sample results to one stable answer,
+// so that a single dark scene or a short intro cannot skew the result.
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// samplePoints returns n timestamps spread evenly across duration, skipping
+// the first and last 5% (logos, credits and fades are not representative).
+func samplePoints(duration float64, n int) []float64 {
+	if n <= 0 || duration <= 0 {
+		return nil
+	}
+	start := duration * 0.05
+	span := duration * 0.90
+
+	points := make([]float64, n)
+	for i := range points {
+		points[i] = start + span*(float64(i)+0.5)/float64(n)
+	}
+	return points
+}
+
+// runFFmpeg runs ffmpeg with a null muxer and returns its log output, where
+// detection filters print their results.
+func runFFmpeg(ctx context.Context, args ...string) (string, error) {
+	full := append([]string{"-hide_banner", "-nostats"}, args...)
+	full = append(full, "-f", "null", "-")
+
+	output, err := exec.CommandContext(ctx, "ffmpeg", full...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("ffmpeg failed: %w (output: %s)", err, lastLines(string(output), 5))
+	}
+	return string(output), nil
+}
+
+// lastLines returns at most n trailing lines of s.
+func lastLines(s string, n int) string {
+	end := len(s)
+	for end > 0 && s[end-1] == '\n' {
+		end--
+	}
+	start := end
+	for count := 0; start > 0; start-- {
+		if s[start-1] == '\n' {
+			count++
+			if count == n {
+				break
+			}
+		}
+	}
+	return s[start:end]
+}
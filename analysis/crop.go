@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"context"
+	"encoder/crop"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// CropOptions controls crop detection.
+type CropOptions struct {
+	Samples int     // Sample points across the input (default 12)
+	Frames  int     // Frames analysed per sample (default 48)
+	Limit   float64 // Black threshold as a fraction of full range (default 24/255)
+	Round   int     // Output width/height alignment: 2, 4, 8 or 16 (default 2)
+}
+
+// CropResult is the outcome of crop detection.
+type CropResult struct {
+	Rect     crop.Rect   // Stable crop rectangle (full frame if nothing to crop)
+	Cropped  bool        // False if the source has no black bars
+	Samples  []crop.Rect // Per-sample detections
+	Rejected int         // Samples discarded as outliers (e.g. dark scenes)
+}
+
+// cropLine matches the final field of a cropdetect log line: "crop=1920:800:0:140"
+var cropLine = regexp.MustCompile(`Parsed_cropdetect.*crop=(\d+):(\d+):(\d+):(\d+)`)
+
+// ParseCropdetectOutput returns the last crop reported in ffmpeg's log. With
+// reset=0 cropdetect accumulates over the window, so the last line covers all
+// analysed frames.
+func ParseCropdetectOutput(output string) (crop.Rect, bool) {
+	matches := cropLine.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return crop.Rect{}, false
+	}
+	m := matches[len(matches)-1]
+	w, _ := strconv.Atoi(m[1])
+	h, _ := strconv.Atoi(m[2])
+	x, _ := strconv.Atoi(m[3])
+	y, _ := strconv.Atoi(m[4])
+	return crop.Rect{Width: w, Height: h, X: x, Y: y}, true
+}
+
+// DetectCrop samples the input's first video stream with cropdetect and
+// returns one crop rectangle for the whole file.
+func DetectCrop(ctx context.Context, input string, duration float64, width, height int, opts CropOptions) (*CropResult, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = 12
+	}
+	if opts.Frames <= 0 {
+		opts.Frames = 48
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 24.0 / 255
+	}
+	if opts.Round <= 0 {
+		opts.Round = 2
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("source dimensions unknown")
+	}
+
+	var samples []crop.Rect
+	for _, at := range samplePoints(duration, opts.Samples) {
+		output, err := runFFmpeg(ctx,
+			"-ss", strconv.FormatFloat(at, 'f', 3, 64),
+			"-i", input,
+			"-map", "0:v:0",
+			"-frames:v", strconv.Itoa(opts.Frames),
+			"-vf", fmt.Sprintf("cropdetect=limit=%.4f:round=2:reset=0", opts.Limit),
+			"-an", "-sn",
+		)
+		if err != nil {
+			return nil, fmt.Errorf("crop detection at %.1fs: %w", at, err)
+		}
+		if rect, ok := ParseCropdetectOutput(output); ok {
+			samples = append(samples, rect)
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("cropdetect reported no results for %s", input)
+	}
+
+	rect, rejected := StableCrop(samples, width, height, opts.Round)
+	return &CropResult{
+		Rect:     rect,
+		Cropped:  rect != (crop.Rect{Width: width, Height: height}),
+		Samples:  samples,
+		Rejected: rejected,
+	}, nil
+}
+
+// StableCrop reduces per-sample detections to one rectangle. Samples whose
+// width or height is more than 5% of the frame away from the median are
+// rejected as outliers (dark or fading scenes look like large black bars).
+// The remaining samples are merged into their bounding box so no picture is
+// lost, then width and height are shrunk to a multiple of round with the
+// rectangle kept centred and offsets even (4:2:0 chroma). Returns the full
+// frame if there is nothing to crop, and the number of rejected samples.
+func StableCrop(samples []crop.Rect, width, height, round int) (crop.Rect, int) {
+	full := crop.Rect{Width: width, Height: height}
+
+	var valid []crop.Rect
+	for _, s := range samples {
+		if s.Width > 0 && s.Height > 0 && s.X+s.Width <= width && s.Y+s.Height <= height {
+			valid = append(valid, s)
+		}
+	}
+	if len(valid) == 0 {
+		return full, len(samples)
+	}
+
+	medW := median(valid, func(r crop.Rect) int { return r.Width })
+	medH := median(valid, func(r crop.Rect) int { return r.Height })
+	tolW, tolH := width/20, height/20
+
+	x1, y1, x2, y2 := width, height, 0, 0
+	inliers := 0
+	for _, s := range valid {
+		if abs(s.Width-medW) > tolW || abs(s.Height-medH) > tolH {
+			continue
+		}
+		inliers++
+		x1, y1 = min(x1, s.X), min(y1, s.Y)
+		x2, y2 = max(x2, s.X+s.Width), max(y2, s.Y+s.Height)
+	}
+	rejected := len(samples) - inliers
+
+	if x1 == 0 && y1 == 0 && x2 == width && y2 == height {
+		return full, rejected
+	}
+
+	w, h := align(x2-x1, round), align(y2-y1, round)
+	x := even(x1 + (x2-x1-w)/2)
+	y := even(y1 + (y2-y1-h)/2)
+	return crop.Rect{Width: w, Height: h, X: x, Y: y}, rejected
+}
+
+func median(rects []crop.Rect, value func(crop.Rect) int) int {
+	values := make([]int, len(rects))
+	for i, r := range rects {
+		values[i] = value(r)
+	}
+	sort.Ints(values)
+	return values[len(values)/2]
+}
+
+// align rounds n down to a multiple of round.
+func align(n, round int) int {
+	if round <= 1 {
+		return n
+	}
+	return n - n%round
+}
+
+func even(n int) int {
+	return n - n%2
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"context"
+	"encoder/crop"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const sampleCropdetectLog = `Input #0, matroska,webm, from 'movie.mkv':
+[Parsed_cropdetect_0 @ 0x55d0c8a0] x1:0 x2:1919 y1:140 y2:938 w:1920 h:784 x:0 y:148 pts:40 t:0.040000 limit:0.094118 crop=1920:784:0:148
+[Parsed_cropdetect_0 @ 0x55d0c8a0] x1:0 x2:1919 y1:138 y2:941 w:1920 h:800 x:0 y:140 pts:80 t:0.080000 limit:0.094118 crop=1920:800:0:140
+frame=   48 fps=0.0 q=-0.0 Lsize=N/A time=00:00:02.00 bitrate=N/A speed=30.1x
+`
+
+func TestParseCropdetectOutput(t *testing.T) {
+	rect, ok := ParseCropdetectOutput(sampleCropdetectLog)
+	if !ok {
+		t.Fatal("Expected crop to be parsed")
+	}
+	if rect != (crop.Rect{Width: 1920, Height: 800, X: 0, Y: 140}) {
+		t.Errorf("Expected last crop line, got %v", rect)
+	}
+	if rect.Filter() != "crop=1920:800:0:140" {
+		t.Errorf("Unexpected filter %q", rect.Filter())
+	}
+
+	if _, ok := ParseCropdetectOutput("no detections here"); ok {
+		t.Error("Expected no crop for output without cropdetect lines")
+	}
+}
+
+func TestStableCrop(t *testing.T) {
+	scope := crop.Rect{Width: 1920, Height: 800, X: 0, Y: 140}
+
+	tests := []struct {
+		name         string
+		samples      []crop.Rect
+		round        int
+		want         crop.Rect
+		wantRejected int
+	}{
+		{
+			name:    "consistent letterbox",
+			samples: []crop.Rect{scope, scope, scope},
+			round:   2,
+			want:    scope,
+		},
+		{
+			name: "dark scene rejected",
+			samples: []crop.Rect{
+				scope, scope, scope,
+				{Width: 1200, Height: 400, X: 360, Y: 340}, // night shot, mostly black
+			},
+			round:        2,
+			want:         scope,
+			wantRejected: 1,
+		},
+		{
+			name: "small variations merged into bounding box",
+			samples: []crop.Rect{
+				{Width: 1920, Height: 800, X: 0, Y: 140},
+				{Width: 1920, Height: 804, X: 0, Y: 138},
+				{Width: 1916, Height: 800, X: 2, Y: 140},
+			},
+			round: 2,
+			want:  crop.Rect{Width: 1920, Height: 804, X: 0, Y: 138},
+		},
+		{
+			name:    "mod-8 keeps the rectangle centred",
+			samples: []crop.Rect{{Width: 1920, Height: 804, X: 0, Y: 138}},
+			round:   8,
+			want:    crop.Rect{Width: 1920, Height: 800, X: 0, Y: 140},
+		},
+		{
+			name:    "no black bars",
+			samples: []crop.Rect{{Width: 1920, Height: 1080}, {Width: 1920, Height: 1080}},
+			round:   16,
+			want:    crop.Rect{Width: 1920, Height: 1080},
+		},
+		{
+			name:         "invalid samples ignored",
+			samples:      []crop.Rect{{Width: 0, Height: 0}, {Width: 4000, Height: 800}},
+			round:        2,
+			want:         crop.Rect{Width: 1920, Height: 1080},
+			wantRejected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rejected := StableCrop(tt.samples, 1920, 1080, tt.round)
+			if got != tt.want {
+				t.Errorf("StableCrop = %v, want %v", got, tt.want)
+			}
+			if rejected != tt.wantRejected {
+				t.Errorf("rejected = %d, want %d", rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestSamplePoints(t *testing.T) {
+	points := samplePoints(100, 4)
+	want := []float64{16.25, 38.75, 61.25, 83.75}
+	if len(points) != len(want) {
+		t.Fatalf("Expected %d points, got %v", len(want), points)
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("point %d = %v, want %v", i, points[i], want[i])
+		}
+	}
+	if samplePoints(0, 4) != nil {
+		t.Error("Expected no points for unknown duration")
+	}
+}
+
+func TestDetectCrop_FakeFFmpeg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script fake ffmpeg requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "cropdetect.log")
+	if err := os.WriteFile(logPath, []byte(sampleCropdetectLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat \"" + logPath + "\" >&2\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result, err := DetectCrop(context.Background(), "movie.mkv", 600, 1920, 1080, CropOptions{Samples: 3})
+	if err != nil {
+		t.Fatalf("DetectCrop failed: %v", err)
+	}
+	if !result.Cropped || result.Rect != (crop.Rect{Width: 1920, Height: 800, X: 0, Y: 140}) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.Samples) != 3 {
+		t.Errorf("Expected 3 samples, got %d", len(result.Samples))
+	}
+}
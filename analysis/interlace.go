@@ -0,0 +1,212 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// ScanType is the classification of a source's scanning.
+type ScanType string
+
+const (
+	ScanProgressive ScanType = "progressive"
+	ScanInterlaced  ScanType = "interlaced" // True interlaced video (broadcast, camcorder)
+	ScanTelecined   ScanType = "telecined"  // Film with 3:2 pulldown to 29.97 fps
+)
+
+// IdetStats are the idet filter's multi-frame counters, summed over samples.
+type IdetStats struct {
+	TFF          int // Frames detected as top field first
+	BFF          int // Frames detected as bottom field first
+	Progressive  int
+	Undetermined int
+
+	RepeatedNeither int // Repeated field counters (pulldown leaves repeats)
+	RepeatedTop     int
+	RepeatedBottom  int
+}
+
+// Add sums two sets of counters.
+func (s IdetStats) Add(o IdetStats) IdetStats {
+	return IdetStats{
+		TFF:             s.TFF + o.TFF,
+		BFF:             s.BFF + o.BFF,
+		Progressive:     s.Progressive + o.Progressive,
+		Undetermined:    s.Undetermined + o.Undetermined,
+		RepeatedNeither: s.RepeatedNeither + o.RepeatedNeither,
+		RepeatedTop:     s.RepeatedTop + o.RepeatedTop,
+		RepeatedBottom:  s.RepeatedBottom + o.RepeatedBottom,
+	}
+}
+
+// InterlacedRatio is the share of determined frames that are interlaced.
+func (s IdetStats) InterlacedRatio() float64 {
+	total := s.TFF + s.BFF + s.Progressive
+	if total == 0 {
+		return 0
+	}
+	return float64(s.TFF+s.BFF) / float64(total)
+}
+
+// RepeatedRatio is the share of frames with a repeated field.
+func (s IdetStats) RepeatedRatio() float64 {
+	total := s.RepeatedNeither + s.RepeatedTop + s.RepeatedBottom
+	if total == 0 {
+		return 0
+	}
+	return float64(s.RepeatedTop+s.RepeatedBottom) / float64(total)
+}
+
+// FieldOrder returns "tff" or "bff", whichever idet saw more often.
+func (s IdetStats) FieldOrder() string {
+	if s.BFF > s.TFF {
+		return "bff"
+	}
+	return "tff"
+}
+
+var (
+	idetMulti    = regexp.MustCompile(`Multi frame detection:\s*TFF:\s*(\d+)\s*BFF:\s*(\d+)\s*Progressive:\s*(\d+)\s*Undetermined:\s*(\d+)`)
+	idetRepeated = regexp.MustCompile(`Repeated Fields:\s*Neither:\s*(\d+)\s*Top:\s*(\d+)\s*Bottom:\s*(\d+)`)
+)
+
+// ParseIdetOutput extracts the idet summary from ffmpeg's log output.
+func ParseIdetOutput(output string) (IdetStats, bool) {
+	m := idetMulti.FindStringSubmatch(output)
+	if m == nil {
+		return IdetStats{}, false
+	}
+
+	var stats IdetStats
+	stats.TFF, _ = strconv.Atoi(m[1])
+	stats.BFF, _ = strconv.Atoi(m[2])
+	stats.Progressive, _ = strconv.Atoi(m[3])
+	stats.Undetermined, _ = strconv.Atoi(m[4])
+
+	if r := idetRepeated.FindStringSubmatch(output); r != nil {
+		stats.RepeatedNeither, _ = strconv.Atoi(r[1])
+		stats.RepeatedTop, _ = strconv.Atoi(r[2])
+		stats.RepeatedBottom, _ = strconv.Atoi(r[3])
+	}
+	return stats, true
+}
+
+// InterlaceOptions controls interlace detection.
+type InterlaceOptions struct {
+	Samples int // Sample points across the input (default 8)
+	Frames  int // Frames analysed per sample (default 250)
+}
+
+// InterlaceResult is the outcome of interlace detection.
+type InterlaceResult struct {
+	Type       ScanType
+	FieldOrder string // "tff" or "bff" (interlaced and telecined sources)
+	Stats      IdetStats
+}
+
+// DetectInterlace samples the input's first video stream with idet and
+// classifies it. fieldOrder is ffprobe's field_order and frameRate the
+// source frame rate in fps; both refine the classification.
+func DetectInterlace(ctx context.Context, input string, duration float64, fieldOrder string, frameRate float64, opts InterlaceOptions) (*InterlaceResult, error) {
+	if opts.Samples <= 0 {
+		opts.Samples = 8
+	}
+	if opts.Frames <= 0 {
+		opts.Frames = 250
+	}
+
+	var stats IdetStats
+	found := false
+	for _, at := range samplePoints(duration, opts.Samples) {
+		output, err := runFFmpeg(ctx,
+			"-ss", strconv.FormatFloat(at, 'f', 3, 64),
+			"-i", input,
+			"-map", "0:v:0",
+			"-frames:v", strconv.Itoa(opts.Frames),
+			"-vf", "idet",
+			"-an", "-sn",
+		)
+		if err != nil {
+			return nil, fmt.Errorf("interlace detection at %.1fs: %w", at, err)
+		}
+		if s, ok := ParseIdetOutput(output); ok {
+			stats = stats.Add(s)
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("idet reported no results for %s", input)
+	}
+
+	result := Classify(stats, fieldOrder, frameRate)
+	return &result, nil
+}
+
+// Classify decides the scan type from idet statistics.
+//
+// Telecined film shows two combed frames in every five (about 40%) and a
+// repeated field in one of five, at a 29.97 fps NTSC rate. True interlaced
+// video is combed in nearly every frame with motion. Sources with very
+// little combing are progressive even if the container flags them as
+// interlaced (common for progressive content in interlaced broadcasts).
+func Classify(stats IdetStats, fieldOrder string, frameRate float64) InterlaceResult {
+	result := InterlaceResult{Type: ScanProgressive, Stats: stats}
+
+	interlaced := stats.InterlacedRatio()
+	flagged := fieldOrder == "tt" || fieldOrder == "bb" || fieldOrder == "tb" || fieldOrder == "bt"
+
+	switch {
+	case isNTSCRate(frameRate) && stats.RepeatedRatio() >= 0.1 && interlaced >= 0.2 && interlaced <= 0.7:
+		result.Type = ScanTelecined
+	case interlaced >= 0.5:
+		result.Type = ScanInterlaced
+	case flagged && interlaced >= 0.15:
+		// Mostly static interlaced content: little combing, but flagged
+		result.Type = ScanInterlaced
+	}
+
+	if result.Type != ScanProgressive {
+		result.FieldOrder = stats.FieldOrder()
+	}
+	return result
+}
+
+// isNTSCRate reports whether fps is 29.97 (or 30), where pulldown occurs.
+func isNTSCRate(fps float64) bool {
+	return math.Abs(fps-30000.0/1001) < 0.01 || math.Abs(fps-30) < 0.01
+}
+
+// Filter returns the filter chain that makes the source progressive, or ""
+// for progressive sources. deinterlacer is "bwdif" (default) or "yadif".
+// Telecined sources are inverse-telecined: fieldmatch rebuilds the film
+// frames, the deinterlacer cleans up frames it could not match, and
+// decimate drops the duplicate frame of each five.
+func (r *InterlaceResult) Filter(deinterlacer string) string {
+	if deinterlacer == "" {
+		deinterlacer = "bwdif"
+	}
+	parity := "tff"
+	if r.FieldOrder == "bff" {
+		parity = "bff"
+	}
+
+	switch r.Type {
+	case ScanInterlaced:
+		return fmt.Sprintf("%s=mode=send_frame:parity=%s:deint=all", deinterlacer, parity)
+	case ScanTelecined:
+		return fmt.Sprintf("fieldmatch=order=%s:combmatch=full,%s=deint=interlaced,decimate", parity, deinterlacer)
+	}
+	return ""
+}
+
+// FrameRateFactor returns the output/input frame rate ratio of Filter as a
+// fraction: 4/5 for inverse telecine, 1/1 otherwise.
+func (r *InterlaceResult) FrameRateFactor() (num, den int64) {
+	if r.Type == ScanTelecined {
+		return 4, 5
+	}
+	return 1, 1
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const sampleIdetLog = `Input #0, mpegts, from 'broadcast.ts':
+[Parsed_idet_0 @ 0x5581f2c0] Repeated Fields: Neither:   200 Top:    25 Bottom:    25
+[Parsed_idet_0 @ 0x5581f2c0] Single frame detection: TFF:    80 BFF:     0 Progressive:   150 Undetermined:    20
+[Parsed_idet_0 @ 0x5581f2c0] Multi frame detection: TFF:   100 BFF:     0 Progressive:   148 Undetermined:     2
+`
+
+func TestParseIdetOutput(t *testing.T) {
+	stats, ok := ParseIdetOutput(sampleIdetLog)
+	if !ok {
+		t.Fatal("Expected idet summary to be parsed")
+	}
+	want := IdetStats{TFF: 100, Progressive: 148, Undetermined: 2, RepeatedNeither: 200, RepeatedTop: 25, RepeatedBottom: 25}
+	if stats != want {
+		t.Errorf("ParseIdetOutput = %+v, want %+v", stats, want)
+	}
+	if r := stats.RepeatedRatio(); r != 0.2 {
+		t.Errorf("RepeatedRatio = %v, want 0.2", r)
+	}
+
+	if _, ok := ParseIdetOutput("frame=  250 fps=0.0"); ok {
+		t.Error("Expected no result without idet summary")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	const ntsc = 30000.0 / 1001
+
+	tests := []struct {
+		name       string
+		stats      IdetStats
+		fieldOrder string
+		fps        float64
+		want       ScanType
+		wantOrder  string
+	}{
+		{
+			name:  "progressive film",
+			stats: IdetStats{Progressive: 990, TFF: 10, RepeatedNeither: 1000},
+			fps:   24000.0 / 1001,
+			want:  ScanProgressive,
+		},
+		{
+			name:       "telecined film",
+			stats:      IdetStats{TFF: 400, Progressive: 600, RepeatedNeither: 800, RepeatedTop: 100, RepeatedBottom: 100},
+			fieldOrder: "tt",
+			fps:        ntsc,
+			want:       ScanTelecined,
+			wantOrder:  "tff",
+		},
+		{
+			name:       "interlaced broadcast",
+			stats:      IdetStats{BFF: 950, Progressive: 50, RepeatedNeither: 1000},
+			fieldOrder: "bb",
+			fps:        25,
+			want:       ScanInterlaced,
+			wantOrder:  "bff",
+		},
+		{
+			name:  "pulldown pattern at PAL rate is interlaced",
+			stats: IdetStats{TFF: 600, Progressive: 400, RepeatedNeither: 800, RepeatedTop: 200},
+			fps:   25,
+			want:  ScanInterlaced,
+		},
+		{
+			name:       "static interlaced content flagged by container",
+			stats:      IdetStats{TFF: 200, Progressive: 800, RepeatedNeither: 1000},
+			fieldOrder: "tt",
+			fps:        25,
+			want:       ScanInterlaced,
+			wantOrder:  "tff",
+		},
+		{
+			name:       "progressive content in interlaced container",
+			stats:      IdetStats{TFF: 20, Progressive: 980, RepeatedNeither: 1000},
+			fieldOrder: "tt",
+			fps:        25,
+			want:       ScanProgressive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.stats, tt.fieldOrder, tt.fps)
+			if got.Type != tt.want {
+				t.Errorf("Type = %s, want %s", got.Type, tt.want)
+			}
+			if tt.wantOrder != "" && got.FieldOrder != tt.wantOrder {
+				t.Errorf("FieldOrder = %s, want %s", got.FieldOrder, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestInterlaceResult_Filter(t *testing.T) {
+	tests := []struct {
+		result       InterlaceResult
+		deinterlacer string
+		want         string
+		wantNum      int64
+		wantDen      int64
+	}{
+		{InterlaceResult{Type: ScanProgressive}, "bwdif", "", 1, 1},
+		{InterlaceResult{Type: ScanInterlaced, FieldOrder: "bff"}, "", "bwdif=mode=send_frame:parity=bff:deint=all", 1, 1},
+		{InterlaceResult{Type: ScanInterlaced, FieldOrder: "tff"}, "yadif", "yadif=mode=send_frame:parity=tff:deint=all", 1, 1},
+		{InterlaceResult{Type: ScanTelecined, FieldOrder: "tff"}, "bwdif", "fieldmatch=order=tff:combmatch=full,bwdif=deint=interlaced,decimate", 4, 5},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.Filter(tt.deinterlacer); got != tt.want {
+			t.Errorf("%s Filter(%q) = %q, want %q", tt.result.Type, tt.deinterlacer, got, tt.want)
+		}
+		if num, den := tt.result.FrameRateFactor(); num != tt.wantNum || den != tt.wantDen {
+			t.Errorf("%s FrameRateFactor = %d/%d, want %d/%d", tt.result.Type, num, den, tt.wantNum, tt.wantDen)
+		}
+	}
+}
+
+func TestDetectInterlace_FakeFFmpeg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script fake ffmpeg requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "idet.log")
+	if err := os.WriteFile(logPath, []byte(sampleIdetLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat \"" + logPath + "\" >&2\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result, err := DetectInterlace(context.Background(), "broadcast.ts", 600, "tt", 30000.0/1001, InterlaceOptions{Samples: 4})
+	if err != nil {
+		t.Fatalf("DetectInterlace failed: %v", err)
+	}
+	if result.Type != ScanTelecined || result.FieldOrder != "tff" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Stats.TFF != 400 {
+		t.Errorf("Expected stats summed over 4 samples, got %+v", result.Stats)
+	}
+}
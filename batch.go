@@ -0,0 +1,558 @@
+package main
+
+import (
+	"context"
+	"encoder/batch"
+	"encoder/command"
+	"encoder/config"
+	"encoder/logging"
+	"encoder/models"
+	"encoder/orchestrator"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// batchKeys are the config keys a batch item cannot override: inputs and
+// outputs come from the item itself, and the worker pool and metrics
+// endpoint are shared.
+var batchKeys = []string{"input", "output", "workers", "mode", "metrics_addr"}
+
+// batchJob is one item of a batch: its configuration and, once prepared, the
+// job whose tasks run on the shared orchestrator.
+type batchJob struct {
+	ID   string
+	Item *batch.Item
+	cfg  *config.Config
+	err  error        // Why the job failed (nil = succeeded or not finished)
+	log  *slog.Logger // Batch log with the job's ID
+
+	enc        *encodeJob
+	audioFiles []string
+	rencodes   []*renditionEncode
+	tasks      []*orchestrator.Task // Audio and video tasks, in the order added
+	finished   time.Time
+}
+
+// prefix returns the prefix of the job's task IDs, e.g. "job003_".
+func (j *batchJob) prefix() string {
+	return j.ID + "_"
+}
+
+// label returns the job ID and input name for terminal lines.
+func (j *batchJob) label() string {
+	return fmt.Sprintf("%s  %s", j.ID, filepath.Base(j.Item.Input))
+}
+
+// runBatchCommand encodes many inputs with one shared worker pool.
+//
+//	encoder batch [-output-template T] [-profile NAME] [-workers N] [-dry-run] DIR|GLOB|MANIFEST
+func runBatchCommand(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file (default: search standard locations)")
+	template := fs.String("output-template", batch.DefaultOutputTemplate, "Output path of items without one: {dir}, {name}, {ext}, {index}, {profile}")
+	profile := fs.String("profile", "", "Profile of items without one (default: $ENCODER_PROFILE or profile: in config)")
+	workers := fs.Int("workers", -1, "Number of parallel workers shared by all jobs (0 = auto-detect, default: from config)")
+	mode := fs.String("mode", "", "Encoding mode: cpu-only, gpu-only, mixed (default: from config)")
+	metricsAddr := fs.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9464 (default: metrics_addr from config)")
+	dryRun := fs.Bool("dry-run", false, "List the jobs and their settings without encoding")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: encoder batch [OPTIONS] DIR|GLOB|MANIFEST (.csv, .json, .yaml)")
+		return 2
+	}
+
+	items, err := batch.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	// Load every job's configuration; a bad item only fails its own job
+	shared := map[string]string{}
+	if *workers >= 0 {
+		shared["workers"] = fmt.Sprint(*workers)
+	}
+	if *mode != "" {
+		shared["mode"] = *mode
+	}
+	if *metricsAddr != "" {
+		shared["metrics_addr"] = *metricsAddr
+	}
+	jobs := newBatchJobs(items, *configPath, *template, *profile, shared)
+
+	if *dryRun {
+		return printBatchPlan(jobs)
+	}
+
+	var ready []*batchJob
+	for _, job := range jobs {
+		if job.err == nil {
+			ready = append(ready, job)
+		}
+	}
+	if len(ready) == 0 {
+		printBatchSummary(jobs, time.Now(), logging.Discard())
+		return 1
+	}
+	cfg := ready[0].cfg
+
+	// One log for the whole batch, next to the first output
+	logBase := filepath.Join(filepath.Dir(cfg.Output), "batch-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(filepath.Dir(logBase), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Logger initialization error: %v\n", err)
+		return 1
+	}
+	log, closeLog, err := openLog(logBase, cfg, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Logger initialization error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+	for _, job := range jobs {
+		job.log = log.With(logging.KeyJobID, job.ID)
+	}
+	if err := enableMetrics(cfg.MetricsAddr, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\n\n⚠️  Interrupt received, cleaning up...")
+		log.Warn("interrupted, cancelling encoding")
+		cancel()
+	}()
+
+	startTime := time.Now()
+	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                   ENCODER - BATCH START                        ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("Jobs:    %d (%d with invalid settings)\n", len(jobs), len(jobs)-len(ready))
+	fmt.Printf("Mode:    %s\n", cfg.Mode)
+	fmt.Printf("Workers: %d (shared)\n", cfg.Workers)
+	fmt.Println()
+	log.Info("batch started", "jobs", len(jobs), "mode", cfg.Mode, "workers", cfg.Workers)
+	for _, job := range jobs {
+		if job.err != nil {
+			job.log.Warn("job skipped", "input", job.Item.Input, "error", job.err)
+		}
+	}
+
+	// Schedule every job's tasks on one orchestrator as soon as the job is
+	// prepared: later jobs are analyzed while earlier ones encode, and jobs
+	// overlap, so no job's tail end leaves workers idle
+	fmt.Println("🎬 Encoding")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	orch := orchestrator.NewDAGOrchestrator(buildResourceConstraints(cfg))
+	orch.SetLogger(log)
+	orch.SetContext(ctx)
+	orch.KeepOpen()
+	var mu sync.Mutex
+	finalizers := make(map[string]*batchJob) // Finalize task ID -> job
+	orch.SetProgressCallback(func(completed, total int, task *orchestrator.Task) {
+		if task.Error != nil {
+			log.Warn("task failed", logging.KeyTaskID, task.ID, "completed", completed, "total", total, "error", task.Error)
+		} else {
+			log.Info("task completed", logging.KeyTaskID, task.ID, "completed", completed, "total", total)
+		}
+		encoderMetrics.TaskDone(task)
+
+		mu.Lock()
+		job, ok := finalizers[task.ID]
+		mu.Unlock()
+		if !ok {
+			return
+		}
+		job.finished = time.Now()
+		if task.Error != nil {
+			job.err = job.failure(orch, task.Error)
+			fmt.Printf("  ❌ %s: %v\n", job.label(), job.err)
+			return
+		}
+		fmt.Printf("  ✓ %s → %s (%.2f MB, %.0fs)\n", job.label(), job.cfg.Output,
+			float64(job.enc.outputSize())/(1024*1024), job.finished.Sub(startTime).Seconds())
+	})
+
+	prepared := make(chan struct{})
+	go func() {
+		defer close(prepared)
+		defer orch.Close()
+		var scheduled []*batchJob
+		for _, job := range ready {
+			waitForWaitingJobs(ctx, orch, scheduled)
+			if ctx.Err() != nil {
+				job.err = ctx.Err()
+				continue
+			}
+			if err := job.prepare(ctx); err != nil {
+				job.err = err
+				job.log.Error("preparation failed", "error", err)
+				fmt.Printf("  ❌ %s: %v\n", job.label(), err)
+				continue
+			}
+
+			// Registered first: the finalize task may finish before addTasks returns
+			mu.Lock()
+			finalizers[job.prefix()+"finalize"] = job
+			mu.Unlock()
+			if _, err := job.addTasks(orch); err != nil {
+				mu.Lock()
+				delete(finalizers, job.prefix()+"finalize")
+				mu.Unlock()
+				job.err = err
+				job.log.Error("scheduling failed", "error", err)
+				fmt.Printf("  ❌ %s: %v\n", job.label(), err)
+				continue
+			}
+			scheduled = append(scheduled, job)
+			fmt.Printf("  ▶ %s: %d chunks, %d tasks → %s\n", job.label(), len(job.enc.chunks), len(job.tasks)+1, job.cfg.Output)
+		}
+	}()
+
+	untrack := encoderMetrics.Track(orch)
+	_, err = orch.Execute()
+	untrack()
+	<-prepared
+	defer func() {
+		for _, job := range ready {
+			if job.enc != nil {
+				job.enc.close()
+			}
+		}
+	}()
+	if err != nil {
+		log.Error("batch encoding failed", "error", err)
+		fmt.Fprintf(os.Stderr, "\n❌ Batch error: %v\n", err)
+		return 1
+	}
+	fmt.Println()
+
+	// Thumbnails are optional and may finish after their job's output
+	for _, job := range ready {
+		if _, ok := finalizers[job.prefix()+"finalize"]; !ok {
+			continue
+		}
+		job.enc.collectThumbnails()
+		if job.err == nil {
+			job.log.Info("job finished", "input", job.Item.Input)
+			job.enc.logSummary(job.finished.Sub(startTime))
+		}
+	}
+
+	failed := printBatchSummary(jobs, startTime, log)
+	if ctx.Err() == context.Canceled {
+		fmt.Println("\n⚠️  Encoding cancelled by user")
+		return 130
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newBatchJobs returns a job for each item, filling in its profile and
+// output, and loads their configurations. A job whose output an earlier job
+// already writes fails without loading.
+func newBatchJobs(items []*batch.Item, configPath, template, profile string, shared map[string]string) []*batchJob {
+	jobs := make([]*batchJob, len(items))
+	outputs := make(map[string]string) // Output -> job ID
+	for i, item := range items {
+		job := &batchJob{ID: fmt.Sprintf("job%03d", i+1), Item: item}
+		jobs[i] = job
+		if item.Profile == "" {
+			item.Profile = profile
+		}
+		if item.Output == "" {
+			item.Output = batch.ExpandOutput(template, item.Input, i+1, item.Profile)
+		}
+		if owner, ok := outputs[item.Output]; ok {
+			job.err = fmt.Errorf("output %s is also written by %s", item.Output, owner)
+			continue
+		}
+		outputs[item.Output] = job.ID
+		job.cfg, job.err = loadBatchJobConfig(configPath, item, shared)
+	}
+	return jobs
+}
+
+// loadBatchJobConfig loads the configuration of a batch item: its profile
+// and overrides on top of the config file and environment, with the batch's
+// shared settings, checked against the local ffmpeg build.
+func loadBatchJobConfig(configPath string, item *batch.Item, shared map[string]string) (*config.Config, error) {
+	overrides := make(map[string]string, len(item.Overrides)+len(shared)+2)
+	for key, value := range item.Overrides {
+		if slices.Contains(batchKeys, key) {
+			return nil, fmt.Errorf("override %s: set for the whole batch or by the item itself", key)
+		}
+		overrides[key] = value
+	}
+	for key, value := range shared {
+		overrides[key] = value
+	}
+	overrides["input"] = item.Input
+	overrides["output"] = item.Output
+	cfg, err := config.LoadJobConfig(configPath, item.Profile, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFFmpeg(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// maxWaitingJobs caps the batch jobs prepared but not started: the next job
+// is analyzed and split while earlier ones encode, without the whole batch's
+// segments on disk at once.
+const maxWaitingJobs = 2
+
+// waitForWaitingJobs waits until fewer than maxWaitingJobs of the scheduled
+// jobs wait for their first task to start, or ctx is done.
+func waitForWaitingJobs(ctx context.Context, orch *orchestrator.DAGOrchestrator, scheduled []*batchJob) {
+	for {
+		waiting := 0
+		for _, job := range scheduled {
+			if !job.started(orch) {
+				waiting++
+			}
+		}
+		if waiting < maxWaitingJobs {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// started reports whether one of the job's chunk tasks has started (or
+// was skipped).
+func (j *batchJob) started(orch *orchestrator.DAGOrchestrator) bool {
+	for _, task := range j.tasks {
+		if status, _ := orch.GetTaskStatus(task.ID); status != orchestrator.TaskPending && status != orchestrator.TaskReady {
+			return true
+		}
+	}
+	return len(j.tasks) == 0
+}
+
+// prepare acquires the job's work directory, analyzes and chunks its input
+// and pre-splits it (Phases 1-3).
+func (j *batchJob) prepare(ctx context.Context) error {
+	j.log.Info("preparing job", "input", j.cfg.Input, "output", j.cfg.Output)
+	enc, err := openJob(j.cfg, io.Discard, j.log)
+	if err != nil {
+		return err
+	}
+	j.enc = enc
+
+	err = enc.analyze(ctx)
+	if err == nil {
+		err = enc.chunk()
+	}
+	if err == nil && enc.preSplits() {
+		err = enc.preSplit()
+	}
+	if err != nil {
+		enc.close()
+		j.enc = nil
+		return err
+	}
+	j.cfg = enc.cfg
+	return nil
+}
+
+// addTasks adds the job's chunk and thumbnail tasks to orch, and a task
+// finishing the job once all its chunks are encoded. A failed chunk fails
+// only that job's finalize task. Returns the finalize task.
+func (j *batchJob) addTasks(orch *orchestrator.DAGOrchestrator) (*orchestrator.Task, error) {
+	enc := j.enc
+	if enc.hasAudio {
+		var tasks []*orchestrator.Task
+		j.audioFiles, tasks = audioTasks(enc.cfg, enc.chunks, enc.audioDir, j.prefix(), j.log, metricsProgress())
+		j.tasks = append(j.tasks, tasks...)
+	}
+	if enc.hasVideo {
+		rencodes, tasks, err := videoTasks(enc.renditions, enc.chunks, enc.src, j.prefix(), enc.out, j.log, metricsProgress())
+		if err != nil {
+			return nil, err
+		}
+		j.rencodes = rencodes
+		j.tasks = append(j.tasks, tasks...)
+	}
+
+	finalize := &orchestrator.Task{
+		ID:       j.prefix() + "finalize",
+		Command:  &finalizeCommand{job: j, priority: command.PriorityHigh},
+		Resource: orchestrator.ResourceIO,
+	}
+	for _, task := range j.tasks {
+		finalize.Dependencies = append(finalize.Dependencies, task.ID)
+	}
+
+	if err := addTasks(orch, append(j.tasks, finalize)); err != nil {
+		return nil, err
+	}
+	if enc.hasVideo {
+		if err := enc.addThumbnailTasks(orch, j.prefix()); err != nil {
+			j.log.Warn("thumbnails skipped", "error", err)
+		}
+	}
+	return finalize, nil
+}
+
+// finalize collects the job's encoded chunks and finishes its outputs
+// (Phases 7-10). It runs once every chunk task of the job succeeded.
+func (j *batchJob) finalize() error {
+	enc := j.enc
+	j.log.Info("finalizing job", "output", enc.cfg.Output)
+	if enc.hasAudio {
+		saveAudioManifest(enc.cfg, enc.chunks, enc.audioDir, j.audioFiles, j.log)
+		enc.audioFiles = j.audioFiles
+	}
+	if enc.hasVideo {
+		var results []*models.EncoderResult
+		for _, task := range j.tasks {
+			if task.Result != nil {
+				results = append(results, task.Result)
+			}
+		}
+		if err := finishVideo(enc.renditions, j.rencodes, enc.chunks, results, enc.out, j.log); err != nil {
+			return err
+		}
+	}
+	return enc.finish()
+}
+
+// failure returns why the job's finalize task failed: the first failed chunk
+// task, or the finalize error itself.
+func (j *batchJob) failure(orch *orchestrator.DAGOrchestrator, err error) error {
+	for _, task := range j.tasks {
+		if status, _ := orch.GetTaskStatus(task.ID); status != orchestrator.TaskFailed {
+			continue
+		}
+		if task.Error != nil && !errors.Is(task.Error, orchestrator.ErrDependencyFailed) {
+			return fmt.Errorf("%s: %w", strings.TrimPrefix(task.ID, j.prefix()), task.Error)
+		}
+	}
+	return err
+}
+
+// printBatchPlan lists the jobs of a dry run. Returns the exit code: 1 if a
+// job has invalid settings.
+func printBatchPlan(jobs []*batchJob) int {
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Println("                   BATCH DRY RUN")
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	invalid := 0
+	for _, job := range jobs {
+		fmt.Printf("\n  %s  %s\n", job.ID, job.Item.Input)
+		fmt.Printf("    Output:    %s\n", job.Item.Output)
+		if job.Item.Profile != "" {
+			fmt.Printf("    Profile:   %s\n", job.Item.Profile)
+		}
+		for _, key := range job.Item.OverrideKeys() {
+			fmt.Printf("    Override:  %s = %s\n", key, job.Item.Overrides[key])
+		}
+		if job.err != nil {
+			fmt.Printf("    ❌ %s\n", strings.ReplaceAll(job.err.Error(), "\n", "\n    "))
+			invalid++
+			continue
+		}
+		fmt.Printf("    Video:     %s, audio: %s %s\n", strings.Join(job.cfg.Video.Codecs(), ", "), job.cfg.Audio.Codec, job.cfg.Audio.Bitrate)
+	}
+	fmt.Println()
+	if invalid > 0 {
+		fmt.Printf("❌ %d of %d jobs have invalid settings.\n", invalid, len(jobs))
+		return 1
+	}
+	fmt.Printf("✓ %d jobs are valid. No encoding will be performed.\n", len(jobs))
+	return 0
+}
+
+// printBatchSummary prints the result of every job and the batch totals,
+// and logs the totals to log. Returns the number of failed jobs.
+func printBatchSummary(jobs []*batchJob, startTime time.Time, log *slog.Logger) int {
+	elapsed := time.Since(startTime)
+	succeeded, failed := 0, 0
+	var totalSize int64
+	var totalDuration float64
+
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Println("                    BATCH SUMMARY")
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	for _, job := range jobs {
+		if job.err != nil || job.enc == nil || job.finished.IsZero() {
+			failed++
+			reason := job.err
+			if reason == nil {
+				reason = fmt.Errorf("not finished")
+			}
+			fmt.Printf("  ❌ %s: %v\n", job.label(), reason)
+			continue
+		}
+		succeeded++
+		size := job.enc.outputSize()
+		totalSize += size
+		totalDuration += job.enc.duration
+		fmt.Printf("  ✓ %s → %s (%.2f MB)\n", job.label(), job.cfg.Output, float64(size)/(1024*1024))
+	}
+	fmt.Println("───────────────────────────────────────────────────────────")
+	fmt.Printf("  Jobs:        %d succeeded, %d failed\n", succeeded, failed)
+	fmt.Printf("  Output:      %.2f MB\n", float64(totalSize)/(1024*1024))
+	fmt.Printf("  Total time:  %.2fs (%.2fx realtime)\n", elapsed.Seconds(), totalDuration/elapsed.Seconds())
+	fmt.Println("═══════════════════════════════════════════════════════════")
+
+	log.Info("batch complete", "succeeded", succeeded, "failed", failed, "elapsed_seconds", elapsed.Seconds())
+	return failed
+}
+
+// finalizeCommand runs a batch job's finalize step as a task of the shared
+// orchestrator, after the job's chunk tasks.
+type finalizeCommand struct {
+	job      *batchJob
+	priority int
+}
+
+// BuildArgs returns nil: the step runs several ffmpeg commands.
+func (c *finalizeCommand) BuildArgs() []string { return nil }
+
+// Run concatenates and muxes the job's chunks into its outputs.
+func (c *finalizeCommand) Run() error { return c.job.finalize() }
+
+// DryRun describes the step.
+func (c *finalizeCommand) DryRun() (string, error) {
+	return fmt.Sprintf("finalize %s → %s", c.job.cfg.Input, c.job.cfg.Output), nil
+}
+
+func (c *finalizeCommand) GetPriority() int { return c.priority }
+
+func (c *finalizeCommand) SetPriority(priority int) command.Command {
+	c.priority = priority
+	return c
+}
+
+func (c *finalizeCommand) GetTaskType() command.TaskType { return command.TaskTypeMixing }
+
+func (c *finalizeCommand) GetInputPath() string { return c.job.cfg.Input }
+
+func (c *finalizeCommand) GetOutputPath() string { return c.job.cfg.Output }
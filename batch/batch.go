@@ -0,0 +1,317 @@
+// Package batch lists the inputs of a batch encode: the media files of a
+// directory or glob, or the items of a CSV, JSON or YAML manifest with
+// per-item output paths, profiles and setting overrides.
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOutputTemplate writes each output to an encoded/ directory next to
+// its input.
+const DefaultOutputTemplate = "{dir}/encoded/{name}.mkv"
+
+// MediaExtensions lists the extensions (without the dot) of the files taken
+// from a directory.
+var MediaExtensions = []string{
+	"avi", "flac", "flv", "m2ts", "m4a", "m4v", "mka", "mkv", "mov", "mp3",
+	"mp4", "mpeg", "mpg", "mts", "mxf", "ogg", "opus", "ts", "wav", "webm", "wmv",
+}
+
+// manifestExtensions maps manifest file extensions to their format.
+var manifestExtensions = map[string]string{
+	".csv":  "csv",
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// Item is one input of a batch.
+type Item struct {
+	Input     string            `json:"input" yaml:"input"`
+	Output    string            `json:"output,omitempty" yaml:"output,omitempty"`       // Empty = output template
+	Profile   string            `json:"profile,omitempty" yaml:"profile,omitempty"`     // Empty = the batch's profile
+	Overrides map[string]string `json:"overrides,omitempty" yaml:"overrides,omitempty"` // Config keys, e.g. "video.crf": "24"
+}
+
+// Load returns the items of source: a manifest file (.csv, .json, .yaml), a
+// directory, whose media files are taken in name order, or a glob pattern.
+// Relative paths in a manifest are relative to the manifest's directory.
+func Load(source string) ([]*Item, error) {
+	info, err := os.Stat(source)
+	switch {
+	case err == nil && info.IsDir():
+		return loadDir(source)
+	case err == nil && manifestExtensions[strings.ToLower(filepath.Ext(source))] != "":
+		return LoadManifest(source)
+	case err == nil:
+		return []*Item{{Input: source}}, nil
+	}
+
+	matches, globErr := filepath.Glob(source)
+	if globErr != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", source, globErr)
+	}
+	var items []*Item
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			items = append(items, &Item{Input: path})
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no inputs match %s", source)
+	}
+	return items, nil
+}
+
+// loadDir returns an item per media file directly in dir.
+func loadDir(dir string) ([]*Item, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") || !IsMedia(e.Name()) {
+			continue
+		}
+		items = append(items, &Item{Input: filepath.Join(dir, e.Name())})
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no media files in %s", dir)
+	}
+	return items, nil
+}
+
+// IsMedia reports whether path has one of the MediaExtensions.
+func IsMedia(path string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	for _, e := range MediaExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadManifest reads the items of a CSV, JSON or YAML manifest.
+//
+// JSON and YAML manifests are a list of items; override values may be any
+// scalar. A CSV manifest has a header row naming its columns: input, output
+// and profile, and any other column is an override of the config key it
+// names (empty cells set nothing).
+func LoadManifest(path string) ([]*Item, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []*Item
+	switch manifestExtensions[strings.ToLower(filepath.Ext(path))] {
+	case "csv":
+		items, err = parseCSV(f)
+	case "json":
+		items, err = parseJSON(f)
+	case "yaml":
+		items, err = parseYAML(f)
+	default:
+		return nil, fmt.Errorf("unknown manifest format %q, use .csv, .json or .yaml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	// Paths are relative to the manifest
+	base := filepath.Dir(path)
+	for i, item := range items {
+		if item.Input == "" {
+			return nil, fmt.Errorf("manifest %s: item %d has no input", path, i+1)
+		}
+		item.Input = resolvePath(base, item.Input)
+		if item.Output != "" {
+			item.Output = resolvePath(base, item.Output)
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no inputs", path)
+	}
+	return items, nil
+}
+
+// resolvePath returns path relative to base unless it is absolute.
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// parseCSV reads a CSV manifest with a header row.
+func parseCSV(r io.Reader) ([]*Item, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if !contains(header, "input") {
+		return nil, fmt.Errorf("header has no input column")
+	}
+
+	items := make([]*Item, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		item := &Item{}
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			switch header[i] {
+			case "input":
+				item.Input = value
+			case "output":
+				item.Output = value
+			case "profile":
+				item.Profile = value
+			default:
+				if item.Overrides == nil {
+					item.Overrides = make(map[string]string)
+				}
+				item.Overrides[header[i]] = value
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// rawItem is an item as decoded from JSON or YAML, before override values
+// are turned into strings.
+type rawItem struct {
+	Input     string                 `json:"input" yaml:"input"`
+	Output    string                 `json:"output" yaml:"output"`
+	Profile   string                 `json:"profile" yaml:"profile"`
+	Overrides map[string]interface{} `json:"overrides" yaml:"overrides"`
+}
+
+// parseJSON reads a JSON manifest.
+func parseJSON(r io.Reader) ([]*Item, error) {
+	var raw []rawItem
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return fromRaw(raw)
+}
+
+// parseYAML reads a YAML manifest.
+func parseYAML(r io.Reader) ([]*Item, error) {
+	var raw []rawItem
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return fromRaw(raw)
+}
+
+// fromRaw converts decoded items, formatting override values as the strings
+// a config key is set from.
+func fromRaw(raw []rawItem) ([]*Item, error) {
+	items := make([]*Item, len(raw))
+	for i, r := range raw {
+		item := &Item{Input: r.Input, Output: r.Output, Profile: r.Profile}
+		for key, value := range r.Overrides {
+			s, err := ScalarString(value)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: override %s: %w", i+1, key, err)
+			}
+			if item.Overrides == nil {
+				item.Overrides = make(map[string]string)
+			}
+			item.Overrides[key] = s
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// ScalarString formats a scalar value decoded from YAML or JSON as the
+// string a config key is set from.
+func ScalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("value must be a string, number or boolean, got %T", value)
+	}
+}
+
+// ExpandOutput returns the output path of the index-th (from 1) input from
+// template. Placeholders:
+//
+//	{dir}     directory of the input
+//	{name}    file name of the input without its extension
+//	{ext}     extension of the input without the dot
+//	{index}   position of the input in the batch, zero-padded to 3 digits
+//	{profile} profile of the item (empty without one)
+func ExpandOutput(template, input string, index int, profile string) string {
+	base := filepath.Base(input)
+	ext := filepath.Ext(base)
+	replacer := strings.NewReplacer(
+		"{dir}", filepath.Dir(input),
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", strings.TrimPrefix(ext, "."),
+		"{index}", fmt.Sprintf("%03d", index),
+		"{profile}", profile,
+	)
+	return filepath.Clean(replacer.Replace(template))
+}
+
+// OverrideKeys returns the keys of the item's overrides, sorted.
+func (i *Item) OverrideKeys() []string {
+	keys := make([]string, 0, len(i.Overrides))
+	for key := range i.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contains reports whether list holds value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
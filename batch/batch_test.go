@@ -0,0 +1,135 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func inputs(items []*Item) []string {
+	var paths []string
+	for _, item := range items {
+		paths = append(paths, item.Input)
+	}
+	return paths
+}
+
+func TestLoad_Directory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.mkv", "a.MP4", "notes.txt", ".hidden.mkv"} {
+		writeFile(t, filepath.Join(dir, name), "x")
+	}
+	writeFile(t, filepath.Join(dir, "sub", "c.mkv"), "x")
+
+	items, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.MP4"), filepath.Join(dir, "b.mkv")}
+	if got := inputs(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLoad_Glob(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ep1.mkv"), "x")
+	writeFile(t, filepath.Join(dir, "ep2.mkv"), "x")
+	writeFile(t, filepath.Join(dir, "extra.mp4"), "x")
+
+	items, err := Load(filepath.Join(dir, "ep*.mkv"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("Expected 2 items, got %v", inputs(items))
+	}
+
+	if _, err := Load(filepath.Join(dir, "*.avi")); err == nil {
+		t.Error("Expected an error for a pattern without matches")
+	}
+}
+
+func TestLoadManifest_Formats(t *testing.T) {
+	dir := t.TempDir()
+	want := []*Item{
+		{Input: filepath.Join(dir, "a.mkv"), Output: filepath.Join(dir, "out", "a.mp4"), Profile: "web-h264"},
+		{Input: "/media/b.mkv", Overrides: map[string]string{"video.crf": "24", "audio.gapless": "false"}},
+	}
+
+	manifests := map[string]string{
+		"jobs.csv": "input,output,profile,video.crf,audio.gapless\n" +
+			"a.mkv,out/a.mp4,web-h264,,\n" +
+			"/media/b.mkv,,,24,false\n",
+		"jobs.json": `[
+			{"input": "a.mkv", "output": "out/a.mp4", "profile": "web-h264"},
+			{"input": "/media/b.mkv", "overrides": {"video.crf": 24, "audio.gapless": false}}
+		]`,
+		"jobs.yaml": "- input: a.mkv\n  output: out/a.mp4\n  profile: web-h264\n" +
+			"- input: /media/b.mkv\n  overrides:\n    video.crf: 24\n    audio.gapless: false\n",
+	}
+	for name, content := range manifests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+
+			items, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if !reflect.DeepEqual(items, want) {
+				t.Errorf("Expected %+v, got %+v", want, items)
+				for _, item := range items {
+					t.Logf("  %+v", *item)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadManifest_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"missing-input.csv": "output,profile\nout.mkv,web\n",
+		"empty.json":        "[]",
+		"no-input.yaml":     "- output: out.mkv\n",
+		"unknown-key.yaml":  "- input: a.mkv\n  crf: 24\n",
+		"nested.json":       `[{"input": "a.mkv", "overrides": {"video": {"crf": 24}}}]`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+			if _, err := LoadManifest(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestExpandOutput(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{DefaultOutputTemplate, "/media/show/encoded/ep1.mkv"},
+		{"/out/{index}_{name}.{ext}", "/out/007_ep1.mp4"},
+		{"/out/{profile}/{name}.mp4", "/out/web/ep1.mp4"},
+	}
+	for _, tt := range tests {
+		if got := ExpandOutput(tt.template, "/media/show/ep1.mp4", 7, "web"); got != tt.want {
+			t.Errorf("ExpandOutput(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoder/batch"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupBatchDir returns an empty config file and an input file in a temp
+// directory, with ffmpeg out of PATH so no build checks run.
+func setupBatchDir(t *testing.T) (configPath, input string) {
+	t.Helper()
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, "config.yaml")
+	input = filepath.Join(dir, "movie.mkv")
+	for _, path := range []string{configPath, input} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configPath, input
+}
+
+func TestLoadBatchJobConfig(t *testing.T) {
+	configPath, input := setupBatchDir(t)
+	output := filepath.Join(filepath.Dir(input), "encoded", "movie.mkv")
+
+	item := &batch.Item{Input: input, Output: output, Overrides: map[string]string{"video.crf": "24"}}
+	cfg, err := loadBatchJobConfig(configPath, item, map[string]string{"workers": "3"})
+	if err != nil {
+		t.Fatalf("loadBatchJobConfig() error = %v", err)
+	}
+	if cfg.Input != input || cfg.Output != output {
+		t.Errorf("Input, Output = %s, %s, want %s, %s", cfg.Input, cfg.Output, input, output)
+	}
+	if cfg.Video.CRF != 24 || cfg.Workers != 3 {
+		t.Errorf("CRF, Workers = %d, %d, want 24, 3", cfg.Video.CRF, cfg.Workers)
+	}
+}
+
+func TestLoadBatchJobConfig_BatchKeys(t *testing.T) {
+	configPath, input := setupBatchDir(t)
+
+	for _, key := range batchKeys {
+		t.Run(key, func(t *testing.T) {
+			item := &batch.Item{Input: input, Output: input + ".out.mkv", Overrides: map[string]string{key: "x"}}
+			_, err := loadBatchJobConfig(configPath, item, nil)
+			if err == nil || !strings.Contains(err.Error(), "override "+key) {
+				t.Errorf("Expected override %s to be rejected, got %v", key, err)
+			}
+		})
+	}
+}
+
+func TestNewBatchJobs_DuplicateOutputs(t *testing.T) {
+	configPath, input := setupBatchDir(t)
+	dir := filepath.Dir(input)
+	other := filepath.Join(dir, "other.mkv")
+	if err := os.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []*batch.Item{
+		{Input: input},
+		{Input: other, Output: filepath.Join(dir, "encoded", "movie.mkv")}, // Collides with the first job's template output
+		{Input: other},
+	}
+	jobs := newBatchJobs(items, configPath, batch.DefaultOutputTemplate, "", nil)
+
+	if len(jobs) != 3 || jobs[0].ID != "job001" || jobs[2].ID != "job003" {
+		t.Fatalf("Unexpected jobs: %v", jobs)
+	}
+	if jobs[0].err != nil || jobs[2].err != nil {
+		t.Errorf("Expected job001 and job003 to load, got %v and %v", jobs[0].err, jobs[2].err)
+	}
+	if jobs[1].err == nil || !strings.Contains(jobs[1].err.Error(), "also written by job001") {
+		t.Errorf("Expected job002 to fail as a duplicate of job001, got %v", jobs[1].err)
+	}
+	if jobs[1].cfg != nil {
+		t.Error("Expected no config for a duplicate job")
+	}
+	if want := filepath.Join(dir, "encoded", "other.mkv"); items[2].Output != want {
+		t.Errorf("Template output = %s, want %s", items[2].Output, want)
+	}
+}
@@ -0,0 +1,393 @@
+// Package codec describes the encoders the pipeline knows about: valid
+// quality ranges, presets, pixel formats, containers and sample rates.
+//
+// The registry is used by config validation and by the command builders so
+// that a bad value is reported with a precise message before ffmpeg runs.
+// Codecs missing from the registry are passed through unchecked, apart from
+// the generic 0-51 quality range.
+package codec
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind distinguishes audio from video encoders.
+type Kind string
+
+const (
+	KindVideo Kind = "video"
+	KindAudio Kind = "audio"
+)
+
+// DefaultQualityMin and DefaultQualityMax bound CRF for codecs not in the registry.
+const (
+	DefaultQualityMin = 0
+	DefaultQualityMax = 51
+)
+
+// Codec describes one ffmpeg encoder.
+type Codec struct {
+	Name   string // ffmpeg encoder name, e.g. "libsvtav1"
+	Kind   Kind
+	Family string // Bitstream format, e.g. "h264", "hevc", "av1", "opus"
+
+	// Video quality control
+	QualityParam string // ffmpeg option for constant quality ("crf", "qp", "cq"), empty = none
+	QualityMin   int
+	QualityMax   int
+
+	// Presets: either a list of names or a numeric range
+	Presets        []string
+	NumericPresets bool
+	PresetMin      int
+	PresetMax      int
+	DefaultPreset  string
+
+	PixelFormats []string // Supported -pix_fmt values (empty = not checked)
+	TenBit       bool     // Can encode 10-bit output
+	Hardware     bool     // GPU/ASIC encoder
+	ParamsOption string   // ffmpeg option taking key=value:... encoder params, e.g. "x265-params"
+	TwoPass      bool     // Supports two-pass rate control
+	PassParams   bool     // Passes are set through ParamsOption (pass=N:stats=FILE) instead of -pass
+	ClosedGOP    []Param  // ParamsOption entries that keep GOPs closed (no references across keyframes)
+	ForcedIDR    bool     // Has -forced-idr, making forced keyframes IDR frames
+
+	// Audio
+	Lossless    bool  // Lossless encoder: takes no bitrate
+	SampleRates []int // Supported sample rates (empty = any)
+	MaxChannels int   // 0 = not checked
+
+	Containers []string // Output file extensions the codec can be muxed into
+}
+
+// x26xPresets are the preset names shared by libx264 and libx265.
+var x26xPresets = []string{
+	"ultrafast", "superfast", "veryfast", "faster", "fast",
+	"medium", "slow", "slower", "veryslow", "placebo",
+}
+
+var nvencPresets = []string{
+	"p1", "p2", "p3", "p4", "p5", "p6", "p7",
+	"default", "slow", "medium", "fast", "hp", "hq", "bd", "ll", "llhq", "llhp", "lossless", "losslesshp",
+}
+
+var qsvPresets = []string{"veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+var registry = map[string]*Codec{
+	// Software video encoders
+	"libx264": {
+		Name: "libx264", Kind: KindVideo, Family: "h264",
+		QualityParam: "crf", QualityMin: 0, QualityMax: 51,
+		Presets: x26xPresets, DefaultPreset: "medium",
+		PixelFormats: []string{"yuv420p", "yuvj420p", "yuv422p", "yuvj422p", "yuv444p", "yuvj444p", "nv12", "nv16", "nv21", "yuv420p10le", "yuv422p10le", "yuv444p10le", "nv20le", "gray", "gray10le"},
+		TenBit:       true,
+		ParamsOption: "x264-params",
+		TwoPass:      true,
+		ClosedGOP:    []Param{{"open-gop", "0"}},
+		ForcedIDR:    true,
+		Containers:   []string{"mp4", "m4v", "mov", "mkv", "ts", "flv", "avi", "h264"},
+	},
+	"libx265": {
+		Name: "libx265", Kind: KindVideo, Family: "hevc",
+		QualityParam: "crf", QualityMin: 0, QualityMax: 51,
+		Presets: x26xPresets, DefaultPreset: "medium",
+		PixelFormats: []string{"yuv420p", "yuvj420p", "yuv422p", "yuvj422p", "yuv444p", "yuvj444p", "gbrp", "yuv420p10le", "yuv422p10le", "yuv444p10le", "gbrp10le", "yuv420p12le", "yuv422p12le", "yuv444p12le", "gbrp12le", "gray", "gray10le", "gray12le"},
+		TenBit:       true,
+		ParamsOption: "x265-params",
+		TwoPass:      true,
+		PassParams:   true,
+		ClosedGOP:    []Param{{"open-gop", "0"}},
+		ForcedIDR:    true,
+		Containers:   []string{"mp4", "m4v", "mov", "mkv", "ts", "hevc"},
+	},
+	"libsvtav1": {
+		Name: "libsvtav1", Kind: KindVideo, Family: "av1",
+		QualityParam: "crf", QualityMin: 0, QualityMax: 63,
+		NumericPresets: true, PresetMin: 0, PresetMax: 13, DefaultPreset: "8",
+		PixelFormats: []string{"yuv420p", "yuv420p10le"},
+		TenBit:       true,
+		ParamsOption: "svtav1-params",
+		ClosedGOP:    []Param{{"irefresh-type", "2"}}, // Key frames instead of forward key frames
+		Containers:   []string{"mkv", "mp4", "webm", "ivf"},
+	},
+	"libaom-av1": {
+		Name: "libaom-av1", Kind: KindVideo, Family: "av1",
+		QualityParam: "crf", QualityMin: 0, QualityMax: 63,
+		PixelFormats: []string{"yuv420p", "yuv422p", "yuv444p", "gbrp", "yuv420p10le", "yuv422p10le", "yuv444p10le", "gbrp10le", "yuv420p12le", "yuv422p12le", "yuv444p12le", "gbrp12le", "gray", "gray10le", "gray12le"},
+		TenBit:       true,
+		ParamsOption: "aom-params",
+		TwoPass:      true,
+		Containers:   []string{"mkv", "mp4", "webm", "ivf"},
+	},
+	"libvpx-vp9": {
+		Name: "libvpx-vp9", Kind: KindVideo, Family: "vp9",
+		QualityParam: "crf", QualityMin: 0, QualityMax: 63,
+		PixelFormats: []string{"yuv420p", "yuva420p", "yuv422p", "yuv440p", "yuv444p", "yuv420p10le", "yuv422p10le", "yuv440p10le", "yuv444p10le", "yuv420p12le", "yuv422p12le", "yuv440p12le", "yuv444p12le", "gbrp", "gbrp10le", "gbrp12le"},
+		TenBit:       true,
+		TwoPass:      true,
+		Containers:   []string{"webm", "mkv", "mp4", "ivf"},
+	},
+
+	// Hardware video encoders
+	"h264_nvenc": {
+		Name: "h264_nvenc", Kind: KindVideo, Family: "h264", Hardware: true, ForcedIDR: true,
+		QualityParam: "cq", QualityMin: 0, QualityMax: 51,
+		Presets: nvencPresets, DefaultPreset: "p4",
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "flv", "avi", "h264"},
+	},
+	"hevc_nvenc": {
+		Name: "hevc_nvenc", Kind: KindVideo, Family: "hevc", Hardware: true, ForcedIDR: true,
+		QualityParam: "cq", QualityMin: 0, QualityMax: 51,
+		Presets: nvencPresets, DefaultPreset: "p4",
+		TenBit:     true,
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "hevc"},
+	},
+	"av1_nvenc": {
+		Name: "av1_nvenc", Kind: KindVideo, Family: "av1", Hardware: true, ForcedIDR: true,
+		QualityParam: "cq", QualityMin: 0, QualityMax: 51,
+		Presets: nvencPresets, DefaultPreset: "p4",
+		TenBit:     true,
+		Containers: []string{"mkv", "mp4", "webm", "ivf"},
+	},
+	"h264_vaapi": {
+		Name: "h264_vaapi", Kind: KindVideo, Family: "h264", Hardware: true,
+		QualityParam: "qp", QualityMin: 0, QualityMax: 52,
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "flv", "avi", "h264"},
+	},
+	"hevc_vaapi": {
+		Name: "hevc_vaapi", Kind: KindVideo, Family: "hevc", Hardware: true,
+		QualityParam: "qp", QualityMin: 0, QualityMax: 52,
+		TenBit:     true,
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "hevc"},
+	},
+	"av1_vaapi": {
+		Name: "av1_vaapi", Kind: KindVideo, Family: "av1", Hardware: true,
+		QualityParam: "qp", QualityMin: 0, QualityMax: 255,
+		TenBit:     true,
+		Containers: []string{"mkv", "mp4", "webm", "ivf"},
+	},
+	"h264_qsv": {
+		Name: "h264_qsv", Kind: KindVideo, Family: "h264", Hardware: true,
+		QualityParam: "global_quality", QualityMin: 1, QualityMax: 51,
+		Presets: qsvPresets, DefaultPreset: "medium",
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "flv", "avi", "h264"},
+	},
+	"hevc_qsv": {
+		Name: "hevc_qsv", Kind: KindVideo, Family: "hevc", Hardware: true,
+		QualityParam: "global_quality", QualityMin: 1, QualityMax: 51,
+		Presets: qsvPresets, DefaultPreset: "medium",
+		TenBit:     true,
+		Containers: []string{"mp4", "m4v", "mov", "mkv", "ts", "hevc"},
+	},
+
+	// Audio encoders
+	"libopus": {
+		Name: "libopus", Kind: KindAudio, Family: "opus",
+		SampleRates: []int{48000, 24000, 16000, 12000, 8000},
+		MaxChannels: 8,
+		Containers:  []string{"opus", "ogg", "mkv", "webm"},
+	},
+	"aac": {
+		Name: "aac", Kind: KindAudio, Family: "aac",
+		SampleRates: []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350},
+		MaxChannels: 8,
+		Containers:  []string{"m4a", "aac", "mp4", "m4v", "mov", "mkv", "ts", "flv"},
+	},
+	"libfdk_aac": {
+		Name: "libfdk_aac", Kind: KindAudio, Family: "aac",
+		SampleRates: []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000},
+		MaxChannels: 8,
+		Containers:  []string{"m4a", "aac", "mp4", "m4v", "mov", "mkv", "ts", "flv"},
+	},
+	"libmp3lame": {
+		Name: "libmp3lame", Kind: KindAudio, Family: "mp3",
+		SampleRates: []int{48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000},
+		MaxChannels: 2,
+		Containers:  []string{"mp3", "mkv", "mp4", "mov", "avi", "flv"},
+	},
+	"libvorbis": {
+		Name: "libvorbis", Kind: KindAudio, Family: "vorbis",
+		MaxChannels: 8,
+		Containers:  []string{"ogg", "webm", "mkv"},
+	},
+	"flac": {
+		Name: "flac", Kind: KindAudio, Family: "flac",
+		Lossless:    true,
+		MaxChannels: 8,
+		Containers:  []string{"flac", "ogg", "mkv", "mp4", "mov"},
+	},
+	"ac3": {
+		Name: "ac3", Kind: KindAudio, Family: "ac3",
+		SampleRates: []int{48000, 44100, 32000},
+		MaxChannels: 6,
+		Containers:  []string{"ac3", "mkv", "mp4", "mov", "ts"},
+	},
+	"eac3": {
+		Name: "eac3", Kind: KindAudio, Family: "eac3",
+		SampleRates: []int{48000, 44100, 32000},
+		MaxChannels: 8,
+		Containers:  []string{"eac3", "mkv", "mp4", "mov", "ts"},
+	},
+}
+
+// Lookup returns the registry entry for an ffmpeg encoder name.
+func Lookup(name string) (*Codec, bool) {
+	c, ok := registry[name]
+	return c, ok
+}
+
+// Names returns the registered encoder names of the given kind in sorted order.
+// An empty kind returns all encoders.
+func Names(kind Kind) []string {
+	var names []string
+	for name, c := range registry {
+		if kind == "" || c.Kind == kind {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QualityRange returns the valid constant-quality range for an encoder,
+// falling back to 0-51 for unknown encoders.
+func QualityRange(name string) (int, int) {
+	if c, ok := Lookup(name); ok && c.QualityParam != "" {
+		return c.QualityMin, c.QualityMax
+	}
+	return DefaultQualityMin, DefaultQualityMax
+}
+
+// ValidateQuality checks a CRF/QP value against the encoder's range.
+func (c *Codec) ValidateQuality(value int) error {
+	if c.QualityParam == "" {
+		return fmt.Errorf("%s does not support constant quality mode", c.Name)
+	}
+	if value < c.QualityMin || value > c.QualityMax {
+		return fmt.Errorf("%s %s must be between %d and %d, got %d",
+			c.Name, strings.ToUpper(c.QualityParam), c.QualityMin, c.QualityMax, value)
+	}
+	return nil
+}
+
+// HasPresets reports whether the encoder has a -preset option.
+func (c *Codec) HasPresets() bool {
+	return c.NumericPresets || len(c.Presets) > 0
+}
+
+// ValidatePreset checks a preset name or number. Encoders without a -preset
+// option accept any value; it is not passed on for them.
+func (c *Codec) ValidatePreset(preset string) error {
+	if !c.HasPresets() {
+		return nil
+	}
+
+	if c.NumericPresets {
+		n, err := strconv.Atoi(preset)
+		if err != nil || n < c.PresetMin || n > c.PresetMax {
+			return fmt.Errorf("%s preset must be a number between %d and %d, got %q",
+				c.Name, c.PresetMin, c.PresetMax, preset)
+		}
+		return nil
+	}
+
+	if !containsString(c.Presets, preset) {
+		return fmt.Errorf("%s preset must be one of %s, got %q",
+			c.Name, strings.Join(c.Presets, ", "), preset)
+	}
+	return nil
+}
+
+// ValidatePixelFormat checks a -pix_fmt value.
+func (c *Codec) ValidatePixelFormat(pixfmt string) error {
+	if len(c.PixelFormats) == 0 || containsString(c.PixelFormats, pixfmt) {
+		return nil
+	}
+	return fmt.Errorf("%s does not support pixel format %q (supported: %s)",
+		c.Name, pixfmt, strings.Join(c.PixelFormats, ", "))
+}
+
+// ValidateSampleRate checks an audio sample rate in Hz.
+func (c *Codec) ValidateSampleRate(rate int) error {
+	if len(c.SampleRates) == 0 {
+		return nil
+	}
+	for _, r := range c.SampleRates {
+		if r == rate {
+			return nil
+		}
+	}
+	rates := make([]string, len(c.SampleRates))
+	for i, r := range c.SampleRates {
+		rates[i] = strconv.Itoa(r)
+	}
+	return fmt.Errorf("%s does not support sample rate %d Hz (supported: %s)",
+		c.Name, rate, strings.Join(rates, ", "))
+}
+
+// ValidateChannels checks an audio channel count.
+func (c *Codec) ValidateChannels(channels int) error {
+	if c.MaxChannels > 0 && channels > c.MaxChannels {
+		return fmt.Errorf("%s supports at most %d channels, got %d", c.Name, c.MaxChannels, channels)
+	}
+	return nil
+}
+
+// ValidateContainer checks that the encoder can be muxed into the file's
+// container, judged by its extension. Unknown extensions are not checked.
+func (c *Codec) ValidateContainer(path string) error {
+	ext := ContainerOf(path)
+	if ext == "" || !IsKnownContainer(ext) || containsString(c.Containers, ext) {
+		return nil
+	}
+	if alternatives := c.encodersFor(ext); len(alternatives) > 0 {
+		return fmt.Errorf("%s cannot be stored in .%s files (use one of: .%s, or encode with %s)",
+			c.Name, ext, strings.Join(c.Containers, ", ."), strings.Join(alternatives, ", "))
+	}
+	return fmt.Errorf("%s cannot be stored in .%s files (use one of: .%s)",
+		c.Name, ext, strings.Join(c.Containers, ", ."))
+}
+
+// encodersFor lists software encoders of the same kind that can be stored in
+// the container, e.g. aac for Opus audio in .mp4. At most three are returned.
+func (c *Codec) encodersFor(ext string) []string {
+	var names []string
+	for _, other := range registry {
+		if other.Kind == c.Kind && !other.Hardware && containsString(other.Containers, ext) {
+			names = append(names, other.Name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 3 {
+		names = names[:3]
+	}
+	return names
+}
+
+// ContainerOf returns the lower-case extension of path without the dot.
+func ContainerOf(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// IsKnownContainer reports whether any registered encoder lists the extension.
+func IsKnownContainer(ext string) bool {
+	for _, c := range registry {
+		if containsString(c.Containers, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
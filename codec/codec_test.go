@@ -0,0 +1,215 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	c, ok := Lookup("libsvtav1")
+	if !ok {
+		t.Fatal("Expected libsvtav1 in registry")
+	}
+	if c.Kind != KindVideo || c.Family != "av1" {
+		t.Errorf("Unexpected entry: %+v", c)
+	}
+
+	if _, ok := Lookup("not-a-codec"); ok {
+		t.Error("Expected unknown codec to be missing")
+	}
+}
+
+func TestQualityRange(t *testing.T) {
+	tests := []struct {
+		codec    string
+		min, max int
+	}{
+		{"libx264", 0, 51},
+		{"libx265", 0, 51},
+		{"libsvtav1", 0, 63},
+		{"libaom-av1", 0, 63},
+		{"libvpx-vp9", 0, 63},
+		{"unknown", 0, 51},
+		{"libopus", 0, 51}, // no quality mode, generic fallback
+	}
+
+	for _, tt := range tests {
+		min, max := QualityRange(tt.codec)
+		if min != tt.min || max != tt.max {
+			t.Errorf("QualityRange(%s) = %d-%d, want %d-%d", tt.codec, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+func TestValidateQuality(t *testing.T) {
+	c, _ := Lookup("libsvtav1")
+	if err := c.ValidateQuality(63); err != nil {
+		t.Errorf("Expected CRF 63 to be valid for SVT-AV1: %v", err)
+	}
+
+	err := c.ValidateQuality(64)
+	if err == nil || !strings.Contains(err.Error(), "libsvtav1 CRF must be between 0 and 63, got 64") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	opus, _ := Lookup("libopus")
+	if err := opus.ValidateQuality(10); err == nil {
+		t.Error("Expected error for quality on an audio codec")
+	}
+}
+
+func TestValidatePreset(t *testing.T) {
+	tests := []struct {
+		codec   string
+		preset  string
+		wantErr bool
+	}{
+		{"libx264", "veryslow", false},
+		{"libx264", "8", true},
+		{"libx265", "placebo", false},
+		{"libsvtav1", "0", false},
+		{"libsvtav1", "13", false},
+		{"libsvtav1", "14", true},
+		{"libsvtav1", "slow", true},
+		{"h264_nvenc", "p4", false},
+		{"h264_nvenc", "placebo", true},
+		{"libaom-av1", "anything", false}, // no -preset option
+	}
+
+	for _, tt := range tests {
+		c, _ := Lookup(tt.codec)
+		err := c.ValidatePreset(tt.preset)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s.ValidatePreset(%q) error = %v, wantErr %v", tt.codec, tt.preset, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePixelFormat(t *testing.T) {
+	c, _ := Lookup("libsvtav1")
+	if err := c.ValidatePixelFormat("yuv420p10le"); err != nil {
+		t.Errorf("Expected yuv420p10le to be valid: %v", err)
+	}
+	if err := c.ValidatePixelFormat("yuv444p"); err == nil {
+		t.Error("Expected yuv444p to be rejected for SVT-AV1")
+	}
+}
+
+func TestValidateSampleRateAndChannels(t *testing.T) {
+	opus, _ := Lookup("libopus")
+	if err := opus.ValidateSampleRate(48000); err != nil {
+		t.Errorf("Expected 48000 to be valid: %v", err)
+	}
+	if err := opus.ValidateSampleRate(44100); err == nil {
+		t.Error("Expected 44100 to be rejected for Opus")
+	}
+
+	flac, _ := Lookup("flac")
+	if err := flac.ValidateSampleRate(44100); err != nil {
+		t.Errorf("Expected any sample rate for FLAC: %v", err)
+	}
+
+	mp3, _ := Lookup("libmp3lame")
+	if err := mp3.ValidateChannels(6); err == nil {
+		t.Error("Expected 6 channels to be rejected for MP3")
+	}
+}
+
+func TestValidateContainer(t *testing.T) {
+	tests := []struct {
+		codec   string
+		path    string
+		wantErr bool
+	}{
+		{"libsvtav1", "out.mkv", false},
+		{"libsvtav1", "out.webm", false},
+		{"libsvtav1", "out.avi", true},
+		{"libx264", "out.webm", true},
+		{"libopus", "out.MP4", true}, // Opus in MP4 is not widely playable; use AAC
+		{"libopus", "out.webm", false},
+		{"aac", "out.webm", true},
+		{"aac", "out.xyz", false}, // unknown extension is not checked
+		{"aac", "out", false},
+	}
+
+	for _, tt := range tests {
+		c, _ := Lookup(tt.codec)
+		err := c.ValidateContainer(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s.ValidateContainer(%q) error = %v, wantErr %v", tt.codec, tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateContainer_SuggestsEncoder(t *testing.T) {
+	opus, _ := Lookup("libopus")
+	err := opus.ValidateContainer("out.mp4")
+	if err == nil || !strings.Contains(err.Error(), "aac") {
+		t.Errorf("Expected error suggesting aac for Opus in .mp4, got %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, name := range Names(KindAudio) {
+		c, _ := Lookup(name)
+		if c.Kind != KindAudio {
+			t.Errorf("Names(KindAudio) returned video codec %s", name)
+		}
+	}
+	if len(Names("")) <= len(Names(KindVideo)) {
+		t.Error("Expected Names(\"\") to include all codecs")
+	}
+}
+
+func TestLookupContainer(t *testing.T) {
+	tests := []struct {
+		path      string
+		subtitle  string
+		fastStart bool
+	}{
+		{"out.mkv", "copy", false},
+		{"out.webm", "webvtt", false},
+		{"out.MP4", "mov_text", true},
+		{"out.mov", "mov_text", true},
+		{"out.m4a", "", true},
+		{"out.ts", "", false},
+		{"out", "", false},
+	}
+
+	for _, tt := range tests {
+		c := LookupContainer(tt.path)
+		if c.SubtitleCodec != tt.subtitle || c.FastStart != tt.fastStart {
+			t.Errorf("LookupContainer(%q) = %+v, want subtitles %q, faststart %v", tt.path, c, tt.subtitle, tt.fastStart)
+		}
+	}
+}
+
+func TestIntermediateContainer(t *testing.T) {
+	tests := map[string]string{
+		"libsvtav1":  "mkv",
+		"libx264":    "mkv",
+		"h264_nvenc": "mkv",
+		"libopus":    "opus",
+		"aac":        "m4a",
+		"libvorbis":  "ogg",
+		"flac":       "flac",
+		"unknown":    "mkv",
+	}
+	for name, want := range tests {
+		if got := IntermediateContainer(name); got != want {
+			t.Errorf("IntermediateContainer(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestKnownContainers(t *testing.T) {
+	exts := KnownContainers()
+	for _, want := range []string{"mkv", "mp4", "webm", "m4a", "opus"} {
+		if !containsString(exts, want) {
+			t.Errorf("KnownContainers() = %v, missing %s", exts, want)
+		}
+	}
+	if containsString(exts, "xyz") {
+		t.Errorf("KnownContainers() = %v, includes xyz", exts)
+	}
+}
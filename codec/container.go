@@ -0,0 +1,61 @@
+package codec
+
+import "sort"
+
+// Container describes what an output container needs beyond the codecs it
+// accepts (see Codec.Containers).
+type Container struct {
+	Ext           string // File extension without the dot
+	SubtitleCodec string // Codec text subtitles are converted to; "copy" keeps any subtitle as is, "" = none
+	FastStart     bool   // MP4-style index that should be moved to the front (-movflags +faststart)
+}
+
+// containers lists the containers with subtitle or index handling; other
+// extensions carry neither.
+var containers = map[string]*Container{
+	"mkv":  {Ext: "mkv", SubtitleCodec: "copy"},
+	"webm": {Ext: "webm", SubtitleCodec: "webvtt"},
+	"mp4":  {Ext: "mp4", SubtitleCodec: "mov_text", FastStart: true},
+	"m4v":  {Ext: "m4v", SubtitleCodec: "mov_text", FastStart: true},
+	"mov":  {Ext: "mov", SubtitleCodec: "mov_text", FastStart: true},
+	"m4a":  {Ext: "m4a", FastStart: true},
+}
+
+// LookupContainer returns the container of path, judged by its extension.
+func LookupContainer(path string) *Container {
+	ext := ContainerOf(path)
+	if c, ok := containers[ext]; ok {
+		return c
+	}
+	return &Container{Ext: ext}
+}
+
+// KnownContainers returns the extensions of every container a registered
+// encoder can be stored in, sorted.
+func KnownContainers() []string {
+	var exts []string
+	for _, c := range registry {
+		for _, ext := range c.Containers {
+			if !containsString(exts, ext) {
+				exts = append(exts, ext)
+			}
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// IntermediateContainer returns the extension of the intermediate files
+// (chunks and joined streams) an encoder writes. Video goes to Matroska,
+// which takes every registered video codec and keeps exact timestamps;
+// audio goes to the codec's own container. Unknown encoders use Matroska.
+func IntermediateContainer(name string) string {
+	c, ok := Lookup(name)
+	if !ok || len(c.Containers) == 0 {
+		return "mkv"
+	}
+	if c.Kind == KindVideo && containsString(c.Containers, "mkv") {
+		return "mkv"
+	}
+	return c.Containers[0]
+}
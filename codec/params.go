@@ -0,0 +1,274 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Param is one key=value entry of an encoder's params option
+// (-x264-params, -x265-params, -svtav1-params).
+type Param struct {
+	Key   string
+	Value string
+}
+
+// EncoderParams holds typed tuning options for each software encoder. Only
+// the block of the encoder in use is applied. Zero values leave the
+// encoder's own default in place.
+//
+//	encoder_params:
+//	  x265: { tune: grain, keyint: 240, aq_mode: 3, psy_rd: 2.0 }
+//	  svtav1: { film_grain: 8, lp: 4, pin: 1 }
+type EncoderParams struct {
+	X264   X264Params   `yaml:"x264"`
+	X265   X265Params   `yaml:"x265"`
+	SVTAV1 SVTAV1Params `yaml:"svtav1"`
+	AOM    AOMParams    `yaml:"aom"`
+	VP9    VP9Params    `yaml:"vp9"`
+}
+
+// X264Params tunes libx264.
+type X264Params struct {
+	Tune       string  `yaml:"tune"`        // film, animation, grain, stillimage, fastdecode, zerolatency, psnr, ssim
+	Keyint     int     `yaml:"keyint"`      // Maximum GOP length in frames
+	MinKeyint  int     `yaml:"min_keyint"`  // Minimum GOP length in frames
+	Lookahead  int     `yaml:"lookahead"`   // Rate control lookahead in frames (rc-lookahead)
+	AQMode     *int    `yaml:"aq_mode"`     // Adaptive quantization: 0 (off) to 3
+	AQStrength float64 `yaml:"aq_strength"` // Adaptive quantization strength, e.g. 0.8
+	PsyRD      float64 `yaml:"psy_rd"`      // Psychovisual rate-distortion strength, e.g. 1.0
+	PsyTrellis float64 `yaml:"psy_trellis"` // Psychovisual trellis strength, e.g. 0.15
+	Threads    int     `yaml:"threads"`     // Encoder threads (0 = auto)
+	TenBit     bool    `yaml:"ten_bit"`     // Encode 10-bit (yuv420p10le)
+}
+
+// X265Params tunes libx265.
+type X265Params struct {
+	Tune         string  `yaml:"tune"`          // grain, animation, fastdecode, zerolatency, psnr, ssim
+	Keyint       int     `yaml:"keyint"`        // Maximum GOP length in frames
+	MinKeyint    int     `yaml:"min_keyint"`    // Minimum GOP length in frames
+	Lookahead    int     `yaml:"lookahead"`     // Rate control lookahead in frames (rc-lookahead)
+	AQMode       *int    `yaml:"aq_mode"`       // Adaptive quantization: 0 (off) to 4
+	AQStrength   float64 `yaml:"aq_strength"`   // Adaptive quantization strength, e.g. 1.0
+	PsyRD        float64 `yaml:"psy_rd"`        // Psychovisual rate-distortion strength, e.g. 2.0
+	PsyRDOQ      float64 `yaml:"psy_rdoq"`      // Psychovisual RDOQ strength, e.g. 1.0
+	FrameThreads int     `yaml:"frame_threads"` // Concurrently encoded frames (0 = auto)
+	TenBit       bool    `yaml:"ten_bit"`       // Encode 10-bit (yuv420p10le)
+}
+
+// SVTAV1Params tunes libsvtav1.
+type SVTAV1Params struct {
+	Tune      *int `yaml:"tune"`       // 0 (visual quality), 1 (PSNR) or 2 (SSIM)
+	Keyint    int  `yaml:"keyint"`     // Maximum GOP length in frames
+	Lookahead int  `yaml:"lookahead"`  // Lookahead in frames (0-120)
+	FilmGrain int  `yaml:"film_grain"` // Film grain synthesis strength (0 = off, up to 50)
+	AQMode    *int `yaml:"aq_mode"`    // Adaptive quantization: 0 (off) to 2
+	LP        int  `yaml:"lp"`         // Level of parallelism (0 = auto)
+	Pin       int  `yaml:"pin"`        // Pin threads to cores (0 = off, 1 = on)
+	TenBit    bool `yaml:"ten_bit"`    // Encode 10-bit (yuv420p10le)
+}
+
+// AOMParams tunes libaom-av1.
+type AOMParams struct {
+	Tune      string `yaml:"tune"`       // psnr or ssim
+	Keyint    int    `yaml:"keyint"`     // Maximum GOP length in frames
+	Lookahead int    `yaml:"lookahead"`  // Frames to look ahead (lag-in-frames, up to 70)
+	FilmGrain int    `yaml:"film_grain"` // Film grain denoise/synthesis level (0 = off, up to 50)
+	AQMode    *int   `yaml:"aq_mode"`    // Adaptive quantization: 0 (off) to 3
+	Threads   int    `yaml:"threads"`    // Encoder threads (0 = auto)
+	TenBit    bool   `yaml:"ten_bit"`    // Encode 10-bit (yuv420p10le)
+}
+
+// VP9Params tunes libvpx-vp9.
+type VP9Params struct {
+	Tune      string `yaml:"tune"`      // Content type: default, screen or film
+	Keyint    int    `yaml:"keyint"`    // Maximum GOP length in frames
+	Lookahead int    `yaml:"lookahead"` // Frames to look ahead (lag-in-frames, up to 25)
+	AQMode    *int   `yaml:"aq_mode"`   // Adaptive quantization: 0 (off) to 4
+	Threads   int    `yaml:"threads"`   // Encoder threads (0 = auto)
+	TenBit    bool   `yaml:"ten_bit"`   // Encode 10-bit (yuv420p10le)
+}
+
+var (
+	x264Tunes = []string{"film", "animation", "grain", "stillimage", "fastdecode", "zerolatency", "psnr", "ssim"}
+	x265Tunes = []string{"grain", "animation", "fastdecode", "zerolatency", "psnr", "ssim"}
+	aomTunes  = []string{"psnr", "ssim"}
+	vp9Tunes  = []string{"default", "screen", "film"}
+)
+
+// Options returns the tuning of encoder: the entries of its params option
+// and plain ffmpeg options. Encoders without a block return nothing.
+func (p *EncoderParams) Options(encoder string) (params []Param, args []string) {
+	switch encoder {
+	case "libx264":
+		x := p.X264
+		if x.Tune != "" {
+			args = append(args, "-tune", x.Tune)
+		}
+		params = appendInt(params, "keyint", x.Keyint)
+		params = appendInt(params, "min-keyint", x.MinKeyint)
+		params = appendInt(params, "rc-lookahead", x.Lookahead)
+		params = appendIntPtr(params, "aq-mode", x.AQMode)
+		params = appendFloat(params, "aq-strength", x.AQStrength)
+		if x.PsyRD > 0 || x.PsyTrellis > 0 {
+			// x264 takes both strengths in one option
+			params = append(params, Param{"psy-rd", formatFloat(x.PsyRD) + "," + formatFloat(x.PsyTrellis)})
+		}
+		params = appendInt(params, "threads", x.Threads)
+
+	case "libx265":
+		x := p.X265
+		if x.Tune != "" {
+			args = append(args, "-tune", x.Tune)
+		}
+		params = appendInt(params, "keyint", x.Keyint)
+		params = appendInt(params, "min-keyint", x.MinKeyint)
+		params = appendInt(params, "rc-lookahead", x.Lookahead)
+		params = appendIntPtr(params, "aq-mode", x.AQMode)
+		params = appendFloat(params, "aq-strength", x.AQStrength)
+		params = appendFloat(params, "psy-rd", x.PsyRD)
+		params = appendFloat(params, "psy-rdoq", x.PsyRDOQ)
+		params = appendInt(params, "frame-threads", x.FrameThreads)
+
+	case "libsvtav1":
+		s := p.SVTAV1
+		params = appendIntPtr(params, "tune", s.Tune)
+		params = appendInt(params, "keyint", s.Keyint)
+		params = appendInt(params, "lookahead", s.Lookahead)
+		params = appendInt(params, "film-grain", s.FilmGrain)
+		params = appendIntPtr(params, "aq-mode", s.AQMode)
+		params = appendInt(params, "lp", s.LP)
+		params = appendInt(params, "pin", s.Pin)
+
+	case "libaom-av1":
+		a := p.AOM
+		if a.Tune != "" {
+			args = append(args, "-tune", a.Tune)
+		}
+		args = appendArg(args, "-g", a.Keyint)
+		args = appendArg(args, "-lag-in-frames", a.Lookahead)
+		args = appendArg(args, "-denoise-noise-level", a.FilmGrain)
+		if a.AQMode != nil {
+			args = append(args, "-aq-mode", strconv.Itoa(*a.AQMode))
+		}
+		args = appendArg(args, "-threads", a.Threads)
+
+	case "libvpx-vp9":
+		v := p.VP9
+		if v.Tune != "" {
+			args = append(args, "-tune-content", v.Tune)
+		}
+		args = appendArg(args, "-g", v.Keyint)
+		args = appendArg(args, "-lag-in-frames", v.Lookahead)
+		if v.AQMode != nil {
+			args = append(args, "-aq-mode", strconv.Itoa(*v.AQMode))
+		}
+		args = appendArg(args, "-threads", v.Threads)
+	}
+	return params, args
+}
+
+// TenBit reports whether encoder is set to encode 10-bit video.
+func (p *EncoderParams) TenBit(encoder string) bool {
+	switch encoder {
+	case "libx264":
+		return p.X264.TenBit
+	case "libx265":
+		return p.X265.TenBit
+	case "libsvtav1":
+		return p.SVTAV1.TenBit
+	case "libaom-av1":
+		return p.AOM.TenBit
+	case "libvpx-vp9":
+		return p.VP9.TenBit
+	}
+	return false
+}
+
+// Validate checks the options of every encoder block.
+func (p *EncoderParams) Validate() error {
+	var errors []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errors = append(errors, fmt.Sprintf(format, args...))
+		}
+	}
+
+	x264 := p.X264
+	check(x264.Tune == "" || containsString(x264Tunes, x264.Tune), "x264 tune must be one of: %s", strings.Join(x264Tunes, ", "))
+	check(x264.Keyint >= 0 && x264.MinKeyint >= 0 && x264.Lookahead >= 0 && x264.Threads >= 0, "x264 keyint, min_keyint, lookahead and threads cannot be negative")
+	check(x264.Keyint == 0 || x264.MinKeyint <= x264.Keyint, "x264 min_keyint cannot exceed keyint")
+	check(inRange(x264.AQMode, 0, 3), "x264 aq_mode must be between 0 and 3")
+	check(x264.AQStrength >= 0 && x264.PsyRD >= 0 && x264.PsyTrellis >= 0, "x264 aq_strength, psy_rd and psy_trellis cannot be negative")
+
+	x265 := p.X265
+	check(x265.Tune == "" || containsString(x265Tunes, x265.Tune), "x265 tune must be one of: %s", strings.Join(x265Tunes, ", "))
+	check(x265.Keyint >= 0 && x265.MinKeyint >= 0 && x265.Lookahead >= 0 && x265.FrameThreads >= 0, "x265 keyint, min_keyint, lookahead and frame_threads cannot be negative")
+	check(x265.Keyint == 0 || x265.MinKeyint <= x265.Keyint, "x265 min_keyint cannot exceed keyint")
+	check(x265.Lookahead <= 250, "x265 lookahead must be at most 250")
+	check(inRange(x265.AQMode, 0, 4), "x265 aq_mode must be between 0 and 4")
+	check(x265.AQStrength >= 0 && x265.PsyRD >= 0 && x265.PsyRDOQ >= 0, "x265 aq_strength, psy_rd and psy_rdoq cannot be negative")
+
+	svt := p.SVTAV1
+	check(inRange(svt.Tune, 0, 2), "svtav1 tune must be 0 (visual quality), 1 (PSNR) or 2 (SSIM)")
+	check(svt.Keyint >= 0 && svt.LP >= 0, "svtav1 keyint and lp cannot be negative")
+	check(svt.Lookahead >= 0 && svt.Lookahead <= 120, "svtav1 lookahead must be between 0 and 120")
+	check(svt.FilmGrain >= 0 && svt.FilmGrain <= 50, "svtav1 film_grain must be between 0 and 50")
+	check(inRange(svt.AQMode, 0, 2), "svtav1 aq_mode must be between 0 and 2")
+	check(svt.Pin == 0 || svt.Pin == 1, "svtav1 pin must be 0 or 1")
+
+	aom := p.AOM
+	check(aom.Tune == "" || containsString(aomTunes, aom.Tune), "aom tune must be one of: %s", strings.Join(aomTunes, ", "))
+	check(aom.Keyint >= 0 && aom.Threads >= 0, "aom keyint and threads cannot be negative")
+	check(aom.Lookahead >= 0 && aom.Lookahead <= 70, "aom lookahead must be between 0 and 70")
+	check(aom.FilmGrain >= 0 && aom.FilmGrain <= 50, "aom film_grain must be between 0 and 50")
+	check(inRange(aom.AQMode, 0, 3), "aom aq_mode must be between 0 and 3")
+
+	vp9 := p.VP9
+	check(vp9.Tune == "" || containsString(vp9Tunes, vp9.Tune), "vp9 tune must be one of: %s", strings.Join(vp9Tunes, ", "))
+	check(vp9.Keyint >= 0 && vp9.Threads >= 0, "vp9 keyint and threads cannot be negative")
+	check(vp9.Lookahead >= 0 && vp9.Lookahead <= 25, "vp9 lookahead must be between 0 and 25")
+	check(inRange(vp9.AQMode, 0, 4), "vp9 aq_mode must be between 0 and 4")
+
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid encoder params: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// inRange reports whether an optional value is unset or within [lo, hi].
+func inRange(value *int, lo, hi int) bool {
+	return value == nil || (*value >= lo && *value <= hi)
+}
+
+func appendInt(params []Param, key string, value int) []Param {
+	if value == 0 {
+		return params
+	}
+	return append(params, Param{key, strconv.Itoa(value)})
+}
+
+func appendIntPtr(params []Param, key string, value *int) []Param {
+	if value == nil {
+		return params
+	}
+	return append(params, Param{key, strconv.Itoa(*value)})
+}
+
+func appendFloat(params []Param, key string, value float64) []Param {
+	if value == 0 {
+		return params
+	}
+	return append(params, Param{key, formatFloat(value)})
+}
+
+func appendArg(args []string, option string, value int) []string {
+	if value == 0 {
+		return args
+	}
+	return append(args, option, strconv.Itoa(value))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
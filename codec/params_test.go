@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestEncoderParams_Options(t *testing.T) {
+	p := EncoderParams{
+		X264:   X264Params{Tune: "film", Keyint: 240, AQMode: intPtr(0), PsyRD: 1, PsyTrellis: 0.15},
+		X265:   X265Params{Tune: "grain", Keyint: 240, MinKeyint: 24, AQMode: intPtr(3), PsyRD: 2, FrameThreads: 2},
+		SVTAV1: SVTAV1Params{Tune: intPtr(0), FilmGrain: 8, LP: 4, Pin: 1},
+		AOM:    AOMParams{Tune: "ssim", Keyint: 240, Lookahead: 48, FilmGrain: 10},
+		VP9:    VP9Params{Tune: "film", Lookahead: 25, AQMode: intPtr(2)},
+	}
+
+	tests := []struct {
+		encoder    string
+		wantParams []Param
+		wantArgs   []string
+	}{
+		{"libx264",
+			[]Param{{"keyint", "240"}, {"aq-mode", "0"}, {"psy-rd", "1,0.15"}},
+			[]string{"-tune", "film"}},
+		{"libx265",
+			[]Param{{"keyint", "240"}, {"min-keyint", "24"}, {"aq-mode", "3"}, {"psy-rd", "2"}, {"frame-threads", "2"}},
+			[]string{"-tune", "grain"}},
+		{"libsvtav1",
+			[]Param{{"tune", "0"}, {"film-grain", "8"}, {"lp", "4"}, {"pin", "1"}},
+			nil},
+		{"libaom-av1",
+			nil,
+			[]string{"-tune", "ssim", "-g", "240", "-lag-in-frames", "48", "-denoise-noise-level", "10"}},
+		{"libvpx-vp9",
+			nil,
+			[]string{"-tune-content", "film", "-lag-in-frames", "25", "-aq-mode", "2"}},
+		{"h264_nvenc", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoder, func(t *testing.T) {
+			params, args := p.Options(tt.encoder)
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestEncoderParams_TenBit(t *testing.T) {
+	p := EncoderParams{X265: X265Params{TenBit: true}}
+	if !p.TenBit("libx265") || p.TenBit("libx264") || p.TenBit("h264_nvenc") {
+		t.Error("Expected ten_bit to apply to the x265 block only")
+	}
+}
+
+func TestEncoderParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  EncoderParams
+		wantErr string
+	}{
+		{"empty", EncoderParams{}, ""},
+		{"valid", EncoderParams{X264: X264Params{Tune: "animation", Keyint: 250, MinKeyint: 25, AQMode: intPtr(3)}}, ""},
+		{"bad x264 tune", EncoderParams{X264: X264Params{Tune: "cinema"}}, "x264 tune must be one of"},
+		{"min keyint above keyint", EncoderParams{X265: X265Params{Keyint: 24, MinKeyint: 48}}, "x265 min_keyint cannot exceed keyint"},
+		{"x265 aq mode", EncoderParams{X265: X265Params{AQMode: intPtr(5)}}, "x265 aq_mode must be between 0 and 4"},
+		{"svt tune", EncoderParams{SVTAV1: SVTAV1Params{Tune: intPtr(3)}}, "svtav1 tune must be"},
+		{"svt film grain", EncoderParams{SVTAV1: SVTAV1Params{FilmGrain: 60}}, "svtav1 film_grain must be between 0 and 50"},
+		{"svt pin", EncoderParams{SVTAV1: SVTAV1Params{Pin: 2}}, "svtav1 pin must be 0 or 1"},
+		{"aom lookahead", EncoderParams{AOM: AOMParams{Lookahead: 100}}, "aom lookahead must be between 0 and 70"},
+		{"vp9 tune", EncoderParams{VP9: VP9Params{Tune: "grain"}}, "vp9 tune must be one of"},
+		{"negative threads", EncoderParams{X264: X264Params{Threads: -1}}, "cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,16 +1,29 @@
 package audio
 
 import (
+	"bytes"
+	"context"
+	"encoder/codec"
 	"encoder/command"
 	"encoder/ffmpeg"
 	"encoder/internal/timeutil"
+	"encoder/logging"
 	"encoder/models"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
 	"strings"
 )
 
+// NormalizationFilters is the chain applied to every audio chunk:
+// downmix to stereo, normalize, boost midrange.
+var NormalizationFilters = []string{
+	"pan=stereo|FL<FC+0.30*FL+0.30*BL|FR<FC+0.30*FR+0.30*BR", // Downmix to stereo with center channel
+	"loudnorm=I=-16:TP=-1.5:LRA=11",                          // Normalize audio (EBU R128)
+	"equalizer=f=1000:width_type=h:width=2:g=3",              // Boost midrange at 1kHz, +3dB
+}
+
 // AudioBuilder implements AudioCommand for building FFmpeg audio encoding commands.
 type AudioBuilder struct {
 	chunk            *models.Chunk
@@ -20,8 +33,10 @@ type AudioBuilder struct {
 	sampleRate       int
 	channels         int
 	filters          []string
-	priority         int // Priority for task scheduling
+	sampleAccurate   bool // Cut at exact sample positions (see SetSampleAccurate)
+	priority         int  // Priority for task scheduling
 	progressCallback models.ProgressCallback
+	log              *slog.Logger
 }
 
 // NewAudioBuilder creates a new AudioBuilder for the given chunk and output path.
@@ -32,6 +47,7 @@ func NewAudioBuilder(chunk *models.Chunk, outputPath string) *AudioBuilder {
 		codec:      "libopus",              // Default codec
 		bitrate:    "128k",                 // Default bitrate
 		priority:   command.PriorityNormal, // Default priority
+		log:        logging.Discard(),
 	}
 }
 
@@ -78,18 +94,25 @@ func (a *AudioBuilder) BuildArgs() []string {
 	// Otherwise fall back to source path with seeking
 	inputPath := a.chunk.SourcePath
 	useSegment := false
-	if a.chunk.SegmentPath != "" {
+	if a.chunk.SegmentPath != "" && !a.sampleAccurate {
 		inputPath = a.chunk.SegmentPath
 		useSegment = true
 	}
 
 	args := []string{
 		"-progress", "pipe:2", // Output progress to stderr in key=value format
-		"-i", inputPath,
 	}
 
+	// Sample-accurate chunks seek the input and cut in the filter chain
+	if a.sampleAccurate {
+		if seek := a.seekSeconds(); seek > 0 {
+			args = append(args, "-ss", timeutil.FormatSeconds(seek))
+		}
+	}
+	args = append(args, "-i", inputPath)
+
 	// Only add seeking if not using pre-split segment
-	if !useSegment {
+	if !useSegment && !a.sampleAccurate {
 		args = append(args,
 			"-ss", timeutil.FormatSeconds(a.chunk.StartTime),
 			"-to", timeutil.FormatSeconds(a.chunk.EndTime),
@@ -103,55 +126,101 @@ func (a *AudioBuilder) BuildArgs() []string {
 		"-nostats", // Disable stats (we use -progress instead)
 	)
 
-	// Build audio filter chain: downmix to stereo, normalize, boost midrange
-	filterChain := []string{
-		"pan=stereo|FL<FC+0.30*FL+0.30*BL|FR<FC+0.30*FR+0.30*BR", // Downmix to stereo with center channel
-		"loudnorm=I=-16:TP=-1.5:LRA=11",                          // Normalize audio (EBU R128)
-		"equalizer=f=1000:width_type=h:width=2:g=3",              // Boost midrange at 1kHz, +3dB
-	}
-
-	// Append any additional user filters
-	if len(a.filters) > 0 {
-		filterChain = append(filterChain, a.filters...)
-	}
-
 	// Add filter chain
-	args = append(args, "-af", strings.Join(filterChain, ","))
+	args = append(args, "-af", a.filterChain())
 
 	// Force stereo output
 	args = append(args, "-ac", "2")
 
-	// Add codec and bitrate
-	args = append(args, "-c:a", a.codec, "-b:a", a.bitrate)
+	// Add codec and bitrate (lossless codecs take no bitrate)
+	args = append(args, "-c:a", a.codec)
+	if c, ok := codec.Lookup(a.codec); !ok || !c.Lossless {
+		args = append(args, "-b:a", a.bitrate)
+	}
 
 	// Add sample rate if specified
-	if a.sampleRate > 0 {
-		args = append(args, "-ar", fmt.Sprintf("%d", a.sampleRate))
+	if rate := a.outputSampleRate(); rate > 0 {
+		args = append(args, "-ar", fmt.Sprintf("%d", rate))
 	}
 
 	args = append(args, "-y", a.outputPath)
 	return args
 }
 
+// filterChain returns the sample-accurate cut (if enabled), the
+// normalization chain and any user filters.
+func (a *AudioBuilder) filterChain() string {
+	filterChain := append(a.trimFilters(), NormalizationFilters...)
+	filterChain = append(filterChain, a.filters...)
+	return strings.Join(filterChain, ",")
+}
+
+// RequiredFilters returns the ffmpeg filters the command uses.
+func (a *AudioBuilder) RequiredFilters() []string {
+	return command.FilterNames(a.filterChain())
+}
+
+// Validate checks the codec, sample rate and channel count against the codec
+// registry. Codecs that are not in the registry are not checked.
+func (a *AudioBuilder) Validate() error {
+	return validateSettings(a.codec, a.sampleRate, a.channels)
+}
+
+// validateSettings checks an audio encoder and its sample rate and channel
+// count (0 = not set) against the codec registry.
+func validateSettings(name string, sampleRate, channels int) error {
+	c, ok := codec.Lookup(name)
+	if !ok {
+		return nil
+	}
+	if c.Kind != codec.KindAudio {
+		return fmt.Errorf("%s is not an audio encoder", c.Name)
+	}
+
+	var errors []string
+	if sampleRate > 0 {
+		if err := c.ValidateSampleRate(sampleRate); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if channels > 0 {
+		if err := c.ValidateChannels(channels); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid audio settings: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
 // Run executes the FFmpeg command.
 func (a *AudioBuilder) Run() error {
+	return a.RunContext(context.Background())
+}
+
+// RunContext executes the audio encoding command, killing ffmpeg once ctx
+// is done.
+func (a *AudioBuilder) RunContext(ctx context.Context) error {
 	// Guard against nil chunk
 	if a.chunk == nil {
 		return fmt.Errorf("cannot run command: chunk is nil")
 	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
 
 	args := a.BuildArgs()
+	a.log.Debug("ffmpeg command", "args", "ffmpeg "+strings.Join(args, " "))
 
-	// Print the actual ffmpeg command being executed
-	cmdStr := "ffmpeg " + strings.Join(args, " ")
-	fmt.Printf("\n🎵 AUDIO CHUNK %d:\n%s\n\n", a.chunk.ChunkID, cmdStr)
-
-	cmd := exec.Command("ffmpeg", args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 
 	// If no progress callback, use simple execution
 	if a.progressCallback == nil {
 		output, err := cmd.CombinedOutput()
 		if err != nil {
+			a.log.Debug("ffmpeg failed", "error", err, "stderr", logging.Tail(output, logging.StderrTailLines))
 			return fmt.Errorf("ffmpeg command failed: %w (output: %s)", err, string(output))
 		}
 		return nil
@@ -188,12 +257,16 @@ func (a *AudioBuilder) runWithProgress(cmd *exec.Cmd) error {
 	progress.State = models.ProgressStateStarting
 	a.progressCallback(progress)
 
+	// Capture stderr in a buffer for error logging while also parsing progress
+	var stderrBuf bytes.Buffer
+	stderrTee := io.TeeReader(stderr, &stderrBuf)
+
 	// Parse progress in a goroutine
 	parser := ffmpeg.NewProgressParser()
 	errChan := make(chan error, 1)
 
 	go func() {
-		errChan <- parser.StreamProgress(stderr, progress, a.progressCallback)
+		errChan <- parser.StreamProgress(stderrTee, progress, a.progressCallback)
 	}()
 
 	// Capture stdout (usually empty for ffmpeg, but might have warnings)
@@ -209,6 +282,7 @@ func (a *AudioBuilder) runWithProgress(cmd *exec.Cmd) error {
 	if cmdErr != nil {
 		progress.State = models.ProgressStateFailed
 		a.progressCallback(progress)
+		a.log.Debug("ffmpeg failed", "error", cmdErr, "stderr", logging.Tail(stderrBuf.Bytes(), logging.StderrTailLines))
 		return fmt.Errorf("ffmpeg command failed: %w (output: %s)", cmdErr, string(stdoutData))
 	}
 
@@ -216,7 +290,7 @@ func (a *AudioBuilder) runWithProgress(cmd *exec.Cmd) error {
 		// Progress parsing failed, but command succeeded
 		// Ignore "file already closed" errors - this is normal when ffmpeg finishes quickly
 		if !strings.Contains(parseErr.Error(), "file already closed") {
-			fmt.Printf("Warning: progress parsing error: %v\n", parseErr)
+			a.log.Warn("progress parsing error", "error", parseErr)
 		}
 	}
 
@@ -233,6 +307,9 @@ func (a *AudioBuilder) DryRun() (string, error) {
 	if a.chunk == nil {
 		return "", fmt.Errorf("cannot build command: chunk is nil")
 	}
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
 
 	args := a.BuildArgs()
 	return fmt.Sprintf("ffmpeg %s", strings.Join(args, " ")), nil
@@ -255,6 +332,13 @@ func (a *AudioBuilder) SetProgressCallback(callback models.ProgressCallback) Aud
 	return a
 }
 
+// SetLogger sets the logger of Run: the ffmpeg command at debug level and,
+// when ffmpeg fails, the tail of its stderr. Records are discarded by default.
+func (a *AudioBuilder) SetLogger(log *slog.Logger) AudioCommand {
+	a.log = log
+	return a
+}
+
 // GetTaskType returns the task type (audio).
 func (a *AudioBuilder) GetTaskType() command.TaskType {
 	return command.TaskTypeAudio
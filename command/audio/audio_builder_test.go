@@ -254,6 +254,44 @@ func TestAudioBuilder_DryRun(t *testing.T) {
 	}
 }
 
+func TestAudioBuilder_DryRun_InvalidSettings(t *testing.T) {
+	chunk := &models.Chunk{
+		ChunkID:    1,
+		StartTime:  0,
+		EndTime:    100,
+		SourcePath: "/input/video.mp4",
+	}
+
+	tests := []struct {
+		name    string
+		codec   string
+		rate    int
+		ch      int
+		wantErr string
+	}{
+		{"opus 44.1kHz", "libopus", 44100, 2, "libopus does not support sample rate 44100"},
+		{"mp3 5.1", "libmp3lame", 44100, 6, "libmp3lame supports at most 2 channels"},
+		{"video codec", "libx264", 0, 0, "not an audio encoder"},
+		{"aac 44.1kHz", "aac", 44100, 2, ""},
+		{"unknown codec", "libtwolame", 12345, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewAudioBuilder(chunk, "/output/audio.mka")
+			builder.SetCodec(tt.codec).SetSampleRate(tt.rate).SetChannels(tt.ch)
+
+			_, err := builder.DryRun()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestAudioBuilder_Run_InvalidCommand(t *testing.T) {
 	// Create a chunk with invalid path to test error handling
 	chunk := &models.Chunk{
@@ -3,6 +3,7 @@ package audio
 import (
 	"encoder/command"
 	"encoder/models"
+	"log/slog"
 )
 
 // AudioCommand extends the base Command interface with audio-specific operations.
@@ -13,5 +14,7 @@ type AudioCommand interface {
 	SetSampleRate(rate int) AudioCommand
 	SetChannels(channels int) AudioCommand
 	SetFilters(filter string) AudioCommand
+	SetSampleAccurate(enabled bool) AudioCommand
 	SetProgressCallback(callback models.ProgressCallback) AudioCommand
+	SetLogger(log *slog.Logger) AudioCommand
 }
@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"encoder/codec"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FinalBuilder builds the single lossy encode of the joined lossless audio
+// chunks. Encoding once keeps the encoder delay (priming samples) out of the
+// chunk boundaries: the muxer records it once for the whole track.
+type FinalBuilder struct {
+	inputPath  string
+	outputPath string
+	codec      string
+	bitrate    string
+	sampleRate int
+}
+
+// NewFinalBuilder creates a FinalBuilder that encodes inputPath to outputPath.
+func NewFinalBuilder(inputPath, outputPath string) *FinalBuilder {
+	return &FinalBuilder{
+		inputPath:  inputPath,
+		outputPath: outputPath,
+		codec:      "libopus", // Default codec
+		bitrate:    "128k",    // Default bitrate
+	}
+}
+
+// SetCodec sets the audio codec (e.g., "libopus", "aac").
+func (f *FinalBuilder) SetCodec(codec string) *FinalBuilder {
+	f.codec = codec
+	return f
+}
+
+// SetBitrate sets the audio bitrate (e.g., "128k").
+func (f *FinalBuilder) SetBitrate(bitrate string) *FinalBuilder {
+	f.bitrate = bitrate
+	return f
+}
+
+// SetSampleRate sets the output sample rate in Hz (0 = keep the input rate).
+func (f *FinalBuilder) SetSampleRate(rate int) *FinalBuilder {
+	f.sampleRate = rate
+	return f
+}
+
+// BuildArgs constructs the FFmpeg command arguments.
+func (f *FinalBuilder) BuildArgs() []string {
+	args := []string{
+		"-i", f.inputPath,
+		"-map", "0:a:0",
+		"-vn",
+		"-nostats",
+		"-c:a", f.codec,
+	}
+	if c, ok := codec.Lookup(f.codec); !ok || !c.Lossless {
+		args = append(args, "-b:a", f.bitrate)
+	}
+	if f.sampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(f.sampleRate))
+	}
+	return append(args, "-y", f.outputPath)
+}
+
+// Validate checks the codec and sample rate against the codec registry.
+func (f *FinalBuilder) Validate() error {
+	return validateSettings(f.codec, f.sampleRate, 0)
+}
+
+// Run executes the FFmpeg command.
+func (f *FinalBuilder) Run() error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	output, err := exec.Command("ffmpeg", f.BuildArgs()...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg command failed: %w (output: %s)", err, string(output))
+	}
+	return nil
+}
+
+// DryRun returns the FFmpeg command without executing it.
+func (f *FinalBuilder) DryRun() (string, error) {
+	if err := f.Validate(); err != nil {
+		return "", err
+	}
+	return "ffmpeg " + strings.Join(f.BuildArgs(), " "), nil
+}
+
+// GetOutputPath returns the output file path.
+func (f *FinalBuilder) GetOutputPath() string {
+	return f.outputPath
+}
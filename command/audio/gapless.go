@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultSampleRate is the sample rate of sample-accurate chunks when none
+// is set.
+const DefaultSampleRate = 48000
+
+// SetSampleAccurate cuts the chunk at exact sample positions of the source
+// instead of seeking to timestamps, so consecutive chunks add up to the
+// source's length without gaps or overlaps. The chunk is always read from
+// the source: pre-split segments are cut at video keyframes.
+func (a *AudioBuilder) SetSampleAccurate(enabled bool) AudioCommand {
+	a.sampleAccurate = enabled
+	return a
+}
+
+// SampleRange returns the first sample and the end sample (exclusive) of the
+// span [start, end) seconds at rate. Chunks that share a boundary share the
+// sample at it, so their sample counts add up exactly.
+func SampleRange(start, end float64, rate int) (first, last int64) {
+	return int64(math.Round(start * float64(rate))), int64(math.Round(end * float64(rate)))
+}
+
+// outputSampleRate returns the sample rate of the encoded chunk, or 0 to
+// keep the rate the filters produce.
+func (a *AudioBuilder) outputSampleRate() int {
+	if a.sampleRate > 0 {
+		return a.sampleRate
+	}
+	if a.sampleAccurate {
+		return DefaultSampleRate
+	}
+	return 0
+}
+
+// seekSeconds returns the whole second before the chunk start that a
+// sample-accurate chunk seeks to. Seeking to whole seconds keeps the sample
+// grid of every chunk aligned with the source.
+func (a *AudioBuilder) seekSeconds() float64 {
+	return math.Floor(a.chunk.StartTime)
+}
+
+// trimFilters returns the filters that cut a sample-accurate chunk out of
+// the input read from seekSeconds: resample to the output rate, keep the
+// chunk's samples and restart the timestamps at zero.
+func (a *AudioBuilder) trimFilters() []string {
+	if !a.sampleAccurate || a.chunk == nil {
+		return nil
+	}
+	rate := a.outputSampleRate()
+	first, last := SampleRange(a.chunk.StartTime, a.chunk.EndTime, rate)
+	base := int64(a.seekSeconds()) * int64(rate)
+	return []string{
+		fmt.Sprintf("aresample=%d", rate),
+		fmt.Sprintf("atrim=start_sample=%d:end_sample=%d", first-base, last-base),
+		"asetpts=N/SR/TB",
+	}
+}
@@ -0,0 +1,187 @@
+package audio
+
+import (
+	"encoder/ffprobe"
+	"encoder/models"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// chapterBoundaries returns n+1 irregular chapter boundaries (seconds) of the
+// kind found in real sources, ending at total.
+func chapterBoundaries(n int, total float64) []float64 {
+	bounds := make([]float64, n+1)
+	for i := 1; i < n; i++ {
+		// Uneven, non-round chapter lengths
+		bounds[i] = total * float64(i) / float64(n) * (1 + 0.013*math.Sin(float64(i)))
+	}
+	bounds[n] = total
+	return bounds
+}
+
+func TestSampleRange_TotalDurationMatchesSource(t *testing.T) {
+	const total = 7261.437291 // ~2h source
+	bounds := chapterBoundaries(48, total)
+
+	for _, rate := range []int{48000, 44100} {
+		t.Run(fmt.Sprintf("%dHz", rate), func(t *testing.T) {
+			var samples int64
+			for i := 0; i+1 < len(bounds); i++ {
+				first, last := SampleRange(bounds[i], bounds[i+1], rate)
+				if i > 0 {
+					if _, prevLast := SampleRange(bounds[i-1], bounds[i], rate); prevLast != first {
+						t.Fatalf("chunk %d starts at sample %d, previous chunk ends at %d", i, first, prevLast)
+					}
+				}
+				samples += last - first
+			}
+
+			// The joined chunks are within one sample of the source
+			durationError := math.Abs(float64(samples)/float64(rate) - total)
+			if durationError > 1/float64(rate) {
+				t.Errorf("total duration error %.9fs exceeds one sample (%d samples for %.6fs)", durationError, samples, total)
+			}
+		})
+	}
+}
+
+func TestAudioBuilder_SampleAccurateArgs(t *testing.T) {
+	chunk := &models.Chunk{
+		ChunkID:     2,
+		StartTime:   125.5,
+		EndTime:     250.25,
+		SourcePath:  "/input/movie.mkv",
+		SegmentPath: "/tmp/segment_001.mkv",
+	}
+	args := NewAudioBuilder(chunk, "/tmp/audio_chunk_002.flac").
+		SetCodec("flac").
+		SetSampleAccurate(true).(*AudioBuilder).
+		BuildArgs()
+	joined := strings.Join(args, " ")
+
+	// Reads the source (segments are cut at video keyframes), seeking to a whole second
+	if !strings.Contains(joined, "-ss 00:02:05.00 -i /input/movie.mkv") {
+		t.Errorf("expected whole-second seek into the source, got: %v", args)
+	}
+	if slices.Contains(args, "-to") {
+		t.Errorf("sample-accurate chunks should not use -to: %v", args)
+	}
+
+	// 125.5s-250.25s at 48kHz, relative to the 125s seek point
+	if !strings.Contains(joined, "aresample=48000,atrim=start_sample=24000:end_sample=6012000,asetpts=N/SR/TB,pan=") {
+		t.Errorf("expected sample trim before the normalization filters, got: %v", args)
+	}
+
+	if !strings.Contains(joined, "-c:a flac -ar 48000") || slices.Contains(args, "-b:a") {
+		t.Errorf("expected lossless FLAC at 48kHz without a bitrate, got: %v", args)
+	}
+}
+
+func TestAudioBuilder_SampleAccurateFirstChunk(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 0.5, SourcePath: "/input/movie.mkv"}
+	args := NewAudioBuilder(chunk, "/tmp/audio_chunk_001.flac").
+		SetCodec("flac").
+		SetSampleRate(44100).
+		SetSampleAccurate(true).(*AudioBuilder).
+		BuildArgs()
+	joined := strings.Join(args, " ")
+
+	if slices.Contains(args, "-ss") {
+		t.Errorf("first chunk should not seek: %v", args)
+	}
+	if !strings.Contains(joined, "aresample=44100,atrim=start_sample=0:end_sample=22050") {
+		t.Errorf("expected trim at 44.1kHz, got: %v", args)
+	}
+}
+
+func TestFinalBuilder_BuildArgs(t *testing.T) {
+	args := NewFinalBuilder("/tmp/final_audio.flac", "/tmp/final_audio.opus").
+		SetCodec("libopus").
+		SetBitrate("160k").
+		SetSampleRate(48000).
+		BuildArgs()
+
+	expected := []string{
+		"-i", "/tmp/final_audio.flac", "-map", "0:a:0", "-vn", "-nostats",
+		"-c:a", "libopus", "-b:a", "160k", "-ar", "48000", "-y", "/tmp/final_audio.opus",
+	}
+	if !slices.Equal(args, expected) {
+		t.Errorf("BuildArgs() = %v, want %v", args, expected)
+	}
+}
+
+func TestFinalBuilder_Validate(t *testing.T) {
+	if _, err := NewFinalBuilder("in.flac", "out.opus").SetSampleRate(44100).DryRun(); err == nil {
+		t.Error("expected libopus to reject 44100 Hz")
+	}
+}
+
+// TestGaplessChunks_DurationMatchesSource encodes a source in sample-accurate
+// chunks, joins them and measures the duration error against the source.
+func TestGaplessChunks_DurationMatchesSource(t *testing.T) {
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mka")
+	const total = 12.345
+	gen := exec.Command("ffmpeg", "-f", "lavfi", "-i", fmt.Sprintf("sine=frequency=440:sample_rate=48000:duration=%g", total),
+		"-ac", "2", "-c:a", "libopus", "-b:a", "96k", "-y", source)
+	if output, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("cannot generate test source: %v (%s)", err, output)
+	}
+
+	probe, err := ffprobe.Probe(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceDuration, err := probe.GetDuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := []float64{0, 2.717, 5.5, 9.001, sourceDuration}
+	list := filepath.Join(dir, "chunks.txt")
+	var entries []string
+	for i := 0; i+1 < len(bounds); i++ {
+		out := filepath.Join(dir, fmt.Sprintf("audio_chunk_%03d.flac", i+1))
+		chunk := &models.Chunk{ChunkID: uint(i + 1), StartTime: bounds[i], EndTime: bounds[i+1], SourcePath: source}
+		if err := NewAudioBuilder(chunk, out).SetCodec("flac").SetSampleAccurate(true).Run(); err != nil {
+			t.Fatalf("chunk %d: %v", i+1, err)
+		}
+		entries = append(entries, fmt.Sprintf("file '%s'", out))
+	}
+	if err := os.WriteFile(list, []byte(strings.Join(entries, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	joined := filepath.Join(dir, "final_audio.flac")
+	concat := exec.Command("ffmpeg", "-f", "concat", "-safe", "0", "-i", list, "-c", "copy", "-y", joined)
+	if output, err := concat.CombinedOutput(); err != nil {
+		t.Fatalf("concat failed: %v (%s)", err, output)
+	}
+
+	result, err := ffprobe.Probe(joined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	duration, err := result.GetDuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Container durations are rounded to milliseconds; per-chunk priming or
+	// padding would add tens of milliseconds per chunk
+	if durationError := math.Abs(duration - sourceDuration); durationError > 0.005 {
+		t.Errorf("joined audio is %.6fs, source is %.6fs (error %.6fs)", duration, sourceDuration, durationError)
+	}
+}
@@ -17,10 +17,13 @@ const (
 type TaskType string
 
 const (
-	TaskTypeAudio    TaskType = "audio"    // Audio-only encoding
-	TaskTypeVideo    TaskType = "video"    // Video encoding with optional audio
-	TaskTypeMixing   TaskType = "mixing"   // Stream mixing/multiplexing
-	TaskTypeSubtitle TaskType = "subtitle" // Subtitle operations
+	TaskTypeAudio     TaskType = "audio"     // Audio-only encoding
+	TaskTypeVideo     TaskType = "video"     // Video encoding with optional audio
+	TaskTypeMixing    TaskType = "mixing"    // Stream mixing/multiplexing
+	TaskTypeSubtitle  TaskType = "subtitle"  // Subtitle operations
+	TaskTypeQuality   TaskType = "quality"   // Probe encodes and quality measurement
+	TaskTypePackaging TaskType = "packaging" // HLS/DASH segmenting
+	TaskTypeThumbnail TaskType = "thumbnail" // Posters, sprite sheets and previews
 )
 
 // Command represents an FFmpeg command that can be built, executed, or previewed.
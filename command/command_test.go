@@ -272,3 +272,23 @@ func TestCommandInterface_TaskTypeSwitch(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterNames(t *testing.T) {
+	tests := []struct {
+		graph    string
+		expected []string
+	}{
+		{"scale=1280:-2,format=yuv420p", []string{"scale", "format"}},
+		{"[0:v]crop=1920:800:0:140[v];[v]fps=24", []string{"crop", "fps"}},
+		{"pan=stereo|FL<FC+0.30*FL|FR<FC+0.30*FR,loudnorm=I=-16", []string{"pan", "loudnorm"}},
+		{"yadif,yadif=1", []string{"yadif"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		got := FilterNames(tt.graph)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("FilterNames(%q) = %v; want %v", tt.graph, got, tt.expected)
+		}
+	}
+}
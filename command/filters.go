@@ -0,0 +1,60 @@
+package command
+
+import (
+	"context"
+	"strings"
+)
+
+// ContextRunner is implemented by commands whose ffmpeg process can be
+// stopped before it finishes. RunContext is Run, with the process killed
+// once ctx is done.
+type ContextRunner interface {
+	RunContext(ctx context.Context) error
+}
+
+// FilterRequirer is implemented by commands that can report the ffmpeg
+// filters they use, so missing filters can be detected before any work starts.
+type FilterRequirer interface {
+	RequiredFilters() []string
+}
+
+// FilterNames returns the filter names used in one or more filtergraph
+// strings, without duplicates and in order of first use.
+//
+//	FilterNames("scale=1280:-2,format=yuv420p") -> ["scale", "format"]
+//	FilterNames("[0:v]crop=w:h[v];[v]fps=24")   -> ["crop", "fps"]
+func FilterNames(graphs ...string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, graph := range graphs {
+		for _, chain := range strings.Split(graph, ";") {
+			for _, filter := range strings.Split(chain, ",") {
+				name := strings.TrimSpace(filter)
+
+				// Strip leading input pad labels ("[0:v][1:v]overlay")
+				for strings.HasPrefix(name, "[") {
+					end := strings.Index(name, "]")
+					if end < 0 {
+						break
+					}
+					name = strings.TrimSpace(name[end+1:])
+				}
+
+				// Cut arguments, instance name and output labels
+				if i := strings.IndexAny(name, "=@["); i >= 0 {
+					name = name[:i]
+				}
+				name = strings.TrimSpace(name)
+
+				if name == "" || seen[name] || strings.ContainsAny(name, ":|<") {
+					continue // empty, duplicate or argument fragment
+				}
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
@@ -12,14 +12,19 @@ import (
 // It supports:
 // - Combining separate audio and video files
 // - Adding multiple audio tracks
-// - Adding subtitle tracks
+// - Adding subtitle tracks, all or selected streams, copied or converted
+// - Audio-only outputs (no video input)
 // - Stream copying (no re-encoding) or re-encoding
 // - Metadata and stream mapping
+// - MP4 fast start (index at the front of the file)
 type MixingBuilder struct {
-	videoInput    string
-	audioInputs   []string
-	subtitleInput string
-	outputPath    string
+	videoInput      string
+	audioInputs     []string
+	subtitleInput   string
+	subtitleStreams []int  // Subtitle streams of subtitleInput (empty = all)
+	subtitleCodec   string // Subtitle codec ("copy" = keep as is)
+	outputPath      string
+	fastStart       bool
 
 	// Stream options
 	copyVideo    bool
@@ -44,16 +49,17 @@ type MixingBuilder struct {
 }
 
 // NewMixingBuilder creates a new mixing builder.
-// videoInput: path to video file (required)
+// videoInput: path to video file (empty for audio-only outputs)
 // outputPath: path to output file (required)
 func NewMixingBuilder(videoInput, outputPath string) *MixingBuilder {
 	return &MixingBuilder{
-		videoInput: videoInput,
-		outputPath: outputPath,
-		copyVideo:  true, // Default: copy video stream (no re-encode)
-		copyAudio:  true, // Default: copy audio stream (no re-encode)
-		priority:   command.PriorityNormal,
-		metadata:   make(map[string]string),
+		videoInput:    videoInput,
+		outputPath:    outputPath,
+		copyVideo:     true, // Default: copy video stream (no re-encode)
+		copyAudio:     true, // Default: copy audio stream (no re-encode)
+		subtitleCodec: "copy",
+		priority:      command.PriorityNormal,
+		metadata:      make(map[string]string),
 	}
 }
 
@@ -70,6 +76,27 @@ func (m *MixingBuilder) AddSubtitleTrack(subtitlePath string) *MixingBuilder {
 	return m
 }
 
+// SetSubtitleStreams selects subtitle streams of the subtitle input by their
+// index among its subtitle streams. By default all are mapped.
+func (m *MixingBuilder) SetSubtitleStreams(streams ...int) *MixingBuilder {
+	m.subtitleStreams = streams
+	return m
+}
+
+// SetSubtitleCodec sets the subtitle codec, e.g. "mov_text" for MP4 or
+// "webvtt" for WebM. The default "copy" keeps subtitles as they are.
+func (m *MixingBuilder) SetSubtitleCodec(codec string) *MixingBuilder {
+	m.subtitleCodec = codec
+	return m
+}
+
+// SetFastStart moves the MP4/MOV index to the front of the file so playback
+// can start before the download completes.
+func (m *MixingBuilder) SetFastStart(fastStart bool) *MixingBuilder {
+	m.fastStart = fastStart
+	return m
+}
+
 // SetCopyVideo sets whether to copy the video stream without re-encoding.
 // If false, video will be re-encoded using videoCodec.
 func (m *MixingBuilder) SetCopyVideo(copy bool) *MixingBuilder {
@@ -149,7 +176,11 @@ func (m *MixingBuilder) BuildArgs() []string {
 	args := []string{}
 
 	// Input video
-	args = append(args, "-i", m.videoInput)
+	input := 0
+	if m.videoInput != "" {
+		args = append(args, "-i", m.videoInput)
+		input++
+	}
 
 	// Input audio tracks
 	for _, audio := range m.audioInputs {
@@ -168,23 +199,31 @@ func (m *MixingBuilder) BuildArgs() []string {
 		}
 	} else {
 		// Default mapping: map all streams
-		args = append(args, "-map", "0:v") // Video from first input
+		if m.videoInput != "" {
+			args = append(args, "-map", "0:v") // Video from first input
+		}
 
 		// Map audio from subsequent inputs
 		for i := range m.audioInputs {
-			args = append(args, "-map", fmt.Sprintf("%d:a", i+1))
+			args = append(args, "-map", fmt.Sprintf("%d:a", input+i))
 		}
 
-		// Map subtitle if present
+		// Map subtitles if present
 		if m.subtitleInput != "" {
-			args = append(args, "-map", fmt.Sprintf("%d:s", len(m.audioInputs)+1))
+			subtitles := input + len(m.audioInputs)
+			if len(m.subtitleStreams) == 0 {
+				args = append(args, "-map", fmt.Sprintf("%d:s", subtitles))
+			}
+			for _, stream := range m.subtitleStreams {
+				args = append(args, "-map", fmt.Sprintf("%d:s:%d", subtitles, stream))
+			}
 		}
 	}
 
-	// Video codec
-	if m.copyVideo {
+	// Video codec (audio-only outputs have none)
+	if m.videoInput != "" && m.copyVideo {
 		args = append(args, "-c:v", "copy")
-	} else {
+	} else if m.videoInput != "" {
 		if m.videoCodec != "" {
 			args = append(args, "-c:v", m.videoCodec)
 		}
@@ -207,7 +246,11 @@ func (m *MixingBuilder) BuildArgs() []string {
 
 	// Subtitle codec (usually copy)
 	if m.subtitleInput != "" {
-		args = append(args, "-c:s", "copy")
+		args = append(args, "-c:s", m.subtitleCodec)
+	}
+
+	if m.fastStart {
+		args = append(args, "-movflags", "+faststart")
 	}
 
 	// Metadata
@@ -255,8 +298,12 @@ func (m *MixingBuilder) GetTaskType() command.TaskType {
 	return command.TaskTypeMixing
 }
 
-// GetInputPath returns the primary input path (video).
+// GetInputPath returns the primary input path (video, or the first audio
+// track of audio-only outputs).
 func (m *MixingBuilder) GetInputPath() string {
+	if m.videoInput == "" && len(m.audioInputs) > 0 {
+		return m.audioInputs[0]
+	}
 	return m.videoInput
 }
 
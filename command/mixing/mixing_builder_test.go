@@ -330,3 +330,41 @@ func TestMixingBuilder_ComplexMixing(t *testing.T) {
 		}
 	}
 }
+
+func TestMixingBuilder_ConvertedSubtitles(t *testing.T) {
+	builder := NewMixingBuilder("/tmp/final_video.mkv", "/output/movie.mp4").
+		AddAudioTrack("/tmp/final_audio.m4a").
+		AddSubtitleTrack("/input/movie.mkv").
+		SetSubtitleStreams(0, 2).
+		SetSubtitleCodec("mov_text").
+		SetFastStart(true)
+
+	args := strings.Join(builder.BuildArgs(), " ")
+	for _, want := range []string{
+		"-map 0:v -map 1:a -map 2:s:0 -map 2:s:2",
+		"-c:s mov_text",
+		"-movflags +faststart",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "-map 2:s ") {
+		t.Errorf("Expected only the selected subtitle streams: %s", args)
+	}
+}
+
+func TestMixingBuilder_AudioOnly(t *testing.T) {
+	builder := NewMixingBuilder("", "/output/album.m4a").
+		AddAudioTrack("/tmp/final_audio.m4a").
+		SetFastStart(true)
+
+	args := strings.Join(builder.BuildArgs(), " ")
+	want := "-i /tmp/final_audio.m4a -map 0:a -c:a copy -movflags +faststart -y /output/album.m4a"
+	if args != want {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+	if builder.GetInputPath() != "/tmp/final_audio.m4a" {
+		t.Errorf("GetInputPath() = %s, want the audio track", builder.GetInputPath())
+	}
+}
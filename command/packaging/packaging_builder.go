@@ -0,0 +1,520 @@
+package packaging
+
+import (
+	"encoder/command"
+	"encoder/ffprobe"
+	"encoder/streaming"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format is an adaptive streaming format.
+type Format string
+
+const (
+	FormatHLS  Format = "hls"
+	FormatDASH Format = "dash"
+)
+
+// SegmentType is the container of HLS segments.
+type SegmentType string
+
+const (
+	SegmentFMP4 SegmentType = "fmp4" // Fragmented MP4 (CMAF), any codec
+	SegmentTS   SegmentType = "ts"   // MPEG-TS, H.264/HEVC with AAC/MP3/AC-3
+)
+
+// DefaultSegmentDuration is the target segment length in seconds.
+const DefaultSegmentDuration = 6.0
+
+// Entry points written to the output directory.
+const (
+	MasterPlaylist = "master.m3u8"
+	DASHManifest   = "manifest.mpd"
+)
+
+// Rendition group IDs in the HLS master playlist.
+const (
+	audioGroup    = "audio"
+	subtitleGroup = "subs"
+)
+
+// VideoTrack is a video variant, e.g. one rendition of an ABR ladder.
+type VideoTrack struct {
+	Path string // Encoded video file
+	Tag  string // Sample entry, e.g. "hvc1" (empty = muxer default)
+}
+
+// AudioTrack is an audio rendition.
+type AudioTrack struct {
+	Path     string // Encoded audio file
+	Language string // ISO 639-2 code, e.g. "eng" (empty = undetermined)
+	Name     string // Display name (empty = language or "Audio N")
+	Default  bool
+}
+
+// SubtitleTrack is a text subtitle rendition, converted to WebVTT.
+type SubtitleTrack struct {
+	Path     string // File containing the subtitle stream, e.g. the source
+	Stream   int    // Index among the file's subtitle streams
+	Language string
+	Name     string
+	Default  bool
+	Forced   bool
+}
+
+// PackagingBuilder constructs the ffmpeg command that segments the
+// concatenated video variants and the audio renditions for adaptive
+// streaming. It supports:
+// - HLS with fMP4 or MPEG-TS segments and a master playlist with CODECS,
+// BANDWIDTH and RESOLUTION measured from the output
+// - DASH with fMP4 segments, the video variants in one adaptation set and
+// one adaptation set per audio track
+// - Multiple video variants sharing the audio renditions
+// - Multiple audio renditions and WebVTT subtitle renditions (HLS)
+//
+// Streams are copied: segments start at the keyframes the encoder produced,
+// so the video should have keyframes forced at the segment duration.
+type PackagingBuilder struct {
+	format          Format
+	outputDir       string
+	segmentDuration float64
+	segmentType     SegmentType
+
+	videoTracks    []VideoTrack
+	audioTracks    []AudioTrack
+	subtitleTracks []SubtitleTrack
+
+	priority int
+}
+
+// NewPackagingBuilder creates a builder that packages videoInput into
+// outputDir in the given format. Further variants are added with
+// AddVideoTrack.
+func NewPackagingBuilder(format Format, videoInput, outputDir string) *PackagingBuilder {
+	return &PackagingBuilder{
+		format:          format,
+		videoTracks:     []VideoTrack{{Path: videoInput}},
+		outputDir:       outputDir,
+		segmentDuration: DefaultSegmentDuration,
+		segmentType:     SegmentFMP4,
+		priority:        command.PriorityNormal,
+	}
+}
+
+// SetSegmentDuration sets the target segment length in seconds. Segments end
+// at the first keyframe after it.
+func (p *PackagingBuilder) SetSegmentDuration(seconds float64) *PackagingBuilder {
+	p.segmentDuration = seconds
+	return p
+}
+
+// SetSegmentType sets the HLS segment container. DASH always uses fMP4.
+func (p *PackagingBuilder) SetSegmentType(segmentType SegmentType) *PackagingBuilder {
+	p.segmentType = segmentType
+	return p
+}
+
+// SetVideoTag sets the sample entry of the first video (e.g., "hvc1", which
+// Apple players require for HEVC).
+func (p *PackagingBuilder) SetVideoTag(tag string) *PackagingBuilder {
+	p.videoTracks[0].Tag = tag
+	return p
+}
+
+// AddVideoTrack adds a video variant. Variants are listed in the order they
+// are added, after the video passed to NewPackagingBuilder.
+func (p *PackagingBuilder) AddVideoTrack(track VideoTrack) *PackagingBuilder {
+	p.videoTracks = append(p.videoTracks, track)
+	return p
+}
+
+// AddAudioTrack adds an audio rendition.
+func (p *PackagingBuilder) AddAudioTrack(track AudioTrack) *PackagingBuilder {
+	p.audioTracks = append(p.audioTracks, track)
+	return p
+}
+
+// AddSubtitleTrack adds a subtitle rendition. Subtitles are only packaged
+// for HLS.
+func (p *PackagingBuilder) AddSubtitleTrack(track SubtitleTrack) *PackagingBuilder {
+	p.subtitleTracks = append(p.subtitleTracks, track)
+	return p
+}
+
+// SetPriority sets the task priority.
+func (p *PackagingBuilder) SetPriority(priority int) command.Command {
+	p.priority = priority
+	return p
+}
+
+// inputs returns the input files in ffmpeg order: the videos, the audio
+// tracks, then each distinct subtitle file.
+func (p *PackagingBuilder) inputs() []string {
+	var inputs []string
+	for _, track := range p.videoTracks {
+		inputs = append(inputs, track.Path)
+	}
+	for _, track := range p.audioTracks {
+		inputs = append(inputs, track.Path)
+	}
+	for _, track := range p.subtitles() {
+		if p.inputIndex(inputs, track.Path) < 0 {
+			inputs = append(inputs, track.Path)
+		}
+	}
+	return inputs
+}
+
+// inputIndex returns the index of path among the subtitle inputs, which
+// follow the video and audio inputs, or -1.
+func (p *PackagingBuilder) inputIndex(inputs []string, path string) int {
+	for i := len(p.videoTracks) + len(p.audioTracks); i < len(inputs); i++ {
+		if inputs[i] == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// subtitles returns the subtitle tracks packaged in this format.
+func (p *PackagingBuilder) subtitles() []SubtitleTrack {
+	if p.format != FormatHLS {
+		return nil
+	}
+	return p.subtitleTracks
+}
+
+// BuildArgs constructs the FFmpeg command arguments.
+func (p *PackagingBuilder) BuildArgs() []string {
+	inputs := p.inputs()
+	args := []string{"-nostats"}
+	for _, input := range inputs {
+		args = append(args, "-i", input)
+	}
+
+	// Video variants and audio renditions, copied
+	for i := range p.videoTracks {
+		args = append(args, "-map", fmt.Sprintf("%d:v:0", i))
+	}
+	for i := range p.audioTracks {
+		args = append(args, "-map", fmt.Sprintf("%d:a:0", len(p.videoTracks)+i))
+	}
+	args = append(args, "-c", "copy")
+	for i, track := range p.videoTracks {
+		if track.Tag != "" {
+			args = append(args, fmt.Sprintf("-tag:v:%d", i), track.Tag)
+		}
+	}
+	for i, track := range p.audioTracks {
+		if track.Language != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:a:%d", i), "language="+track.Language)
+		}
+	}
+
+	switch p.format {
+	case FormatHLS:
+		args = append(args, p.hlsArgs()...)
+	case FormatDASH:
+		args = append(args, p.dashArgs()...)
+	}
+
+	// Each subtitle track is a separate WebVTT output
+	for i, track := range p.subtitles() {
+		args = append(args,
+			"-map", fmt.Sprintf("%d:s:%d", p.inputIndex(inputs, track.Path), track.Stream),
+			"-c:s", "webvtt",
+			"-y", filepath.Join(p.outputDir, subtitleFile(i)),
+		)
+	}
+	return args
+}
+
+// hlsArgs returns the hls muxer options and output. Stream N (the videos,
+// then each audio track) is written to stream_N/.
+func (p *PackagingBuilder) hlsArgs() []string {
+	segmentType, ext := "mpegts", "ts"
+	if p.segmentType == SegmentFMP4 {
+		segmentType, ext = "fmp4", "m4s"
+	}
+
+	var streamMap []string
+	for i := range p.videoTracks {
+		entry := fmt.Sprintf("v:%d", i)
+		if len(p.audioTracks) > 0 {
+			entry += ",agroup:" + audioGroup
+		}
+		streamMap = append(streamMap, entry)
+	}
+	for i := range p.audioTracks {
+		streamMap = append(streamMap, fmt.Sprintf("a:%d,agroup:%s", i, audioGroup))
+	}
+
+	return []string{
+		"-f", "hls",
+		"-hls_time", formatSeconds(p.segmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_type", segmentType,
+		"-hls_segment_filename", filepath.Join(p.outputDir, "stream_%v", "segment_%05d."+ext),
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-y", filepath.Join(p.outputDir, "stream_%v", "index.m3u8"),
+	}
+}
+
+// dashArgs returns the dash muxer options and output, with the videos and
+// each audio track in their own adaptation set.
+func (p *PackagingBuilder) dashArgs() []string {
+	sets := []string{"id=0,streams=v"}
+	for i := range p.audioTracks {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=%d", i+1, len(p.videoTracks)+i))
+	}
+
+	return []string{
+		"-f", "dash",
+		"-seg_duration", formatSeconds(p.segmentDuration),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-adaptation_sets", strings.Join(sets, " "),
+		"-y", filepath.Join(p.outputDir, DASHManifest),
+	}
+}
+
+// Validate checks the format and segment settings.
+func (p *PackagingBuilder) Validate() error {
+	var errors []string
+	if p.format != FormatHLS && p.format != FormatDASH {
+		errors = append(errors, fmt.Sprintf("unknown format %q", p.format))
+	}
+	for i, track := range p.videoTracks {
+		if track.Path == "" {
+			errors = append(errors, fmt.Sprintf("video %d has no file", i+1))
+		}
+	}
+	if p.outputDir == "" {
+		errors = append(errors, "output directory is required")
+	}
+	if p.segmentDuration <= 0 {
+		errors = append(errors, "segment duration must be positive")
+	}
+	if p.segmentType != SegmentFMP4 && p.segmentType != SegmentTS {
+		errors = append(errors, fmt.Sprintf("unknown segment type %q", p.segmentType))
+	}
+	for i, track := range p.audioTracks {
+		if track.Path == "" {
+			errors = append(errors, fmt.Sprintf("audio track %d has no file", i+1))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid packaging settings: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// Run executes the FFmpeg command. For HLS it then writes the master
+// playlist and the subtitle playlists.
+func (p *PackagingBuilder) Run() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if p.format == FormatHLS {
+		for i := 0; i < len(p.videoTracks)+len(p.audioTracks); i++ {
+			if err := os.MkdirAll(filepath.Join(p.outputDir, streamDir(i)), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+		}
+	}
+
+	output, err := exec.Command("ffmpeg", p.BuildArgs()...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("packaging failed: %w (output: %s)", err, string(output))
+	}
+
+	if p.format == FormatHLS {
+		return p.writeMasterPlaylist()
+	}
+	return nil
+}
+
+// writeMasterPlaylist writes the HLS master playlist from the probed inputs
+// and the segments ffmpeg wrote.
+func (p *PackagingBuilder) writeMasterPlaylist() error {
+	playlist := &streaming.MasterPlaylist{Version: 6}
+	if p.segmentType == SegmentTS {
+		playlist.Version = 4
+	}
+
+	var variants []streaming.Variant
+	var videoCodecs []string
+	duration := 0.0
+	for i, track := range p.videoTracks {
+		probe, err := ffprobe.Probe(track.Path)
+		if err != nil {
+			return err
+		}
+		videoStream := probe.PrimaryVideoStream()
+		if videoStream == nil {
+			return fmt.Errorf("no video stream in %s", track.Path)
+		}
+		stats, err := streaming.ReadMediaStats(filepath.Join(p.outputDir, mediaPlaylist(i)))
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			if duration, err = probe.GetDuration(); err != nil {
+				duration = stats.Duration
+			}
+		}
+
+		hevcTag := track.Tag
+		if hevcTag == "" && p.segmentType == SegmentFMP4 {
+			hevcTag = "hev1" // ffmpeg's default sample entry for HEVC in MP4
+		}
+		variants = append(variants, streaming.Variant{
+			URI:              mediaPlaylist(i),
+			Bandwidth:        stats.PeakBandwidth,
+			AverageBandwidth: stats.AverageBandwidth,
+			Width:            videoStream.Width,
+			Height:           videoStream.Height,
+			FrameRate:        videoStream.FrameRate().Float(),
+		})
+		videoCodecs = append(videoCodecs, streaming.CodecString(*videoStream, hevcTag))
+	}
+
+	// Each variant must fit the largest audio rendition
+	var audioCodecs []string
+	var audioPeak, audioAverage int64
+	hasDefault := false
+	for _, track := range p.audioTracks {
+		hasDefault = hasDefault || track.Default
+	}
+	for i, track := range p.audioTracks {
+		audioProbe, err := ffprobe.Probe(track.Path)
+		if err != nil {
+			return err
+		}
+		streams := audioProbe.GetAudioStreams()
+		if len(streams) == 0 {
+			return fmt.Errorf("no audio stream in %s", track.Path)
+		}
+		stream := len(p.videoTracks) + i
+		stats, err := streaming.ReadMediaStats(filepath.Join(p.outputDir, mediaPlaylist(stream)))
+		if err != nil {
+			return err
+		}
+		audioPeak = max(audioPeak, stats.PeakBandwidth)
+		audioAverage = max(audioAverage, stats.AverageBandwidth)
+		audioCodecs = append(audioCodecs, streaming.CodecString(streams[0], ""))
+
+		playlist.Renditions = append(playlist.Renditions, streaming.Rendition{
+			Type:     "AUDIO",
+			GroupID:  audioGroup,
+			Name:     trackName(track.Name, track.Language, "Audio", i),
+			Language: track.Language,
+			Default:  track.Default || (!hasDefault && i == 0),
+			Channels: streams[0].Channels,
+			URI:      mediaPlaylist(stream),
+		})
+	}
+
+	// Subtitles are a single WebVTT file spanning the programme
+	for i, track := range p.subtitleTracks {
+		name := fmt.Sprintf("subtitles_%d.m3u8", i)
+		if err := os.WriteFile(filepath.Join(p.outputDir, name), []byte(streaming.SubtitlePlaylist(subtitleFile(i), duration)), 0644); err != nil {
+			return err
+		}
+		playlist.Renditions = append(playlist.Renditions, streaming.Rendition{
+			Type:     "SUBTITLES",
+			GroupID:  subtitleGroup,
+			Name:     trackName(track.Name, track.Language, "Subtitles", i),
+			Language: track.Language,
+			Default:  track.Default,
+			Forced:   track.Forced,
+			URI:      name,
+		})
+	}
+
+	for i, variant := range variants {
+		if len(p.audioTracks) > 0 {
+			variant.Audio = audioGroup
+			variant.Bandwidth += audioPeak
+			variant.AverageBandwidth += audioAverage
+		}
+		if len(p.subtitleTracks) > 0 {
+			variant.Subtitles = subtitleGroup
+		}
+		variant.Codecs = streaming.JoinCodecs(append([]string{videoCodecs[i]}, audioCodecs...)...)
+		playlist.Variants = append(playlist.Variants, variant)
+	}
+	return playlist.WriteFile(filepath.Join(p.outputDir, MasterPlaylist))
+}
+
+// DryRun returns the command that would be executed without running it.
+func (p *PackagingBuilder) DryRun() (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	return "ffmpeg " + strings.Join(p.BuildArgs(), " "), nil
+}
+
+// GetPriority returns the task priority.
+func (p *PackagingBuilder) GetPriority() int {
+	return p.priority
+}
+
+// GetTaskType returns the task type identifier.
+func (p *PackagingBuilder) GetTaskType() command.TaskType {
+	return command.TaskTypePackaging
+}
+
+// GetInputPath returns the primary input path (the first video).
+func (p *PackagingBuilder) GetInputPath() string {
+	return p.videoTracks[0].Path
+}
+
+// GetOutputPath returns the entry point of the package: the HLS master
+// playlist or the DASH manifest.
+func (p *PackagingBuilder) GetOutputPath() string {
+	if p.format == FormatDASH {
+		return filepath.Join(p.outputDir, DASHManifest)
+	}
+	return filepath.Join(p.outputDir, MasterPlaylist)
+}
+
+// streamDir returns the HLS directory of stream n (videos first).
+func streamDir(n int) string {
+	return "stream_" + strconv.Itoa(n)
+}
+
+// mediaPlaylist returns the HLS media playlist of stream n, relative to the
+// output directory.
+func mediaPlaylist(n int) string {
+	return streamDir(n) + "/index.m3u8"
+}
+
+// subtitleFile returns the WebVTT file of subtitle track n.
+func subtitleFile(n int) string {
+	return fmt.Sprintf("subtitles_%d.vtt", n)
+}
+
+// trackName returns the display name of a rendition.
+func trackName(name, language, kind string, index int) string {
+	if name != "" {
+		return name
+	}
+	if language != "" {
+		return language
+	}
+	return fmt.Sprintf("%s %d", kind, index+1)
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
@@ -0,0 +1,157 @@
+package packaging
+
+import (
+	"encoder/command"
+	"strings"
+	"testing"
+)
+
+func TestNewPackagingBuilder(t *testing.T) {
+	builder := NewPackagingBuilder(FormatHLS, "/tmp/final_video.mkv", "/out/hls")
+
+	if builder.segmentDuration != DefaultSegmentDuration {
+		t.Errorf("Expected default segment duration %v, got %v", DefaultSegmentDuration, builder.segmentDuration)
+	}
+	if builder.segmentType != SegmentFMP4 {
+		t.Errorf("Expected fMP4 segments by default, got %s", builder.segmentType)
+	}
+	if builder.GetTaskType() != command.TaskTypePackaging {
+		t.Errorf("Expected packaging task type, got %s", builder.GetTaskType())
+	}
+	if builder.GetOutputPath() != "/out/hls/master.m3u8" {
+		t.Errorf("Expected master playlist output, got %s", builder.GetOutputPath())
+	}
+
+	// The builder is usable as an orchestrator task
+	var _ command.Command = builder
+}
+
+func TestPackagingBuilder_HLSArgs(t *testing.T) {
+	builder := NewPackagingBuilder(FormatHLS, "/tmp/final_video.mkv", "/out/hls").
+		SetSegmentDuration(4).
+		SetVideoTag("hvc1").
+		AddAudioTrack(AudioTrack{Path: "/tmp/final_audio.opus", Language: "eng"}).
+		AddAudioTrack(AudioTrack{Path: "/tmp/commentary.opus", Language: "eng", Name: "Commentary"}).
+		AddSubtitleTrack(SubtitleTrack{Path: "/in/movie.mkv", Stream: 0, Language: "eng"}).
+		AddSubtitleTrack(SubtitleTrack{Path: "/in/movie.mkv", Stream: 2, Language: "ger"})
+
+	args := strings.Join(builder.BuildArgs(), " ")
+
+	for _, want := range []string{
+		"-i /tmp/final_video.mkv -i /tmp/final_audio.opus -i /tmp/commentary.opus -i /in/movie.mkv ",
+		"-map 0:v:0 -map 1:a:0 -map 2:a:0 -c copy -tag:v:0 hvc1",
+		"-metadata:s:a:0 language=eng -metadata:s:a:1 language=eng",
+		"-f hls -hls_time 4 -hls_playlist_type vod",
+		"-hls_segment_type fmp4 -hls_segment_filename /out/hls/stream_%v/segment_%05d.m4s",
+		"-var_stream_map v:0,agroup:audio a:0,agroup:audio a:1,agroup:audio",
+		"-y /out/hls/stream_%v/index.m3u8",
+		// One source input shared by both subtitle tracks
+		"-map 3:s:0 -c:s webvtt -y /out/hls/subtitles_0.vtt",
+		"-map 3:s:2 -c:s webvtt -y /out/hls/subtitles_1.vtt",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q:\n%s", want, args)
+		}
+	}
+}
+
+func TestPackagingBuilder_VideoVariants(t *testing.T) {
+	hls := NewPackagingBuilder(FormatHLS, "/tmp/1080p.mkv", "/out/hls").
+		SetVideoTag("hvc1").
+		AddVideoTrack(VideoTrack{Path: "/tmp/720p.mkv", Tag: "hvc1"}).
+		AddVideoTrack(VideoTrack{Path: "/tmp/480p.mkv"}).
+		AddAudioTrack(AudioTrack{Path: "/tmp/final_audio.opus"}).
+		AddSubtitleTrack(SubtitleTrack{Path: "/in/movie.mkv", Stream: 1})
+
+	args := strings.Join(hls.BuildArgs(), " ")
+	for _, want := range []string{
+		"-i /tmp/1080p.mkv -i /tmp/720p.mkv -i /tmp/480p.mkv -i /tmp/final_audio.opus -i /in/movie.mkv ",
+		"-map 0:v:0 -map 1:v:0 -map 2:v:0 -map 3:a:0 -c copy -tag:v:0 hvc1 -tag:v:1 hvc1 -f hls",
+		// Every variant plays the shared audio rendition
+		"-var_stream_map v:0,agroup:audio v:1,agroup:audio v:2,agroup:audio a:0,agroup:audio",
+		"-map 4:s:1 -c:s webvtt",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q:\n%s", want, args)
+		}
+	}
+	if strings.Contains(args, "-tag:v:2") {
+		t.Errorf("expected no tag for the third variant:\n%s", args)
+	}
+
+	dash := NewPackagingBuilder(FormatDASH, "/tmp/1080p.mkv", "/out/dash").
+		AddVideoTrack(VideoTrack{Path: "/tmp/720p.mkv"}).
+		AddAudioTrack(AudioTrack{Path: "/tmp/final_audio.opus"})
+	if args := strings.Join(dash.BuildArgs(), " "); !strings.Contains(args, "-adaptation_sets id=0,streams=v id=1,streams=2") {
+		t.Errorf("expected the videos in one adaptation set and the audio in another:\n%s", args)
+	}
+}
+
+func TestPackagingBuilder_HLSTransportStream(t *testing.T) {
+	args := strings.Join(NewPackagingBuilder(FormatHLS, "/tmp/v.mkv", "/out").
+		SetSegmentType(SegmentTS).
+		BuildArgs(), " ")
+
+	if !strings.Contains(args, "-hls_segment_type mpegts -hls_segment_filename /out/stream_%v/segment_%05d.ts") {
+		t.Errorf("expected MPEG-TS segments:\n%s", args)
+	}
+	if !strings.Contains(args, "-var_stream_map v:0 ") {
+		t.Errorf("expected a video-only stream map without audio:\n%s", args)
+	}
+}
+
+func TestPackagingBuilder_DASHArgs(t *testing.T) {
+	builder := NewPackagingBuilder(FormatDASH, "/tmp/final_video.mkv", "/out/dash").
+		AddAudioTrack(AudioTrack{Path: "/tmp/final_audio.opus", Language: "eng"}).
+		AddAudioTrack(AudioTrack{Path: "/tmp/dub.opus", Language: "fra"}).
+		AddSubtitleTrack(SubtitleTrack{Path: "/in/movie.mkv"})
+
+	args := strings.Join(builder.BuildArgs(), " ")
+
+	for _, want := range []string{
+		"-f dash -seg_duration 6 -use_template 1 -use_timeline 1",
+		"-adaptation_sets id=0,streams=v id=1,streams=1 id=2,streams=2",
+		"-y /out/dash/manifest.mpd",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q:\n%s", want, args)
+		}
+	}
+	if strings.Contains(args, "/in/movie.mkv") || strings.Contains(args, "webvtt") {
+		t.Errorf("DASH packages no subtitles:\n%s", args)
+	}
+	if builder.GetOutputPath() != "/out/dash/manifest.mpd" {
+		t.Errorf("Expected manifest output, got %s", builder.GetOutputPath())
+	}
+}
+
+func TestPackagingBuilder_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *PackagingBuilder
+		wantErr string
+	}{
+		{"valid", NewPackagingBuilder(FormatHLS, "v.mkv", "/out"), ""},
+		{"unknown format", NewPackagingBuilder("smooth", "v.mkv", "/out"), `unknown format "smooth"`},
+		{"no segments", NewPackagingBuilder(FormatDASH, "v.mkv", "/out").SetSegmentDuration(0), "segment duration must be positive"},
+		{"no output", NewPackagingBuilder(FormatHLS, "v.mkv", ""), "output directory is required"},
+		{"bad segment type", NewPackagingBuilder(FormatHLS, "v.mkv", "/out").SetSegmentType("mkv"), `unknown segment type "mkv"`},
+		{"video without file", NewPackagingBuilder(FormatHLS, "v.mkv", "/out").AddVideoTrack(VideoTrack{}), "video 2 has no file"},
+		{"audio without file", NewPackagingBuilder(FormatHLS, "v.mkv", "/out").AddAudioTrack(AudioTrack{}), "audio track 1 has no file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.DryRun()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
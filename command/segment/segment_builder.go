@@ -2,17 +2,27 @@ package segment
 
 import (
 	"encoder/chunker"
+	"encoder/ffmpeg"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
+// DefaultContainer is the segment container unless SetContainer picks
+// another: Matroska holds nearly any stream (better AV1 compatibility).
+const DefaultContainer = "mkv"
+
+// CopyContainers are the containers segments can be written to with stream
+// copy and reset timestamps.
+var CopyContainers = []string{"mkv", "webm", "mp4", "m4v", "mov", "ts"}
+
 // SegmentBuilder builds FFmpeg commands to split input files into segments.
 type SegmentBuilder struct {
 	sourcePath string
 	outputDir  string
 	chapters   []chunker.ChapterInfo
+	container  string // Segment file extension
 }
 
 // NewSegmentBuilder creates a new SegmentBuilder.
@@ -21,25 +31,41 @@ func NewSegmentBuilder(sourcePath string, outputDir string, chapters []chunker.C
 		sourcePath: sourcePath,
 		outputDir:  outputDir,
 		chapters:   chapters,
+		container:  DefaultContainer,
+	}
+}
+
+// SetContainer sets the segment container by file extension, e.g. the
+// source's own, which holds every stream the source has. Containers not in
+// CopyContainers keep the default.
+func (s *SegmentBuilder) SetContainer(ext string) *SegmentBuilder {
+	ext = strings.ToLower(ext)
+	for _, c := range CopyContainers {
+		if c == ext {
+			s.container = ext
+			return s
+		}
 	}
+	s.container = DefaultContainer
+	return s
 }
 
 // BuildArgs constructs the FFmpeg command arguments for segment splitting.
 // Uses -c copy for fast stream copying without re-encoding.
-// Outputs Matroska format (.mkv) for better AV1 codec compatibility.
 func (s *SegmentBuilder) BuildArgs() []string {
+	muxer, _ := ffmpeg.MuxerForExtension(s.container)
 	args := []string{
 		"-i", s.sourcePath,
 		"-c", "copy", // Copy streams without re-encoding (very fast)
 		"-map", "0", // Map all streams
 		"-f", "segment", // Segment muxer
-		"-segment_format", "matroska", // Use Matroska format (better AV1 compatibility)
+		"-segment_format", muxer,
 		"-segment_times", s.buildSegmentTimes(),
 		"-reset_timestamps", "1", // Reset timestamps for each segment
 	}
 
 	// Output pattern: tmp/segment_%03d.mkv
-	outputPattern := filepath.Join(s.outputDir, "segment_%03d.mkv")
+	outputPattern := filepath.Join(s.outputDir, "segment_%03d."+s.container)
 	args = append(args, outputPattern)
 
 	return args
@@ -82,5 +108,5 @@ func (s *SegmentBuilder) DryRun() string {
 
 // GetSegmentPath returns the path for a segment at the given index.
 func (s *SegmentBuilder) GetSegmentPath(index int) string {
-	return filepath.Join(s.outputDir, fmt.Sprintf("segment_%03d.mkv", index))
+	return filepath.Join(s.outputDir, fmt.Sprintf("segment_%03d.%s", index, s.container))
 }
@@ -0,0 +1,44 @@
+package segment
+
+import (
+	"encoder/chunker"
+	"strings"
+	"testing"
+)
+
+func TestSegmentBuilder_BuildArgs(t *testing.T) {
+	chapters := []chunker.ChapterInfo{{StartTime: "0.000000"}, {StartTime: "141.640000"}, {StartTime: "282.070000"}}
+
+	tests := []struct {
+		container string
+		muxer     string
+		segment   string
+	}{
+		{"", "matroska", "/tmp/segments/segment_002.mkv"},
+		{"mp4", "mp4", "/tmp/segments/segment_002.mp4"},
+		{"MOV", "mov", "/tmp/segments/segment_002.mov"},
+		{"ts", "mpegts", "/tmp/segments/segment_002.ts"},
+		{"avi", "matroska", "/tmp/segments/segment_002.mkv"},
+	}
+
+	for _, tt := range tests {
+		builder := NewSegmentBuilder("/in/movie", "/tmp/segments", chapters)
+		if tt.container != "" {
+			builder.SetContainer(tt.container)
+		}
+
+		args := strings.Join(builder.BuildArgs(), " ")
+		for _, want := range []string{
+			"-segment_format " + tt.muxer,
+			"-segment_times 141.640000,282.070000",
+			"/tmp/segments/segment_%03d.",
+		} {
+			if !strings.Contains(args, want) {
+				t.Errorf("%q: args missing %q: %s", tt.container, want, args)
+			}
+		}
+		if got := builder.GetSegmentPath(2); got != tt.segment {
+			t.Errorf("%q: GetSegmentPath(2) = %s, want %s", tt.container, got, tt.segment)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package thumbnail builds the ffmpeg commands for the still and animated
+// previews of a video: poster frames, WebVTT-indexed sprite sheets for
+// scrubbing and a short animated preview clip.
+package thumbnail
+
+import (
+	"encoder/ffprobe"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mode is how poster and sprite frames are chosen.
+type Mode string
+
+const (
+	ModeInterval Mode = "interval" // One frame every Interval seconds
+	ModeScene    Mode = "scene"    // Frames whose scene change score exceeds SceneThreshold
+	ModeChapters Mode = "chapters" // The first frame of each chapter
+)
+
+// Defaults of the selection modes.
+const (
+	DefaultInterval       = 10.0
+	DefaultSceneThreshold = 0.4
+)
+
+// Selection chooses the frames of posters and sprites.
+type Selection struct {
+	Mode           Mode
+	Interval       float64   // Seconds between frames (interval mode)
+	SceneThreshold float64   // Minimum scene change score, 0-1 (scene mode)
+	Times          []float64 // Frame times in seconds (chapters mode)
+}
+
+// IntervalSelection selects a frame every seconds.
+func IntervalSelection(seconds float64) Selection {
+	return Selection{Mode: ModeInterval, Interval: seconds}
+}
+
+// SceneSelection selects the frames where the scene changes by more than
+// threshold (0-1; lower finds more changes).
+func SceneSelection(threshold float64) Selection {
+	return Selection{Mode: ModeScene, SceneThreshold: threshold}
+}
+
+// ChapterSelection selects the first frame of each chapter. Chapters whose
+// start time cannot be parsed are skipped.
+func ChapterSelection(chapters []ffprobe.Chapter) Selection {
+	sel := Selection{Mode: ModeChapters}
+	for _, ch := range chapters {
+		if start, err := strconv.ParseFloat(ch.StartTime, 64); err == nil && start >= 0 {
+			sel.Times = append(sel.Times, start)
+		}
+	}
+	return sel
+}
+
+// Filter returns the filter that passes only the selected frames. Commas
+// inside expressions are escaped for the filtergraph parser.
+//
+//	interval 10 -> fps=1/10
+//	scene 0.4   -> select=gt(scene\,0.4)
+//	chapters    -> select=gte(t\,0)*(isnan(prev_t)+lt(prev_t\,0))+gte(t\,312.5)*(...)
+func (s Selection) Filter() string {
+	switch s.Mode {
+	case ModeScene:
+		return `select=gt(scene\,` + formatFloat(s.SceneThreshold) + `)`
+	case ModeChapters:
+		// The first frame at or after each start
+		terms := make([]string, len(s.Times))
+		for i, t := range s.Times {
+			start := formatFloat(t)
+			terms[i] = `gte(t\,` + start + `)*(isnan(prev_t)+lt(prev_t\,` + start + `))`
+		}
+		return "select=" + strings.Join(terms, "+")
+	default:
+		return "fps=1/" + formatFloat(s.Interval)
+	}
+}
+
+// filterName returns the name of the filter Filter uses.
+func (s Selection) filterName() string {
+	if s.Mode == ModeScene || s.Mode == ModeChapters {
+		return "select"
+	}
+	return "fps"
+}
+
+// Validate checks the mode and its setting.
+func (s Selection) Validate() error {
+	switch s.Mode {
+	case ModeInterval:
+		if s.Interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+	case ModeScene:
+		if s.SceneThreshold <= 0 || s.SceneThreshold > 1 {
+			return fmt.Errorf("scene threshold must be between 0 and 1")
+		}
+	case ModeChapters:
+		if len(s.Times) == 0 {
+			return fmt.Errorf("chapter selection needs chapters")
+		}
+	default:
+		return fmt.Errorf("unknown selection mode %q", s.Mode)
+	}
+	return nil
+}
+
+// formatFloat formats seconds or scores without trailing zeros.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
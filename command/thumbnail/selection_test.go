@@ -0,0 +1,70 @@
+package thumbnail
+
+import (
+	"encoder/ffprobe"
+	"testing"
+)
+
+func TestSelection_Filter(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  Selection
+		want string
+	}{
+		{"interval", IntervalSelection(10), "fps=1/10"},
+		{"fractional interval", IntervalSelection(2.5), "fps=1/2.5"},
+		{"scene", SceneSelection(0.4), `select=gt(scene\,0.4)`},
+		{
+			"chapters",
+			Selection{Mode: ModeChapters, Times: []float64{0, 312.5}},
+			`select=gte(t\,0)*(isnan(prev_t)+lt(prev_t\,0))+gte(t\,312.5)*(isnan(prev_t)+lt(prev_t\,312.5))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.Filter(); got != tt.want {
+				t.Errorf("Filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChapterSelection(t *testing.T) {
+	sel := ChapterSelection([]ffprobe.Chapter{
+		{ID: 0, StartTime: "0.000000"},
+		{ID: 1, StartTime: "312.500000"},
+		{ID: 2, StartTime: "N/A"},
+	})
+
+	if sel.Mode != ModeChapters {
+		t.Errorf("Mode = %s, want chapters", sel.Mode)
+	}
+	if len(sel.Times) != 2 || sel.Times[0] != 0 || sel.Times[1] != 312.5 {
+		t.Errorf("Times = %v, want [0 312.5]", sel.Times)
+	}
+}
+
+func TestSelection_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sel     Selection
+		wantErr bool
+	}{
+		{"interval", IntervalSelection(10), false},
+		{"zero interval", IntervalSelection(0), true},
+		{"scene", SceneSelection(0.3), false},
+		{"scene threshold above 1", SceneSelection(1.5), true},
+		{"chapters", Selection{Mode: ModeChapters, Times: []float64{0}}, false},
+		{"no chapters", ChapterSelection(nil), true},
+		{"unknown mode", Selection{Mode: "random"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sel.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package thumbnail
+
+import (
+	"encoder/command"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind is the artefact a ThumbnailBuilder produces.
+type Kind string
+
+const (
+	KindPoster  Kind = "poster"  // JPEG poster frames
+	KindSprite  Kind = "sprite"  // Tiled JPEG sprite sheets with a WebVTT index for scrubbing
+	KindPreview Kind = "preview" // Short animated clip
+)
+
+// PreviewFormat is the container of the animated preview.
+type PreviewFormat string
+
+const (
+	PreviewWebP PreviewFormat = "webp" // Animated WebP
+	PreviewGIF  PreviewFormat = "gif"  // Animated GIF with an optimized palette
+	PreviewMP4  PreviewFormat = "mp4"  // Silent H.264
+)
+
+// Encoder returns the ffmpeg encoder of the format, or "" for the built-in
+// GIF encoder.
+func (f PreviewFormat) Encoder() string {
+	switch f {
+	case PreviewWebP:
+		return "libwebp"
+	case PreviewMP4:
+		return "libx264"
+	}
+	return ""
+}
+
+// Defaults of the thumbnail outputs.
+const (
+	DefaultPosterCount   = 5
+	DefaultPosterWidth   = 1280
+	DefaultTileWidth     = 160
+	DefaultTileHeight    = 90
+	DefaultColumns       = 10
+	DefaultRows          = 10
+	DefaultPreviewClips  = 6
+	DefaultPreviewLength = 2.0 // Seconds per clip
+	DefaultPreviewWidth  = 480
+	DefaultPreviewFPS    = 12
+)
+
+// SpriteIndex is the WebVTT file mapping time ranges to sprite tiles.
+const SpriteIndex = "sprite.vtt"
+
+// showinfoTime matches the time of a frame in the showinfo filter's log.
+var showinfoTime = regexp.MustCompile(`pts_time:\s*(-?[0-9.]+)`)
+
+// ThumbnailBuilder constructs the ffmpeg command for one thumbnail output of
+// a source. It supports:
+// - Poster frames at the selected frames, scaled to a width
+// - Sprite sheets of fixed-size tiles and a WebVTT index (sprite.vtt) whose
+// cues point at the tile covering each time range
+// - An animated preview joined from short clips (WebP, GIF or MP4)
+// - Source filters applied before scaling (deinterlace, crop, tone mapping)
+//
+// Thumbnails are optional, so builders default to low priority.
+type ThumbnailBuilder struct {
+	kind      Kind
+	input     string
+	outputDir string
+	duration  float64  // Source duration in seconds
+	filters   []string // Source filters, applied first
+
+	selection Selection
+	count     int // Maximum poster frames
+	width     int // Poster and preview width, sprite tile width
+	height    int // Sprite tile height
+	columns   int
+	rows      int
+
+	clipStarts    []float64 // Preview clip starts (empty = spread evenly)
+	clipLength    float64
+	previewFormat PreviewFormat
+	fps           int
+
+	priority int
+	times    []float64 // Times of the frames in the sprite sheets, after Run
+}
+
+// NewThumbnailBuilder creates a builder that writes the given kind of output
+// for input into outputDir.
+func NewThumbnailBuilder(kind Kind, input, outputDir string) *ThumbnailBuilder {
+	b := &ThumbnailBuilder{
+		kind:          kind,
+		input:         input,
+		outputDir:     outputDir,
+		selection:     IntervalSelection(DefaultInterval),
+		count:         DefaultPosterCount,
+		width:         DefaultPosterWidth,
+		height:        DefaultTileHeight,
+		columns:       DefaultColumns,
+		rows:          DefaultRows,
+		clipLength:    DefaultPreviewLength,
+		previewFormat: PreviewWebP,
+		fps:           DefaultPreviewFPS,
+		priority:      command.PriorityLow,
+	}
+	switch kind {
+	case KindSprite:
+		b.width = DefaultTileWidth
+	case KindPreview:
+		b.width = DefaultPreviewWidth
+	}
+	return b
+}
+
+// SetDuration sets the source duration in seconds. The last sprite cue ends
+// at it and preview clips are spread over it.
+func (b *ThumbnailBuilder) SetDuration(seconds float64) *ThumbnailBuilder {
+	b.duration = seconds
+	return b
+}
+
+// AddFilter adds a filter applied to the source frames before selection
+// and scaling, e.g. a deinterlacer, crop or tone mapping.
+func (b *ThumbnailBuilder) AddFilter(filter string) *ThumbnailBuilder {
+	b.filters = append(b.filters, filter)
+	return b
+}
+
+// SetSelection sets how poster and sprite frames are chosen.
+func (b *ThumbnailBuilder) SetSelection(selection Selection) *ThumbnailBuilder {
+	b.selection = selection
+	return b
+}
+
+// SetCount sets the maximum number of poster frames.
+func (b *ThumbnailBuilder) SetCount(count int) *ThumbnailBuilder {
+	b.count = count
+	return b
+}
+
+// SetSize sets the poster or preview width, or the sprite tile size. The
+// height only applies to sprite tiles; posters and previews keep the aspect
+// ratio.
+func (b *ThumbnailBuilder) SetSize(width, height int) *ThumbnailBuilder {
+	b.width = width
+	b.height = height
+	return b
+}
+
+// SetGrid sets the tiles per sprite sheet.
+func (b *ThumbnailBuilder) SetGrid(columns, rows int) *ThumbnailBuilder {
+	b.columns = columns
+	b.rows = rows
+	return b
+}
+
+// SetClips sets the preview clip starts and length in seconds. Without
+// starts, DefaultPreviewClips clips are spread evenly over the duration.
+func (b *ThumbnailBuilder) SetClips(starts []float64, length float64) *ThumbnailBuilder {
+	b.clipStarts = starts
+	b.clipLength = length
+	return b
+}
+
+// SetPreviewFormat sets the preview container.
+func (b *ThumbnailBuilder) SetPreviewFormat(format PreviewFormat) *ThumbnailBuilder {
+	b.previewFormat = format
+	return b
+}
+
+// SetPriority sets the task priority.
+func (b *ThumbnailBuilder) SetPriority(priority int) command.Command {
+	b.priority = priority
+	return b
+}
+
+// Kind returns the output the builder produces.
+func (b *ThumbnailBuilder) Kind() Kind {
+	return b.kind
+}
+
+// Times returns the source times of the sprite frames, known after Run.
+func (b *ThumbnailBuilder) Times() []float64 {
+	return append([]float64{}, b.times...)
+}
+
+// BuildArgs constructs the FFmpeg command arguments.
+func (b *ThumbnailBuilder) BuildArgs() []string {
+	switch b.kind {
+	case KindSprite:
+		return b.spriteArgs()
+	case KindPreview:
+		return b.previewArgs()
+	default:
+		return b.posterArgs()
+	}
+}
+
+// frameFilters returns the source filters followed by the frame selection.
+func (b *ThumbnailBuilder) frameFilters() []string {
+	return append(append([]string{}, b.filters...), b.selection.Filter())
+}
+
+// posterArgs writes up to count selected frames as poster_NNN.jpg.
+func (b *ThumbnailBuilder) posterArgs() []string {
+	filters := append(b.frameFilters(), fmt.Sprintf("scale=w=%d:h=-2", b.width))
+	return []string{
+		"-nostats",
+		"-i", b.input,
+		"-map", "0:v:0", "-an", "-sn",
+		"-vf", strings.Join(filters, ","),
+		"-fps_mode", "vfr",
+		"-frames:v", strconv.Itoa(b.count),
+		"-q:v", "2",
+		"-y", filepath.Join(b.outputDir, "poster_%03d.jpg"),
+	}
+}
+
+// spriteArgs tiles the selected frames into sprite_NNN.jpg sheets. Frames
+// are fitted into the tile and padded, so every tile has the same size; the
+// showinfo filter logs each frame's time for the index.
+func (b *ThumbnailBuilder) spriteArgs() []string {
+	filters := append(b.frameFilters(),
+		"showinfo",
+		fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease", b.width, b.height),
+		fmt.Sprintf("pad=%d:%d:(ow-iw)/2:(oh-ih)/2", b.width, b.height),
+		fmt.Sprintf("tile=%dx%d", b.columns, b.rows),
+	)
+	return []string{
+		"-nostats",
+		"-i", b.input,
+		"-map", "0:v:0", "-an", "-sn",
+		"-vf", strings.Join(filters, ","),
+		"-fps_mode", "vfr",
+		"-q:v", "4",
+		"-y", filepath.Join(b.outputDir, "sprite_%03d.jpg"),
+	}
+}
+
+// previewArgs seeks to each clip, so only the clips are decoded, and joins
+// them with the concat filter.
+func (b *ThumbnailBuilder) previewArgs() []string {
+	starts := b.previewStarts()
+	args := []string{"-nostats"}
+	for _, start := range starts {
+		args = append(args, "-ss", formatFloat(start), "-t", formatFloat(b.clipLength), "-i", b.input)
+	}
+
+	var graph []string
+	inputs := ""
+	for i := range starts {
+		chain := append(append([]string{}, b.filters...),
+			fmt.Sprintf("fps=%d", b.fps),
+			fmt.Sprintf("scale=w=%d:h=-2", b.width),
+			"setsar=1",
+		)
+		graph = append(graph, fmt.Sprintf("[%d:v:0]%s[v%d]", i, strings.Join(chain, ","), i))
+		inputs += fmt.Sprintf("[v%d]", i)
+	}
+	if b.previewFormat == PreviewGIF {
+		graph = append(graph,
+			fmt.Sprintf("%sconcat=n=%d:v=1:a=0,split[a][b]", inputs, len(starts)),
+			"[a]palettegen[p]",
+			"[b][p]paletteuse[out]",
+		)
+	} else {
+		graph = append(graph, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[out]", inputs, len(starts)))
+	}
+	args = append(args, "-filter_complex", strings.Join(graph, ";"), "-map", "[out]", "-an")
+
+	switch b.previewFormat {
+	case PreviewWebP:
+		args = append(args, "-c:v", b.previewFormat.Encoder(), "-quality", "75", "-loop", "0")
+	case PreviewGIF:
+		args = append(args, "-loop", "0")
+	case PreviewMP4:
+		args = append(args, "-c:v", b.previewFormat.Encoder(), "-crf", "28", "-preset", "veryfast", "-pix_fmt", "yuv420p", "-movflags", "+faststart")
+	}
+	return append(args, "-y", b.GetOutputPath())
+}
+
+// previewStarts returns the clip starts: the configured ones, or
+// DefaultPreviewClips spread evenly with each clip centered in its share of
+// the duration.
+func (b *ThumbnailBuilder) previewStarts() []float64 {
+	if len(b.clipStarts) > 0 {
+		return b.clipStarts
+	}
+	starts := make([]float64, DefaultPreviewClips)
+	share := b.duration / DefaultPreviewClips
+	for i := range starts {
+		starts[i] = math.Max(0, share*(float64(i)+0.5)-b.clipLength/2)
+	}
+	return starts
+}
+
+// RequiredFilters returns the ffmpeg filters the command uses. Selection
+// expressions contain escaped commas, so names are listed, not parsed.
+func (b *ThumbnailBuilder) RequiredFilters() []string {
+	names := command.FilterNames(b.filters...)
+	var own []string
+	switch b.kind {
+	case KindSprite:
+		own = []string{b.selection.filterName(), "showinfo", "scale", "pad", "tile"}
+	case KindPreview:
+		own = []string{"fps", "scale", "setsar", "concat"}
+		if b.previewFormat == PreviewGIF {
+			own = append(own, "split", "palettegen", "paletteuse")
+		}
+	default:
+		own = []string{b.selection.filterName(), "scale"}
+	}
+	for _, name := range own {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Validate checks the output settings.
+func (b *ThumbnailBuilder) Validate() error {
+	var errors []string
+	if b.input == "" {
+		errors = append(errors, "input is required")
+	}
+	if b.outputDir == "" {
+		errors = append(errors, "output directory is required")
+	}
+	if b.width <= 0 {
+		errors = append(errors, "width must be positive")
+	}
+
+	switch b.kind {
+	case KindPoster, KindSprite:
+		if err := b.selection.Validate(); err != nil {
+			errors = append(errors, err.Error())
+		}
+		if b.kind == KindPoster && b.count <= 0 {
+			errors = append(errors, "poster count must be positive")
+		}
+		if b.kind == KindSprite && (b.height <= 0 || b.columns <= 0 || b.rows <= 0) {
+			errors = append(errors, "sprite tile height and grid must be positive")
+		}
+	case KindPreview:
+		if b.clipLength <= 0 {
+			errors = append(errors, "preview clip length must be positive")
+		}
+		if len(b.clipStarts) == 0 && b.duration <= 0 {
+			errors = append(errors, "preview needs clip starts or the source duration")
+		}
+		switch b.previewFormat {
+		case PreviewWebP, PreviewGIF, PreviewMP4:
+		default:
+			errors = append(errors, fmt.Sprintf("unknown preview format %q", b.previewFormat))
+		}
+	default:
+		errors = append(errors, fmt.Sprintf("unknown thumbnail kind %q", b.kind))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid thumbnail settings: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// Run executes the FFmpeg command. For sprites it then writes the WebVTT
+// index from the frame times ffmpeg logged.
+func (b *ThumbnailBuilder) Run() error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	output, err := exec.Command("ffmpeg", b.BuildArgs()...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s generation failed: %w (output: %s)", b.kind, err, string(output))
+	}
+	if b.kind != KindSprite {
+		return nil
+	}
+
+	b.times = parseFrameTimes(string(output))
+	if len(b.times) == 0 {
+		return fmt.Errorf("no frames selected for the sprite")
+	}
+	index := SpriteIndexVTT(b.times, b.duration, b.width, b.height, b.columns, b.rows)
+	return os.WriteFile(filepath.Join(b.outputDir, SpriteIndex), []byte(index), 0644)
+}
+
+// parseFrameTimes returns the frame times the showinfo filter logged.
+func parseFrameTimes(log string) []float64 {
+	var times []float64
+	for _, line := range strings.Split(log, "\n") {
+		if !strings.Contains(line, "showinfo") {
+			continue
+		}
+		if m := showinfoTime.FindStringSubmatch(line); m != nil {
+			if t, err := strconv.ParseFloat(m[1], 64); err == nil {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// SpriteIndexVTT returns the WebVTT index of sprite sheets holding the frames
+// at times, tileWidth×tileHeight each and columns×rows per sheet. Each cue
+// lasts until the next frame; the last one until duration.
+//
+//	00:00:10.000 --> 00:00:20.000
+//	sprite_001.jpg#xywh=160,0,160,90
+func SpriteIndexVTT(times []float64, duration float64, tileWidth, tileHeight, columns, rows int) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	perSheet := columns * rows
+	for i, start := range times {
+		end := duration
+		if i+1 < len(times) {
+			end = times[i+1]
+		}
+		if end <= start {
+			end = start + 1
+		}
+		tile := i % perSheet
+		fmt.Fprintf(&sb, "\n%s --> %s\nsprite_%03d.jpg#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), i/perSheet+1,
+			(tile%columns)*tileWidth, (tile/columns)*tileHeight, tileWidth, tileHeight)
+	}
+	return sb.String()
+}
+
+// vttTimestamp formats seconds as a WebVTT timestamp (HH:MM:SS.mmm).
+func vttTimestamp(seconds float64) string {
+	ms := int64(math.Round(math.Max(0, seconds) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// DryRun returns the command that would be executed without running it.
+func (b *ThumbnailBuilder) DryRun() (string, error) {
+	if err := b.Validate(); err != nil {
+		return "", err
+	}
+	return "ffmpeg " + strings.Join(b.BuildArgs(), " "), nil
+}
+
+// GetPriority returns the task priority.
+func (b *ThumbnailBuilder) GetPriority() int {
+	return b.priority
+}
+
+// GetTaskType returns the task type identifier.
+func (b *ThumbnailBuilder) GetTaskType() command.TaskType {
+	return command.TaskTypeThumbnail
+}
+
+// GetInputPath returns the source path.
+func (b *ThumbnailBuilder) GetInputPath() string {
+	return b.input
+}
+
+// GetOutputPath returns the main output: the first poster, the sprite index
+// or the preview.
+func (b *ThumbnailBuilder) GetOutputPath() string {
+	switch b.kind {
+	case KindSprite:
+		return filepath.Join(b.outputDir, SpriteIndex)
+	case KindPreview:
+		return filepath.Join(b.outputDir, "preview."+string(b.previewFormat))
+	default:
+		return filepath.Join(b.outputDir, "poster_001.jpg")
+	}
+}
+
+// contains reports whether list holds value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
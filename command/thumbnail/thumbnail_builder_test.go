@@ -0,0 +1,199 @@
+package thumbnail
+
+import (
+	"encoder/command"
+	"strings"
+	"testing"
+)
+
+func TestNewThumbnailBuilder(t *testing.T) {
+	tests := []struct {
+		kind   Kind
+		width  int
+		output string
+	}{
+		{KindPoster, DefaultPosterWidth, "/out/thumbs/poster_001.jpg"},
+		{KindSprite, DefaultTileWidth, "/out/thumbs/sprite.vtt"},
+		{KindPreview, DefaultPreviewWidth, "/out/thumbs/preview.webp"},
+	}
+
+	for _, tt := range tests {
+		builder := NewThumbnailBuilder(tt.kind, "/in/movie.mkv", "/out/thumbs")
+		if builder.width != tt.width {
+			t.Errorf("%s: width = %d, want %d", tt.kind, builder.width, tt.width)
+		}
+		if builder.GetOutputPath() != tt.output {
+			t.Errorf("%s: output = %s, want %s", tt.kind, builder.GetOutputPath(), tt.output)
+		}
+		if builder.GetPriority() != command.PriorityLow {
+			t.Errorf("%s: priority = %d, want low", tt.kind, builder.GetPriority())
+		}
+		if builder.GetTaskType() != command.TaskTypeThumbnail {
+			t.Errorf("%s: task type = %s, want thumbnail", tt.kind, builder.GetTaskType())
+		}
+	}
+
+	// The builder is usable as an orchestrator task
+	var _ command.Command = NewThumbnailBuilder(KindPoster, "in.mkv", "out")
+}
+
+func TestThumbnailBuilder_PosterArgs(t *testing.T) {
+	builder := NewThumbnailBuilder(KindPoster, "/in/movie.mkv", "/out/thumbs").
+		AddFilter("bwdif=mode=send_frame").
+		SetSelection(SceneSelection(0.3)).
+		SetCount(3)
+
+	args := strings.Join(builder.BuildArgs(), " ")
+	for _, want := range []string{
+		"-i /in/movie.mkv",
+		`-vf bwdif=mode=send_frame,select=gt(scene\,0.3),scale=w=1280:h=-2`,
+		"-frames:v 3",
+		"/out/thumbs/poster_%03d.jpg",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+
+	names := builder.RequiredFilters()
+	if strings.Join(names, ",") != "bwdif,select,scale" {
+		t.Errorf("RequiredFilters() = %v, want [bwdif select scale]", names)
+	}
+}
+
+func TestThumbnailBuilder_SpriteArgs(t *testing.T) {
+	builder := NewThumbnailBuilder(KindSprite, "/in/movie.mkv", "/out/thumbs").
+		SetSelection(IntervalSelection(5)).
+		SetGrid(5, 4)
+
+	args := strings.Join(builder.BuildArgs(), " ")
+	want := "-vf fps=1/5,showinfo,scale=w=160:h=90:force_original_aspect_ratio=decrease,pad=160:90:(ow-iw)/2:(oh-ih)/2,tile=5x4"
+	if !strings.Contains(args, want) {
+		t.Errorf("args missing %q: %s", want, args)
+	}
+	if !strings.Contains(args, "/out/thumbs/sprite_%03d.jpg") {
+		t.Errorf("args missing sprite sheet pattern: %s", args)
+	}
+}
+
+func TestThumbnailBuilder_PreviewArgs(t *testing.T) {
+	t.Run("evenly spread webp", func(t *testing.T) {
+		builder := NewThumbnailBuilder(KindPreview, "/in/movie.mkv", "/out/thumbs").
+			SetDuration(120)
+
+		args := strings.Join(builder.BuildArgs(), " ")
+		// 6 clips of 2s centered in 20s shares
+		for _, want := range []string{
+			"-ss 9 -t 2 -i /in/movie.mkv",
+			"-ss 109 -t 2 -i /in/movie.mkv",
+			"[5:v:0]fps=12,scale=w=480:h=-2,setsar=1[v5]",
+			"[v0][v1][v2][v3][v4][v5]concat=n=6:v=1:a=0[out]",
+			"-c:v libwebp",
+			"/out/thumbs/preview.webp",
+		} {
+			if !strings.Contains(args, want) {
+				t.Errorf("args missing %q: %s", want, args)
+			}
+		}
+	})
+
+	t.Run("gif with given clips", func(t *testing.T) {
+		builder := NewThumbnailBuilder(KindPreview, "/in/movie.mkv", "/out/thumbs").
+			SetClips([]float64{30, 60.5}, 1.5).
+			SetPreviewFormat(PreviewGIF)
+
+		args := strings.Join(builder.BuildArgs(), " ")
+		for _, want := range []string{
+			"-ss 30 -t 1.5 -i /in/movie.mkv -ss 60.5 -t 1.5 -i /in/movie.mkv",
+			"[v0][v1]concat=n=2:v=1:a=0,split[a][b];[a]palettegen[p];[b][p]paletteuse[out]",
+			"/out/thumbs/preview.gif",
+		} {
+			if !strings.Contains(args, want) {
+				t.Errorf("args missing %q: %s", want, args)
+			}
+		}
+		if names := strings.Join(builder.RequiredFilters(), ","); !strings.Contains(names, "palettegen") {
+			t.Errorf("RequiredFilters() = %s, want palettegen", names)
+		}
+	})
+}
+
+func TestThumbnailBuilder_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *ThumbnailBuilder
+		wantErr bool
+	}{
+		{"poster", NewThumbnailBuilder(KindPoster, "in.mkv", "out"), false},
+		{"no input", NewThumbnailBuilder(KindPoster, "", "out"), true},
+		{"no output directory", NewThumbnailBuilder(KindSprite, "in.mkv", ""), true},
+		{"zero posters", NewThumbnailBuilder(KindPoster, "in.mkv", "out").SetCount(0), true},
+		{"empty grid", NewThumbnailBuilder(KindSprite, "in.mkv", "out").SetGrid(0, 10), true},
+		{"chapters without chapters", NewThumbnailBuilder(KindSprite, "in.mkv", "out").SetSelection(ChapterSelection(nil)), true},
+		{"preview without duration", NewThumbnailBuilder(KindPreview, "in.mkv", "out"), true},
+		{"preview", NewThumbnailBuilder(KindPreview, "in.mkv", "out").SetDuration(60), false},
+		{"unknown preview format", NewThumbnailBuilder(KindPreview, "in.mkv", "out").SetDuration(60).SetPreviewFormat("avif"), true},
+		{"unknown kind", NewThumbnailBuilder("gallery", "in.mkv", "out"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.builder.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSpriteIndexVTT(t *testing.T) {
+	times := []float64{0, 10, 20, 30, 40}
+	got := SpriteIndexVTT(times, 45.5, 160, 90, 2, 2)
+
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+sprite_001.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+sprite_001.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:30.000
+sprite_001.jpg#xywh=0,90,160,90
+
+00:00:30.000 --> 00:00:40.000
+sprite_001.jpg#xywh=160,90,160,90
+
+00:00:40.000 --> 00:00:45.500
+sprite_002.jpg#xywh=0,0,160,90
+`
+	if got != want {
+		t.Errorf("SpriteIndexVTT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseFrameTimes(t *testing.T) {
+	log := `[Parsed_showinfo_1 @ 0x55] n:   0 pts:      0 pts_time:0       duration:1
+[Parsed_showinfo_1 @ 0x55] n:   1 pts: 128000 pts_time:10      duration:1
+frame=    2 fps=0.0 q=-0.0 size=N/A time=00:00:10.00
+[Parsed_showinfo_1 @ 0x55] n:   2 pts: 256000 pts_time:20.02   duration:1`
+
+	times := parseFrameTimes(log)
+	if len(times) != 3 || times[0] != 0 || times[1] != 10 || times[2] != 20.02 {
+		t.Errorf("parseFrameTimes() = %v, want [0 10 20.02]", times)
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:       "00:00:00.000",
+		61.25:   "00:01:01.250",
+		3725.5:  "01:02:05.500",
+		-1:      "00:00:00.000",
+		59.9996: "00:01:00.000",
+	}
+	for in, want := range tests {
+		if got := vttTimestamp(in); got != want {
+			t.Errorf("vttTimestamp(%v) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package video
+
+import (
+	"encoder/codec"
+	"math"
+	"strconv"
+)
+
+// mp4Timescale is the track timescale of MP4/MOV chunks. A fixed value keeps
+// the timebase identical across chunks so they can be joined with -c copy.
+const mp4Timescale = "90000"
+
+// SetClosedGOP controls the options that make chunks safe to concatenate:
+// a forced IDR frame at the chunk start, closed GOPs and consistent timing.
+// Enabled by default.
+func (v *VideoBuilder) SetClosedGOP(enabled bool) *VideoBuilder {
+	v.closedGOP = enabled
+	return v
+}
+
+// SetKeyframeInterval forces a keyframe every seconds of source time, e.g. at
+// the segment duration of a streaming package so segments can be cut on
+// them. The grid is anchored at the start of the source, not of the chunk,
+// so keyframes stay evenly spaced across chunk joins. The chunk start is
+// always a keyframe. 0 only forces the keyframe at the chunk start.
+func (v *VideoBuilder) SetKeyframeInterval(seconds float64) *VideoBuilder {
+	v.keyframeInterval = seconds
+	return v
+}
+
+// gopArgs returns the keyframe and timing options for a chunk that must
+// start with a keyframe no later frame refers across.
+func (v *VideoBuilder) gopArgs() []string {
+	if v.keyframeInterval > 0 {
+		forced := v.keyframeExpr()
+		if !v.closedGOP {
+			return []string{"-force_key_frames", forced}
+		}
+		return v.closedGOPArgs(forced)
+	}
+	if !v.closedGOP {
+		return nil
+	}
+	return v.closedGOPArgs("expr:eq(n,0)")
+}
+
+// keyframeExpr returns the -force_key_frames expression placing keyframes on
+// multiples of the interval in source time. t restarts at 0 in every chunk,
+// so the chunk's offset into its grid cell is subtracted: after the first
+// frame (n_forced is 0, always forced) the n-th keyframe lands on the n-th
+// grid point after the chunk start.
+func (v *VideoBuilder) keyframeExpr() string {
+	interval := v.keyframeInterval
+	// Rounded to milliseconds so float noise in chunk starts cannot put a
+	// keyframe just after the chunk start
+	offset := math.Round(math.Mod(v.chunk.StartTime, interval)*1000) / 1000
+	if offset >= interval {
+		offset = 0
+	}
+
+	expr := "n_forced*" + strconv.FormatFloat(interval, 'f', -1, 64)
+	if offset > 0 {
+		expr += "-" + strconv.FormatFloat(offset, 'f', -1, 64)
+	}
+	return "expr:gte(t," + expr + ")"
+}
+
+// closedGOPArgs returns the closed-GOP options with the given keyframe
+// expression.
+func (v *VideoBuilder) closedGOPArgs(forced string) []string {
+	args := []string{"-force_key_frames", forced}
+	if c, ok := codec.Lookup(v.encoderName()); ok && c.ForcedIDR {
+		args = append(args, "-forced-idr", "1")
+	}
+
+	// Constant frame timing when the rate is set, else the source timing
+	if v.frameRate > 0 || v.chunk.FrameRate != "" {
+		args = append(args, "-fps_mode", "cfr")
+	} else {
+		args = append(args, "-fps_mode", "passthrough")
+	}
+
+	// Matroska always uses a 1ms timebase; MP4/MOV pick one per file
+	if v.pass != 1 {
+		switch codec.ContainerOf(v.outputPath) {
+		case "mp4", "m4v", "mov":
+			args = append(args, "-video_track_timescale", mp4Timescale)
+		}
+	}
+	return args
+}
+
+// gopParams returns the encoder params that keep GOPs closed.
+func (v *VideoBuilder) gopParams(c *codec.Codec) []codecParam {
+	if !v.closedGOP {
+		return nil
+	}
+	params := make([]codecParam, len(c.ClosedGOP))
+	for i, p := range c.ClosedGOP {
+		params[i] = codecParam{key: p.Key, value: p.Value}
+	}
+	return params
+}
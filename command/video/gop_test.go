@@ -0,0 +1,95 @@
+package video
+
+import (
+	"encoder/models"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVideoBuilder_ClosedGOPDefaults(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10}
+	args := NewVideoBuilder(chunk, "out.mkv").SetCodec("libx264").BuildArgs()
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{"-force_key_frames expr:eq(n,0)", "-forced-idr 1", "-fps_mode passthrough", "-x264-params open-gop=0"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args missing %q: %v", want, args)
+		}
+	}
+	if slices.Contains(args, "-video_track_timescale") {
+		t.Errorf("Matroska output should not set a track timescale: %v", args)
+	}
+}
+
+func TestVideoBuilder_ClosedGOPTimingOptions(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10, FrameRate: "24000/1001"}
+	args := NewVideoBuilder(chunk, "out.mp4").SetCodec("libsvtav1").BuildArgs()
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{"-fps_mode cfr", "-video_track_timescale 90000", "irefresh-type=2"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args missing %q: %v", want, args)
+		}
+	}
+	if slices.Contains(args, "-forced-idr") {
+		t.Errorf("SVT-AV1 has no -forced-idr option: %v", args)
+	}
+}
+
+func TestVideoBuilder_ClosedGOPCodecParamOverride(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10}
+	args := NewVideoBuilder(chunk, "out.mkv").
+		SetCodec("libx265").
+		SetCodecParam("open-gop", "1").
+		BuildArgs()
+
+	if !strings.Contains(strings.Join(args, " "), "-x265-params open-gop=1") || strings.Count(strings.Join(args, " "), "open-gop") != 1 {
+		t.Errorf("SetCodecParam should replace the closed-GOP param: %v", args)
+	}
+}
+
+func TestVideoBuilder_SetClosedGOPDisabled(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10}
+	args := NewVideoBuilder(chunk, "out.mp4").SetCodec("libx264").SetClosedGOP(false).BuildArgs()
+	joined := strings.Join(args, " ")
+
+	for _, unwanted := range []string{"-force_key_frames", "-forced-idr", "-fps_mode", "-video_track_timescale", "open-gop"} {
+		if strings.Contains(joined, unwanted) {
+			t.Errorf("args should not contain %q: %v", unwanted, args)
+		}
+	}
+}
+
+func TestVideoBuilder_SetKeyframeInterval(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10}
+
+	args := strings.Join(NewVideoBuilder(chunk, "out.mkv").SetCodec("libx264").SetKeyframeInterval(6).BuildArgs(), " ")
+	if !strings.Contains(args, "-force_key_frames expr:gte(t,n_forced*6) -forced-idr 1") {
+		t.Errorf("expected keyframes every 6s with closed GOPs: %s", args)
+	}
+
+	args = strings.Join(NewVideoBuilder(chunk, "out.mkv").SetCodec("libx264").SetClosedGOP(false).SetKeyframeInterval(2.5).BuildArgs(), " ")
+	if !strings.Contains(args, "-force_key_frames expr:gte(t,n_forced*2.5)") || strings.Contains(args, "-forced-idr") {
+		t.Errorf("expected only forced keyframes without closed GOPs: %s", args)
+	}
+}
+
+func TestVideoBuilder_SetKeyframeInterval_SourceGrid(t *testing.T) {
+	tests := []struct {
+		start float64
+		want  string
+	}{
+		{start: 12, want: "expr:gte(t,n_forced*6)"},         // On the grid
+		{start: 14.5, want: "expr:gte(t,n_forced*6-2.5)"},   // Next keyframe at 18s
+		{start: 17.9999999, want: "expr:gte(t,n_forced*6)"}, // Float noise
+	}
+
+	for _, tt := range tests {
+		chunk := &models.Chunk{ChunkID: 2, SourcePath: "input.mkv", StartTime: tt.start, EndTime: tt.start + 30}
+		args := strings.Join(NewVideoBuilder(chunk, "out.mkv").SetCodec("libx264").SetKeyframeInterval(6).BuildArgs(), " ")
+		if !strings.Contains(args, "-force_key_frames "+tt.want+" ") {
+			t.Errorf("start %v: expected %s: %s", tt.start, tt.want, args)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package video
+
+import (
+	"encoder/codec"
+	"encoder/models"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// HDRMode selects what happens to HDR sources
+type HDRMode string
+
+const (
+	HDRPreserve HDRMode = "preserve" // Keep HDR: 10-bit output with color tags and static metadata
+	HDRTonemap  HDRMode = "tonemap"  // Convert to SDR BT.709
+	HDRIgnore   HDRMode = "ignore"   // Encode as-is without HDR handling (old behavior)
+)
+
+// ResolveHDRMode returns the mode to use for an HDR source with the given
+// encoder. Preserving needs an encoder that can produce 10-bit output;
+// otherwise the result falls back to tone-mapping and reason explains why.
+// An empty mode means HDRPreserve.
+func ResolveHDRMode(mode HDRMode, encoder string) (resolved HDRMode, reason string) {
+	if mode == "" {
+		mode = HDRPreserve
+	}
+	if mode != HDRPreserve {
+		return mode, ""
+	}
+	if c, ok := codec.Lookup(encoder); ok && !c.TenBit {
+		return HDRTonemap, fmt.Sprintf("%s cannot encode 10-bit video", encoder)
+	}
+	return HDRPreserve, ""
+}
+
+// ApplyHDR configures the builder for an HDR source. A nil meta (SDR
+// source) leaves the builder unchanged. Call ResolveHDRMode first to pick
+// a mode the encoder supports.
+func (v *VideoBuilder) ApplyHDR(meta *models.HDRMetadata, mode HDRMode, tonemapAlgorithm string) *VideoBuilder {
+	if meta == nil {
+		return v
+	}
+	switch mode {
+	case HDRPreserve:
+		v.PreserveHDR(meta)
+	case HDRTonemap:
+		v.AddToneMapping(tonemapAlgorithm)
+	}
+	return v
+}
+
+// PreserveHDR keeps the source's HDR signalling: 10-bit output, color tags
+// and, for x265 and SVT-AV1, the mastering display and content light level
+// metadata. The encoder-specific params are generated when the arguments
+// are built, so the codec may be changed afterwards.
+func (v *VideoBuilder) PreserveHDR(meta *models.HDRMetadata) *VideoBuilder {
+	v.hdr = meta
+	if v.pixelFormat == "" {
+		v.pixelFormat = "yuv420p10le"
+	}
+	return v
+}
+
+// SetCodecParam sets an encoder-private parameter passed through the
+// encoder's params option (-x265-params, -svtav1-params, ...). Setting the
+// same key again replaces the value. Ignored for encoders without one.
+func (v *VideoBuilder) SetCodecParam(key, value string) *VideoBuilder {
+	for i := range v.codecParams {
+		if v.codecParams[i].key == key {
+			v.codecParams[i].value = value
+			return v
+		}
+	}
+	v.codecParams = append(v.codecParams, codecParam{key: key, value: value})
+	return v
+}
+
+// codecParam is one key=value entry of the encoder params option
+type codecParam struct {
+	key   string
+	value string
+}
+
+// hdrColorArgs returns the ffmpeg color tagging options for preserved HDR
+func (v *VideoBuilder) hdrColorArgs() []string {
+	if v.hdr == nil {
+		return nil
+	}
+	return []string{
+		"-color_primaries", v.hdr.ColorPrimaries,
+		"-color_trc", v.hdr.ColorTransfer,
+		"-colorspace", v.hdr.ColorSpace,
+		"-color_range", v.hdr.ColorRange,
+	}
+}
+
+// encoderParamArgs merges the HDR, two-pass and typed params (see
+// SetEncoderParams) for the selected encoder with the params set through
+// SetCodecParam (which win) into a single option. The closed-GOP params come
+// last and are only added for keys not already set.
+func (v *VideoBuilder) encoderParamArgs() []string {
+	c, ok := codec.Lookup(v.encoderName())
+	if !ok || c.ParamsOption == "" {
+		return nil
+	}
+
+	var params []codecParam
+	if v.hdr != nil {
+		params = append(params, hdrCodecParams(c.Name, v.hdr)...)
+	}
+	params = append(params, v.passParams(c)...)
+	if v.encoderParams != nil {
+		typed, _ := v.encoderParams.Options(c.Name)
+		for _, p := range typed {
+			params = append(params, codecParam{key: p.Key, value: p.Value})
+		}
+	}
+	for _, p := range v.codecParams {
+		replaced := false
+		for i := range params {
+			if params[i].key == p.key {
+				params[i].value = p.value
+				replaced = true
+			}
+		}
+		if !replaced {
+			params = append(params, p)
+		}
+	}
+	for _, p := range v.gopParams(c) {
+		if !slices.ContainsFunc(params, func(q codecParam) bool { return q.key == p.key }) {
+			params = append(params, p)
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.key + "=" + p.value
+	}
+	return []string{"-" + c.ParamsOption, strings.Join(parts, ":")}
+}
+
+// hdrCodecParams returns the encoder params that carry HDR static metadata.
+// Other encoders rely on the -color_* options alone.
+func hdrCodecParams(encoder string, meta *models.HDRMetadata) []codecParam {
+	md := meta.MasteringDisplay
+
+	switch encoder {
+	case "libx265":
+		params := []codecParam{
+			{"repeat-headers", "1"},
+			{"colorprim", meta.ColorPrimaries},
+			{"transfer", meta.ColorTransfer},
+			{"colormatrix", meta.ColorSpace},
+		}
+		if meta.ColorTransfer == "smpte2084" {
+			params = append(params, codecParam{"hdr10", "1"})
+		}
+		// x265 wants chromaticity in 0.00002 units and luminance in 0.0001 cd/m²
+		if md != nil {
+			params = append(params, codecParam{"master-display", fmt.Sprintf("G(%d,%d)B(%d,%d)R(%d,%d)WP(%d,%d)L(%d,%d)",
+				scale(md.GreenX, 50000), scale(md.GreenY, 50000),
+				scale(md.BlueX, 50000), scale(md.BlueY, 50000),
+				scale(md.RedX, 50000), scale(md.RedY, 50000),
+				scale(md.WhiteX, 50000), scale(md.WhiteY, 50000),
+				scale(md.MaxLuminance, 10000), scale(md.MinLuminance, 10000))})
+		}
+		if meta.HasContentLight() {
+			params = append(params, codecParam{"max-cll", fmt.Sprintf("%d,%d", meta.MaxCLL, meta.MaxFALL)})
+		}
+		return params
+
+	case "libsvtav1":
+		// Color primaries/transfer/matrix are taken from the -color_* options
+		params := []codecParam{{"enable-hdr", "1"}}
+		if md != nil {
+			params = append(params, codecParam{"mastering-display", fmt.Sprintf("G(%.4f,%.4f)B(%.4f,%.4f)R(%.4f,%.4f)WP(%.4f,%.4f)L(%.4f,%.4f)",
+				md.GreenX, md.GreenY, md.BlueX, md.BlueY, md.RedX, md.RedY,
+				md.WhiteX, md.WhiteY, md.MaxLuminance, md.MinLuminance)})
+		}
+		if meta.HasContentLight() {
+			params = append(params, codecParam{"content-light", fmt.Sprintf("%d,%d", meta.MaxCLL, meta.MaxFALL)})
+		}
+		return params
+	}
+
+	return nil
+}
+
+func scale(value float64, factor float64) int64 {
+	return int64(math.Round(value * factor))
+}
@@ -0,0 +1,33 @@
+package video
+
+import "encoder/codec"
+
+// SetEncoderParams applies typed encoder tuning. Only the block of the
+// selected encoder is used; it is serialised into the encoder's params
+// option (-x264-params, -x265-params, -svtav1-params) or into plain ffmpeg
+// options when the arguments are built. Params set through SetCodecParam
+// take precedence.
+func (v *VideoBuilder) SetEncoderParams(params codec.EncoderParams) *VideoBuilder {
+	v.encoderParams = &params
+	return v
+}
+
+// encoderOptionArgs returns the typed tuning that is passed as plain ffmpeg
+// options (e.g. -tune, or -g and -lag-in-frames for libaom and libvpx).
+func (v *VideoBuilder) encoderOptionArgs() []string {
+	if v.encoderParams == nil {
+		return nil
+	}
+	_, args := v.encoderParams.Options(v.encoderName())
+	return args
+}
+
+// outputPixelFormat returns the pixel format to encode to: the one set
+// explicitly (or by PreserveHDR), else 10-bit when the encoder params ask
+// for it.
+func (v *VideoBuilder) outputPixelFormat() string {
+	if v.pixelFormat == "" && v.encoderParams != nil && v.encoderParams.TenBit(v.encoderName()) {
+		return "yuv420p10le"
+	}
+	return v.pixelFormat
+}
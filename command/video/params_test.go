@@ -0,0 +1,48 @@
+package video
+
+import (
+	"encoder/codec"
+	"encoder/models"
+	"strings"
+	"testing"
+)
+
+func TestVideoBuilder_SetEncoderParams(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+	params := codec.EncoderParams{
+		X265:   codec.X265Params{Tune: "grain", Keyint: 240, TenBit: true},
+		SVTAV1: codec.SVTAV1Params{LP: 4, Pin: 1},
+	}
+
+	args := strings.Join(NewVideoBuilder(chunk, "/out.mkv").
+		SetCodec("libx265").
+		SetEncoderParams(params).
+		SetCodecParam("keyint", "120").
+		BuildArgs(), " ")
+
+	// SetCodecParam wins over the typed value; other encoders' blocks are ignored
+	if !strings.Contains(args, "-x265-params keyint=120") {
+		t.Errorf("Expected typed params overridden by SetCodecParam, got: %s", args)
+	}
+	if strings.Contains(args, "lp=4") {
+		t.Errorf("Expected SVT-AV1 params to be ignored for x265, got: %s", args)
+	}
+	if !strings.Contains(args, "-tune grain") || !strings.Contains(args, "-pix_fmt yuv420p10le") {
+		t.Errorf("Expected -tune and 10-bit output, got: %s", args)
+	}
+
+	args = strings.Join(NewVideoBuilder(chunk, "/out.mkv").
+		SetCodec("libsvtav1").
+		SetPreset("8").
+		SetEncoderParams(params).
+		BuildArgs(), " ")
+	if !strings.Contains(args, "-svtav1-params lp=4:pin=1") {
+		t.Errorf("Expected SVT-AV1 params, got: %s", args)
+	}
+
+	bad := NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").
+		SetEncoderParams(codec.EncoderParams{X264: codec.X264Params{Tune: "cinema"}})
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), "x264 tune") {
+		t.Errorf("Expected invalid tune to be rejected, got %v", err)
+	}
+}
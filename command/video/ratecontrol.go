@@ -0,0 +1,163 @@
+package video
+
+import (
+	"encoder/codec"
+	"encoder/units"
+	"fmt"
+	"strconv"
+)
+
+// containerOverhead is the share of a target file size reserved for the
+// container (headers, index, packet framing).
+const containerOverhead = 0.01
+
+// SetAverageBitrate encodes at an average bitrate (ABR) instead of constant
+// quality. Unlike SetBitrate, no CRF is passed alongside the bitrate.
+func (v *VideoBuilder) SetAverageBitrate(bitrate string) *VideoBuilder {
+	v.bitrate = bitrate
+	v.abr = true
+	return v
+}
+
+// SetMaxRate caps the bitrate through the encoder's VBV (e.g., "8M"). Used
+// with CRF this gives capped CRF; used with a bitrate it bounds the peaks.
+func (v *VideoBuilder) SetMaxRate(rate string) *VideoBuilder {
+	v.maxRate = rate
+	return v
+}
+
+// SetBufSize sets the VBV buffer size (e.g., "16M"). Without it, twice the
+// max rate is used.
+func (v *VideoBuilder) SetBufSize(size string) *VideoBuilder {
+	v.bufSize = size
+	return v
+}
+
+// SetPass makes the command one pass of a two-pass encode. Both passes use
+// the same logPrefix; the first pass writes the stats to PassLogPath and
+// discards the video. pass 0 is a single-pass encode.
+func (v *VideoBuilder) SetPass(pass int, logPrefix string) *VideoBuilder {
+	v.pass = pass
+	v.passLogPrefix = logPrefix
+	return v
+}
+
+// TwoPass turns the builder into the second pass of a two-pass encode and
+// returns a copy configured as the first pass. Settings changed afterwards
+// only apply to the pass they are made on.
+func (v *VideoBuilder) TwoPass(logPrefix string) *VideoBuilder {
+	first := *v
+	first.cpuFilters = append([]string{}, v.cpuFilters...)
+	first.gpuFilters = append([]string{}, v.gpuFilters...)
+	first.extraArgs = append([]string{}, v.extraArgs...)
+	first.codecParams = append([]codecParam{}, v.codecParams...)
+	first.SetPass(1, logPrefix)
+
+	v.SetPass(2, logPrefix)
+	return &first
+}
+
+// Pass returns the pass of a two-pass encode, or 0 for a single pass.
+func (v *VideoBuilder) Pass() int {
+	return v.pass
+}
+
+// PassLogPath returns the stats file shared by the two passes.
+func (v *VideoBuilder) PassLogPath() string {
+	if v.pass == 0 {
+		return ""
+	}
+	// ffmpeg names the log after the prefix and the output stream index
+	return v.passLogPrefix + "-0.log"
+}
+
+// rateControlArgs returns the VBV options.
+func (v *VideoBuilder) rateControlArgs() []string {
+	if v.maxRate == "" {
+		return nil
+	}
+	args := []string{"-maxrate", v.maxRate}
+	bufSize := v.bufSize
+	if bufSize == "" {
+		if rate, err := units.ParseBitrate(v.maxRate); err == nil {
+			bufSize = units.FormatBitrate(2 * rate)
+		}
+	}
+	if bufSize != "" {
+		args = append(args, "-bufsize", bufSize)
+	}
+	return args
+}
+
+// passArgs returns the two-pass options for encoders that take -pass.
+// Encoders that configure passes through their params option get them from
+// passParams instead.
+func (v *VideoBuilder) passArgs() []string {
+	if v.pass == 0 {
+		return nil
+	}
+	if c, ok := codec.Lookup(v.encoderName()); ok && c.PassParams {
+		return nil
+	}
+	return []string{"-pass", strconv.Itoa(v.pass), "-passlogfile", v.passLogPrefix}
+}
+
+// passParams returns the two-pass encoder params (x265: pass=N:stats=FILE).
+func (v *VideoBuilder) passParams(c *codec.Codec) []codecParam {
+	if v.pass == 0 || !c.PassParams {
+		return nil
+	}
+	return []codecParam{
+		{"pass", strconv.Itoa(v.pass)},
+		{"stats", v.PassLogPath()},
+	}
+}
+
+// validateRateControl checks the bitrate, VBV and two-pass settings.
+func (v *VideoBuilder) validateRateControl() []string {
+	var errors []string
+	for _, opt := range []struct{ name, value string }{
+		{"bitrate", v.bitrate},
+		{"max rate", v.maxRate},
+		{"buffer size", v.bufSize},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		if _, err := units.ParseBitrate(opt.value); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid %s: %v", opt.name, err))
+		}
+	}
+
+	if v.pass != 0 {
+		if v.pass != 1 && v.pass != 2 {
+			errors = append(errors, fmt.Sprintf("pass must be 1 or 2, got %d", v.pass))
+		}
+		if v.bitrate == "" {
+			errors = append(errors, "two-pass encoding needs a bitrate")
+		}
+		if v.passLogPrefix == "" {
+			errors = append(errors, "two-pass encoding needs a pass log file")
+		}
+		if c, ok := codec.Lookup(v.encoderName()); ok && !c.TwoPass {
+			errors = append(errors, fmt.Sprintf("%s does not support two-pass encoding", c.Name))
+		}
+	}
+	return errors
+}
+
+// TargetBitrate returns the video bitrate (bits per second) that makes a
+// file of duration seconds come out at size bytes, given the audio bitrate
+// muxed alongside it. A small share is reserved for container overhead.
+func TargetBitrate(size int64, duration float64, audioBitrate int64) (int64, error) {
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	total := float64(size) * 8 * (1 - containerOverhead) / duration
+	video := int64(total) - audioBitrate
+	if video <= 0 {
+		return 0, fmt.Errorf("%d bytes over %.0fs leave no room for video after %s of audio",
+			size, duration, units.FormatBitrate(audioBitrate))
+	}
+	return video, nil
+}
@@ -0,0 +1,129 @@
+package video
+
+import (
+	"encoder/models"
+	"strings"
+	"testing"
+)
+
+func TestVideoBuilder_TwoPass(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 3, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	second := NewVideoBuilder(chunk, "/work/video_chunk_003.mkv").
+		SetCodec("libx264").
+		SetCRF(23).
+		SetAverageBitrate("4500k")
+	first := second.TwoPass("/work/passlogs/chunk_003")
+
+	firstArgs := strings.Join(first.BuildArgs(), " ")
+	if !strings.Contains(firstArgs, "-pass 1 -passlogfile /work/passlogs/chunk_003") {
+		t.Errorf("Expected first pass options, got: %s", firstArgs)
+	}
+	if !strings.HasSuffix(firstArgs, "-f null -y -") {
+		t.Errorf("Expected first pass to discard the video, got: %s", firstArgs)
+	}
+	if strings.Contains(firstArgs, "-crf") {
+		t.Errorf("Two-pass encodes should not pass a CRF: %s", firstArgs)
+	}
+	if first.GetOutputPath() != "/work/passlogs/chunk_003-0.log" {
+		t.Errorf("Expected first pass output to be its stats file, got %s", first.GetOutputPath())
+	}
+
+	secondArgs := strings.Join(second.BuildArgs(), " ")
+	if !strings.Contains(secondArgs, "-b:v 4500k") || !strings.Contains(secondArgs, "-pass 2 -passlogfile /work/passlogs/chunk_003") {
+		t.Errorf("Expected second pass options, got: %s", secondArgs)
+	}
+	if !strings.HasSuffix(secondArgs, "-y /work/video_chunk_003.mkv") {
+		t.Errorf("Expected second pass to write the chunk, got: %s", secondArgs)
+	}
+
+	// The passes are independent copies
+	first.AddCPUFilter("yadif")
+	if len(second.CPUFilters()) != 0 {
+		t.Error("Expected first pass filters not to leak into the second pass")
+	}
+}
+
+func TestVideoBuilder_TwoPassX265Params(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+	second := NewVideoBuilder(chunk, "/work/out.mkv").SetCodec("libx265").SetAverageBitrate("3M")
+	first := second.TwoPass("/work/passlogs/chunk_001")
+
+	args := strings.Join(first.BuildArgs(), " ")
+	if !strings.Contains(args, "-x265-params pass=1:stats=/work/passlogs/chunk_001-0.log") {
+		t.Errorf("Expected x265 pass params, got: %s", args)
+	}
+	if strings.Contains(args, "-pass ") {
+		t.Errorf("x265 passes should not use -pass: %s", args)
+	}
+}
+
+func TestVideoBuilder_Validate_RateControl(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	tests := []struct {
+		name    string
+		build   func() *VideoBuilder
+		wantErr string
+	}{
+		{"capped CRF", func() *VideoBuilder {
+			return NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").SetCRF(23).SetMaxRate("8M")
+		}, ""},
+		{"two-pass without bitrate", func() *VideoBuilder {
+			return NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").SetPass(2, "/work/log")
+		}, "needs a bitrate"},
+		{"two-pass on SVT-AV1", func() *VideoBuilder {
+			return NewVideoBuilder(chunk, "/out.mkv").SetCodec("libsvtav1").SetPreset("8").SetAverageBitrate("3M").SetPass(1, "/work/log")
+		}, "libsvtav1 does not support two-pass"},
+		{"bad max rate", func() *VideoBuilder {
+			return NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").SetMaxRate("fast")
+		}, "invalid max rate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build().Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVideoBuilder_VBV(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	args := strings.Join(NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").SetCRF(23).SetMaxRate("8M").BuildArgs(), " ")
+	if !strings.Contains(args, "-maxrate 8M -bufsize 16000k") || !strings.Contains(args, "-crf 23") {
+		t.Errorf("Expected capped CRF with a default buffer, got: %s", args)
+	}
+
+	args = strings.Join(NewVideoBuilder(chunk, "/out.mkv").SetCodec("libx264").SetAverageBitrate("5M").SetMaxRate("8M").SetBufSize("10M").BuildArgs(), " ")
+	if !strings.Contains(args, "-b:v 5M -maxrate 8M -bufsize 10M") || strings.Contains(args, "-crf") {
+		t.Errorf("Expected constrained ABR without CRF, got: %s", args)
+	}
+}
+
+func TestTargetBitrate(t *testing.T) {
+	// 100 MB over 800s: 1,000,000 bps before overhead, minus 128k audio
+	bitrate, err := TargetBitrate(100000000, 800, 128000)
+	if err != nil {
+		t.Fatalf("TargetBitrate failed: %v", err)
+	}
+	if bitrate != 862000 {
+		t.Errorf("Expected 862000 bps, got %d", bitrate)
+	}
+
+	if _, err := TargetBitrate(1000000, 3600, 128000); err == nil || !strings.Contains(err.Error(), "no room for video") {
+		t.Errorf("Expected error for a size too small for the audio, got %v", err)
+	}
+	if _, err := TargetBitrate(1000000, 0, 0); err == nil {
+		t.Error("Expected error for zero duration")
+	}
+}
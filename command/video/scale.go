@@ -0,0 +1,31 @@
+package video
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseResolution parses a WIDTHxHEIGHT resolution such as "1280x720".
+func ParseResolution(res string) (width, height int, err error) {
+	w, h, ok := strings.Cut(res, "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid resolution %q, expected WIDTHxHEIGHT", res)
+	}
+	return width, height, nil
+}
+
+// AddScale scales the picture to fit inside width×height (CPU operation).
+// The aspect ratio is kept and both sides are rounded to even numbers, so
+// sources cropped to another shape come out e.g. 1280x536 for 1280x720.
+func (v *VideoBuilder) AddScale(width, height int) *VideoBuilder {
+	filter := fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2", width, height)
+	v.cpuFilters = append(v.cpuFilters, filter)
+	return v
+}
@@ -0,0 +1,49 @@
+package video
+
+import (
+	"encoder/models"
+	"testing"
+)
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		in            string
+		width, height int
+		wantErr       bool
+	}{
+		{"1920x1080", 1920, 1080, false},
+		{"854x480", 854, 480, false},
+		{"", 0, 0, true},
+		{"720p", 0, 0, true},
+		{"1280x", 0, 0, true},
+		{"0x720", 0, 0, true},
+		{"1280x-720", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		width, height, err := ParseResolution(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseResolution(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if width != tt.width || height != tt.height {
+			t.Errorf("ParseResolution(%q) = %dx%d, want %dx%d", tt.in, width, height, tt.width, tt.height)
+		}
+	}
+}
+
+func TestVideoBuilder_AddScale(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, SourcePath: "input.mkv", StartTime: 0, EndTime: 10}
+	builder := NewVideoBuilder(chunk, "out.mkv").
+		SetCodec("libx264").
+		AddCPUFilter("crop=1920:800:0:140").
+		AddScale(1280, 720)
+
+	want := "crop=1920:800:0:140,scale=w=1280:h=720:force_original_aspect_ratio=decrease:force_divisible_by=2"
+	if got := builder.buildFilterChain(); got != want {
+		t.Errorf("filter chain = %q, want %q", got, want)
+	}
+	if names := builder.RequiredFilters(); len(names) != 2 || names[1] != "scale" {
+		t.Errorf("RequiredFilters() = %v, want [crop scale]", names)
+	}
+}
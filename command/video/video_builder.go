@@ -2,11 +2,15 @@ package video
 
 import (
 	"bytes"
+	"context"
+	"encoder/codec"
 	"encoder/command"
 	"encoder/ffmpeg"
+	"encoder/logging"
 	"encoder/models"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
 	"strings"
 )
@@ -41,9 +45,24 @@ type VideoBuilder struct {
 	crf     int
 	preset  string
 
+	// Rate control (see ratecontrol.go)
+	abr           bool   // Average bitrate without constant quality
+	maxRate       string // VBV
+	bufSize       string
+	pass          int // Two-pass: 1 or 2, 0 = single pass
+	passLogPrefix string
+
 	// Video properties
 	frameRate   int
 	pixelFormat string
+	closedGOP   bool // Chunk-safe keyframes and timing (see gop.go)
+
+	keyframeInterval float64 // Forced keyframe interval in seconds (0 = chunk start only)
+
+	// Encoder-private params and preserved HDR signalling (see hdr.go)
+	codecParams   []codecParam
+	encoderParams *codec.EncoderParams
+	hdr           *models.HDRMetadata
 
 	// CPU filters (applied before GPU encoding)
 	cpuFilters []string
@@ -55,6 +74,7 @@ type VideoBuilder struct {
 	extraArgs        []string
 	priority         int
 	progressCallback models.ProgressCallback
+	log              *slog.Logger
 }
 
 // NewVideoBuilder creates a new video encoding command builder
@@ -68,10 +88,12 @@ func NewVideoBuilder(chunk *models.Chunk, outputPath string) *VideoBuilder {
 		crf:         23,
 		preset:      "medium",
 		pixelFormat: "", // No default - let ffmpeg decide based on codec
+		closedGOP:   true,
 		priority:    5,
 		cpuFilters:  []string{},
 		gpuFilters:  []string{},
 		extraArgs:   []string{},
+		log:         logging.Discard(),
 	}
 }
 
@@ -105,13 +127,14 @@ func (v *VideoBuilder) SetBitrate(bitrate string) *VideoBuilder {
 	return v
 }
 
-// SetCRF sets the Constant Rate Factor (0-51, lower is better quality)
+// SetCRF sets the Constant Rate Factor (lower is better quality).
+// The valid range depends on the codec: 0-51 for x264/x265, 0-63 for AV1 and VP9.
 func (v *VideoBuilder) SetCRF(crf int) *VideoBuilder {
 	v.crf = crf
 	return v
 }
 
-// SetPreset sets the encoding preset (ultrafast, superfast, veryfast, faster, fast, medium, slow, slower, veryslow)
+// SetPreset sets the encoding preset (ultrafast ... veryslow for x264/x265, 0-13 for SVT-AV1, p1-p7 for NVENC)
 func (v *VideoBuilder) SetPreset(preset string) *VideoBuilder {
 	v.preset = preset
 	return v
@@ -137,15 +160,25 @@ func (v *VideoBuilder) AddCPUFilter(filter string) *VideoBuilder {
 	return v
 }
 
+// CPUFilters returns the CPU filters in the order they are applied.
+func (v *VideoBuilder) CPUFilters() []string {
+	return append([]string{}, v.cpuFilters...)
+}
+
 // AddToneMapping adds HDR to SDR tone mapping (CPU operation)
 // Example: "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
 func (v *VideoBuilder) AddToneMapping(algorithm string) *VideoBuilder {
+	v.cpuFilters = append(v.cpuFilters, ToneMappingFilter(algorithm))
+	return v
+}
+
+// ToneMappingFilter returns the HDR to SDR tone-mapping filter chain for a
+// curve (empty = hable).
+func ToneMappingFilter(algorithm string) string {
 	if algorithm == "" {
 		algorithm = "hable" // Default to hable tone mapping
 	}
-	filter := fmt.Sprintf("zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=%s:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p", algorithm)
-	v.cpuFilters = append(v.cpuFilters, filter)
-	return v
+	return fmt.Sprintf("zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=%s:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p", algorithm)
 }
 
 // AddColorspaceConversion adds colorspace conversion (CPU operation)
@@ -203,6 +236,13 @@ func (v *VideoBuilder) SetProgressCallback(callback models.ProgressCallback) *Vi
 	return v
 }
 
+// SetLogger sets the logger of Run: the ffmpeg command at debug level and,
+// when ffmpeg fails, the tail of its stderr. Records are discarded by default.
+func (v *VideoBuilder) SetLogger(log *slog.Logger) *VideoBuilder {
+	v.log = log
+	return v
+}
+
 // BuildArgs constructs the ffmpeg arguments for video encoding
 func (v *VideoBuilder) BuildArgs() []string {
 	args := []string{}
@@ -263,36 +303,118 @@ func (v *VideoBuilder) BuildArgs() []string {
 		args = append(args, "-b:v", v.bitrate)
 	}
 
-	if v.crf >= 0 && v.crf <= 51 && v.encoder == "" {
-		// CRF only works with software encoders
-		args = append(args, "-crf", fmt.Sprintf("%d", v.crf))
+	args = append(args, v.rateControlArgs()...)
+
+	// Constant quality; hardware encoders set via SetHardwareEncoder use bitrate control,
+	// as do ABR and two-pass encodes
+	if v.encoder == "" && !v.abr && v.pass == 0 {
+		minQ, maxQ := codec.QualityRange(v.codec)
+		param := "crf"
+		if c, ok := codec.Lookup(v.codec); ok {
+			param = c.QualityParam
+		}
+		if param != "" && v.crf >= minQ && v.crf <= maxQ {
+			args = append(args, "-"+param, fmt.Sprintf("%d", v.crf))
+		}
 	}
 
 	if v.preset != "" {
-		args = append(args, "-preset", v.preset)
+		// Encoders without a -preset option (libaom-av1, libvpx-vp9, VAAPI) would ignore it
+		if c, ok := codec.Lookup(v.encoderName()); !ok || c.HasPresets() {
+			args = append(args, "-preset", v.preset)
+		}
 	}
 
 	if v.frameRate > 0 {
 		args = append(args, "-r", fmt.Sprintf("%d", v.frameRate))
+	} else if v.chunk.FrameRate != "" {
+		// Frame rate changed by filtering (e.g. inverse telecine)
+		args = append(args, "-r", v.chunk.FrameRate)
 	}
 
-	if v.pixelFormat != "" && v.encoder == "" {
+	// Start each chunk with an IDR frame so chunks join cleanly
+	args = append(args, v.gopArgs()...)
+
+	if pixfmt := v.outputPixelFormat(); pixfmt != "" && v.encoder == "" {
 		// Pixel format for software encoding
-		args = append(args, "-pix_fmt", v.pixelFormat)
+		args = append(args, "-pix_fmt", pixfmt)
 	}
 
+	// HDR color tags and encoder params (-x265-params, -svtav1-params, ...)
+	args = append(args, v.hdrColorArgs()...)
+	args = append(args, v.encoderParamArgs()...)
+	args = append(args, v.encoderOptionArgs()...)
+	args = append(args, v.passArgs()...)
+
 	// Note: We're using -an (no audio) above, so we don't add -c:a copy here
 	// Adding both -an and -c:a copy causes undefined behavior in ffmpeg
 
 	// Add extra custom arguments
 	args = append(args, v.extraArgs...)
 
-	// Overwrite output
-	args = append(args, "-y", v.outputPath)
+	// Overwrite output; the first of two passes only writes its stats
+	if v.pass == 1 {
+		args = append(args, "-f", "null", "-y", "-")
+	} else {
+		args = append(args, "-y", v.outputPath)
+	}
 
 	return args
 }
 
+// encoderName returns the ffmpeg encoder that BuildArgs selects
+func (v *VideoBuilder) encoderName() string {
+	if v.encoder != "" {
+		return v.encoder
+	}
+	return v.codec
+}
+
+// RequiredFilters returns the ffmpeg filters the command uses.
+func (v *VideoBuilder) RequiredFilters() []string {
+	return command.FilterNames(v.buildFilterChain())
+}
+
+// Validate checks the encoding settings against the codec registry.
+// Encoders that are not in the registry are not checked.
+func (v *VideoBuilder) Validate() error {
+	c, ok := codec.Lookup(v.encoderName())
+	if !ok {
+		return nil
+	}
+	if c.Kind != codec.KindVideo {
+		return fmt.Errorf("%s is not a video encoder", c.Name)
+	}
+
+	var errors []string
+	if v.encoder == "" && v.bitrate == "" && v.pass == 0 && c.QualityParam != "" {
+		if err := c.ValidateQuality(v.crf); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if v.preset != "" {
+		if err := c.ValidatePreset(v.preset); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if pixfmt := v.outputPixelFormat(); pixfmt != "" && v.encoder == "" {
+		if err := c.ValidatePixelFormat(pixfmt); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	errors = append(errors, v.validateRateControl()...)
+	if v.encoderParams != nil {
+		if err := v.encoderParams.Validate(); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid video settings: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
 // buildFilterChain constructs the complete filter chain
 // Optimized pipeline:
 // 1. GPU scaling first (if present) to reduce resolution early
@@ -374,18 +496,26 @@ func (v *VideoBuilder) buildFilterChain() string {
 
 // Run executes the video encoding command
 func (v *VideoBuilder) Run() error {
-	args := v.BuildArgs()
+	return v.RunContext(context.Background())
+}
 
-	// Print the actual ffmpeg command being executed
-	cmdStr := "ffmpeg " + strings.Join(args, " ")
-	fmt.Printf("\n🎬 VIDEO CHUNK %d:\n%s\n\n", v.chunk.ChunkID, cmdStr)
+// RunContext executes the video encoding command, killing ffmpeg once ctx
+// is done.
+func (v *VideoBuilder) RunContext(ctx context.Context) error {
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	args := v.BuildArgs()
+	v.log.Debug("ffmpeg command", "args", "ffmpeg "+strings.Join(args, " "))
 
-	cmd := exec.Command("ffmpeg", args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 
 	// If no progress callback, use simple execution
 	if v.progressCallback == nil {
 		output, err := cmd.CombinedOutput()
 		if err != nil {
+			v.log.Debug("ffmpeg failed", "error", err, "stderr", logging.Tail(output, logging.StderrTailLines))
 			return fmt.Errorf("ffmpeg failed: %w\nOutput: %s", err, string(output))
 		}
 		return nil
@@ -448,16 +578,10 @@ func (v *VideoBuilder) runWithProgress(cmd *exec.Cmd) error {
 		progress.State = models.ProgressStateFailed
 		v.progressCallback(progress)
 
-		// Log detailed error information
-		fmt.Printf("VIDEO: ========================================\n")
-		fmt.Printf("VIDEO: FFmpeg failed for chunk %d\n", v.chunk.ChunkID)
-		fmt.Printf("VIDEO: Exit error: %v\n", cmdErr)
-		fmt.Printf("VIDEO: ========================================\n")
-		fmt.Printf("VIDEO: STDERR output:\n%s\n", stderrBuf.String())
-		fmt.Printf("VIDEO: ========================================\n")
+		// The error only carries the exit status; the reason is in stderr
+		v.log.Debug("ffmpeg failed", "error", cmdErr, "stderr", logging.Tail(stderrBuf.Bytes(), logging.StderrTailLines))
 		if len(stdoutData) > 0 {
-			fmt.Printf("VIDEO: STDOUT output:\n%s\n", string(stdoutData))
-			fmt.Printf("VIDEO: ========================================\n")
+			v.log.Debug("ffmpeg stdout", "stdout", logging.Tail(stdoutData, logging.StderrTailLines))
 		}
 
 		return fmt.Errorf("ffmpeg command failed: %w", cmdErr)
@@ -467,7 +591,7 @@ func (v *VideoBuilder) runWithProgress(cmd *exec.Cmd) error {
 		// Progress parsing failed, but command succeeded
 		// Ignore "file already closed" errors - this is normal when ffmpeg finishes quickly
 		if !strings.Contains(parseErr.Error(), "file already closed") {
-			fmt.Printf("Warning: progress parsing error: %v\n", parseErr)
+			v.log.Warn("progress parsing error", "error", parseErr)
 		}
 	}
 
@@ -480,6 +604,10 @@ func (v *VideoBuilder) runWithProgress(cmd *exec.Cmd) error {
 
 // DryRun returns the command that would be executed without running it
 func (v *VideoBuilder) DryRun() (string, error) {
+	if err := v.Validate(); err != nil {
+		return "", err
+	}
+
 	args := v.BuildArgs()
 	return "ffmpeg " + strings.Join(args, " "), nil
 }
@@ -499,8 +627,12 @@ func (v *VideoBuilder) GetInputPath() string {
 	return v.chunk.SourcePath
 }
 
-// GetOutputPath returns the output file path
+// GetOutputPath returns the output file path, or the stats file for the
+// first of two passes
 func (v *VideoBuilder) GetOutputPath() string {
+	if v.pass == 1 {
+		return v.PassLogPath()
+	}
 	return v.outputPath
 }
 
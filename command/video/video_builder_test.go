@@ -1,7 +1,10 @@
 package video
 
 import (
+	"bytes"
+	"encoder/logging"
 	"encoder/models"
+	"log/slog"
 	"strings"
 	"testing"
 )
@@ -30,6 +33,30 @@ func TestNewVideoBuilder(t *testing.T) {
 	}
 }
 
+func TestVideoBuilder_SetLogger(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 3, StartTime: 0, EndTime: 10, SourcePath: "/nonexistent/input.mkv"}
+
+	// The command is logged at debug level before ffmpeg runs (and fails)
+	var buf bytes.Buffer
+	builder := NewVideoBuilder(chunk, t.TempDir()+"/out.mkv")
+	builder.SetLogger(logging.New(&buf, logging.FormatText, slog.LevelDebug).With(logging.KeyChunkID, chunk.ChunkID))
+	if err := builder.Run(); err == nil {
+		t.Fatal("Expected Run to fail for a missing input")
+	}
+	if !strings.Contains(buf.String(), `msg="ffmpeg command"`) || !strings.Contains(buf.String(), "chunk_id=3") ||
+		!strings.Contains(buf.String(), "/nonexistent/input.mkv") {
+		t.Errorf("Expected the ffmpeg command in the debug log, got %q", buf.String())
+	}
+
+	// Above debug level nothing is logged
+	buf.Reset()
+	builder.SetLogger(logging.New(&buf, logging.FormatText, slog.LevelInfo))
+	builder.Run()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no records at info level, got %q", buf.String())
+	}
+}
+
 func TestVideoBuilder_SoftwareEncoding_H264(t *testing.T) {
 	chunk := &models.Chunk{
 		ChunkID:    1,
@@ -439,3 +466,211 @@ func TestVideoBuilder_FluentAPI(t *testing.T) {
 		t.Error("Fluent API failed to add extra args")
 	}
 }
+
+func TestVideoBuilder_CodecSpecificCRF(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	// SVT-AV1 accepts CRF up to 63
+	builder := NewVideoBuilder(chunk, "/output/test.mkv")
+	builder.SetCodec("libsvtav1").SetCRF(55).SetPreset("8")
+
+	argsStr := strings.Join(builder.BuildArgs(), " ")
+	if !strings.Contains(argsStr, "-crf 55") {
+		t.Errorf("Expected -crf 55 for libsvtav1, got: %s", argsStr)
+	}
+	if _, err := builder.DryRun(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+
+	// x264 does not
+	builder = NewVideoBuilder(chunk, "/output/test.mp4")
+	builder.SetCodec("libx264").SetCRF(55)
+	if _, err := builder.DryRun(); err == nil || !strings.Contains(err.Error(), "libx264 CRF must be between 0 and 51") {
+		t.Errorf("Expected CRF range error, got: %v", err)
+	}
+}
+
+func TestVideoBuilder_Validate(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	tests := []struct {
+		name    string
+		setup   func(*VideoBuilder)
+		wantErr string
+	}{
+		{"valid x264", func(b *VideoBuilder) { b.SetCodec("libx264").SetPreset("slow") }, ""},
+		{"svt-av1 named preset", func(b *VideoBuilder) { b.SetCodec("libsvtav1").SetCRF(30).SetPreset("slow") }, "libsvtav1 preset must be a number between 0 and 13"},
+		{"bad pixel format", func(b *VideoBuilder) { b.SetCodec("libsvtav1").SetCRF(30).SetPreset("6").SetPixelFormat("yuv444p") }, "does not support pixel format"},
+		{"audio codec", func(b *VideoBuilder) { b.SetCodec("libopus") }, "not a video encoder"},
+		{"unknown codec", func(b *VideoBuilder) { b.SetCodec("librav1e").SetPreset("whatever") }, ""},
+		{"nvenc preset", func(b *VideoBuilder) { b.SetHardwareEncoder("h264_nvenc", HWAccelNVENC).SetPreset("p7") }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewVideoBuilder(chunk, "/output/test.mkv")
+			tt.setup(builder)
+			err := builder.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVideoBuilder_NoPresetForAOM(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	builder := NewVideoBuilder(chunk, "/output/test.mkv")
+	builder.SetCodec("libaom-av1").SetCRF(30)
+
+	argsStr := strings.Join(builder.BuildArgs(), " ")
+	if strings.Contains(argsStr, "-preset") {
+		t.Errorf("libaom-av1 has no -preset option, got: %s", argsStr)
+	}
+}
+
+func hdr10Metadata() *models.HDRMetadata {
+	return &models.HDRMetadata{
+		Format:         models.HDRFormatHDR10,
+		ColorPrimaries: "bt2020",
+		ColorTransfer:  "smpte2084",
+		ColorSpace:     "bt2020nc",
+		ColorRange:     "tv",
+		MasteringDisplay: &models.MasteringDisplay{
+			RedX: 0.68, RedY: 0.32,
+			GreenX: 0.265, GreenY: 0.69,
+			BlueX: 0.15, BlueY: 0.06,
+			WhiteX: 0.3127, WhiteY: 0.329,
+			MinLuminance: 0.005, MaxLuminance: 4000,
+		},
+		MaxCLL:  1000,
+		MaxFALL: 400,
+	}
+}
+
+func TestVideoBuilder_PreserveHDR_X265(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/hdr.mkv"}
+
+	builder := NewVideoBuilder(chunk, "/output/test.mkv")
+	builder.SetCodec("libx265").SetPreset("slow").PreserveHDR(hdr10Metadata())
+
+	argsStr := strings.Join(builder.BuildArgs(), " ")
+
+	for _, want := range []string{
+		"-pix_fmt yuv420p10le",
+		"-color_primaries bt2020 -color_trc smpte2084 -colorspace bt2020nc -color_range tv",
+		"-x265-params repeat-headers=1:colorprim=bt2020:transfer=smpte2084:colormatrix=bt2020nc:hdr10=1:" +
+			"master-display=G(13250,34500)B(7500,3000)R(34000,16000)WP(15635,16450)L(40000000,50):max-cll=1000,400",
+	} {
+		if !strings.Contains(argsStr, want) {
+			t.Errorf("Expected %q in args: %s", want, argsStr)
+		}
+	}
+}
+
+func TestVideoBuilder_PreserveHDR_SVTAV1_MergesParams(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/hdr.mkv"}
+
+	builder := NewVideoBuilder(chunk, "/output/test.mkv")
+	builder.SetCodec("libsvtav1").SetCRF(30).SetPreset("6").
+		SetCodecParam("lp", "4").
+		SetCodecParam("pin", "1").
+		PreserveHDR(hdr10Metadata())
+
+	args := builder.BuildArgs()
+	argsStr := strings.Join(args, " ")
+
+	want := "-svtav1-params enable-hdr=1:mastering-display=G(0.2650,0.6900)B(0.1500,0.0600)R(0.6800,0.3200)WP(0.3127,0.3290)L(4000.0000,0.0050):content-light=1000,400:lp=4:pin=1"
+	if !strings.Contains(argsStr, want) {
+		t.Errorf("Expected %q in args: %s", want, argsStr)
+	}
+	if strings.Count(argsStr, "-svtav1-params") != 1 {
+		t.Errorf("Expected a single -svtav1-params option: %s", argsStr)
+	}
+	if err := builder.Validate(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
+
+func TestVideoBuilder_SetCodecParam_IgnoredWithoutParamsOption(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mp4"}
+
+	builder := NewVideoBuilder(chunk, "/output/test.webm")
+	builder.SetCodec("libvpx-vp9").SetCodecParam("lp", "4").PreserveHDR(hdr10Metadata())
+
+	argsStr := strings.Join(builder.BuildArgs(), " ")
+	if strings.Contains(argsStr, "-params") {
+		t.Errorf("libvpx-vp9 has no params option: %s", argsStr)
+	}
+	if !strings.Contains(argsStr, "-color_trc smpte2084") {
+		t.Errorf("Expected color tags for VP9: %s", argsStr)
+	}
+}
+
+func TestVideoBuilder_ChunkFrameRate(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mkv", FrameRate: "24000/1001"}
+
+	builder := NewVideoBuilder(chunk, "/output/test.mkv")
+	builder.SetCodec("libx264")
+	argsStr := strings.Join(builder.BuildArgs(), " ")
+	if !strings.Contains(argsStr, "-r 24000/1001") {
+		t.Errorf("Expected chunk frame rate: %s", argsStr)
+	}
+
+	// An explicit frame rate wins over the chunk's
+	builder.SetFrameRate(30)
+	argsStr = strings.Join(builder.BuildArgs(), " ")
+	if strings.Contains(argsStr, "24000/1001") || !strings.Contains(argsStr, "-r 30") {
+		t.Errorf("Expected explicit frame rate only: %s", argsStr)
+	}
+}
+
+func TestVideoBuilder_ApplyHDR(t *testing.T) {
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 0, EndTime: 10, SourcePath: "/input/test.mkv"}
+
+	// SDR source: nothing changes
+	sdr := NewVideoBuilder(chunk, "/output/test.mkv").ApplyHDR(nil, HDRPreserve, "hable")
+	if argsStr := strings.Join(sdr.BuildArgs(), " "); strings.Contains(argsStr, "-color_trc") || strings.Contains(argsStr, "-vf") {
+		t.Errorf("SDR source should not get HDR handling: %s", argsStr)
+	}
+
+	// Tone-mapping uses the existing filter chain and drops HDR tags
+	tm := NewVideoBuilder(chunk, "/output/test.mkv").SetCodec("libx265").ApplyHDR(hdr10Metadata(), HDRTonemap, "mobius")
+	argsStr := strings.Join(tm.BuildArgs(), " ")
+	if !strings.Contains(argsStr, "tonemap=tonemap=mobius") || strings.Contains(argsStr, "-color_trc") {
+		t.Errorf("Expected tone-mapping without HDR tags: %s", argsStr)
+	}
+
+	// Ignore keeps the old behavior
+	ig := NewVideoBuilder(chunk, "/output/test.mkv").ApplyHDR(hdr10Metadata(), HDRIgnore, "hable")
+	if argsStr := strings.Join(ig.BuildArgs(), " "); strings.Contains(argsStr, "-color_trc") || strings.Contains(argsStr, "-vf") {
+		t.Errorf("Ignore mode should not change args: %s", argsStr)
+	}
+}
+
+func TestResolveHDRMode(t *testing.T) {
+	tests := []struct {
+		mode       HDRMode
+		encoder    string
+		want       HDRMode
+		wantReason bool
+	}{
+		{HDRPreserve, "libx265", HDRPreserve, false},
+		{HDRPreserve, "libsvtav1", HDRPreserve, false},
+		{HDRPreserve, "h264_nvenc", HDRTonemap, true},
+		{HDRPreserve, "unknown_encoder", HDRPreserve, false},
+		{HDRTonemap, "libx265", HDRTonemap, false},
+		{HDRIgnore, "h264_nvenc", HDRIgnore, false},
+	}
+
+	for _, tt := range tests {
+		got, reason := ResolveHDRMode(tt.mode, tt.encoder)
+		if got != tt.want || (reason != "") != tt.wantReason {
+			t.Errorf("ResolveHDRMode(%s, %s) = %s, %q; want %s (reason: %v)", tt.mode, tt.encoder, got, reason, tt.want, tt.wantReason)
+		}
+	}
+}
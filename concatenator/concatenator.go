@@ -1,8 +1,10 @@
 package concatenator
 
 import (
+	"encoder/logging"
 	"encoder/models"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,16 +14,33 @@ import (
 
 // Concatenator handles merging encoded chunks into a final output file
 type Concatenator struct {
-	strictMode bool // If true, fail if any chunks are missing. If false, skip missing chunks.
+	strictMode bool   // If true, fail if any chunks are missing. If false, skip missing chunks.
+	frameRate  string // Output video frame rate (e.g. "24000/1001"), empty = from the chunks
+	log        *slog.Logger
 }
 
 // NewConcatenator creates a new concatenator
 func NewConcatenator(strictMode bool) *Concatenator {
 	return &Concatenator{
 		strictMode: strictMode,
+		log:        logging.Discard(),
 	}
 }
 
+// SetFrameRate sets the frame rate recorded for the output video stream.
+// Use it when the chunks were encoded at a different rate than the source.
+func (c *Concatenator) SetFrameRate(rate string) *Concatenator {
+	c.frameRate = rate
+	return c
+}
+
+// SetLogger sets the logger of skipped chunks and the ffmpeg command.
+// Records are discarded by default.
+func (c *Concatenator) SetLogger(log *slog.Logger) *Concatenator {
+	c.log = log
+	return c
+}
+
 // Concatenate merges encoded chunks into a final output file using ffmpeg's concat demuxer
 func (c *Concatenator) Concatenate(results []*models.EncoderResult, finalOutputPath string) error {
 	// Validate results
@@ -34,7 +53,7 @@ func (c *Concatenator) Concatenate(results []*models.EncoderResult, finalOutputP
 		if c.strictMode {
 			return fmt.Errorf("strict mode: %d chunks failed encoding", len(failed))
 		}
-		fmt.Printf("Warning: %d chunks failed, proceeding with %d successful chunks\n", len(failed), len(successful))
+		c.log.Warn("chunks failed, proceeding without them", "failed", len(failed), "successful", len(successful))
 	}
 
 	if len(successful) == 0 {
@@ -46,7 +65,7 @@ func (c *Concatenator) Concatenate(results []*models.EncoderResult, finalOutputP
 		if c.strictMode {
 			return fmt.Errorf("strict mode: %w", err)
 		}
-		fmt.Printf("Warning: %v\n", err)
+		c.log.Warn("gap in chunk sequence", "error", err)
 	}
 
 	// Create concat file for ffmpeg
@@ -157,15 +176,22 @@ func (c *Concatenator) runConcat(concatFilePath, outputPath string) error {
 		"-safe", "0",
 		"-i", concatFilePath,
 		"-c", "copy", // Copy without re-encoding
+	}
+	if c.frameRate != "" {
+		args = append(args, "-r", c.frameRate)
+	}
+	args = append(args,
 		"-y", // Overwrite output file
 		outputPath,
-	}
+	)
 
+	c.log.Debug("ffmpeg command", "args", "ffmpeg "+strings.Join(args, " "))
 	cmd := exec.Command("ffmpeg", args...)
 
 	// Capture output for error reporting
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		c.log.Debug("ffmpeg failed", "error", err, "stderr", logging.Tail(output, logging.StderrTailLines))
 		return fmt.Errorf("ffmpeg error: %w\nOutput: %s", err, string(output))
 	}
 
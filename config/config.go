@@ -1,5 +1,12 @@
 package config
 
+import (
+	"encoder/codec"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
 // Config holds all encoder configuration options
 type Config struct {
 	// Required fields
@@ -10,6 +17,8 @@ type Config struct {
 	ChunkDuration int    `yaml:"chunk_duration"` // seconds per chunk
 	Workers       int    `yaml:"workers"`        // 0 = auto-detect
 	Mode          string `yaml:"mode"`           // "cpu-only", "gpu-only", "mixed"
+	WorkDir       string `yaml:"work_dir"`       // Root for per-job work directories (empty = tmp/ next to output)
+	MetricsAddr   string `yaml:"metrics_addr"`   // Serve Prometheus metrics on this address, e.g. ":9464" (empty = off)
 
 	// Audio settings
 	Audio AudioConfig `yaml:"audio"`
@@ -20,11 +29,43 @@ type Config struct {
 	// Mixing settings
 	Mixing MixingConfig `yaml:"mixing"`
 
+	// Quality metrics stage
+	Metrics MetricsConfig `yaml:"metrics"`
+
+	// Adaptive streaming packaging (HLS/DASH)
+	Packaging PackagingConfig `yaml:"packaging"`
+
+	// Posters, sprite sheets and preview clips
+	Thumbnails ThumbnailsConfig `yaml:"thumbnails"`
+
+	// Watch-folder daemon (encoder watch)
+	Watch WatchConfig `yaml:"watch"`
+
+	// Job API server (encoder serve)
+	Serve ServeConfig `yaml:"serve"`
+
+	// Profiles
+	Profile     string              `yaml:"profile"`      // Profile to apply (see -profile)
+	ProfilesDir string              `yaml:"profiles_dir"` // Drop-in directory with shared profiles (empty = ~/.encoder/profiles.d)
+	Profiles    map[string]*Profile `yaml:"profiles"`     // Named profiles defined in the config file
+
 	// Behavioral flags
-	StrictMode bool `yaml:"strict_mode"` // Fail on any chunk error
-	PreSplit   bool `yaml:"pre_split"`   // Pre-split input file to avoid seeking overhead
-	Verbose    bool `yaml:"verbose"`     // Show detailed logs
-	DryRun     bool `yaml:"dry_run"`     // Show config without encoding
+	StrictMode bool   `yaml:"strict_mode"` // Fail on any chunk error
+	PreSplit   bool   `yaml:"pre_split"`   // Pre-split input file to avoid seeking overhead
+	Verbose    bool   `yaml:"verbose"`     // Log at debug level unless log_level is set
+	DryRun     bool   `yaml:"dry_run"`     // Show config without encoding
+	LogLevel   string `yaml:"log_level"`   // debug, info, warn, error (empty = info, or debug with verbose)
+	LogFormat  string `yaml:"log_format"`  // Log file records: text or json (empty = text)
+
+	// OutputFormat is what an encode prints on stdout: text for people, or
+	// json events (one object per line) with the text moved to stderr
+	OutputFormat string `yaml:"output_format"`
+
+	// CLI-only actions
+	ShowSources bool `yaml:"-"` // Print each effective value with its source layer and exit
+
+	// sources records which layer set each value (key -> source), see Source()
+	sources map[string]Source
 }
 
 // AudioConfig holds audio encoding settings
@@ -33,22 +74,255 @@ type AudioConfig struct {
 	Bitrate    string `yaml:"bitrate"`     // e.g., "128k", "192k", "320k"
 	SampleRate int    `yaml:"sample_rate"` // e.g., 48000, 44100
 	Channels   int    `yaml:"channels"`    // 1 (mono), 2 (stereo), 6 (5.1)
+
+	// Gapless encodes the chunks losslessly (FLAC) at exact sample positions
+	// and encodes the joined track once, avoiding clicks and drift at chunk
+	// boundaries from the encoder's priming samples
+	Gapless bool `yaml:"gapless"`
 }
 
 // VideoConfig holds video encoding settings
 type VideoConfig struct {
 	Codec      string `yaml:"codec"`      // e.g., "libx264", "libx265", "h264_nvenc"
-	CRF        int    `yaml:"crf"`        // Constant Rate Factor (0-51, lower = better quality)
+	CRF        int    `yaml:"crf"`        // Constant Rate Factor (0-51 x264/x265, 0-63 AV1/VP9; lower = better)
 	Preset     string `yaml:"preset"`     // e.g., "ultrafast", "medium", "slow", "veryslow"
 	Bitrate    string `yaml:"bitrate"`    // e.g., "5M", "10M" (alternative to CRF)
 	Resolution string `yaml:"resolution"` // e.g., "1920x1080", "1280x720" (empty = keep original)
 	FrameRate  int    `yaml:"frame_rate"` // e.g., 30, 60 (0 = keep original)
+	HDR        string `yaml:"hdr"`        // HDR sources: "preserve", "tonemap" (to SDR) or "ignore"
+	Tonemap    string `yaml:"tonemap"`    // Tone-mapping curve: hable, mobius, reinhard, clip, linear, gamma
+	Crop       string `yaml:"crop"`       // Black bar removal: "off", "auto" (cropdetect) or manual "W:H:X:Y"
+	CropRound  int    `yaml:"crop_round"` // Auto crop width/height alignment: 2, 4, 8 or 16
+
+	Deinterlace  string `yaml:"deinterlace"`  // "auto" (idet), "off", "deinterlace" or "ivtc" (force)
+	Deinterlacer string `yaml:"deinterlacer"` // Deinterlacing filter: "bwdif" or "yadif"
+
+	// Target-quality mode: probe each chunk at a few CRFs and encode it at the
+	// CRF interpolated to reach TargetQuality (CRF is the starting point)
+	TargetQuality float64 `yaml:"target_quality"` // Target score, e.g. VMAF 93 (0 = fixed CRF)
+	QualityMetric string  `yaml:"quality_metric"` // "vmaf" (SSIM fallback without libvmaf), "ssim" or "psnr"
+	QualityProbes int     `yaml:"quality_probes"` // CRFs probed per chunk (2-7)
+
+	// Rate control: CRF unless bitrate or target_size is set, which encode at
+	// an average bitrate (in two passes with passes: 2); max_rate caps either
+	Passes     int    `yaml:"passes"`      // 1, or 2 for two-pass (needs bitrate or target_size)
+	MaxRate    string `yaml:"max_rate"`    // VBV maximum bitrate, e.g. "8M" (empty = uncapped)
+	BufSize    string `yaml:"buf_size"`    // VBV buffer size, e.g. "16M" (empty = 2 × max_rate)
+	TargetSize string `yaml:"target_size"` // Output file size, e.g. "700M" (bitrate from duration and audio)
+
+	// Typed per-encoder tuning (tune, keyint, film grain, lookahead, threads, AQ, psy, 10-bit)
+	EncoderParams codec.EncoderParams `yaml:"encoder_params"`
+
+	// ABR ladder: every chunk is encoded once per rendition from the same
+	// segments and each rendition is muxed with the same audio (empty = one output)
+	Renditions []RenditionConfig `yaml:"renditions"`
+}
+
+// RenditionConfig is one rung of an ABR ladder. Unset fields fall back to
+// the video settings; the first rendition is written to the output and the
+// others next to it as <output name>_<name>.<ext>.
+type RenditionConfig struct {
+	Name       string `yaml:"name"`       // e.g., "720p" (used in file and task names)
+	Resolution string `yaml:"resolution"` // e.g., "1280x720", fitted inside keeping the aspect ratio
+	Codec      string `yaml:"codec"`      // Empty = video codec
+	Preset     string `yaml:"preset"`     // Empty = video preset (codec default if the codec differs)
+	CRF        int    `yaml:"crf"`        // 0 = video CRF; switches a bitrate-driven video section to CRF
+	Bitrate    string `yaml:"bitrate"`    // Average bitrate instead of CRF, e.g. "3M"
+	MaxRate    string `yaml:"max_rate"`   // VBV cap (empty = video max_rate)
+	BufSize    string `yaml:"buf_size"`   // VBV buffer (empty = video buf_size)
+}
+
+// Rendition returns the video settings of a rendition: its values over the
+// video section, without the ladder.
+func (vc *VideoConfig) Rendition(r RenditionConfig) VideoConfig {
+	out := *vc
+	out.Renditions = nil
+
+	if r.Codec != "" && r.Codec != vc.Codec {
+		out.Codec = r.Codec
+		if c, ok := codec.Lookup(r.Codec); ok && c.DefaultPreset != "" {
+			out.Preset = c.DefaultPreset
+		}
+	}
+	if r.Preset != "" {
+		out.Preset = r.Preset
+	}
+	if r.Resolution != "" {
+		out.Resolution = r.Resolution
+	}
+
+	// An explicit CRF or bitrate replaces the section's rate control
+	switch {
+	case r.Bitrate != "":
+		out.Bitrate = r.Bitrate
+		out.TargetSize = ""
+		out.TargetQuality = 0
+	case r.CRF > 0:
+		out.CRF = r.CRF
+		out.Bitrate = ""
+		out.TargetSize = ""
+		out.Passes = 0
+	}
+	if r.MaxRate != "" {
+		out.MaxRate = r.MaxRate
+		out.BufSize = r.BufSize
+	} else if r.BufSize != "" {
+		out.BufSize = r.BufSize
+	}
+	return out
+}
+
+// Ladder returns the video settings of every output: one per rendition, or
+// the video section itself when no renditions are configured.
+func (vc *VideoConfig) Ladder() []VideoConfig {
+	if len(vc.Renditions) == 0 {
+		return []VideoConfig{*vc}
+	}
+	ladder := make([]VideoConfig, len(vc.Renditions))
+	for i, r := range vc.Renditions {
+		ladder[i] = vc.Rendition(r)
+	}
+	return ladder
+}
+
+// Codecs returns the distinct video codecs of the ladder.
+func (vc *VideoConfig) Codecs() []string {
+	var codecs []string
+	for _, v := range vc.Ladder() {
+		if v.Codec != "" && !containsValue(codecs, v.Codec) {
+			codecs = append(codecs, v.Codec)
+		}
+	}
+	return codecs
+}
+
+// MetricsConfig controls the optional quality metrics stage, which compares
+// every encoded video chunk with the source after encoding
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"` // Run the metrics stage
+	Compute string `yaml:"compute"` // Comma-separated metrics: vmaf, ssim, psnr (vmaf is skipped without libvmaf)
+	Report  string `yaml:"report"`  // JSON report path (empty = <output>.metrics.json)
+}
+
+// MetricNames returns the metrics listed in Compute.
+func (mc *MetricsConfig) MetricNames() []string {
+	var names []string
+	for _, name := range strings.Split(mc.Compute, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PackagingConfig controls the optional packaging stage, which segments the
+// concatenated audio and video for HLS and/or DASH players
+type PackagingConfig struct {
+	Formats         string  `yaml:"formats"`          // Comma-separated: hls, dash (empty = no packaging)
+	SegmentDuration float64 `yaml:"segment_duration"` // Target segment length in seconds; keyframes are forced at this interval
+	HLSSegmentType  string  `yaml:"hls_segment_type"` // HLS segments: "fmp4" or "ts"
+	Dir             string  `yaml:"dir"`              // Output directory (empty = <output name>_stream next to the output)
+	Subtitles       bool    `yaml:"subtitles"`        // Add the source's text subtitles as WebVTT renditions (HLS)
+}
+
+// FormatNames returns the formats listed in Formats.
+func (pc *PackagingConfig) FormatNames() []string {
+	var names []string
+	for _, name := range strings.Split(pc.Formats, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Enabled reports whether any packaging format is selected.
+func (pc *PackagingConfig) Enabled() bool {
+	return len(pc.FormatNames()) > 0
+}
+
+// ThumbnailsConfig controls the optional thumbnail outputs, generated from
+// the source while it is encoded
+type ThumbnailsConfig struct {
+	Outputs        string  `yaml:"outputs"`         // Comma-separated: poster, sprite, preview (empty = no thumbnails)
+	Selection      string  `yaml:"selection"`       // Poster and sprite frames: "interval", "scene" or "chapters"
+	Interval       float64 `yaml:"interval"`        // Seconds between frames (interval selection)
+	SceneThreshold float64 `yaml:"scene_threshold"` // Minimum scene change score, 0-1 (scene selection)
+	Posters        int     `yaml:"posters"`         // Maximum poster frames
+	PosterWidth    int     `yaml:"poster_width"`    // Poster width in pixels (height keeps the aspect ratio)
+	SpriteTile     string  `yaml:"sprite_tile"`     // Sprite tile size (e.g., "160x90")
+	SpriteColumns  int     `yaml:"sprite_columns"`  // Tiles per sprite sheet row
+	SpriteRows     int     `yaml:"sprite_rows"`     // Tile rows per sprite sheet
+	PreviewLength  float64 `yaml:"preview_length"`  // Seconds per preview clip
+	PreviewFormat  string  `yaml:"preview_format"`  // Animated preview: "webp", "gif" or "mp4"
+	Dir            string  `yaml:"dir"`             // Output directory (empty = <output name>_thumbs next to the output)
+}
+
+// OutputNames returns the outputs listed in Outputs.
+func (tc *ThumbnailsConfig) OutputNames() []string {
+	var names []string
+	for _, name := range strings.Split(tc.Outputs, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Enabled reports whether any thumbnail output is selected.
+func (tc *ThumbnailsConfig) Enabled() bool {
+	return len(tc.OutputNames()) > 0
+}
+
+// WatchConfig configures the watch-folder daemon, which encodes the files
+// dropped into a directory
+type WatchConfig struct {
+	Dir            string      `yaml:"dir"`             // Ingest directory (empty = -dir is required)
+	OutputTemplate string      `yaml:"output_template"` // Output path: {dir}, {name}, {ext}, {profile} (empty = {dir}/encoded/{name}.mkv)
+	DoneDir        string      `yaml:"done_dir"`        // Where encoded sources are moved (empty = <dir>/done)
+	FailedDir      string      `yaml:"failed_dir"`      // Where sources that failed are moved (empty = <dir>/failed)
+	StateDir       string      `yaml:"state_dir"`       // Job queue kept across restarts (empty = <dir>/.encoder-watch)
+	PollInterval   float64     `yaml:"poll_interval"`   // Seconds between directory scans
+	SettleTime     float64     `yaml:"settle_time"`     // Seconds a file's size and mtime must stay unchanged before it is encoded
+	Rules          []WatchRule `yaml:"rules"`           // Profile rules, the first matching rule wins (no match = the default profile)
+}
+
+// WatchRule picks the profile of the files it matches. Conditions left at
+// their zero value match any file.
+type WatchRule struct {
+	Match      string `yaml:"match"`       // Glob on the file name, case-insensitive (e.g., "*.mov")
+	VideoCodec string `yaml:"video_codec"` // Codec of the main video stream as ffprobe names it (e.g., "mpeg2video")
+	MinHeight  int    `yaml:"min_height"`  // Minimum video height in pixels
+	MaxHeight  int    `yaml:"max_height"`  // Maximum video height in pixels (0 = no limit)
+	HDR        string `yaml:"hdr"`         // "yes" = HDR video only, "no" = SDR video only
+	AudioOnly  bool   `yaml:"audio_only"`  // Only files without a video stream
+	Profile    string `yaml:"profile"`     // Profile to encode matching files with (empty = none)
+}
+
+// NeedsProbe reports whether the rule looks at stream properties, which
+// need ffprobe, rather than only the file name.
+func (r *WatchRule) NeedsProbe() bool {
+	return r.VideoCodec != "" || r.MinHeight > 0 || r.MaxHeight > 0 || r.HDR != "" || r.AudioOnly
+}
+
+// ServeConfig configures the job API server, which encodes the jobs
+// submitted over HTTP
+type ServeConfig struct {
+	Addr        string `yaml:"addr"`        // Listen address, must be a loopback address (the API has no authentication)
+	Concurrency int    `yaml:"concurrency"` // Jobs encoded at once (each uses up to workers encoder slots)
+	StateDir    string `yaml:"state_dir"`   // Job store and server log (empty = ~/.encoder/serve)
+}
+
+// DefaultServeStateDir returns the directory of the job API's store and log.
+func DefaultServeStateDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".encoder", "serve")
 }
 
 // MixingConfig holds mixing/muxing settings
 type MixingConfig struct {
 	CopyVideo bool `yaml:"copy_video"` // If true, copy video stream without re-encoding
 	CopyAudio bool `yaml:"copy_audio"` // If true, copy audio stream without re-encoding
+	Subtitles bool `yaml:"subtitles"`  // Carry the source's subtitles into the output (text subtitles are converted for MP4/WebM)
 }
 
 // DefaultConfig returns configuration with sensible defaults
@@ -62,6 +336,8 @@ func DefaultConfig() *Config {
 		ChunkDuration: 600,        // 10 minute chunks (fallback if no chapters)
 		Workers:       0,          // Auto-detect CPU count
 		Mode:          "cpu-only", // CPU-only for parallel software encoding
+		WorkDir:       "",         // tmp/ next to the output file
+		MetricsAddr:   "",         // No metrics endpoint
 
 		// Audio defaults (Opus: high quality, small size)
 		Audio: AudioConfig{
@@ -69,6 +345,7 @@ func DefaultConfig() *Config {
 			Bitrate:    "128k",
 			SampleRate: 48000,
 			Channels:   2, // Stereo
+			Gapless:    true,
 		},
 
 		// Video defaults (AV1: best compression, future-proof)
@@ -79,19 +356,91 @@ func DefaultConfig() *Config {
 			Bitrate:    "",  // Use CRF instead
 			Resolution: "",  // Keep original
 			FrameRate:  0,   // Keep original
+			HDR:        "preserve",
+			Tonemap:    "hable",
+			Crop:       "off", // Keep black bars (set "auto" to detect)
+			CropRound:  2,
+
+			Deinterlace:  "auto", // Detect interlaced/telecined sources
+			Deinterlacer: "bwdif",
+
+			TargetQuality: 0, // Fixed CRF
+			QualityMetric: "vmaf",
+			QualityProbes: 3,
+
+			Passes:     1,
+			MaxRate:    "", // Uncapped
+			BufSize:    "",
+			TargetSize: "",
+
+			// SVT-AV1: lp=4 limits parallelism (and memory) per chunk since
+			// chunks already run in parallel; pin=1 pins threads to cores
+			EncoderParams: codec.EncoderParams{
+				SVTAV1: codec.SVTAV1Params{LP: 4, Pin: 1},
+			},
 		},
 
 		// Mixing defaults (fast copy, no re-encode)
 		Mixing: MixingConfig{
 			CopyVideo: true,
 			CopyAudio: true,
+			Subtitles: true,
+		},
+
+		// Metrics stage (off: it decodes everything again)
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Compute: "vmaf,ssim,psnr",
+			Report:  "",
+		},
+
+		// Packaging stage (off: the output file is the deliverable)
+		Packaging: PackagingConfig{
+			Formats:         "",
+			SegmentDuration: 6,
+			HLSSegmentType:  "fmp4",
+			Dir:             "",
+			Subtitles:       true,
+		},
+
+		// Thumbnails (off: they need another pass over the source)
+		Thumbnails: ThumbnailsConfig{
+			Outputs:        "",
+			Selection:      "interval",
+			Interval:       10,
+			SceneThreshold: 0.4,
+			Posters:        5,
+			PosterWidth:    1280,
+			SpriteTile:     "160x90",
+			SpriteColumns:  10,
+			SpriteRows:     10,
+			PreviewLength:  2,
+			PreviewFormat:  "webp",
+			Dir:            "",
+		},
+
+		// Watch folder (only used by "encoder watch")
+		Watch: WatchConfig{
+			PollInterval: 5,
+			SettleTime:   30, // Long enough for a slow network copy to show progress
+		},
+
+		// Job API (only used by "encoder serve")
+		Serve: ServeConfig{
+			Addr:        "127.0.0.1:8080",
+			Concurrency: 1, // Each job already uses every worker
+			StateDir:    "",
 		},
 
 		// Behavioral defaults
-		StrictMode: true,  // Fail on any error
-		PreSplit:   true,  // Pre-split for better performance
-		Verbose:    false, // Quiet mode
-		DryRun:     false, // Actually encode
+		StrictMode: true,   // Fail on any error
+		PreSplit:   true,   // Pre-split for better performance
+		Verbose:    false,  // Quiet mode
+		DryRun:     false,  // Actually encode
+		LogLevel:   "",     // info, or debug with verbose
+		LogFormat:  "text", // key=value lines
+
+		OutputFormat: "text", // Human-readable progress
 	}
 }
 
@@ -100,7 +449,22 @@ func (c *Config) Copy() *Config {
 	copy := *c
 	copy.Audio = c.Audio
 	copy.Video = c.Video
+	copy.Video.Renditions = append([]RenditionConfig(nil), c.Video.Renditions...)
 	copy.Mixing = c.Mixing
+	copy.Metrics = c.Metrics
+	copy.Packaging = c.Packaging
+	copy.Thumbnails = c.Thumbnails
+	copy.Watch = c.Watch
+	copy.Watch.Rules = append([]WatchRule(nil), c.Watch.Rules...)
+	copy.Serve = c.Serve
+	copy.Profiles = make(map[string]*Profile, len(c.Profiles))
+	for name, p := range c.Profiles {
+		copy.Profiles[name] = p
+	}
+	copy.sources = make(map[string]Source, len(c.sources))
+	for key, src := range c.sources {
+		copy.sources[key] = src
+	}
 	return &copy
 }
 
@@ -109,6 +473,99 @@ func ModeValues() []string {
 	return []string{"cpu-only", "gpu-only", "mixed"}
 }
 
+// LogLevelValues returns valid log_level values
+func LogLevelValues() []string {
+	return []string{"debug", "info", "warn", "error"}
+}
+
+// LogFormatValues returns valid log_format values
+func LogFormatValues() []string {
+	return []string{"text", "json"}
+}
+
+// OutputFormatValues returns valid output_format values
+func OutputFormatValues() []string {
+	return []string{"text", "json"}
+}
+
+// JSONOutput reports whether an encode prints json events instead of text.
+func (c *Config) JSONOutput() bool {
+	return c.OutputFormat == "json"
+}
+
+// EffectiveLogLevel returns the level to log at: log_level, or debug in
+// verbose mode and info otherwise.
+func (c *Config) EffectiveLogLevel() string {
+	switch {
+	case c.LogLevel != "":
+		return c.LogLevel
+	case c.Verbose:
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// HDRModeValues returns valid video.hdr values
+func HDRModeValues() []string {
+	return []string{"preserve", "tonemap", "ignore"}
+}
+
+// TonemapValues returns valid video.tonemap curves
+func TonemapValues() []string {
+	return []string{"hable", "mobius", "reinhard", "clip", "linear", "gamma"}
+}
+
+// DeinterlaceValues returns valid video.deinterlace values
+func DeinterlaceValues() []string {
+	return []string{"auto", "off", "deinterlace", "ivtc"}
+}
+
+// DeinterlacerValues returns valid video.deinterlacer filters
+func DeinterlacerValues() []string {
+	return []string{"bwdif", "yadif"}
+}
+
+// QualityMetricValues returns valid video.quality_metric values
+func QualityMetricValues() []string {
+	return []string{"vmaf", "ssim", "psnr"}
+}
+
+// PackagingFormatValues returns valid packaging.formats entries
+func PackagingFormatValues() []string {
+	return []string{"hls", "dash"}
+}
+
+// HLSSegmentTypeValues returns valid packaging.hls_segment_type values
+func HLSSegmentTypeValues() []string {
+	return []string{"fmp4", "ts"}
+}
+
+// ThumbnailOutputValues returns valid thumbnails.outputs entries
+func ThumbnailOutputValues() []string {
+	return []string{"poster", "sprite", "preview"}
+}
+
+// ThumbnailSelectionValues returns valid thumbnails.selection values
+func ThumbnailSelectionValues() []string {
+	return []string{"interval", "scene", "chapters"}
+}
+
+// PreviewFormatValues returns valid thumbnails.preview_format values
+func PreviewFormatValues() []string {
+	return []string{"webp", "gif", "mp4"}
+}
+
+// WatchHDRValues returns valid watch rule hdr values
+func WatchHDRValues() []string {
+	return []string{"yes", "no"}
+}
+
+// CropRoundValues returns valid video.crop_round alignments
+func CropRoundValues() []int {
+	return []int{2, 4, 8, 16}
+}
+
 // IsValidMode checks if mode is valid
 func IsValidMode(mode string) bool {
 	for _, valid := range ModeValues() {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoder/codec"
 	"os"
 	"testing"
 )
@@ -41,7 +42,7 @@ func TestValidate(t *testing.T) {
 			config: func() *Config {
 				cfg := DefaultConfig()
 				cfg.Input = createTempFile(t)
-				cfg.Output = "/tmp/output.mp4"
+				cfg.Output = "/tmp/output.mkv"
 				return cfg
 			},
 			expectError: false,
@@ -50,7 +51,7 @@ func TestValidate(t *testing.T) {
 			name: "missing input",
 			config: func() *Config {
 				cfg := DefaultConfig()
-				cfg.Output = "/tmp/output.mp4"
+				cfg.Output = "/tmp/output.mkv"
 				return cfg
 			},
 			expectError: true,
@@ -71,7 +72,7 @@ func TestValidate(t *testing.T) {
 			config: func() *Config {
 				cfg := DefaultConfig()
 				cfg.Input = createTempFile(t)
-				cfg.Output = "/tmp/output.mp4"
+				cfg.Output = "/tmp/output.mkv"
 				cfg.Mode = "invalid"
 				return cfg
 			},
@@ -83,7 +84,7 @@ func TestValidate(t *testing.T) {
 			config: func() *Config {
 				cfg := DefaultConfig()
 				cfg.Input = createTempFile(t)
-				cfg.Output = "/tmp/output.mp4"
+				cfg.Output = "/tmp/output.mkv"
 				cfg.ChunkDuration = -1
 				return cfg
 			},
@@ -95,13 +96,61 @@ func TestValidate(t *testing.T) {
 			config: func() *Config {
 				cfg := DefaultConfig()
 				cfg.Input = createTempFile(t)
-				cfg.Output = "/tmp/output.mp4"
+				cfg.Output = "/tmp/output.mkv"
 				cfg.Workers = -1
 				return cfg
 			},
 			expectError: true,
 			errorText:   "workers cannot be negative",
 		},
+		{
+			name: "invalid metrics address",
+			config: func() *Config {
+				cfg := DefaultConfig()
+				cfg.Input = createTempFile(t)
+				cfg.Output = "/tmp/output.mkv"
+				cfg.MetricsAddr = "9464"
+				return cfg
+			},
+			expectError: true,
+			errorText:   "invalid metrics_addr '9464'",
+		},
+		{
+			name: "invalid log level",
+			config: func() *Config {
+				cfg := DefaultConfig()
+				cfg.Input = createTempFile(t)
+				cfg.Output = "/tmp/output.mkv"
+				cfg.LogLevel = "trace"
+				return cfg
+			},
+			expectError: true,
+			errorText:   "invalid log_level 'trace'",
+		},
+		{
+			name: "invalid log format",
+			config: func() *Config {
+				cfg := DefaultConfig()
+				cfg.Input = createTempFile(t)
+				cfg.Output = "/tmp/output.mkv"
+				cfg.LogFormat = "xml"
+				return cfg
+			},
+			expectError: true,
+			errorText:   "invalid log_format 'xml'",
+		},
+		{
+			name: "invalid output format",
+			config: func() *Config {
+				cfg := DefaultConfig()
+				cfg.Input = createTempFile(t)
+				cfg.Output = "/tmp/output.mkv"
+				cfg.OutputFormat = "yaml"
+				return cfg
+			},
+			expectError: true,
+			errorText:   "invalid output_format 'yaml'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -218,6 +267,60 @@ func TestVideoConfigValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "svt-av1 CRF above 51",
+			config: VideoConfig{
+				Codec:  "libsvtav1",
+				CRF:    55,
+				Preset: "8",
+			},
+			expectError: false,
+		},
+		{
+			name: "svt-av1 CRF above 63",
+			config: VideoConfig{
+				Codec:  "libsvtav1",
+				CRF:    64,
+				Preset: "8",
+			},
+			expectError: true,
+		},
+		{
+			name: "svt-av1 named preset",
+			config: VideoConfig{
+				Codec:  "libsvtav1",
+				CRF:    28,
+				Preset: "medium",
+			},
+			expectError: true,
+		},
+		{
+			name: "x264 numeric preset",
+			config: VideoConfig{
+				Codec:  "libx264",
+				CRF:    23,
+				Preset: "8",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown codec falls back to 0-51",
+			config: VideoConfig{
+				Codec:  "librav1e",
+				CRF:    52,
+				Preset: "anything",
+			},
+			expectError: true,
+		},
+		{
+			name: "audio codec as video codec",
+			config: VideoConfig{
+				Codec:  "aac",
+				CRF:    23,
+				Preset: "medium",
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -233,6 +336,262 @@ func TestVideoConfigValidate(t *testing.T) {
 	}
 }
 
+func TestValidate_CodecErrors(t *testing.T) {
+	inputPath := createTempFile(t)
+
+	cfg := DefaultConfig()
+	cfg.Input = inputPath
+	cfg.Output = "out.mp4"
+	cfg.Audio.SampleRate = 44100 // not supported by libopus
+	cfg.Video.CRF = 70
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, want := range []string{
+		"libopus does not support sample rate 44100",
+		"libsvtav1 CRF must be between 0 and 63, got 70",
+	} {
+		if !contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	// AV1 + Opus cannot go into an AVI file
+	cfg = DefaultConfig()
+	cfg.Input = inputPath
+	cfg.Output = "out.avi"
+	err = cfg.Validate()
+	if err == nil || !contains(err.Error(), "cannot be stored in .avi files") {
+		t.Errorf("Expected container error, got: %v", err)
+	}
+}
+
+func TestValidate_HDRSettings(t *testing.T) {
+	tests := []struct {
+		hdr     string
+		tonemap string
+		wantErr string
+	}{
+		{"preserve", "hable", ""},
+		{"tonemap", "mobius", ""},
+		{"ignore", "", ""},
+		{"", "", ""},
+		{"auto", "hable", "hdr must be one of"},
+		{"tonemap", "aces", "tonemap must be one of"},
+	}
+
+	for _, tt := range tests {
+		vc := DefaultConfig().Video
+		vc.HDR = tt.hdr
+		vc.Tonemap = tt.tonemap
+		err := vc.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("hdr=%s tonemap=%s: unexpected error %v", tt.hdr, tt.tonemap, err)
+		}
+		if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+			t.Errorf("hdr=%s tonemap=%s: expected error containing %q, got %v", tt.hdr, tt.tonemap, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_CropSettings(t *testing.T) {
+	tests := []struct {
+		crop    string
+		round   int
+		wantErr bool
+	}{
+		{"off", 2, false},
+		{"", 0, false},
+		{"auto", 8, false},
+		{"auto", 6, true},
+		{"1920:800:0:140", 0, false},
+		{"1920x800", 2, true},
+	}
+
+	for _, tt := range tests {
+		vc := DefaultConfig().Video
+		vc.Crop = tt.crop
+		vc.CropRound = tt.round
+		err := vc.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("crop=%q round=%d: error = %v, wantErr %v", tt.crop, tt.round, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_DeinterlaceSettings(t *testing.T) {
+	tests := []struct {
+		mode         string
+		deinterlacer string
+		wantErr      bool
+	}{
+		{"auto", "bwdif", false},
+		{"", "", false},
+		{"ivtc", "yadif", false},
+		{"deinterlace", "bwdif", false},
+		{"always", "bwdif", true},
+		{"auto", "w3fdif", true},
+	}
+
+	for _, tt := range tests {
+		vc := DefaultConfig().Video
+		vc.Deinterlace = tt.mode
+		vc.Deinterlacer = tt.deinterlacer
+		err := vc.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("deinterlace=%q deinterlacer=%q: error = %v, wantErr %v", tt.mode, tt.deinterlacer, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_TargetQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(vc *VideoConfig)
+		wantErr string
+	}{
+		{"fixed CRF", func(vc *VideoConfig) {}, ""},
+		{"vmaf target", func(vc *VideoConfig) { vc.TargetQuality = 93 }, ""},
+		{"ssim target", func(vc *VideoConfig) { vc.TargetQuality, vc.QualityMetric = 0.98, "ssim" }, ""},
+		{"empty metric is vmaf", func(vc *VideoConfig) { vc.TargetQuality, vc.QualityMetric = 93, "" }, ""},
+		{"ssim out of range", func(vc *VideoConfig) { vc.TargetQuality, vc.QualityMetric = 93, "ssim" }, "between 0 and 1"},
+		{"unknown metric", func(vc *VideoConfig) { vc.QualityMetric = "butteraugli" }, "quality_metric must be one of"},
+		{"negative target", func(vc *VideoConfig) { vc.TargetQuality = -1 }, "cannot be negative"},
+		{"with bitrate", func(vc *VideoConfig) { vc.TargetQuality, vc.Bitrate = 93, "5M" }, "cannot be combined with bitrate"},
+		{"too many probes", func(vc *VideoConfig) { vc.TargetQuality, vc.QualityProbes = 93, 9 }, "quality_probes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := DefaultConfig().Video
+			tt.modify(&vc)
+			err := vc.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RateControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(vc *VideoConfig)
+		wantErr string
+	}{
+		{"CRF", func(vc *VideoConfig) {}, ""},
+		{"capped CRF", func(vc *VideoConfig) { vc.MaxRate, vc.BufSize = "8M", "16M" }, ""},
+		{"two-pass x264", func(vc *VideoConfig) {
+			vc.Codec, vc.Preset, vc.Passes, vc.Bitrate = "libx264", "medium", 2, "5M"
+		}, ""},
+		{"two-pass target size", func(vc *VideoConfig) {
+			vc.Codec, vc.Preset, vc.Passes, vc.TargetSize = "libx265", "slow", 2, "700M"
+		}, ""},
+		{"zero passes is one", func(vc *VideoConfig) { vc.Passes = 0 }, ""},
+		{"two-pass without bitrate", func(vc *VideoConfig) {
+			vc.Codec, vc.Preset, vc.Passes = "libx264", "medium", 2
+		}, "needs bitrate or target_size"},
+		{"two-pass SVT-AV1", func(vc *VideoConfig) { vc.Passes, vc.Bitrate = 2, "3M" }, "libsvtav1 does not support two-pass"},
+		{"three passes", func(vc *VideoConfig) { vc.Passes = 3 }, "passes must be 1 or 2"},
+		{"bad max rate", func(vc *VideoConfig) { vc.MaxRate = "lots" }, "max_rate must be a bitrate"},
+		{"buffer without max rate", func(vc *VideoConfig) { vc.BufSize = "16M" }, "buf_size needs max_rate"},
+		{"bad target size", func(vc *VideoConfig) { vc.TargetSize = "big" }, "target_size must be a size"},
+		{"target size and bitrate", func(vc *VideoConfig) { vc.TargetSize, vc.Bitrate = "700M", "5M" }, "cannot be combined with bitrate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := DefaultConfig().Video
+			tt.modify(&vc)
+			err := vc.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_EncoderParams(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.Video.EncoderParams.SVTAV1; got.LP != 4 || got.Pin != 1 {
+		t.Errorf("Expected SVT-AV1 defaults lp=4 pin=1, got %+v", got)
+	}
+
+	vc := cfg.Video
+	vc.EncoderParams.SVTAV1.FilmGrain = 80
+	if err := vc.Validate(); err == nil || !contains(err.Error(), "film_grain") {
+		t.Errorf("Expected film_grain error, got %v", err)
+	}
+}
+
+func TestValidate_MetricsSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(mc *MetricsConfig)
+		wantErr string
+	}{
+		{"disabled", func(mc *MetricsConfig) { mc.Compute = "bogus" }, ""},
+		{"defaults", func(mc *MetricsConfig) { mc.Enabled = true }, ""},
+		{"spaces", func(mc *MetricsConfig) { mc.Enabled, mc.Compute = true, " ssim , psnr " }, ""},
+		{"empty list", func(mc *MetricsConfig) { mc.Enabled, mc.Compute = true, " , " }, "at least one metric"},
+		{"unknown metric", func(mc *MetricsConfig) { mc.Enabled, mc.Compute = true, "ssim,ms-ssim" }, `unknown metric "ms-ssim"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Input = createTempFile(t)
+			cfg.Output = "/tmp/output.mkv"
+			tt.modify(&cfg.Metrics)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_PresetFollowsCodec(t *testing.T) {
+	inputPath := createTempFile(t)
+	emptyConfig := createTempFile(t) // keep any local encoder.yaml out of the test
+	os.Args = []string{"encoder", "-config", emptyConfig, "-input", inputPath, "-output", "out.mkv", "-video-codec", "libx264"}
+
+	// Built-in preset "8" is for SVT-AV1; switching codecs must not make it invalid
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Video.Preset != "medium" {
+		t.Errorf("Expected libx264 default preset 'medium', got %q", cfg.Video.Preset)
+	}
+
+	os.Args = append(os.Args, "-video-preset", "9")
+	if _, err := LoadConfig(); err == nil || !contains(err.Error(), "libx264 preset must be one of") {
+		t.Errorf("Expected explicit bad preset to be rejected, got: %v", err)
+	}
+}
+
 func TestIsValidMode(t *testing.T) {
 	validModes := []string{"cpu-only", "gpu-only", "mixed"}
 	for _, mode := range validModes {
@@ -293,3 +652,291 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestValidate_Packaging(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(pc *PackagingConfig)
+		video   string
+		audio   string
+		wantErr string
+	}{
+		{"off", func(pc *PackagingConfig) {}, "libsvtav1", "libopus", ""},
+		{"zero values", func(pc *PackagingConfig) { *pc = PackagingConfig{} }, "libsvtav1", "libopus", ""},
+		{"hls and dash", func(pc *PackagingConfig) { pc.Formats = "hls, dash" }, "libsvtav1", "libopus", ""},
+		{"hls ts", func(pc *PackagingConfig) { pc.Formats, pc.HLSSegmentType = "hls", "ts" }, "libx264", "aac", ""},
+		{"dash ignores ts", func(pc *PackagingConfig) { pc.Formats, pc.HLSSegmentType = "dash", "ts" }, "libsvtav1", "libopus", ""},
+		{"unknown format", func(pc *PackagingConfig) { pc.Formats = "hls,smooth" }, "libx264", "aac", `unknown format "smooth"`},
+		{"negative segments", func(pc *PackagingConfig) { pc.SegmentDuration = -2 }, "libx264", "aac", "cannot be negative"},
+		{"bad segment type", func(pc *PackagingConfig) { pc.HLSSegmentType = "mkv" }, "libx264", "aac", "invalid hls_segment_type"},
+		{"AV1 in ts", func(pc *PackagingConfig) { pc.Formats, pc.HLSSegmentType = "hls", "ts" }, "libsvtav1", "aac", "libsvtav1 cannot be packaged in MPEG-TS"},
+		{"Opus in ts", func(pc *PackagingConfig) { pc.Formats, pc.HLSSegmentType = "hls", "ts" }, "libx264", "libopus", "libopus cannot be packaged in MPEG-TS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := DefaultConfig().Packaging
+			tt.modify(&pc)
+			err := pc.Validate([]string{tt.video}, tt.audio)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVideoConfig_Rendition(t *testing.T) {
+	vc := DefaultConfig().Video
+	vc.Codec, vc.Preset, vc.CRF = "libx265", "slow", 24
+	vc.Bitrate, vc.Passes = "6M", 2
+	vc.Renditions = []RenditionConfig{{Name: "1080p"}}
+
+	// Unset fields fall back to the video section
+	same := vc.Rendition(RenditionConfig{Name: "1080p"})
+	if same.Codec != "libx265" || same.Preset != "slow" || same.Bitrate != "6M" || same.Passes != 2 || same.Renditions != nil {
+		t.Errorf("Expected the video settings without the ladder, got %+v", same)
+	}
+
+	// A CRF switches the bitrate-driven section back to CRF
+	crf := vc.Rendition(RenditionConfig{Name: "720p", Resolution: "1280x720", CRF: 26})
+	if crf.CRF != 26 || crf.Bitrate != "" || crf.Passes != 0 || crf.Resolution != "1280x720" {
+		t.Errorf("Expected CRF 26 at 1280x720, got %+v", crf)
+	}
+
+	// Another codec starts from its default preset
+	av1 := vc.Rendition(RenditionConfig{Name: "av1", Codec: "libsvtav1", CRF: 35})
+	if av1.Codec != "libsvtav1" || av1.Preset == "slow" || av1.Preset == "" {
+		t.Errorf("Expected libsvtav1 with its default preset, got %q %q", av1.Codec, av1.Preset)
+	}
+
+	if codecs := vc.Codecs(); len(codecs) != 1 || codecs[0] != "libx265" {
+		t.Errorf("Expected [libx265], got %v", codecs)
+	}
+	vc.Renditions = append(vc.Renditions, RenditionConfig{Name: "av1", Codec: "libsvtav1", CRF: 35})
+	if codecs := vc.Codecs(); len(codecs) != 2 || codecs[1] != "libsvtav1" {
+		t.Errorf("Expected [libx265 libsvtav1], got %v", codecs)
+	}
+}
+
+func TestValidate_Renditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		renditions []RenditionConfig
+		modify     func(vc *VideoConfig)
+		wantErr    string
+	}{
+		{"none", nil, nil, ""},
+		{"ladder", []RenditionConfig{
+			{Name: "1080p", Resolution: "1920x1080"},
+			{Name: "720p", Resolution: "1280x720", CRF: 40},
+			{Name: "480p", Resolution: "854x480", Codec: "libx264", Bitrate: "1200k", MaxRate: "1800k"},
+		}, nil, ""},
+		{"missing name", []RenditionConfig{{Resolution: "1280x720"}}, nil, "rendition 1: name is required"},
+		{"bad name", []RenditionConfig{{Name: "720p/low"}}, nil, "name may only contain"},
+		{"duplicate name", []RenditionConfig{{Name: "720p"}, {Name: "720p"}}, nil, `rendition "720p" is defined more than once`},
+		{"bad resolution", []RenditionConfig{{Name: "720p", Resolution: "720p"}}, nil, "resolution must be in format"},
+		{"crf and bitrate", []RenditionConfig{{Name: "720p", CRF: 30, Bitrate: "3M"}}, nil, "crf cannot be combined with bitrate"},
+		{"crf out of codec range", []RenditionConfig{{Name: "h264", Codec: "libx264", CRF: 60}}, nil, `rendition "h264"`},
+		{"target size", []RenditionConfig{{Name: "720p"}}, func(vc *VideoConfig) { vc.TargetSize = "700M" }, "target_size cannot be combined with renditions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc := DefaultConfig().Video
+			vc.Renditions = tt.renditions
+			if tt.modify != nil {
+				tt.modify(&vc)
+			}
+			err := vc.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Thumbnails(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(tc *ThumbnailsConfig)
+		wantErr string
+	}{
+		{"off", func(tc *ThumbnailsConfig) {}, ""},
+		{"zero values", func(tc *ThumbnailsConfig) { *tc = ThumbnailsConfig{} }, ""},
+		{"all outputs", func(tc *ThumbnailsConfig) { tc.Outputs = "poster, sprite, preview" }, ""},
+		{"scene", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Selection = "sprite", "scene" }, ""},
+		{"chapters", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Selection = "poster", "chapters" }, ""},
+		{"unknown output", func(tc *ThumbnailsConfig) { tc.Outputs = "poster,gallery" }, `unknown output "gallery"`},
+		{"unknown selection", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Selection = "sprite", "random" }, "invalid selection"},
+		{"preview ignores selection", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Selection = "preview", "random" }, ""},
+		{"zero interval", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Interval = "poster", 0 }, "interval must be positive"},
+		{"scene threshold", func(tc *ThumbnailsConfig) { tc.Outputs, tc.Selection, tc.SceneThreshold = "poster", "scene", 2 }, "scene_threshold"},
+		{"bad tile", func(tc *ThumbnailsConfig) { tc.Outputs, tc.SpriteTile = "sprite", "160" }, "invalid sprite_tile"},
+		{"empty grid", func(tc *ThumbnailsConfig) { tc.Outputs, tc.SpriteRows = "sprite", 0 }, "sprite_rows"},
+		{"bad preview format", func(tc *ThumbnailsConfig) { tc.Outputs, tc.PreviewFormat = "preview", "apng" }, "invalid preview_format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := DefaultConfig().Thumbnails
+			tt.modify(&tc)
+			err := tc.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Watch(t *testing.T) {
+	profiles := map[string]*Profile{"web": {}}
+	tests := []struct {
+		name    string
+		modify  func(wc *WatchConfig)
+		wantErr string
+	}{
+		{"defaults", func(wc *WatchConfig) {}, ""},
+		{"rules", func(wc *WatchConfig) {
+			wc.Rules = []WatchRule{
+				{Match: "*.mov", MinHeight: 720, MaxHeight: 1080, HDR: "no", Profile: "web"},
+				{AudioOnly: true},
+			}
+		}, ""},
+		{"zero poll interval", func(wc *WatchConfig) { wc.PollInterval = 0 }, "poll_interval must be positive"},
+		{"negative settle time", func(wc *WatchConfig) { wc.SettleTime = -1 }, "settle_time"},
+		{"index placeholder", func(wc *WatchConfig) { wc.OutputTemplate = "/out/{index}_{name}.mkv" }, "output_template cannot use {index}"},
+		{"bad pattern", func(wc *WatchConfig) { wc.Rules = []WatchRule{{Match: "[a-"}} }, "rule 1: invalid match pattern"},
+		{"height range", func(wc *WatchConfig) { wc.Rules = []WatchRule{{MinHeight: 2160, MaxHeight: 1080}} }, "min_height is above max_height"},
+		{"bad hdr", func(wc *WatchConfig) { wc.Rules = []WatchRule{{HDR: "maybe"}} }, "hdr must be one of"},
+		{"audio with video conditions", func(wc *WatchConfig) { wc.Rules = []WatchRule{{AudioOnly: true, MinHeight: 720}} }, "audio_only cannot be combined"},
+		{"unknown profile", func(wc *WatchConfig) { wc.Rules = []WatchRule{{Match: "*.mkv"}, {Profile: "archive"}} }, `rule 2: unknown profile "archive"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := DefaultConfig().Watch
+			tt.modify(&wc)
+			err := wc.Validate(profiles)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Serve(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(sc *ServeConfig)
+		wantErr string
+	}{
+		{"defaults", func(sc *ServeConfig) {}, ""},
+		{"localhost", func(sc *ServeConfig) { sc.Addr = "localhost:9000" }, ""},
+		{"ipv6 loopback", func(sc *ServeConfig) { sc.Addr = "[::1]:9000" }, ""},
+		{"all interfaces", func(sc *ServeConfig) { sc.Addr = ":8080" }, "must be a loopback address"},
+		{"public address", func(sc *ServeConfig) { sc.Addr = "0.0.0.0:8080" }, "must be a loopback address"},
+		{"no port", func(sc *ServeConfig) { sc.Addr = "127.0.0.1" }, "invalid addr"},
+		{"zero concurrency", func(sc *ServeConfig) { sc.Concurrency = 0 }, "concurrency must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := DefaultConfig().Serve
+			tt.modify(&sc)
+			err := sc.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_OutputContainer(t *testing.T) {
+	tests := []struct {
+		output  string
+		video   string
+		audio   string
+		wantErr string
+	}{
+		{"/tmp/output.mkv", "libsvtav1", "libopus", ""},
+		{"/tmp/output.mp4", "libx264", "aac", ""},
+		{"/tmp/output.webm", "libsvtav1", "libopus", ""},
+		{"/tmp/output.webm", "libx264", "libopus", "libx264 cannot be stored in .webm files"},
+		{"/tmp/output.mp4", "libx264", "libvorbis", "libvorbis cannot be stored in .mp4 files"},
+		{"/tmp/output.mp4", "libx264", "libopus", "libopus cannot be stored in .mp4 files"},
+		{"/tmp/output.xyz", "libx264", "aac", `unknown container ".xyz"`},
+		{"/tmp/output", "libx264", "aac", `unknown container ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output+" "+tt.video+" "+tt.audio, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Input = createTempFile(t)
+			cfg.Output = tt.output
+			cfg.Video.Codec = tt.video
+			c, _ := codec.Lookup(tt.video)
+			cfg.Video.Preset = c.DefaultPreset
+			cfg.Audio.Codec = tt.audio
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEffectiveLogLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		verbose bool
+		want    string
+	}{
+		{"", false, "info"},
+		{"", true, "debug"},
+		{"warn", true, "warn"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.LogLevel = tt.level
+		cfg.Verbose = tt.verbose
+		if got := cfg.EffectiveLogLevel(); got != tt.want {
+			t.Errorf("EffectiveLogLevel(%q, verbose %v) = %q, want %q", tt.level, tt.verbose, got, tt.want)
+		}
+	}
+}
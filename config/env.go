@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of all environment variables read by the encoder.
+//
+// Every Config field maps to ENCODER_<YAML_PATH>, with nested keys joined by
+// underscores: video.crf -> ENCODER_VIDEO_CRF, chunk_duration -> ENCODER_CHUNK_DURATION.
+// List and map fields (video.renditions, watch.rules, profiles) have no
+// variable and are only read from config files.
+const EnvPrefix = "ENCODER_"
+
+// EnvConfigPath names the config file to load (equivalent to -config).
+const EnvConfigPath = EnvPrefix + "CONFIG"
+
+// configField is a leaf Config field addressed by its dotted YAML path.
+type configField struct {
+	Key        string        // e.g. "video.crf"
+	Env        string        // e.g. "ENCODER_VIDEO_CRF"
+	Value      reflect.Value // settable field value
+	Structured bool          // list or map, e.g. video.renditions; config file only
+}
+
+// fields returns all scalar leaf fields of the config in declaration order.
+func (c *Config) fields() []configField {
+	var scalars []configField
+	for _, field := range collectFields(reflect.ValueOf(c).Elem(), "") {
+		if !field.Structured {
+			scalars = append(scalars, field)
+		}
+	}
+	return scalars
+}
+
+// structuredFields returns the list and map fields of the config
+// (video.renditions, watch.rules, profiles). They cannot be given as a
+// single value and are only read from config files.
+func (c *Config) structuredFields() []configField {
+	var structured []configField
+	for _, field := range collectFields(reflect.ValueOf(c).Elem(), "") {
+		if field.Structured {
+			structured = append(structured, field)
+		}
+	}
+	return structured
+}
+
+// collectFields walks a struct and returns its leaves keyed by YAML path.
+// Fields tagged yaml:"-" are skipped; map and slice fields are marked Structured.
+func collectFields(v reflect.Value, prefix string) []configField {
+	var result []configField
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "-" || name == "" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		env := EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		switch fv.Kind() {
+		case reflect.Struct:
+			result = append(result, collectFields(fv, key)...)
+		case reflect.String, reflect.Int, reflect.Int64, reflect.Bool, reflect.Float64:
+			result = append(result, configField{Key: key, Env: env, Value: fv})
+		case reflect.Slice, reflect.Map:
+			result = append(result, configField{Key: key, Env: env, Value: fv, Structured: true})
+		}
+	}
+
+	return result
+}
+
+// MergeFromEnv overrides config values from ENCODER_* environment variables.
+func (c *Config) MergeFromEnv() error {
+	return c.MergeFromEnvLookup(os.LookupEnv)
+}
+
+// MergeFromEnvLookup overrides config values using the given lookup function.
+//
+// Values are parsed according to the field type; all parse errors are
+// collected and reported together, naming the offending variable. Variables
+// naming a list or map field (e.g. ENCODER_VIDEO_RENDITIONS) are rejected.
+func (c *Config) MergeFromEnvLookup(lookup func(string) (string, bool)) error {
+	var errors []string
+
+	for _, field := range c.structuredFields() {
+		if _, ok := lookup(field.Env); ok {
+			errors = append(errors, fmt.Sprintf("%s: %s", field.Env, structuredError(field)))
+		}
+	}
+
+	for _, field := range c.fields() {
+		raw, ok := lookup(field.Env)
+		if !ok {
+			continue
+		}
+
+		if err := setFieldFromString(field.Value, raw); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", field.Env, err))
+			continue
+		}
+		c.setSource(field.Key, SourceEnv)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid environment configuration:\n  - %s", strings.Join(errors, "\n  - "))
+	}
+
+	return nil
+}
+
+// Set overrides the value at key (e.g. "video.crf"), parsed like the
+// ENCODER_* variable of the key, and records src as its origin.
+func (c *Config) Set(key, raw string, src Source) error {
+	for _, field := range c.fields() {
+		if field.Key != key {
+			continue
+		}
+		if err := setFieldFromString(field.Value, raw); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		c.setSource(key, src)
+		return nil
+	}
+	for _, field := range c.structuredFields() {
+		if field.Key == key {
+			return fmt.Errorf("%s: %s", key, structuredError(field))
+		}
+	}
+	return fmt.Errorf("%s: unknown config key", key)
+}
+
+// structuredError explains that a list or map field cannot be set from a single value.
+func structuredError(field configField) string {
+	kind := "a list"
+	if field.Value.Kind() == reflect.Map {
+		kind = "a map"
+	}
+	return fmt.Sprintf("%s is %s and can only be set in a config file", field.Key, kind)
+}
+
+// setFieldFromString parses raw into the field according to its kind.
+func setFieldFromString(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer value %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean value %q (use true/false/1/0)", raw)
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number value %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Kind())
+	}
+
+	return nil
+}
+
+// EnvVars returns the names of all supported ENCODER_* variables.
+func EnvVars() []string {
+	cfg := DefaultConfig()
+	fields := cfg.fields()
+	names := make([]string, 0, len(fields)+1)
+	names = append(names, EnvConfigPath)
+	for _, field := range fields {
+		names = append(names, field.Env)
+	}
+	return names
+}
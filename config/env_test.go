@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeFromEnvLookup_AllTypes(t *testing.T) {
+	env := map[string]string{
+		"ENCODER_WORKERS":           "12",
+		"ENCODER_MODE":              "gpu-only",
+		"ENCODER_WORK_DIR":          "/scratch",
+		"ENCODER_AUDIO_BITRATE":     "160k",
+		"ENCODER_AUDIO_CHANNELS":    "6",
+		"ENCODER_VIDEO_CRF":         "35",
+		"ENCODER_VIDEO_CODEC":       "libx265",
+		"ENCODER_MIXING_COPY_AUDIO": "false",
+		"ENCODER_STRICT_MODE":       "0",
+	}
+
+	cfg := DefaultConfig()
+	if err := cfg.MergeFromEnvLookup(mapLookup(env)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.Workers != 12 {
+		t.Errorf("Expected workers 12, got %d", cfg.Workers)
+	}
+	if cfg.Mode != "gpu-only" {
+		t.Errorf("Expected mode 'gpu-only', got '%s'", cfg.Mode)
+	}
+	if cfg.WorkDir != "/scratch" {
+		t.Errorf("Expected work dir '/scratch', got '%s'", cfg.WorkDir)
+	}
+	if cfg.Audio.Bitrate != "160k" {
+		t.Errorf("Expected audio bitrate '160k', got '%s'", cfg.Audio.Bitrate)
+	}
+	if cfg.Audio.Channels != 6 {
+		t.Errorf("Expected audio channels 6, got %d", cfg.Audio.Channels)
+	}
+	if cfg.Video.CRF != 35 {
+		t.Errorf("Expected video CRF 35, got %d", cfg.Video.CRF)
+	}
+	if cfg.Video.Codec != "libx265" {
+		t.Errorf("Expected video codec 'libx265', got '%s'", cfg.Video.Codec)
+	}
+	if cfg.Mixing.CopyAudio {
+		t.Error("Expected mixing copy_audio false")
+	}
+	if cfg.StrictMode {
+		t.Error("Expected strict mode false")
+	}
+
+	if cfg.Source("video.crf") != SourceEnv {
+		t.Errorf("Expected video.crf source env, got %s", cfg.Source("video.crf"))
+	}
+	if cfg.Source("video.preset") != SourceDefault {
+		t.Errorf("Expected video.preset source default, got %s", cfg.Source("video.preset"))
+	}
+}
+
+func TestMergeFromEnvLookup_InvalidValues(t *testing.T) {
+	env := map[string]string{
+		"ENCODER_VIDEO_CRF":   "high",
+		"ENCODER_STRICT_MODE": "maybe",
+		"ENCODER_WORKERS":     "4",
+	}
+
+	cfg := DefaultConfig()
+	err := cfg.MergeFromEnvLookup(mapLookup(env))
+	if err == nil {
+		t.Fatal("Expected error for invalid values")
+	}
+
+	for _, want := range []string{"ENCODER_VIDEO_CRF", "invalid integer", "ENCODER_STRICT_MODE", "invalid boolean"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	// Valid variables are still applied
+	if cfg.Workers != 4 {
+		t.Errorf("Expected workers 4, got %d", cfg.Workers)
+	}
+}
+
+func TestMergeFromEnvLookup_StructuredFields(t *testing.T) {
+	env := map[string]string{
+		"ENCODER_VIDEO_RENDITIONS": `[{"name": "720p", "resolution": "1280x720"}]`,
+		"ENCODER_WATCH_RULES":      "[]",
+		"ENCODER_PROFILES":         "{}",
+	}
+
+	cfg := DefaultConfig()
+	err := cfg.MergeFromEnvLookup(mapLookup(env))
+	if err == nil {
+		t.Fatal("Expected error for variables naming list and map fields")
+	}
+	for _, want := range []string{
+		"ENCODER_VIDEO_RENDITIONS: video.renditions is a list",
+		"ENCODER_WATCH_RULES: watch.rules is a list",
+		"ENCODER_PROFILES: profiles is a map",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	vars := strings.Join(EnvVars(), " ")
+	if strings.Contains(vars, "ENCODER_VIDEO_RENDITIONS") {
+		t.Errorf("Expected no variable for video.renditions, got %s", vars)
+	}
+}
+
+func TestEnvVars_CoversNestedFields(t *testing.T) {
+	vars := strings.Join(EnvVars(), " ")
+	for _, want := range []string{
+		"ENCODER_CONFIG",
+		"ENCODER_INPUT",
+		"ENCODER_CHUNK_DURATION",
+		"ENCODER_AUDIO_SAMPLE_RATE",
+		"ENCODER_VIDEO_FRAME_RATE",
+		"ENCODER_MIXING_COPY_VIDEO",
+		"ENCODER_PRE_SPLIT",
+	} {
+		if !strings.Contains(vars, want) {
+			t.Errorf("Expected %s in supported variables", want)
+		}
+	}
+}
+
+func TestLoadConfig_EnvPrecedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "encoder.yaml")
+	inputPath := filepath.Join(tmpDir, "test.mp4")
+	if err := os.WriteFile(inputPath, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create temp input file: %v", err)
+	}
+
+	configContent := `workers: 4
+chunk_duration: 10
+video:
+  crf: 23
+audio:
+  bitrate: 96k
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp config: %v", err)
+	}
+
+	// Config file selected through ENCODER_CONFIG
+	t.Setenv("ENCODER_CONFIG", configPath)
+	t.Setenv("ENCODER_WORKERS", "6")    // env beats file
+	t.Setenv("ENCODER_VIDEO_CRF", "30") // flag beats env
+
+	os.Args = []string{
+		"encoder",
+		"-input", inputPath,
+		"-output", "out.mkv",
+		"-video-crf", "40",
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Workers != 6 || cfg.Source("workers") != SourceEnv {
+		t.Errorf("Expected workers 6 from env, got %d from %s", cfg.Workers, cfg.Source("workers"))
+	}
+	if cfg.Video.CRF != 40 || cfg.Source("video.crf") != SourceFlag {
+		t.Errorf("Expected video CRF 40 from flag, got %d from %s", cfg.Video.CRF, cfg.Source("video.crf"))
+	}
+	if cfg.ChunkDuration != 10 || cfg.Source("chunk_duration") != SourceFile {
+		t.Errorf("Expected chunk duration 10 from file, got %d from %s", cfg.ChunkDuration, cfg.Source("chunk_duration"))
+	}
+	if cfg.Audio.Bitrate != "96k" || cfg.Source("audio.bitrate") != SourceFile {
+		t.Errorf("Expected audio bitrate 96k from file, got %s from %s", cfg.Audio.Bitrate, cfg.Source("audio.bitrate"))
+	}
+	if cfg.Source("audio.codec") != SourceDefault {
+		t.Errorf("Expected audio codec from defaults, got %s", cfg.Source("audio.codec"))
+	}
+}
+
+func TestConfigCopy_PreservesSources(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.setSource("workers", SourceFlag)
+
+	copy := cfg.Copy()
+	cfg.setSource("workers", SourceEnv)
+
+	if copy.Source("workers") != SourceFlag {
+		t.Errorf("Expected copy to keep source flag, got %s", copy.Source("workers"))
+	}
+}
+
+// mapLookup adapts a map to the os.LookupEnv signature.
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Set("video.crf", "22", SourceBatch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Video.CRF != 22 {
+		t.Errorf("Expected CRF 22, got %d", cfg.Video.CRF)
+	}
+	if cfg.Source("video.crf") != SourceBatch {
+		t.Errorf("Expected source %q, got %q", SourceBatch, cfg.Source("video.crf"))
+	}
+
+	if err := cfg.Set("video.crf", "high", SourceBatch); err == nil || !strings.Contains(err.Error(), "invalid integer") {
+		t.Errorf("Expected invalid integer error, got %v", err)
+	}
+	if err := cfg.Set("video.quality", "1", SourceBatch); err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+	if err := cfg.Set("watch.rules", "[]", SourceBatch); err == nil || !strings.Contains(err.Error(), "config file") {
+		t.Errorf("Expected config file only error, got %v", err)
+	}
+}
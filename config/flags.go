@@ -3,7 +3,9 @@ package config
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
 // MergeFromFlags parses command-line flags and overrides config values
@@ -19,6 +21,9 @@ func (c *Config) MergeFromFlags() error {
 	// Config file override (handled by LoadConfig before this function is called)
 	_ = fs.String("config", "", "Path to config file (default: search standard locations)")
 
+	// Profile selection (applied by LoadConfig, recorded here for display)
+	profile := fs.String("profile", "", "Named profile to apply (default: $ENCODER_PROFILE or profile: in config)")
+
 	// Mode shortcuts
 	cpuOnly := fs.Bool("cpu-only", false, "Use CPU-only encoding mode")
 	gpuOnly := fs.Bool("gpu-only", false, "Use GPU-only encoding mode")
@@ -28,26 +33,64 @@ func (c *Config) MergeFromFlags() error {
 	workers := fs.Int("workers", -1, "Number of parallel workers (0 = auto-detect, default: from config)")
 	chunkDuration := fs.Int("chunk-duration", -1, "Chunk duration in seconds (default: chapters or 600s)")
 	mode := fs.String("mode", "", "Encoding mode: cpu-only, gpu-only, mixed (default: from config)")
+	workDir := fs.String("work-dir", "", "Root directory for per-job work directories (default: tmp/ next to output)")
+	metricsAddr := fs.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9464 (default: off)")
 
 	// Audio settings
 	audioCodec := fs.String("audio-codec", "", "Audio codec (default: from config)")
 	audioBitrate := fs.String("audio-bitrate", "", "Audio bitrate, e.g., 128k (default: from config)")
 	audioSampleRate := fs.Int("audio-sample-rate", -1, "Audio sample rate in Hz (default: from config)")
 	audioChannels := fs.Int("audio-channels", -1, "Number of audio channels (default: from config)")
+	audioGapless := fs.Bool("audio-gapless", false, "Encode audio chunks losslessly and the joined track once (default: from config)")
+	noAudioGapless := fs.Bool("no-audio-gapless", false, "Encode each audio chunk with the audio codec directly")
 
 	// Video settings
 	videoCodec := fs.String("video-codec", "", "Video codec (default: from config)")
-	videoCRF := fs.Int("video-crf", -1, "Video CRF (0-51, lower = better quality) (default: from config)")
+	videoCRF := fs.Int("video-crf", -1, "Video CRF (0-51 for x264/x265, 0-63 for AV1/VP9, lower = better quality) (default: from config)")
 	videoPreset := fs.String("video-preset", "", "Video preset: ultrafast, fast, medium, slow, veryslow (default: from config)")
 	videoBitrate := fs.String("video-bitrate", "", "Video bitrate, e.g., 5M (default: from config)")
 	videoResolution := fs.String("video-resolution", "", "Video resolution, e.g., 1920x1080 (default: from config)")
 	videoFrameRate := fs.Int("video-frame-rate", -1, "Video frame rate (default: from config)")
+	videoHDR := fs.String("video-hdr", "", "HDR sources: preserve, tonemap, ignore (default: from config)")
+	videoTonemap := fs.String("video-tonemap", "", "Tone-mapping curve for -video-hdr tonemap (default: from config)")
+	videoCrop := fs.String("video-crop", "", "Black bar removal: off, auto, or W:H:X:Y (default: from config)")
+	videoCropRound := fs.Int("video-crop-round", -1, "Auto crop alignment: 2, 4, 8 or 16 (default: from config)")
+	videoDeinterlace := fs.String("video-deinterlace", "", "Interlaced sources: auto, off, deinterlace, ivtc (default: from config)")
+	videoDeinterlacer := fs.String("video-deinterlacer", "", "Deinterlacing filter: bwdif, yadif (default: from config)")
+	videoTargetQuality := fs.Float64("video-target-quality", -1, "Target quality score per chunk, e.g. 93 for VMAF (0 = fixed CRF, default: from config)")
+	videoQualityMetric := fs.String("video-quality-metric", "", "Target quality metric: vmaf, ssim, psnr (default: from config)")
+	videoQualityProbes := fs.Int("video-quality-probes", -1, "CRFs probed per chunk in target-quality mode (default: from config)")
+	videoPasses := fs.Int("video-passes", -1, "Encoding passes: 1, or 2 for two-pass with -video-bitrate or -video-target-size (default: from config)")
+	videoMaxRate := fs.String("video-max-rate", "", "VBV maximum bitrate, e.g., 8M (default: from config)")
+	videoBufSize := fs.String("video-buf-size", "", "VBV buffer size, e.g., 16M (default: from config)")
+	videoTargetSize := fs.String("video-target-size", "", "Output file size, e.g., 700M (default: from config)")
+
+	// Metrics stage
+	metrics := fs.Bool("metrics", false, "Compare the encoded video to the source and write a metrics report")
+	metricsCompute := fs.String("metrics-compute", "", "Metrics to compute: comma-separated vmaf, ssim, psnr (default: from config)")
+	metricsReport := fs.String("metrics-report", "", "Metrics report path (default: <output>.metrics.json)")
+
+	// Packaging stage
+	packageFormats := fs.String("package", "", "Package for adaptive streaming: comma-separated hls, dash (default: from config)")
+	packageSegmentDuration := fs.Float64("package-segment-duration", -1, "Segment length in seconds (default: from config)")
+	packageHLSSegments := fs.String("package-hls-segments", "", "HLS segment type: fmp4, ts (default: from config)")
+	packageDir := fs.String("package-dir", "", "Packaging output directory (default: <output name>_stream)")
+
+	// Thumbnails
+	thumbnails := fs.String("thumbnails", "", "Thumbnail outputs: comma-separated poster, sprite, preview (default: from config)")
+	thumbnailSelection := fs.String("thumbnail-selection", "", "Poster and sprite frames: interval, scene, chapters (default: from config)")
+	thumbnailInterval := fs.Float64("thumbnail-interval", -1, "Seconds between frames with interval selection (default: from config)")
+	thumbnailDir := fs.String("thumbnail-dir", "", "Thumbnail output directory (default: <output name>_thumbs)")
 
 	// Behavioral flags
 	strict := fs.Bool("strict", false, "Enable strict mode (fail on any error)")
 	noStrict := fs.Bool("no-strict", false, "Disable strict mode (continue on errors)")
-	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	verbose := fs.Bool("verbose", false, "Log at debug level, including ffmpeg commands and error output")
+	logLevel := fs.String("log-level", "", "Log level: debug, info, warn, error (default: info, or debug with -verbose)")
+	logFormat := fs.String("log-format", "", "Log file format: text, json (default: from config)")
+	outputFormat := fs.String("output-format", "", "Stdout format: text, json (newline-delimited events, text on stderr) (default: from config)")
 	dryRun := fs.Bool("dry-run", false, "Show configuration without encoding")
+	showSources := fs.Bool("config-sources", false, "Print each effective setting and the layer it came from, then exit")
 
 	// Parse flags
 	if err := fs.Parse(os.Args[1:]); err != nil {
@@ -65,6 +108,10 @@ func (c *Config) MergeFromFlags() error {
 		c.Output = *output
 	}
 
+	if *profile != "" {
+		c.Profile = *profile
+	}
+
 	// Handle mode shortcuts
 	if *cpuOnly {
 		c.Mode = "cpu-only"
@@ -83,6 +130,12 @@ func (c *Config) MergeFromFlags() error {
 	if *chunkDuration > 0 {
 		c.ChunkDuration = *chunkDuration
 	}
+	if *workDir != "" {
+		c.WorkDir = *workDir
+	}
+	if *metricsAddr != "" {
+		c.MetricsAddr = *metricsAddr
+	}
 
 	// Audio settings
 	if *audioCodec != "" {
@@ -97,6 +150,12 @@ func (c *Config) MergeFromFlags() error {
 	if *audioChannels > 0 {
 		c.Audio.Channels = *audioChannels
 	}
+	if *audioGapless {
+		c.Audio.Gapless = true
+	}
+	if *noAudioGapless {
+		c.Audio.Gapless = false
+	}
 
 	// Video settings
 	if *videoCodec != "" {
@@ -117,6 +176,84 @@ func (c *Config) MergeFromFlags() error {
 	if *videoFrameRate >= 0 {
 		c.Video.FrameRate = *videoFrameRate
 	}
+	if *videoHDR != "" {
+		c.Video.HDR = *videoHDR
+	}
+	if *videoTonemap != "" {
+		c.Video.Tonemap = *videoTonemap
+	}
+	if *videoCrop != "" {
+		c.Video.Crop = *videoCrop
+	}
+	if *videoCropRound > 0 {
+		c.Video.CropRound = *videoCropRound
+	}
+	if *videoDeinterlace != "" {
+		c.Video.Deinterlace = *videoDeinterlace
+	}
+	if *videoDeinterlacer != "" {
+		c.Video.Deinterlacer = *videoDeinterlacer
+	}
+	if *videoTargetQuality >= 0 {
+		c.Video.TargetQuality = *videoTargetQuality
+	}
+	if *videoQualityMetric != "" {
+		c.Video.QualityMetric = *videoQualityMetric
+	}
+	if *videoQualityProbes > 0 {
+		c.Video.QualityProbes = *videoQualityProbes
+	}
+	if *videoPasses > 0 {
+		c.Video.Passes = *videoPasses
+	}
+	if *videoMaxRate != "" {
+		c.Video.MaxRate = *videoMaxRate
+	}
+	if *videoBufSize != "" {
+		c.Video.BufSize = *videoBufSize
+	}
+	if *videoTargetSize != "" {
+		c.Video.TargetSize = *videoTargetSize
+	}
+
+	// Metrics stage
+	if *metrics {
+		c.Metrics.Enabled = true
+	}
+	if *metricsCompute != "" {
+		c.Metrics.Compute = *metricsCompute
+	}
+	if *metricsReport != "" {
+		c.Metrics.Report = *metricsReport
+	}
+
+	// Packaging stage
+	if *packageFormats != "" {
+		c.Packaging.Formats = *packageFormats
+	}
+	if *packageSegmentDuration > 0 {
+		c.Packaging.SegmentDuration = *packageSegmentDuration
+	}
+	if *packageHLSSegments != "" {
+		c.Packaging.HLSSegmentType = *packageHLSSegments
+	}
+	if *packageDir != "" {
+		c.Packaging.Dir = *packageDir
+	}
+
+	// Thumbnails
+	if *thumbnails != "" {
+		c.Thumbnails.Outputs = *thumbnails
+	}
+	if *thumbnailSelection != "" {
+		c.Thumbnails.Selection = *thumbnailSelection
+	}
+	if *thumbnailInterval > 0 {
+		c.Thumbnails.Interval = *thumbnailInterval
+	}
+	if *thumbnailDir != "" {
+		c.Thumbnails.Dir = *thumbnailDir
+	}
 
 	// Behavioral flags
 	if *strict {
@@ -128,19 +265,101 @@ func (c *Config) MergeFromFlags() error {
 	if *verbose {
 		c.Verbose = true
 	}
+	if *logLevel != "" {
+		c.LogLevel = *logLevel
+	}
+	if *logFormat != "" {
+		c.LogFormat = *logFormat
+	}
+	if *outputFormat != "" {
+		c.OutputFormat = *outputFormat
+	}
 	if *dryRun {
 		c.DryRun = true
 	}
+	if *showSources {
+		c.ShowSources = true
+	}
+
+	// Record which settings were given on the command line
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			c.setSource(key, SourceFlag)
+		}
+	})
 
 	return nil
 }
 
+// flagKeys maps command-line flags to the config keys they set.
+var flagKeys = map[string]string{
+	"input":                    "input",
+	"output":                   "output",
+	"profile":                  "profile",
+	"cpu-only":                 "mode",
+	"gpu-only":                 "mode",
+	"mixed":                    "mode",
+	"mode":                     "mode",
+	"workers":                  "workers",
+	"chunk-duration":           "chunk_duration",
+	"work-dir":                 "work_dir",
+	"metrics-addr":             "metrics_addr",
+	"audio-codec":              "audio.codec",
+	"audio-bitrate":            "audio.bitrate",
+	"audio-sample-rate":        "audio.sample_rate",
+	"audio-channels":           "audio.channels",
+	"audio-gapless":            "audio.gapless",
+	"no-audio-gapless":         "audio.gapless",
+	"video-codec":              "video.codec",
+	"video-crf":                "video.crf",
+	"video-preset":             "video.preset",
+	"video-bitrate":            "video.bitrate",
+	"video-resolution":         "video.resolution",
+	"video-frame-rate":         "video.frame_rate",
+	"video-hdr":                "video.hdr",
+	"video-tonemap":            "video.tonemap",
+	"video-crop":               "video.crop",
+	"video-crop-round":         "video.crop_round",
+	"video-deinterlace":        "video.deinterlace",
+	"video-deinterlacer":       "video.deinterlacer",
+	"video-target-quality":     "video.target_quality",
+	"video-quality-metric":     "video.quality_metric",
+	"video-quality-probes":     "video.quality_probes",
+	"video-passes":             "video.passes",
+	"video-max-rate":           "video.max_rate",
+	"video-buf-size":           "video.buf_size",
+	"video-target-size":        "video.target_size",
+	"metrics":                  "metrics.enabled",
+	"metrics-compute":          "metrics.compute",
+	"metrics-report":           "metrics.report",
+	"package":                  "packaging.formats",
+	"package-segment-duration": "packaging.segment_duration",
+	"package-hls-segments":     "packaging.hls_segment_type",
+	"package-dir":              "packaging.dir",
+	"thumbnails":               "thumbnails.outputs",
+	"thumbnail-selection":      "thumbnails.selection",
+	"thumbnail-interval":       "thumbnails.interval",
+	"thumbnail-dir":            "thumbnails.dir",
+	"strict":                   "strict_mode",
+	"no-strict":                "strict_mode",
+	"verbose":                  "verbose",
+	"log-level":                "log_level",
+	"log-format":               "log_format",
+	"output-format":            "output_format",
+	"dry-run":                  "dry_run",
+}
+
 // printUsage prints help text
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `encoder - Parallel video encoding with intelligent chunking
 
 USAGE:
   encoder -input FILE -output FILE [OPTIONS]
+  encoder batch [OPTIONS] DIR|GLOB|MANIFEST
+  encoder watch [-config FILE] [-dir DIR]
+  encoder serve [-config FILE] [-addr HOST:PORT] [-concurrency N]
+  encoder workdirs list|gc [OPTIONS]
+  encoder profiles
 
 REQUIRED FLAGS:
   -input string
@@ -150,7 +369,9 @@ REQUIRED FLAGS:
 
 CONFIGURATION:
   -config string
-        Path to config file (default: search ./encoder.yaml, ~/.encoder/config.yaml, /etc/encoder/config.yaml)
+        Path to config file (default: $ENCODER_CONFIG, then ./encoder.yaml, ~/.encoder/config.yaml, /etc/encoder/config.yaml)
+  -profile string
+        Named profile to apply on top of the config file (default: $ENCODER_PROFILE, then profile: in config)
 
 EXECUTION MODE:
   --cpu-only
@@ -167,6 +388,10 @@ EXECUTION SETTINGS:
         Number of parallel workers (0 = auto-detect CPU count) (default: 0)
   -chunk-duration int
         Duration of each chunk in seconds (default: uses chapters if available, otherwise 600s/10min)
+  -work-dir string
+        Root for per-job work directories, e.g. a fast scratch disk (default: tmp/ next to output)
+  -metrics-addr string
+        Serve Prometheus metrics at http://ADDR/metrics while encoding, e.g. :9464 (default: off)
 
 AUDIO SETTINGS:
   -audio-codec string
@@ -177,20 +402,89 @@ AUDIO SETTINGS:
         Audio sample rate in Hz (default: 48000)
   -audio-channels int
         Number of audio channels (default: 2)
+  -audio-gapless
+        Encode audio chunks losslessly at exact sample positions and encode the
+        joined track once, for clean chunk joins (default: true)
+  -no-audio-gapless
+        Encode each audio chunk with the audio codec directly
 
 VIDEO SETTINGS:
   -video-codec string
         Video codec (default: libx264)
   -video-crf int
-        Video CRF: 0-51, lower = better quality (default: 23)
+        Video CRF, lower = better quality: 0-51 for x264/x265, 0-63 for AV1/VP9 (default: 28)
   -video-preset string
-        Video preset: ultrafast, fast, medium, slow, veryslow (default: medium)
+        Video preset: ultrafast ... veryslow for x264/x265, 0-13 for SVT-AV1, p1-p7 for NVENC (default: 8)
   -video-bitrate string
         Video bitrate, e.g., 5M, 10M (alternative to CRF)
   -video-resolution string
         Video resolution, e.g., 1920x1080 (empty = keep original)
   -video-frame-rate int
         Video frame rate (0 = keep original)
+  -video-hdr string
+        HDR10/HLG sources: preserve (10-bit with HDR metadata), tonemap (to SDR), ignore (default: preserve)
+        Encoders without 10-bit support fall back to tonemap
+  -video-tonemap string
+        Tone-mapping curve: hable, mobius, reinhard, clip, linear, gamma (default: hable)
+  -video-crop string
+        Remove black bars: off, auto (cropdetect samples across the input), or W:H:X:Y (default: off)
+  -video-crop-round int
+        Align auto-detected width/height to a multiple of 2, 4, 8 or 16 (default: 2)
+  -video-deinterlace string
+        Interlaced sources: auto (detect with idet), off, deinterlace or ivtc to force (default: auto)
+        Telecined film is inverse-telecined to 23.976 fps, interlaced video deinterlaced
+  -video-deinterlacer string
+        Deinterlacing filter: bwdif, yadif (default: bwdif)
+  -video-target-quality float
+        Encode each chunk at the CRF that reaches this score, e.g. 93 (VMAF), 0.98 (SSIM), 42 (PSNR dB)
+        Chunks are probed at a few CRFs around -video-crf (default: 0 = fixed CRF)
+  -video-quality-metric string
+        Target quality metric: vmaf, ssim, psnr (default: vmaf, ssim if ffmpeg lacks libvmaf)
+  -video-quality-probes int
+        CRFs probed per chunk, 2-7 (default: 3)
+  -video-passes int
+        1, or 2 for two-pass ABR at -video-bitrate or -video-target-size (default: 1)
+        Each chunk runs its own first pass; needs x264, x265, libaom-av1 or VP9
+  -video-max-rate string
+        VBV maximum bitrate, e.g., 8M; with CRF this gives capped CRF (default: uncapped)
+  -video-buf-size string
+        VBV buffer size, e.g., 16M (default: 2 × -video-max-rate)
+  -video-target-size string
+        Output file size, e.g., 700M or 4.7G; the video bitrate is computed from the
+        duration and the audio bitrate (alternative to -video-bitrate)
+
+METRICS:
+  --metrics
+        Compare every encoded video chunk to the source (PSNR, SSIM, VMAF) and report
+        mean, harmonic mean, 1st percentile and the worst segments (default: off)
+  -metrics-compute string
+        Metrics to compute, comma-separated: vmaf, ssim, psnr (default: all; vmaf needs libvmaf)
+  -metrics-report string
+        JSON report path (default: <output>.metrics.json)
+
+PACKAGING:
+  -package string
+        Package the output for adaptive streaming, comma-separated: hls, dash
+        (default: off)
+  -package-segment-duration float
+        Segment length in seconds; keyframes are forced at this interval (default: 6)
+  -package-hls-segments string
+        HLS segment type: fmp4, ts (default: fmp4; ts needs H.264/HEVC and AAC/MP3/AC-3)
+  -package-dir string
+        Output directory for playlists and segments (default: <output name>_stream)
+
+THUMBNAILS:
+  -thumbnails string
+        Generate thumbnails while encoding, comma-separated: poster (JPEG frames),
+        sprite (sprite sheets with a WebVTT index for scrubbing), preview (animated clip)
+        (default: off)
+  -thumbnail-selection string
+        Poster and sprite frames: interval, scene (scene changes), chapters (chapter
+        starts; falls back to interval without chapters) (default: interval)
+  -thumbnail-interval float
+        Seconds between frames with interval selection (default: 10)
+  -thumbnail-dir string
+        Output directory for thumbnails (default: <output name>_thumbs)
 
 BEHAVIORAL FLAGS:
   --strict
@@ -202,25 +496,108 @@ BEHAVIORAL FLAGS:
   --no-cleanup
         Keep temporary chunk files after encoding
   --verbose
-        Enable verbose logging
+        Log at debug level: ffmpeg commands, task scheduling and the tail of
+        ffmpeg's error output (default: false)
+  -log-level string
+        Log level: debug, info, warn, error (default: info, or debug with --verbose)
+  -log-format string
+        Log file format: text (key=value) or json (one object per line) (default: text)
+  -output-format string
+        Stdout format: text, or json events (see OUTPUT FORMAT; text moves to stderr) (default: text)
   --dry-run
-        Show effective configuration without encoding
+        Show effective configuration and sample commands, and check them against the local ffmpeg build
+  --config-sources
+        Print every effective setting with the layer it came from (flag, env, profile, file, default)
 
 EXAMPLES:
   # Basic usage (uses defaults from config file)
-  encoder -input movie.mp4 -output encoded.mp4
+  encoder -input movie.mp4 -output encoded.mkv
 
   # CPU-only mode with 8 workers
-  encoder -input movie.mp4 -output encoded.mp4 --cpu-only -workers 8
+  encoder -input movie.mp4 -output encoded.mkv --cpu-only -workers 8
 
   # Override audio settings
   encoder -input movie.mp4 -output encoded.mp4 -audio-codec aac -audio-bitrate 192k
 
   # Show effective configuration
-  encoder -input movie.mp4 -output encoded.mp4 --dry-run
+  encoder -input movie.mp4 -output encoded.mkv --dry-run
 
   # Use custom config file
-  encoder -config custom.yaml -input movie.mp4 -output encoded.mp4
+  encoder -config custom.yaml -input movie.mp4 -output encoded.mkv
+
+  # Use a shared profile, overriding one of its settings
+  encoder -profile archive-av1 -video-crf 22 -input movie.mp4 -output movie.mkv
+
+BATCH MODE:
+  Encodes many inputs at once: the media files of a directory, the files a
+  glob matches, or the items of a manifest (.csv, .json, .yaml). All jobs'
+  chunks share one worker pool; a failed job does not stop the others.
+
+  encoder batch [-output-template T] [-profile NAME] [-workers N] [-dry-run] SOURCE
+        -output-template  Output of items without one (default: {dir}/encoded/{name}.mkv)
+                          Placeholders: {dir} {name} {ext} {index} {profile}
+
+  Manifest items have input, output, profile and overrides of config keys:
+    - { input: ep1.mkv, profile: web-h264, overrides: { video.crf: 24 } }
+  A CSV manifest has a header row; columns other than input, output and
+  profile are overrides, e.g. "input,output,video.crf". Mode, workers and
+  -metrics-addr apply to the whole batch.
+
+WATCH FOLDER:
+  Encodes the files dropped into a directory, one at a time. A file is taken
+  once its size and modification time have not changed for watch.settle_time
+  seconds; its source is then moved to the done or failed directory. The job
+  queue is kept in watch.state_dir, so jobs survive a restart of the daemon.
+
+  encoder watch [-config FILE] [-dir DIR] [-metrics-addr ADDR]
+        -dir  Directory to watch (default: watch.dir from config)
+
+  watch.rules pick each file's profile by file name glob or by ffprobe
+  properties (video_codec, min_height, max_height, hdr, audio_only); the
+  first matching rule wins. See encoder.yaml.example.
+
+JOB API:
+  Serves a JSON HTTP API on a loopback address to queue and follow jobs.
+  Jobs are kept in serve.state_dir, so queued jobs survive a restart.
+
+  encoder serve [-config FILE] [-addr HOST:PORT] [-concurrency N] [-state-dir DIR]
+        -addr         Listen address, loopback only (default: 127.0.0.1:8080)
+        -concurrency  Jobs encoded at once (default: 1)
+
+  POST /jobs               Queue {"input", "output", "profile", "overrides"}
+  GET  /jobs               List jobs (?status=queued|running|done|failed|canceled)
+  GET  /jobs/{id}          Job and, while it runs, its phase, stats and chunks
+  POST /jobs/{id}/cancel   Cancel a queued or running job
+  GET  /jobs/{id}/events   Server-Sent Events: job, progress, phase, stats,
+                           chunk and task; the stream ends with the job
+  GET  /metrics            Prometheus metrics (see METRICS)
+
+METRICS:
+  -metrics-addr (or metrics_addr) serves Prometheus metrics at
+  http://ADDR/metrics for as long as the run, batch or daemon lasts:
+    encoder_tasks{status}                          Tasks of the running stages
+    encoder_resource_slots_active{resource}        Slots held, and
+    encoder_resource_slots{resource}               slots available, per resource
+    encoder_resource_utilization_ratio{resource}   Held share of the slots
+    encoder_task_duration_seconds{task_type}       Histogram of task run times
+    encoder_speed, encoder_encodes_running         Summed realtime speed of encodes
+    encoder_tasks_finished_total{task_type,status}
+    encoder_bytes_written_total{task_type}         Size of completed task outputs
+    encoder_task_failures_total{class}             canceled, timeout, dependency,
+                                                   missing_binary, encoder, io, other
+    encoder_job_restarts_total                     Queued jobs restarted by watch or serve
+                                                   after an interrupted attempt
+
+WORK DIRECTORIES:
+  Each job uses its own directory <work-dir>/<input>-<hash>/ holding segments,
+  encoded chunks and cache manifests. A lock file prevents two jobs from using
+  the same directory at once. Without -work-dir (work_dir) the work root is
+  tmp/ next to each output, so list and gc need -root to find it.
+
+  encoder workdirs list [-root DIR]
+        List work directories with owner, size and lock state
+  encoder workdirs gc [-root DIR] [-older-than 168h] [-dry-run]
+        Remove unlocked work directories not used within the given age
 
 CONFIGURATION FILES:
   Config files are searched in order:
@@ -228,44 +605,182 @@ CONFIGURATION FILES:
     2. ~/.encoder/config.yaml
     3. /etc/encoder/config.yaml
 
-  Priority: CLI flags > Config file > Defaults
+PROFILES:
+  A profile is a named bundle of audio, video, mixing, chunk_duration and
+  pre_split settings. Profiles are defined under profiles: in the config file
+  or in *.yaml files in ~/.encoder/profiles.d/ (profiles_dir). A profile may
+  set inherits: to build on another profile; flags still override it.
+
+  encoder profiles
+        List the available profiles and where they are defined
+
+OUTPUT FORMAT:
+  With -output-format json, stdout carries one JSON object per line and the
+  usual text goes to stderr. Every object has "event" and "time"; fields are
+  only ever added. Events, in order:
+    start        input, output, mode
+    phase_start  phase (analysis, chunking, pre-split, setup, audio, video,
+                 concat, mux, metrics, packaging)
+    phase_end    phase, elapsed_seconds
+    chunk_plan   strategy (chapters, time), chunks: chunk_id, start, end, duration
+    progress     task, state, progress (percent), frame, fps, speed, position,
+                 bitrate, size - encoder progress of a chunk task
+    task         task, task_type, chunk_id, status (completed, failed, skipped),
+                 output, elapsed_seconds, error
+    dry_run      work_dir, commands: step, name, command, output, error;
+                 missing_features (instead of the events above)
+    result       status, exit_code, error, elapsed_seconds, phases; on success
+                 output, size_bytes, duration_seconds, bitrate_kbps, speed,
+                 chunks, audio_chunks, video_chunks, renditions, quality,
+                 metrics, packages, thumbnails
+  result is always the last event, except for flag and configuration errors,
+  which are reported on stderr before the output format is known.
+
+EXIT CODES:
+  0    Success (a dry run: the configuration is valid)
+  1    Encoding failed: a chunk, concatenation, mux or packaging step
+  2    Invalid flags or configuration
+  3    Input cannot be read: probe failed or no audio/video streams
+  4    Environment: ffmpeg features missing, log or work directory unusable
+  130  Interrupted (SIGINT/SIGTERM)
+
+ENVIRONMENT VARIABLES:
+  Every config key can be set as ENCODER_<KEY>, nested keys joined by "_":
+    ENCODER_WORKERS=8  ENCODER_MODE=cpu-only  ENCODER_VIDEO_CRF=30  ENCODER_AUDIO_BITRATE=160k
+  Lists and maps (video.renditions, watch.rules, profiles) can only be set in a
+  config file; an ENCODER_ variable naming one is an error.
+  ENCODER_CONFIG names the config file to load (like -config).
+  ENCODER_PROFILE selects the profile to apply (like -profile).
+
+  Priority: CLI flags > Environment variables > Profile > Config file > Defaults
 
 `)
 }
 
-// PrintConfig prints the effective configuration
-func (c *Config) PrintConfig() {
-	fmt.Println("═══════════════════════════════════════════════════════════")
-	fmt.Println("                 Effective Configuration                  ")
-	fmt.Println("═══════════════════════════════════════════════════════════")
-	fmt.Printf("Input:          %s\n", c.Input)
-	fmt.Printf("Output:         %s\n", c.Output)
-	fmt.Printf("Mode:           %s\n", c.Mode)
-	fmt.Printf("Workers:        %d\n", c.Workers)
-	fmt.Printf("Chunk Duration: %d seconds\n", c.ChunkDuration)
-
-	fmt.Println("\nAudio Settings:")
-	fmt.Printf("  Codec:        %s\n", c.Audio.Codec)
-	fmt.Printf("  Bitrate:      %s\n", c.Audio.Bitrate)
-	fmt.Printf("  Sample Rate:  %d Hz\n", c.Audio.SampleRate)
-	fmt.Printf("  Channels:     %d\n", c.Audio.Channels)
-
-	fmt.Println("\nVideo Settings:")
-	fmt.Printf("  Codec:        %s\n", c.Video.Codec)
-	fmt.Printf("  CRF:          %d\n", c.Video.CRF)
-	fmt.Printf("  Preset:       %s\n", c.Video.Preset)
+// PrintConfig prints the effective configuration to out
+func (c *Config) PrintConfig(out io.Writer) {
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+	fmt.Fprintln(out, "                 Effective Configuration                  ")
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+	fmt.Fprintf(out, "Input:          %s\n", c.Input)
+	fmt.Fprintf(out, "Output:         %s\n", c.Output)
+	if c.Profile != "" {
+		fmt.Fprintf(out, "Profile:        %s\n", c.Profile)
+	}
+	fmt.Fprintf(out, "Mode:           %s\n", c.Mode)
+	fmt.Fprintf(out, "Workers:        %d\n", c.Workers)
+	fmt.Fprintf(out, "Chunk Duration: %d seconds\n", c.ChunkDuration)
+	if c.WorkDir != "" {
+		fmt.Fprintf(out, "Work Dir:       %s\n", c.WorkDir)
+	}
+
+	fmt.Fprintln(out, "\nAudio Settings:")
+	fmt.Fprintf(out, "  Codec:        %s\n", c.Audio.Codec)
+	fmt.Fprintf(out, "  Bitrate:      %s\n", c.Audio.Bitrate)
+	fmt.Fprintf(out, "  Sample Rate:  %d Hz\n", c.Audio.SampleRate)
+	fmt.Fprintf(out, "  Channels:     %d\n", c.Audio.Channels)
+	fmt.Fprintf(out, "  Gapless:      %v\n", c.Audio.Gapless)
+
+	fmt.Fprintln(out, "\nVideo Settings:")
+	fmt.Fprintf(out, "  Codec:        %s\n", c.Video.Codec)
+	if c.Video.TargetQuality > 0 {
+		fmt.Fprintf(out, "  CRF:          target %s %g (%d probes around %d)\n", c.Video.QualityMetric, c.Video.TargetQuality, c.Video.QualityProbes, c.Video.CRF)
+	} else {
+		fmt.Fprintf(out, "  CRF:          %d\n", c.Video.CRF)
+	}
+	fmt.Fprintf(out, "  Preset:       %s\n", c.Video.Preset)
 	if c.Video.Bitrate != "" {
-		fmt.Printf("  Bitrate:      %s\n", c.Video.Bitrate)
+		fmt.Fprintf(out, "  Bitrate:      %s\n", c.Video.Bitrate)
+	}
+	if c.Video.TargetSize != "" {
+		fmt.Fprintf(out, "  Target Size:  %s\n", c.Video.TargetSize)
+	}
+	if c.Video.Passes == 2 {
+		fmt.Fprintf(out, "  Passes:       2\n")
+	}
+	if params, args := c.Video.EncoderParams.Options(c.Video.Codec); len(params) > 0 || len(args) > 0 {
+		entries := make([]string, 0, len(params))
+		for _, p := range params {
+			entries = append(entries, p.Key+"="+p.Value)
+		}
+		fmt.Fprintf(out, "  Tuning:       %s\n", strings.TrimSpace(strings.Join(entries, ":")+" "+strings.Join(args, " ")))
+	}
+	if c.Video.MaxRate != "" {
+		bufSize := c.Video.BufSize
+		if bufSize == "" {
+			bufSize = "2 × max rate"
+		}
+		fmt.Fprintf(out, "  Max Rate:     %s (buffer %s)\n", c.Video.MaxRate, bufSize)
 	}
 	if c.Video.Resolution != "" {
-		fmt.Printf("  Resolution:   %s\n", c.Video.Resolution)
+		fmt.Fprintf(out, "  Resolution:   %s\n", c.Video.Resolution)
 	}
 	if c.Video.FrameRate > 0 {
-		fmt.Printf("  Frame Rate:   %d\n", c.Video.FrameRate)
+		fmt.Fprintf(out, "  Frame Rate:   %d\n", c.Video.FrameRate)
+	}
+	if c.Video.Crop != "" && c.Video.Crop != "off" {
+		fmt.Fprintf(out, "  Crop:         %s\n", c.Video.Crop)
+	}
+	if c.Video.Deinterlace != "" && c.Video.Deinterlace != "off" {
+		fmt.Fprintf(out, "  Deinterlace:  %s (%s)\n", c.Video.Deinterlace, c.Video.Deinterlacer)
+	}
+	if c.Video.HDR == "tonemap" {
+		fmt.Fprintf(out, "  HDR:          tonemap (%s)\n", c.Video.Tonemap)
+	} else {
+		fmt.Fprintf(out, "  HDR:          %s\n", c.Video.HDR)
+	}
+	for i, r := range c.Video.Renditions {
+		label := ""
+		if i == 0 {
+			label = "Renditions:"
+		}
+		rv := c.Video.Rendition(r)
+		resolution, rate := rv.Resolution, fmt.Sprintf("CRF %d", rv.CRF)
+		if resolution == "" {
+			resolution = "source size"
+		}
+		if rv.Bitrate != "" {
+			rate = rv.Bitrate
+		}
+		fmt.Fprintf(out, "  %-14s%s: %s, %s, %s\n", label, r.Name, rv.Codec, resolution, rate)
+	}
+
+	if c.Metrics.Enabled {
+		fmt.Fprintln(out, "\nMetrics:")
+		fmt.Fprintf(out, "  Compute:      %s\n", c.Metrics.Compute)
+		if c.Metrics.Report != "" {
+			fmt.Fprintf(out, "  Report:       %s\n", c.Metrics.Report)
+		}
+	}
+
+	if c.Packaging.Enabled() {
+		fmt.Fprintln(out, "\nPackaging:")
+		fmt.Fprintf(out, "  Formats:      %s\n", c.Packaging.Formats)
+		fmt.Fprintf(out, "  Segments:     %gs (HLS: %s)\n", c.Packaging.SegmentDuration, c.Packaging.HLSSegmentType)
+		if c.Packaging.Dir != "" {
+			fmt.Fprintf(out, "  Directory:    %s\n", c.Packaging.Dir)
+		}
+	}
+
+	if c.Thumbnails.Enabled() {
+		fmt.Fprintln(out, "\nThumbnails:")
+		fmt.Fprintf(out, "  Outputs:      %s\n", c.Thumbnails.Outputs)
+		switch c.Thumbnails.Selection {
+		case "interval":
+			fmt.Fprintf(out, "  Selection:    every %gs\n", c.Thumbnails.Interval)
+		case "scene":
+			fmt.Fprintf(out, "  Selection:    scene changes (threshold %g)\n", c.Thumbnails.SceneThreshold)
+		default:
+			fmt.Fprintf(out, "  Selection:    %s\n", c.Thumbnails.Selection)
+		}
+		if c.Thumbnails.Dir != "" {
+			fmt.Fprintf(out, "  Directory:    %s\n", c.Thumbnails.Dir)
+		}
 	}
 
-	fmt.Println("\nBehavioral Flags:")
-	fmt.Printf("  Strict Mode:   %v\n", c.StrictMode)
-	fmt.Printf("  Verbose:       %v\n", c.Verbose)
-	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Fprintln(out, "\nBehavioral Flags:")
+	fmt.Fprintf(out, "  Strict Mode:   %v\n", c.StrictMode)
+	fmt.Fprintf(out, "  Verbose:       %v\n", c.Verbose)
+	fmt.Fprintf(out, "  Log:           %s, %s\n", c.EffectiveLogLevel(), c.LogFormat)
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
 }
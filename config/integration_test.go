@@ -36,7 +36,7 @@ video:
 	os.Args = []string{
 		"encoder",
 		"-input", inputPath,
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-mode", "cpu-only",
 		"-workers", "8",
 		"-audio-bitrate", "192k",
@@ -92,7 +92,7 @@ func TestLoadConfig_DefaultsOnly(t *testing.T) {
 	os.Args = []string{
 		"encoder",
 		"-input", inputPath,
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 	}
 
 	cfg, err := LoadConfig()
@@ -151,7 +151,7 @@ video:
 	os.Args = []string{
 		"encoder",
 		"-input", inputPath,
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-config", configPath,
 	}
 
@@ -207,7 +207,7 @@ func TestLoadConfig_WorkersAutoDetect(t *testing.T) {
 	os.Args = []string{
 		"encoder",
 		"-input", inputPath,
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-workers", "0", // Explicitly set to 0 to trigger auto-detect
 	}
 
@@ -227,7 +227,7 @@ func TestLoadConfig_InvalidConfig(t *testing.T) {
 	os.Args = []string{
 		"encoder",
 		"-input", "test.mp4",
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-mode", "invalid-mode",
 	}
 
@@ -252,7 +252,7 @@ workers: not-a-number
 	os.Args = []string{
 		"encoder",
 		"-input", "test.mp4",
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-config", configPath,
 	}
 
@@ -267,7 +267,7 @@ func TestLoadConfig_MissingConfigFile(t *testing.T) {
 	os.Args = []string{
 		"encoder",
 		"-input", "test.mp4",
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 		"-config", "/nonexistent/config.yaml",
 	}
 
@@ -290,7 +290,7 @@ func TestLoadConfig_NoConfigSpecified(t *testing.T) {
 	os.Args = []string{
 		"encoder",
 		"-input", inputPath,
-		"-output", "out.mp4",
+		"-output", "out.mkv",
 	}
 
 	cfg, err := LoadConfig()
@@ -302,7 +302,7 @@ func TestLoadConfig_NoConfigSpecified(t *testing.T) {
 	if cfg.Input != inputPath {
 		t.Errorf("Expected input '%s', got '%s'", inputPath, cfg.Input)
 	}
-	if cfg.Output != "out.mp4" {
-		t.Errorf("Expected output 'out.mp4', got '%s'", cfg.Output)
+	if cfg.Output != "out.mkv" {
+		t.Errorf("Expected output 'out.mkv', got '%s'", cfg.Output)
 	}
 }
@@ -1,26 +1,71 @@
 package config
 
 import (
+	"encoder/codec"
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
+	"strings"
 )
 
-// LoadConfig loads configuration with priority: CLI flags > Config file > Defaults
+// LoadConfig loads configuration with priority: CLI flags > Environment variables > Profile > Config file > Defaults
 func LoadConfig() (*Config, error) {
-	// 1. Start with defaults
-	cfg := DefaultConfig()
+	cfg, err := loadLayers(argValue("config"), argValue("profile"))
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Merge CLI flags (highest priority, overwrites everything)
+	if err := cfg.MergeFromFlags(); err != nil {
+		return nil, err
+	}
+
+	return cfg.complete()
+}
 
-	// 2. Check if -config flag was provided (quick parse to extract it)
-	configPath := ""
-	for i, arg := range os.Args {
-		if arg == "-config" && i+1 < len(os.Args) {
-			configPath = os.Args[i+1]
-			break
+// LoadJobConfig loads the configuration of one job of a batch, which has no
+// per-job flags: the config file, the profile, ENCODER_* variables and then
+// the job's overrides, keyed by YAML path (e.g. "video.crf"). An empty
+// configPath or profile is looked up like LoadConfig does without flags.
+func LoadJobConfig(configPath, profile string, overrides map[string]string) (*Config, error) {
+	cfg, err := loadLayers(configPath, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var errors []string
+	for key, value := range overrides {
+		if err := cfg.Set(key, value, SourceBatch); err != nil {
+			errors = append(errors, err.Error())
 		}
 	}
+	if len(errors) > 0 {
+		sort.Strings(errors)
+		return nil, fmt.Errorf("invalid overrides:\n  - %s", strings.Join(errors, "\n  - "))
+	}
 
-	// If no config flag, try to find config file in standard locations
+	cfg.ShowSources = false
+	return cfg.complete()
+}
+
+// LoadSettings loads the configuration of a daemon subcommand such as
+// "encoder watch": every layer below the CLI flags, without validation, since
+// there is no input or output yet. Each job is loaded with LoadJobConfig.
+func LoadSettings(configPath string) (*Config, error) {
+	return loadLayers(configPath, "")
+}
+
+// loadLayers loads every layer below the CLI flags: defaults, the config
+// file, the profile and ENCODER_* variables.
+func loadLayers(configPath, profile string) (*Config, error) {
+	// 1. Start with defaults
+	cfg := DefaultConfig()
+
+	// 2. Find the config file: -config flag, then ENCODER_CONFIG, then standard locations
+	if configPath == "" {
+		configPath = os.Getenv(EnvConfigPath)
+	}
 	if configPath == "" {
 		configPath = FindConfigFile()
 	}
@@ -35,20 +80,105 @@ func LoadConfig() (*Config, error) {
 		cfg = fileCfg
 	}
 
-	// 3. Merge CLI flags (highest priority, overwrites everything)
-	if err := cfg.MergeFromFlags(); err != nil {
+	// 3. Apply the selected profile (overwrites file and defaults)
+	if err := cfg.LoadProfiles(); err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = cfg.Profile
+	}
+	if profile != "" {
+		if err := cfg.ApplyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Merge ENCODER_* environment variables (overwrite profile, file and defaults)
+	if err := cfg.MergeFromEnv(); err != nil {
 		return nil, err
 	}
 
+	return cfg, nil
+}
+
+// complete fills in automatic values and validates the merged configuration.
+func (c *Config) complete() (*Config, error) {
 	// Auto-detect workers if set to 0
-	if cfg.Workers == 0 {
-		cfg.Workers = runtime.NumCPU()
+	if c.Workers == 0 {
+		c.Workers = runtime.NumCPU()
+	}
+
+	// A preset nobody set follows the codec (the built-in "8" only suits SVT-AV1)
+	c.applyCodecDefaults()
+
+	// Inspection only - show the layers even for an incomplete config
+	if c.ShowSources {
+		return c, nil
 	}
 
 	// Validate final configuration
-	if err := cfg.Validate(); err != nil {
+	if err := c.Validate(); err != nil {
 		return nil, err
 	}
 
-	return cfg, nil
+	return c, nil
+}
+
+// applyCodecDefaults replaces the default preset with the codec's default when
+// the codec was changed but the preset was left alone and does not fit it.
+func (c *Config) applyCodecDefaults() {
+	if c.Source("video.preset") != SourceDefault {
+		return
+	}
+	cd, ok := codec.Lookup(c.Video.Codec)
+	if !ok || cd.DefaultPreset == "" || cd.ValidatePreset(c.Video.Preset) == nil {
+		return
+	}
+	c.Video.Preset = cd.DefaultPreset
+}
+
+// LoadProfiles merges the drop-in profiles directory into the profiles of the
+// config file. Profiles defined in the config file win over drop-ins of the
+// same name.
+func (c *Config) LoadProfiles() error {
+	dir := c.ProfilesDir
+	if env := os.Getenv(EnvPrefix + "PROFILES_DIR"); env != "" {
+		dir = env
+	}
+	if dir == "" {
+		dir = DefaultProfilesDir()
+	}
+
+	profiles, err := LoadProfilesDir(dir)
+	if err != nil {
+		return err
+	}
+	for name, p := range c.Profiles {
+		profiles[name] = p
+	}
+	c.Profiles = profiles
+
+	return nil
+}
+
+// argValue returns the value of a command-line flag before the full flag set
+// is parsed. It accepts "-name value", "--name value" and "-name=value".
+func argValue(name string) string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if trimmed == arg {
+			continue // not a flag
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			return value
+		}
+	}
+	return ""
 }
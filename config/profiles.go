@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvProfile selects the profile to apply (equivalent to -profile).
+const EnvProfile = EnvPrefix + "PROFILE"
+
+// profileKeys are the top-level config keys a profile may set. Everything
+// else (input, output, workers, ...) belongs to the job or the machine.
+var profileKeys = map[string]bool{
+	"audio":          true,
+	"video":          true,
+	"mixing":         true,
+	"chunk_duration": true,
+	"pre_split":      true,
+}
+
+// Profile is a named bundle of audio/video/mixing/chunking settings.
+//
+//	profiles:
+//	  archive-av1:
+//	    description: "Archival AV1, slow preset"
+//	    video: { codec: libsvtav1, crf: 24, preset: "4" }
+//	  archive-av1-fast:
+//	    inherits: archive-av1
+//	    video: { preset: "8" }
+//
+// Only the keys present in the profile are applied, so a profile overlays
+// the values of the profile it inherits from and of the config file.
+type Profile struct {
+	Inherits    string // Name of the base profile (optional)
+	Description string // Free-form description shown by "encoder profiles"
+	Origin      string // File the profile was loaded from
+
+	settings yaml.Node // Mapping node holding the profile settings
+}
+
+// UnmarshalYAML keeps the raw settings so they can be overlaid later.
+func (p *Profile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: profile must be a mapping", node.Line)
+	}
+
+	settings := yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "inherits":
+			p.Inherits = value.Value
+		case "description":
+			p.Description = value.Value
+		default:
+			if !profileKeys[key.Value] {
+				return fmt.Errorf("line %d: %q cannot be set in a profile (allowed: %s)",
+					key.Line, key.Value, strings.Join(sortedKeys(profileKeys), ", "))
+			}
+			settings.Content = append(settings.Content, key, value)
+		}
+	}
+	p.settings = settings
+
+	return nil
+}
+
+// MarshalYAML writes the profile back in the form it was read.
+func (p *Profile) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if p.Description != "" {
+		node.Content = append(node.Content, scalarNode("description"), scalarNode(p.Description))
+	}
+	if p.Inherits != "" {
+		node.Content = append(node.Content, scalarNode("inherits"), scalarNode(p.Inherits))
+	}
+	node.Content = append(node.Content, p.settings.Content...)
+	return node, nil
+}
+
+// Keys returns the dotted config keys the profile sets itself (not inherited).
+func (p *Profile) Keys() []string {
+	var raw map[string]interface{}
+	if err := p.settings.Decode(&raw); err != nil {
+		return nil
+	}
+	keys := flattenKeys(raw, "")
+	sort.Strings(keys)
+	return keys
+}
+
+// DefaultProfilesDir returns the drop-in directory searched for shared profiles.
+func DefaultProfilesDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".encoder", "profiles.d")
+}
+
+// LoadProfilesDir loads every *.yaml/*.yml file in dir. Each file maps profile
+// names to profiles, like the profiles: section of the config file. Files are
+// read in lexical order; a later definition replaces an earlier one.
+// A missing directory is not an error.
+func LoadProfilesDir(dir string) (map[string]*Profile, error) {
+	profiles := make(map[string]*Profile)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile file: %w", err)
+		}
+
+		var fileProfiles map[string]*Profile
+		if err := yaml.Unmarshal(data, &fileProfiles); err != nil {
+			return nil, fmt.Errorf("failed to parse profile file %s: %w", path, err)
+		}
+		for name, p := range fileProfiles {
+			if p == nil {
+				p = &Profile{}
+			}
+			p.Origin = path
+			profiles[name] = p
+		}
+	}
+
+	return profiles, nil
+}
+
+// ApplyProfile overlays the named profile (and the profiles it inherits from)
+// onto the config and records the keys it set as SourceProfile.
+func (c *Config) ApplyProfile(name string) error {
+	chain, err := c.profileChain(name)
+	if err != nil {
+		return err
+	}
+
+	// Apply base profiles first so children override them
+	for i := len(chain) - 1; i >= 0; i-- {
+		p := chain[i]
+		if len(p.settings.Content) == 0 {
+			continue
+		}
+		if err := p.settings.Decode(c); err != nil {
+			return fmt.Errorf("failed to apply profile %q: %w", name, err)
+		}
+		for _, key := range p.Keys() {
+			c.setSource(key, SourceProfile)
+		}
+	}
+
+	c.Profile = name
+	return nil
+}
+
+// profileChain returns the profile followed by its ancestors.
+func (c *Config) profileChain(name string) ([]*Profile, error) {
+	var chain []*Profile
+	seen := make(map[string]bool)
+
+	for current := name; current != ""; {
+		if seen[current] {
+			return nil, fmt.Errorf("profile %q has an inheritance cycle via %q", name, current)
+		}
+		seen[current] = true
+
+		p, ok := c.Profiles[current]
+		if !ok {
+			if current == name {
+				return nil, fmt.Errorf("unknown profile %q (available: %s)", name, c.profileNames())
+			}
+			return nil, fmt.Errorf("profile %q inherits from unknown profile %q", name, current)
+		}
+		chain = append(chain, p)
+		current = p.Inherits
+	}
+
+	return chain, nil
+}
+
+// ProfileNames returns the names of all known profiles in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) profileNames() string {
+	names := c.ProfileNames()
+	if len(names) == 0 {
+		return "none defined"
+	}
+	return strings.Join(names, ", ")
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesYAML = `video:
+  codec: libx265
+  crf: 26
+profiles:
+  archive-av1:
+    description: Archival AV1
+    chunk_duration: 120
+    video:
+      codec: libsvtav1
+      crf: 24
+      preset: "4"
+    audio:
+      bitrate: 160k
+  archive-av1-fast:
+    inherits: archive-av1
+    video:
+      preset: "8"
+`
+
+func TestApplyProfile_OverlaysOnlyListedKeys(t *testing.T) {
+	cfg := loadProfilesConfig(t, profilesYAML)
+
+	if err := cfg.ApplyProfile("archive-av1"); err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+
+	if cfg.Video.Codec != "libsvtav1" || cfg.Video.CRF != 24 || cfg.Video.Preset != "4" {
+		t.Errorf("Expected profile video settings, got %+v", cfg.Video)
+	}
+	if cfg.ChunkDuration != 120 {
+		t.Errorf("Expected chunk duration 120, got %d", cfg.ChunkDuration)
+	}
+	if cfg.Audio.Bitrate != "160k" {
+		t.Errorf("Expected audio bitrate 160k, got %s", cfg.Audio.Bitrate)
+	}
+
+	// Keys the profile does not mention keep their previous values
+	if cfg.Audio.Codec != "libopus" || cfg.Audio.SampleRate != 48000 {
+		t.Errorf("Expected untouched audio defaults, got %+v", cfg.Audio)
+	}
+
+	if cfg.Source("video.crf") != SourceProfile {
+		t.Errorf("Expected video.crf source profile, got %s", cfg.Source("video.crf"))
+	}
+	if cfg.Profile != "archive-av1" {
+		t.Errorf("Expected selected profile to be recorded, got %q", cfg.Profile)
+	}
+}
+
+func TestApplyProfile_Inherits(t *testing.T) {
+	cfg := loadProfilesConfig(t, profilesYAML)
+
+	if err := cfg.ApplyProfile("archive-av1-fast"); err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+
+	if cfg.Video.Preset != "8" {
+		t.Errorf("Expected child preset 8, got %s", cfg.Video.Preset)
+	}
+	if cfg.Video.Codec != "libsvtav1" || cfg.Video.CRF != 24 {
+		t.Errorf("Expected inherited codec/CRF, got %s/%d", cfg.Video.Codec, cfg.Video.CRF)
+	}
+}
+
+func TestApplyProfile_Errors(t *testing.T) {
+	cfg := loadProfilesConfig(t, `profiles:
+  a:
+    inherits: b
+  b:
+    inherits: a
+  c:
+    inherits: missing
+`)
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"nope", "unknown profile"},
+		{"a", "cycle"},
+		{"c", "unknown profile \"missing\""},
+	}
+
+	for _, tt := range tests {
+		err := cfg.ApplyProfile(tt.profile)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ApplyProfile(%q) = %v, want error containing %q", tt.profile, err, tt.want)
+		}
+	}
+}
+
+func TestProfile_RejectsJobKeys(t *testing.T) {
+	path := writeConfigFile(t, `profiles:
+  bad:
+    input: movie.mkv
+`)
+
+	_, err := LoadConfigFile(path)
+	if err == nil || !strings.Contains(err.Error(), "cannot be set in a profile") {
+		t.Errorf("Expected error for input in profile, got %v", err)
+	}
+}
+
+func TestLoadProfilesDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "10-team.yaml"), []byte(`web-h264:
+  video:
+    codec: libx264
+    crf: 23
+shared:
+  video:
+    crf: 30
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "20-override.yml"), []byte(`shared:
+  video:
+    crf: 18
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not a profile"), 0644)
+
+	profiles, err := LoadProfilesDir(dir)
+	if err != nil {
+		t.Fatalf("LoadProfilesDir failed: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("Expected 2 profiles, got %d", len(profiles))
+	}
+	if !strings.HasSuffix(profiles["shared"].Origin, "20-override.yml") {
+		t.Errorf("Expected later file to win, got origin %s", profiles["shared"].Origin)
+	}
+
+	missing, err := LoadProfilesDir(filepath.Join(dir, "missing"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected empty result for missing dir, got %v, %v", missing, err)
+	}
+}
+
+func TestLoadConfig_ProfilePrecedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "test.mp4")
+	os.WriteFile(inputPath, []byte("test"), 0644)
+
+	profilesDir := filepath.Join(tmpDir, "profiles.d")
+	os.MkdirAll(profilesDir, 0755)
+	os.WriteFile(filepath.Join(profilesDir, "team.yaml"), []byte(`web-h264:
+  video:
+    codec: libx264
+    crf: 23
+    preset: fast
+  audio:
+    codec: aac
+`), 0644)
+
+	configPath := filepath.Join(tmpDir, "encoder.yaml")
+	os.WriteFile(configPath, []byte(`profiles_dir: `+profilesDir+`
+video:
+  codec: libx265
+  crf: 28
+  preset: slow
+`), 0644)
+
+	t.Setenv("ENCODER_AUDIO_BITRATE", "192k")
+	os.Args = []string{
+		"encoder",
+		"-config", configPath,
+		"-profile=web-h264",
+		"-video-crf", "20",
+		"-input", inputPath,
+		"-output", "out.mp4",
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Video.Codec != "libx264" || cfg.Source("video.codec") != SourceProfile {
+		t.Errorf("Expected codec from profile, got %s from %s", cfg.Video.Codec, cfg.Source("video.codec"))
+	}
+	if cfg.Video.CRF != 20 || cfg.Source("video.crf") != SourceFlag {
+		t.Errorf("Expected flag to override profile CRF, got %d from %s", cfg.Video.CRF, cfg.Source("video.crf"))
+	}
+	if cfg.Audio.Bitrate != "192k" || cfg.Source("audio.bitrate") != SourceEnv {
+		t.Errorf("Expected env bitrate, got %s from %s", cfg.Audio.Bitrate, cfg.Source("audio.bitrate"))
+	}
+	if cfg.Profile != "web-h264" {
+		t.Errorf("Expected profile web-h264, got %q", cfg.Profile)
+	}
+}
+
+// writeConfigFile writes content to a config file in a temp directory.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "encoder.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// loadProfilesConfig writes content to a temp config file and loads it.
+func loadProfilesConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	cfg, err := LoadConfigFile(writeConfigFile(t, content))
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	return cfg
+}
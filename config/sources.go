@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source identifies the configuration layer an effective value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default" // Built-in default
+	SourceFile    Source = "file"    // Config file
+	SourceProfile Source = "profile" // Selected profile
+	SourceEnv     Source = "env"     // ENCODER_* environment variable
+	SourceFlag    Source = "flag"    // Command-line flag
+	SourceBatch   Source = "batch"   // Batch manifest item override
+)
+
+// setSource records which layer last set the value at key.
+func (c *Config) setSource(key string, src Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = src
+}
+
+// Source returns the layer the effective value at key (e.g. "video.crf") came from.
+func (c *Config) Source(key string) Source {
+	if src, ok := c.sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// Setting is one effective configuration value with its origin.
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+}
+
+// Settings returns every effective value together with the layer it came from.
+func (c *Config) Settings() []Setting {
+	fields := c.fields()
+	settings := make([]Setting, 0, len(fields))
+	for _, field := range fields {
+		settings = append(settings, Setting{
+			Key:    field.Key,
+			Env:    field.Env,
+			Value:  fmt.Sprintf("%v", field.Value.Interface()),
+			Source: c.Source(field.Key),
+		})
+	}
+	return settings
+}
+
+// PrintSources prints each effective value and the layer it came from.
+func (c *Config) PrintSources() {
+	settings := c.Settings()
+
+	keyWidth := 0
+	valueWidth := 0
+	for _, s := range settings {
+		if len(s.Key) > keyWidth {
+			keyWidth = len(s.Key)
+		}
+		if len(s.Value) > valueWidth {
+			valueWidth = len(s.Value)
+		}
+	}
+
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	fmt.Println("              Configuration Value Sources                 ")
+	fmt.Println("═══════════════════════════════════════════════════════════")
+	for _, s := range settings {
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+		fmt.Printf("  %-*s  %-*s  %s\n", keyWidth, s.Key, valueWidth, value, s.Source)
+	}
+	fmt.Println("───────────────────────────────────────────────────────────")
+	fmt.Printf("  Priority: %s\n", strings.Join([]string{"flag", "env", "profile", "file", "default"}, " > "))
+	fmt.Println("═══════════════════════════════════════════════════════════")
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"encoder/codec"
+	"encoder/crop"
+	"encoder/quality/metric"
+	"encoder/units"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -30,6 +36,20 @@ func (c *Config) Validate() error {
 			c.Mode, strings.Join(ModeValues(), ", ")))
 	}
 
+	// Validate logging
+	if c.LogLevel != "" && !containsValue(LogLevelValues(), c.LogLevel) {
+		errors = append(errors, fmt.Sprintf("invalid log_level '%s', must be one of: %s",
+			c.LogLevel, strings.Join(LogLevelValues(), ", ")))
+	}
+	if c.LogFormat != "" && !containsValue(LogFormatValues(), c.LogFormat) {
+		errors = append(errors, fmt.Sprintf("invalid log_format '%s', must be one of: %s",
+			c.LogFormat, strings.Join(LogFormatValues(), ", ")))
+	}
+	if c.OutputFormat != "" && !containsValue(OutputFormatValues(), c.OutputFormat) {
+		errors = append(errors, fmt.Sprintf("invalid output_format '%s', must be one of: %s",
+			c.OutputFormat, strings.Join(OutputFormatValues(), ", ")))
+	}
+
 	// Validate chunk duration
 	if c.ChunkDuration <= 0 {
 		errors = append(errors, "chunk duration must be positive")
@@ -40,6 +60,13 @@ func (c *Config) Validate() error {
 		errors = append(errors, "workers cannot be negative (use 0 for auto-detect)")
 	}
 
+	// Validate the metrics endpoint address
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid metrics_addr '%s': %v", c.MetricsAddr, err))
+		}
+	}
+
 	// Validate audio config
 	if err := c.Audio.Validate(); err != nil {
 		errors = append(errors, fmt.Sprintf("audio config: %v", err))
@@ -50,6 +77,52 @@ func (c *Config) Validate() error {
 		errors = append(errors, fmt.Sprintf("video config: %v", err))
 	}
 
+	// Validate metrics stage
+	if c.Metrics.Enabled {
+		names := c.Metrics.MetricNames()
+		if len(names) == 0 {
+			errors = append(errors, "metrics config: compute must list at least one metric")
+		}
+		for _, name := range names {
+			if !containsValue(QualityMetricValues(), name) {
+				errors = append(errors, fmt.Sprintf("metrics config: unknown metric %q, must be one of: %s", name, strings.Join(QualityMetricValues(), ", ")))
+			}
+		}
+	}
+
+	// Validate packaging stage
+	if err := c.Packaging.Validate(c.Video.Codecs(), c.Audio.Codec); err != nil {
+		errors = append(errors, fmt.Sprintf("packaging config: %v", err))
+	}
+
+	// Validate thumbnails
+	if err := c.Thumbnails.Validate(); err != nil {
+		errors = append(errors, fmt.Sprintf("thumbnails config: %v", err))
+	}
+
+	// Validate watch folder settings
+	if err := c.Watch.Validate(c.Profiles); err != nil {
+		errors = append(errors, fmt.Sprintf("watch config: %v", err))
+	}
+
+	// Validate job API settings
+	if err := c.Serve.Validate(); err != nil {
+		errors = append(errors, fmt.Sprintf("serve config: %v", err))
+	}
+
+	// Both streams end up in the output container, which the extension picks
+	if c.Output != "" && !containsValue(codec.KnownContainers(), codec.ContainerOf(c.Output)) {
+		errors = append(errors, fmt.Sprintf("output: unknown container %q, use one of: .%s", filepath.Ext(c.Output), strings.Join(codec.KnownContainers(), ", .")))
+	} else if c.Output != "" {
+		for _, name := range append(c.Video.Codecs(), c.Audio.Codec) {
+			if cd, ok := codec.Lookup(name); ok {
+				if err := cd.ValidateContainer(c.Output); err != nil {
+					errors = append(errors, fmt.Sprintf("output: %v", err))
+				}
+			}
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(errors, "\n  - "))
 	}
@@ -57,6 +130,160 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// Validate checks the packaging formats and segment settings. MPEG-TS
+// segments only carry the codecs HLS allows in TS.
+func (pc *PackagingConfig) Validate(videoCodecs []string, audioCodec string) error {
+	var errors []string
+	for _, name := range pc.FormatNames() {
+		if !containsValue(PackagingFormatValues(), name) {
+			errors = append(errors, fmt.Sprintf("unknown format %q, must be one of: %s", name, strings.Join(PackagingFormatValues(), ", ")))
+		}
+	}
+	if pc.SegmentDuration < 0 {
+		errors = append(errors, "segment duration cannot be negative")
+	}
+	if pc.HLSSegmentType != "" && !containsValue(HLSSegmentTypeValues(), pc.HLSSegmentType) {
+		errors = append(errors, fmt.Sprintf("invalid hls_segment_type '%s', must be one of: %s", pc.HLSSegmentType, strings.Join(HLSSegmentTypeValues(), ", ")))
+	}
+
+	if pc.HLSSegmentType == "ts" && containsValue(pc.FormatNames(), "hls") {
+		type check struct {
+			name     string
+			families []string
+		}
+		var checks []check
+		for _, name := range videoCodecs {
+			checks = append(checks, check{name, []string{"h264", "hevc"}})
+		}
+		checks = append(checks, check{audioCodec, []string{"aac", "mp3", "ac3", "eac3"}})
+		for _, check := range checks {
+			if c, ok := codec.Lookup(check.name); ok && !containsValue(check.families, c.Family) {
+				errors = append(errors, fmt.Sprintf("%s cannot be packaged in MPEG-TS segments, use hls_segment_type fmp4", c.Name))
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// Validate checks the thumbnail outputs and the settings they use. Settings
+// of outputs that are not selected are not checked.
+func (tc *ThumbnailsConfig) Validate() error {
+	var errors []string
+	names := tc.OutputNames()
+	for _, name := range names {
+		if !containsValue(ThumbnailOutputValues(), name) {
+			errors = append(errors, fmt.Sprintf("unknown output %q, must be one of: %s", name, strings.Join(ThumbnailOutputValues(), ", ")))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if containsValue(names, "poster") || containsValue(names, "sprite") {
+		if !containsValue(ThumbnailSelectionValues(), tc.Selection) {
+			errors = append(errors, fmt.Sprintf("invalid selection '%s', must be one of: %s", tc.Selection, strings.Join(ThumbnailSelectionValues(), ", ")))
+		}
+		if tc.Selection == "interval" && tc.Interval <= 0 {
+			errors = append(errors, "interval must be positive")
+		}
+		if tc.Selection == "scene" && (tc.SceneThreshold <= 0 || tc.SceneThreshold > 1) {
+			errors = append(errors, "scene_threshold must be between 0 and 1")
+		}
+	}
+	if containsValue(names, "poster") && (tc.Posters <= 0 || tc.PosterWidth <= 0) {
+		errors = append(errors, "posters and poster_width must be positive")
+	}
+	if containsValue(names, "sprite") {
+		if tc.SpriteTile == "" || !isValidResolution(tc.SpriteTile) {
+			errors = append(errors, fmt.Sprintf("invalid sprite_tile '%s', expected WIDTHxHEIGHT", tc.SpriteTile))
+		}
+		if tc.SpriteColumns <= 0 || tc.SpriteRows <= 0 {
+			errors = append(errors, "sprite_columns and sprite_rows must be positive")
+		}
+	}
+	if containsValue(names, "preview") {
+		if tc.PreviewLength <= 0 {
+			errors = append(errors, "preview_length must be positive")
+		}
+		if !containsValue(PreviewFormatValues(), tc.PreviewFormat) {
+			errors = append(errors, fmt.Sprintf("invalid preview_format '%s', must be one of: %s", tc.PreviewFormat, strings.Join(PreviewFormatValues(), ", ")))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// Validate checks the watch folder timings and rules. A rule's profile must
+// be one of profiles.
+func (wc *WatchConfig) Validate(profiles map[string]*Profile) error {
+	var errors []string
+	if wc.PollInterval <= 0 {
+		errors = append(errors, "poll_interval must be positive")
+	}
+	if wc.SettleTime < 0 {
+		errors = append(errors, "settle_time cannot be negative")
+	}
+	// {index} is a position in a batch; dropped files have none
+	if strings.Contains(wc.OutputTemplate, "{index}") {
+		errors = append(errors, "output_template cannot use {index} (use {dir}, {name}, {ext} or {profile})")
+	}
+
+	for i, r := range wc.Rules {
+		prefix := fmt.Sprintf("rule %d", i+1)
+		if r.Match != "" {
+			if _, err := filepath.Match(r.Match, ""); err != nil {
+				errors = append(errors, fmt.Sprintf("%s: invalid match pattern %q", prefix, r.Match))
+			}
+		}
+		if r.MinHeight < 0 || r.MaxHeight < 0 {
+			errors = append(errors, fmt.Sprintf("%s: min_height and max_height cannot be negative", prefix))
+		} else if r.MaxHeight > 0 && r.MinHeight > r.MaxHeight {
+			errors = append(errors, fmt.Sprintf("%s: min_height is above max_height", prefix))
+		}
+		if r.HDR != "" && !containsValue(WatchHDRValues(), r.HDR) {
+			errors = append(errors, fmt.Sprintf("%s: hdr must be one of: %s", prefix, strings.Join(WatchHDRValues(), ", ")))
+		}
+		if r.AudioOnly && (r.VideoCodec != "" || r.MinHeight > 0 || r.MaxHeight > 0 || r.HDR != "") {
+			errors = append(errors, fmt.Sprintf("%s: audio_only cannot be combined with video conditions", prefix))
+		}
+		if r.Profile != "" && profiles[r.Profile] == nil {
+			errors = append(errors, fmt.Sprintf("%s: unknown profile %q", prefix, r.Profile))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// Validate checks the job API settings. The server only listens on loopback
+// addresses since its API has no authentication.
+func (sc *ServeConfig) Validate() error {
+	var errors []string
+	host, _, err := net.SplitHostPort(sc.Addr)
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("invalid addr '%s', expected HOST:PORT", sc.Addr))
+	} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		errors = append(errors, fmt.Sprintf("addr '%s' must be a loopback address such as 127.0.0.1:8080", sc.Addr))
+	}
+	if sc.Concurrency < 1 {
+		errors = append(errors, "concurrency must be at least 1")
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
 // Validate checks if audio configuration is valid
 func (ac *AudioConfig) Validate() error {
 	var errors []string
@@ -69,14 +296,28 @@ func (ac *AudioConfig) Validate() error {
 		errors = append(errors, "bitrate is required")
 	}
 
+	cd, known := codec.Lookup(ac.Codec)
+	if known && cd.Kind != codec.KindAudio {
+		errors = append(errors, fmt.Sprintf("%s is not an audio encoder", ac.Codec))
+		known = false
+	}
+
 	if ac.SampleRate <= 0 {
 		errors = append(errors, "sample rate must be positive")
+	} else if known {
+		if err := cd.ValidateSampleRate(ac.SampleRate); err != nil {
+			errors = append(errors, err.Error())
+		}
 	}
 
 	if ac.Channels <= 0 {
 		errors = append(errors, "channels must be positive")
 	} else if ac.Channels > 8 {
 		errors = append(errors, "channels cannot exceed 8")
+	} else if known {
+		if err := cd.ValidateChannels(ac.Channels); err != nil {
+			errors = append(errors, err.Error())
+		}
 	}
 
 	if len(errors) > 0 {
@@ -94,13 +335,27 @@ func (vc *VideoConfig) Validate() error {
 		errors = append(errors, "codec is required")
 	}
 
-	// CRF validation (if using CRF mode)
-	if vc.CRF < 0 || vc.CRF > 51 {
-		errors = append(errors, "CRF must be between 0 and 51")
+	cd, known := codec.Lookup(vc.Codec)
+	if known && cd.Kind != codec.KindVideo {
+		errors = append(errors, fmt.Sprintf("%s is not a video encoder", vc.Codec))
+		known = false
+	}
+
+	// CRF validation (codec-specific range, 0-51 for unknown codecs)
+	if known && cd.QualityParam != "" {
+		if err := cd.ValidateQuality(vc.CRF); err != nil {
+			errors = append(errors, err.Error())
+		}
+	} else if vc.CRF < codec.DefaultQualityMin || vc.CRF > codec.DefaultQualityMax {
+		errors = append(errors, fmt.Sprintf("CRF must be between %d and %d", codec.DefaultQualityMin, codec.DefaultQualityMax))
 	}
 
 	if vc.Preset == "" {
 		errors = append(errors, "preset is required")
+	} else if known {
+		if err := cd.ValidatePreset(vc.Preset); err != nil {
+			errors = append(errors, err.Error())
+		}
 	}
 
 	// Frame rate validation
@@ -108,6 +363,109 @@ func (vc *VideoConfig) Validate() error {
 		errors = append(errors, "frame rate cannot be negative (use 0 for original)")
 	}
 
+	// HDR handling (empty = preserve, empty curve = hable)
+	if vc.HDR != "" && !containsValue(HDRModeValues(), vc.HDR) {
+		errors = append(errors, fmt.Sprintf("hdr must be one of: %s", strings.Join(HDRModeValues(), ", ")))
+	}
+	if vc.Tonemap != "" && !containsValue(TonemapValues(), vc.Tonemap) {
+		errors = append(errors, fmt.Sprintf("tonemap must be one of: %s", strings.Join(TonemapValues(), ", ")))
+	}
+
+	// Crop: off, auto or a manual W:H:X:Y rectangle (empty = off)
+	switch vc.Crop {
+	case "", "off", "auto":
+	default:
+		if _, err := crop.Parse(vc.Crop); err != nil {
+			errors = append(errors, "crop must be off, auto or W:H:X:Y")
+		}
+	}
+	if vc.Crop == "auto" && !containsInt(CropRoundValues(), vc.CropRound) {
+		errors = append(errors, "crop_round must be one of: 2, 4, 8, 16")
+	}
+
+	// Deinterlacing (empty = auto, empty filter = bwdif)
+	if vc.Deinterlace != "" && !containsValue(DeinterlaceValues(), vc.Deinterlace) {
+		errors = append(errors, fmt.Sprintf("deinterlace must be one of: %s", strings.Join(DeinterlaceValues(), ", ")))
+	}
+	if vc.Deinterlacer != "" && !containsValue(DeinterlacerValues(), vc.Deinterlacer) {
+		errors = append(errors, fmt.Sprintf("deinterlacer must be one of: %s", strings.Join(DeinterlacerValues(), ", ")))
+	}
+
+	// Target quality (0 = off, empty metric = vmaf)
+	if vc.QualityMetric != "" && !containsValue(QualityMetricValues(), vc.QualityMetric) {
+		errors = append(errors, fmt.Sprintf("quality_metric must be one of: %s", strings.Join(QualityMetricValues(), ", ")))
+	}
+	if vc.TargetQuality < 0 {
+		errors = append(errors, "target_quality cannot be negative (use 0 for fixed CRF)")
+	} else if vc.TargetQuality > 0 {
+		m := metric.Metric(vc.QualityMetric)
+		if m == "" {
+			m = metric.VMAF
+		}
+		if !m.ValidTarget(vc.TargetQuality) {
+			switch m {
+			case metric.SSIM:
+				errors = append(errors, "target_quality must be between 0 and 1 for ssim")
+			case metric.VMAF, metric.PSNR:
+				errors = append(errors, fmt.Sprintf("target_quality must be between 0 and 100 for %s", m))
+			}
+		}
+		if vc.Bitrate != "" {
+			errors = append(errors, "target_quality cannot be combined with bitrate")
+		}
+		if known && cd.QualityParam == "" {
+			errors = append(errors, fmt.Sprintf("target_quality needs a constant-quality encoder, %s has none", vc.Codec))
+		}
+		if vc.QualityProbes != 0 && (vc.QualityProbes < 2 || vc.QualityProbes > 7) {
+			errors = append(errors, "quality_probes must be between 2 and 7")
+		}
+	}
+
+	// Rate control (0 passes = 1)
+	for _, opt := range []struct{ name, value string }{
+		{"bitrate", vc.Bitrate},
+		{"max_rate", vc.MaxRate},
+		{"buf_size", vc.BufSize},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		if _, err := units.ParseBitrate(opt.value); err != nil {
+			errors = append(errors, fmt.Sprintf("%s must be a bitrate such as 5M or 1500k, got %q", opt.name, opt.value))
+		}
+	}
+	if vc.BufSize != "" && vc.MaxRate == "" {
+		errors = append(errors, "buf_size needs max_rate")
+	}
+	if vc.TargetSize != "" {
+		if _, err := units.ParseSize(vc.TargetSize); err != nil {
+			errors = append(errors, fmt.Sprintf("target_size must be a size such as 700M or 4.7G, got %q", vc.TargetSize))
+		}
+		if vc.Bitrate != "" {
+			errors = append(errors, "target_size cannot be combined with bitrate")
+		}
+		if vc.TargetQuality > 0 {
+			errors = append(errors, "target_size cannot be combined with target_quality")
+		}
+	}
+	switch vc.Passes {
+	case 0, 1:
+	case 2:
+		if vc.Bitrate == "" && vc.TargetSize == "" {
+			errors = append(errors, "passes: 2 needs bitrate or target_size")
+		}
+		if known && !cd.TwoPass {
+			errors = append(errors, fmt.Sprintf("%s does not support two-pass encoding (use max_rate for capped CRF)", vc.Codec))
+		}
+	default:
+		errors = append(errors, "passes must be 1 or 2")
+	}
+
+	// Encoder tuning
+	if err := vc.EncoderParams.Validate(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	// Resolution validation (if specified)
 	if vc.Resolution != "" {
 		if !isValidResolution(vc.Resolution) {
@@ -115,6 +473,31 @@ func (vc *VideoConfig) Validate() error {
 		}
 	}
 
+	// ABR ladder: each rendition must be valid on its own
+	seen := make(map[string]bool)
+	for i, r := range vc.Renditions {
+		switch {
+		case r.Name == "":
+			errors = append(errors, fmt.Sprintf("rendition %d: name is required", i+1))
+			continue
+		case !isValidRenditionName(r.Name):
+			errors = append(errors, fmt.Sprintf("rendition %q: name may only contain letters, digits, '-', '_' and '.'", r.Name))
+		case seen[r.Name]:
+			errors = append(errors, fmt.Sprintf("rendition %q is defined more than once", r.Name))
+		}
+		seen[r.Name] = true
+		if r.CRF != 0 && r.Bitrate != "" {
+			errors = append(errors, fmt.Sprintf("rendition %q: crf cannot be combined with bitrate", r.Name))
+		}
+		rv := vc.Rendition(r)
+		if err := rv.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("rendition %q: %v", r.Name, err))
+		}
+	}
+	if len(vc.Renditions) > 0 && vc.TargetSize != "" {
+		errors = append(errors, "target_size cannot be combined with renditions")
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("%s", strings.Join(errors, ", "))
 	}
@@ -122,6 +505,36 @@ func (vc *VideoConfig) Validate() error {
 	return nil
 }
 
+// containsValue reports whether value is in list
+func containsValue(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt reports whether value is in list
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidRenditionName reports whether name is safe in file and task names
+func isValidRenditionName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return name != "." && name != ".."
+}
+
 // isValidResolution checks if resolution string is valid (e.g., "1920x1080")
 func isValidResolution(res string) bool {
 	if res == "" {
@@ -19,10 +19,42 @@ func LoadConfigFile(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			p = &Profile{} // "name:" with no settings
+			cfg.Profiles[name] = p
+		}
+		p.Origin = path
+	}
+
+	// Record which keys the file actually sets
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err == nil {
+		for _, key := range flattenKeys(raw, "") {
+			cfg.setSource(key, SourceFile)
+		}
+	}
 
 	return cfg, nil
 }
 
+// flattenKeys returns the dotted paths of all leaf keys in a decoded YAML map.
+func flattenKeys(m map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			keys = append(keys, flattenKeys(nested, key)...)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // FindConfigFile searches for config file in standard locations
 // Returns empty string if not found (non-fatal)
 func FindConfigFile() string {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoder/chunker"
+	"encoder/codec"
+	"encoder/command/mixing"
+	"encoder/command/segment"
+	"encoder/config"
+	"encoder/ffprobe"
+	"encoder/logging"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// videoExt returns the extension of the intermediate video files (chunks,
+// probes and the concatenated video) of the configured encoder.
+func videoExt(cfg *config.Config) string {
+	return codec.IntermediateContainer(cfg.Video.Codec)
+}
+
+// audioExt returns the extension of the encoded audio track: the audio
+// encoder's own container.
+func audioExt(cfg *config.Config) string {
+	return codec.IntermediateContainer(cfg.Audio.Codec)
+}
+
+// newSegmentBuilder creates the splitter of the source into chapter
+// segments. Segments keep the source's container, which holds every stream
+// the source has; other sources are split into Matroska.
+func newSegmentBuilder(cfg *config.Config, dir string, chapters []chunker.ChapterInfo) *segment.SegmentBuilder {
+	return segment.NewSegmentBuilder(cfg.Input, dir, chapters).
+		SetContainer(codec.ContainerOf(cfg.Input))
+}
+
+// outputSubtitles returns the subtitle streams of the source (indexes among
+// its subtitle streams) to carry into output, and their codec. Matroska
+// takes every subtitle as is; MP4 and WebM only take text subtitles, which
+// are converted; other containers get none.
+func outputSubtitles(cfg *config.Config, output string, probeResult *ffprobe.ProbeResult, log *slog.Logger) ([]int, string) {
+	container := codec.LookupContainer(output)
+	if !cfg.Mixing.Subtitles || container.SubtitleCodec == "" || probeResult == nil {
+		return nil, ""
+	}
+
+	var streams []int
+	index := 0
+	for _, stream := range probeResult.Streams {
+		if !stream.IsSubtitle() {
+			continue
+		}
+		if container.SubtitleCodec == "copy" || slices.Contains(textSubtitleCodecs, stream.CodecName) {
+			streams = append(streams, index)
+		} else {
+			log.Info("skipping subtitle stream: container only takes text subtitles", "codec", stream.CodecName, "stream", index, "container", container.Ext)
+		}
+		index++
+	}
+	return streams, container.SubtitleCodec
+}
+
+// newMuxBuilder creates the mux of the encoded video and audio into output,
+// either of which may be empty. The source's subtitles are added in a codec
+// the container takes, and MP4-style containers get a fast-start index.
+func newMuxBuilder(cfg *config.Config, videoPath, audioPath, output string, probeResult *ffprobe.ProbeResult, log *slog.Logger) *mixing.MixingBuilder {
+	builder := mixing.NewMixingBuilder(videoPath, output).
+		SetCopyAudio(true).
+		SetCopyVideo(true).
+		SetFastStart(codec.LookupContainer(output).FastStart)
+	if audioPath != "" {
+		builder.AddAudioTrack(audioPath)
+	}
+	if streams, subtitleCodec := outputSubtitles(cfg, output, probeResult, log); len(streams) > 0 {
+		builder.AddSubtitleTrack(cfg.Input).
+			SetSubtitleStreams(streams...).
+			SetSubtitleCodec(subtitleCodec)
+	}
+	return builder
+}
+
+// muxOutput writes the encoded video and audio to output (see
+// newMuxBuilder).
+func muxOutput(cfg *config.Config, videoPath, audioPath, output string, probeResult *ffprobe.ProbeResult, log *slog.Logger) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	builder := newMuxBuilder(cfg, videoPath, audioPath, output, probeResult, log)
+	if cmd, err := builder.DryRun(); err == nil {
+		log.Debug("ffmpeg command", logging.KeyTaskType, builder.GetTaskType(), "args", cmd)
+	}
+	return builder.Run()
+}
@@ -0,0 +1,52 @@
+// Package crop describes crop rectangles: the manual W:H:X:Y form accepted by
+// the configuration and the rectangles crop detection produces.
+//
+// It only uses the standard library, so config can validate a manual crop
+// without importing the analysis package.
+package crop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rect is a crop rectangle in source pixels.
+type Rect struct {
+	Width  int
+	Height int
+	X      int
+	Y      int
+}
+
+// Filter returns the ffmpeg crop filter for the rectangle.
+func (r Rect) Filter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", r.Width, r.Height, r.X, r.Y)
+}
+
+// String formats the rectangle as "WxH+X+Y".
+func (r Rect) String() string {
+	return fmt.Sprintf("%dx%d+%d+%d", r.Width, r.Height, r.X, r.Y)
+}
+
+// Parse parses a manual crop in cropdetect's "W:H:X:Y" form.
+func Parse(s string) (Rect, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "crop="), ":")
+	if len(parts) != 4 {
+		return Rect{}, fmt.Errorf("invalid crop %q, expected W:H:X:Y", s)
+	}
+
+	values := make([]int, 4)
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 {
+			return Rect{}, fmt.Errorf("invalid crop %q, expected W:H:X:Y", s)
+		}
+		values[i] = n
+	}
+	if values[0] == 0 || values[1] == 0 {
+		return Rect{}, fmt.Errorf("invalid crop %q, width and height must be positive", s)
+	}
+
+	return Rect{Width: values[0], Height: values[1], X: values[2], Y: values[3]}, nil
+}
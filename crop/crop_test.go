@@ -0,0 +1,38 @@
+package crop
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rect
+		wantErr bool
+	}{
+		{"1920:800:0:140", Rect{1920, 800, 0, 140}, false},
+		{"crop=1440:1080:240:0", Rect{1440, 1080, 240, 0}, false},
+		{"1920x800", Rect{}, true},
+		{"0:800:0:0", Rect{}, true},
+		{"1920:-8:0:0", Rect{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRect_Filter(t *testing.T) {
+	r := Rect{Width: 1920, Height: 800, X: 0, Y: 140}
+	if got := r.Filter(); got != "crop=1920:800:0:140" {
+		t.Errorf("Filter() = %q", got)
+	}
+	if got := r.String(); got != "1920x800+0+140" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoder/models"
+	"encoder/orchestrator"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// eventWriter writes the events of -output-format json: one JSON object per
+// line, each with the event type in "event" and the time it was written in
+// "time". A run's last event is always "result". The types are documented
+// under OUTPUT FORMAT in the usage; fields are only ever added. All methods
+// are safe for concurrent use and on a nil writer (text output).
+type eventWriter struct {
+	mu         sync.Mutex
+	enc        *json.Encoder
+	start      time.Time
+	phase      string        // Phase in progress
+	phaseStart time.Time     // When phase started
+	phases     []phaseTiming // Finished phases, in order
+	summary    *jobSummary   // Result of a successful pipeline
+}
+
+// eventHeader starts every event.
+type eventHeader struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+}
+
+// phaseTiming is the run time of a finished phase.
+type phaseTiming struct {
+	Phase          string  `json:"phase"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// chunkPlan is one chunk of a "chunk_plan" event.
+type chunkPlan struct {
+	ChunkID  uint    `json:"chunk_id"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+}
+
+// dryRunCommand is one command of a "dry_run" event.
+type dryRunCommand struct {
+	Step    string `json:"step"` // audio, final_audio, video, mux, packaging, thumbnail
+	Name    string `json:"name,omitempty"`
+	Command string `json:"command,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newEventWriter returns a writer of events to w.
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+// write writes event, whose eventHeader is filled in with name. The caller
+// holds e.mu.
+func (e *eventWriter) write(name string, header *eventHeader, event interface{}) {
+	header.Event = name
+	header.Time = time.Now()
+	// Nothing to report an error to: stdout is the report
+	_ = e.enc.Encode(event)
+}
+
+// started reports the start of an encode.
+func (e *eventWriter) started(input, output, mode string) {
+	if e == nil {
+		return
+	}
+	event := struct {
+		eventHeader
+		Input  string `json:"input"`
+		Output string `json:"output"`
+		Mode   string `json:"mode"`
+	}{Input: input, Output: output, Mode: mode}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.write("start", &event.eventHeader, &event)
+}
+
+// endPhase reports the end of the phase in progress, if any. The caller
+// holds e.mu.
+func (e *eventWriter) endPhase() {
+	if e.phase == "" {
+		return
+	}
+	timing := phaseTiming{Phase: e.phase, ElapsedSeconds: time.Since(e.phaseStart).Seconds()}
+	e.phases = append(e.phases, timing)
+	e.phase = ""
+
+	event := struct {
+		eventHeader
+		phaseTiming
+	}{phaseTiming: timing}
+	e.write("phase_end", &event.eventHeader, &event)
+}
+
+// monitor returns the pipelineMonitor reporting a pipeline run as events, or
+// nil for text output.
+func (e *eventWriter) monitor() *pipelineMonitor {
+	if e == nil {
+		return nil
+	}
+	return &pipelineMonitor{
+		OnPhase: func(phase string) {
+			event := struct {
+				eventHeader
+				Phase string `json:"phase"`
+			}{Phase: phase}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.endPhase()
+			e.phase = phase
+			e.phaseStart = time.Now()
+			e.write("phase_start", &event.eventHeader, &event)
+		},
+		OnPlan: func(chunks []*models.Chunk, useChapters bool) {
+			event := struct {
+				eventHeader
+				Strategy string      `json:"strategy"` // chapters or time
+				Chunks   []chunkPlan `json:"chunks"`
+			}{Strategy: "time", Chunks: make([]chunkPlan, len(chunks))}
+			if useChapters {
+				event.Strategy = "chapters"
+			}
+			for i, chunk := range chunks {
+				event.Chunks[i] = chunkPlan{
+					ChunkID:  chunk.ChunkID,
+					Start:    chunk.StartTime,
+					End:      chunk.EndTime,
+					Duration: chunk.EndTime - chunk.StartTime,
+				}
+			}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.write("chunk_plan", &event.eventHeader, &event)
+		},
+		OnProgress: func(taskID string, progress *models.EncodingProgress) {
+			event := struct {
+				eventHeader
+				Task     string  `json:"task"`
+				State    string  `json:"state"`
+				Progress float64 `json:"progress"` // Percent of the task's duration
+				Frame    int64   `json:"frame"`
+				FPS      float64 `json:"fps"`
+				Speed    float64 `json:"speed"`
+				Position string  `json:"position,omitempty"` // Encoder timestamp, HH:MM:SS.MS
+				Bitrate  string  `json:"bitrate,omitempty"`
+				Size     string  `json:"size,omitempty"`
+			}{
+				Task:     taskID,
+				State:    string(progress.State),
+				Progress: progress.Progress,
+				Frame:    progress.Frame,
+				FPS:      progress.FPS,
+				Speed:    progress.Speed,
+				Position: progress.CurrentTime,
+				Bitrate:  progress.Bitrate,
+				Size:     progress.Size,
+			}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.write("progress", &event.eventHeader, &event)
+		},
+		OnTask: func(task *orchestrator.Task) {
+			event := struct {
+				eventHeader
+				Task           string  `json:"task"`
+				TaskType       string  `json:"task_type"`
+				ChunkID        uint    `json:"chunk_id,omitempty"`
+				Status         string  `json:"status"` // completed, failed or skipped
+				Output         string  `json:"output,omitempty"`
+				ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+				Error          string  `json:"error,omitempty"`
+			}{
+				Task:     task.ID,
+				TaskType: string(task.Command.GetTaskType()),
+				Status:   "completed",
+				Output:   task.Command.GetOutputPath(),
+			}
+			if task.Result != nil {
+				event.ChunkID = task.Result.ChunkID
+			}
+			if !task.StartTime.IsZero() && !task.EndTime.IsZero() {
+				event.ElapsedSeconds = task.EndTime.Sub(task.StartTime).Seconds()
+			}
+			switch {
+			case errors.Is(task.Error, orchestrator.ErrDependencyFailed):
+				event.Status = "skipped"
+				event.Error = task.Error.Error()
+			case task.Error != nil:
+				event.Status = "failed"
+				event.Error = task.Error.Error()
+			}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.write("task", &event.eventHeader, &event)
+		},
+		OnDone: func(summary *jobSummary) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.summary = summary
+		},
+	}
+}
+
+// dryRun reports the commands of a dry run and the number of features they
+// need that the local ffmpeg build is missing.
+func (e *eventWriter) dryRun(workDir string, commands []dryRunCommand, missing int) {
+	if e == nil {
+		return
+	}
+	event := struct {
+		eventHeader
+		WorkDir         string          `json:"work_dir"`
+		Commands        []dryRunCommand `json:"commands"`
+		MissingFeatures int             `json:"missing_features"`
+	}{WorkDir: workDir, Commands: commands, MissingFeatures: missing}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.write("dry_run", &event.eventHeader, &event)
+}
+
+// result reports the end of the run with exit code code, ending the phase in
+// progress. A successful pipeline's summary is included.
+func (e *eventWriter) result(code int, err error) {
+	if e == nil {
+		return
+	}
+	event := struct {
+		eventHeader
+		Status   string `json:"status"` // Failure class of the exit code, "ok" on success
+		ExitCode int    `json:"exit_code"`
+		Error    string `json:"error,omitempty"`
+		// Shadows the summary's elapsed_seconds, which it is set to on success
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+		*jobSummary
+		Phases []phaseTiming `json:"phases,omitempty"`
+	}{Status: exitNames[code], ExitCode: code, ElapsedSeconds: time.Since(e.start).Seconds()}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.endPhase()
+	if e.summary != nil {
+		event.jobSummary = e.summary
+		event.ElapsedSeconds = e.summary.ElapsedSeconds
+	}
+	event.Phases = e.phases
+	e.write("result", &event.eventHeader, &event)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoder/models"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// readEvents decodes the JSON lines written by an eventWriter.
+func readEvents(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Invalid event line %q: %v", line, err)
+		}
+		if event["event"] == nil || event["time"] == nil {
+			t.Errorf("Event without event or time: %s", line)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// eventNames returns the "event" field of each event.
+func eventNames(events []map[string]interface{}) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i], _ = event["event"].(string)
+	}
+	return names
+}
+
+func TestEventWriter_Sequence(t *testing.T) {
+	var out bytes.Buffer
+	events := newEventWriter(&out)
+	monitor := events.monitor()
+
+	events.started("in.mkv", "out.mkv", "cpu-only")
+	monitor.OnPhase("analysis")
+	monitor.OnPlan([]*models.Chunk{{ChunkID: 0, StartTime: 0, EndTime: 60}, {ChunkID: 1, StartTime: 60, EndTime: 90}}, false)
+	monitor.OnPhase("video")
+	monitor.OnDone(&jobSummary{Output: "out.mkv", Chunks: 2, ElapsedSeconds: 12})
+	events.result(exitOK, nil)
+
+	got := readEvents(t, &out)
+	want := []string{"start", "phase_start", "chunk_plan", "phase_end", "phase_start", "phase_end", "result"}
+	if strings.Join(eventNames(got), " ") != strings.Join(want, " ") {
+		t.Fatalf("Events = %v, want %v", eventNames(got), want)
+	}
+
+	if got[3]["phase"] != "analysis" || got[5]["phase"] != "video" {
+		t.Errorf("Expected phase_end for analysis then video, got %v and %v", got[3]["phase"], got[5]["phase"])
+	}
+	plan, _ := got[2]["chunks"].([]interface{})
+	if got[2]["strategy"] != "time" || len(plan) != 2 {
+		t.Errorf("Unexpected chunk_plan: %v", got[2])
+	}
+
+	result := got[len(got)-1]
+	if result["status"] != "ok" || result["exit_code"] != float64(exitOK) {
+		t.Errorf("Unexpected result status: %v", result)
+	}
+	if result["output"] != "out.mkv" || result["elapsed_seconds"] != float64(12) {
+		t.Errorf("Expected the summary in the result, got %v", result)
+	}
+	if phases, _ := result["phases"].([]interface{}); len(phases) != 2 {
+		t.Errorf("Expected 2 phase timings, got %v", result["phases"])
+	}
+}
+
+func TestEventWriter_FailedResultIsLast(t *testing.T) {
+	var out bytes.Buffer
+	events := newEventWriter(&out)
+	monitor := events.monitor()
+
+	events.started("in.mkv", "out.mkv", "mixed")
+	monitor.OnPhase("concat")
+	events.result(exitFailed, errors.New("concat failed"))
+
+	got := readEvents(t, &out)
+	want := []string{"start", "phase_start", "phase_end", "result"}
+	if strings.Join(eventNames(got), " ") != strings.Join(want, " ") {
+		t.Fatalf("Events = %v, want %v", eventNames(got), want)
+	}
+	result := got[len(got)-1]
+	if result["status"] != "failed" || result["exit_code"] != float64(exitFailed) || result["error"] != "concat failed" {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if _, ok := result["output"]; ok {
+		t.Errorf("Expected no summary in a failed result, got %v", result)
+	}
+}
+
+func TestEventWriter_Nil(t *testing.T) {
+	var events *eventWriter
+	events.started("in.mkv", "out.mkv", "mixed")
+	events.dryRun("/tmp/work", nil, 0)
+	events.result(exitConfig, errors.New("bad config"))
+	if events.monitor() != nil {
+		t.Error("Expected no monitor for text output")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoder/preflight"
+	"errors"
+)
+
+// Exit codes of an encode, one per failure class. They are part of the CLI's
+// interface (see "EXIT CODES" in the usage) and must not change.
+const (
+	exitOK          = 0   // Encoded, or dry run found the configuration valid
+	exitFailed      = 1   // Encoding failed: a chunk, concatenation, mux or packaging step
+	exitConfig      = 2   // Invalid flags or configuration
+	exitInput       = 3   // Input cannot be read: probe failed or no audio/video streams
+	exitEnvironment = 4   // Environment: ffmpeg features missing, log or work directory unusable
+	exitCancelled   = 130 // Interrupted (SIGINT/SIGTERM)
+)
+
+// exitNames names the exit codes in the "result" event of -output-format json.
+var exitNames = map[int]string{
+	exitOK:          "ok",
+	exitFailed:      "failed",
+	exitConfig:      "config",
+	exitInput:       "input",
+	exitEnvironment: "environment",
+	exitCancelled:   "cancelled",
+}
+
+// classError is an error of a failure class other than exitFailed.
+type classError struct {
+	code int
+	err  error
+}
+
+func (e *classError) Error() string { return e.err.Error() }
+func (e *classError) Unwrap() error { return e.err }
+
+// withExitCode marks err as a failure of the class of exit code code.
+func withExitCode(code int, err error) error {
+	return &classError{code: code, err: err}
+}
+
+// exitCode returns the exit code of a run that ended with err. Features
+// missing from the local ffmpeg build are environment failures.
+func exitCode(err error) int {
+	var classified *classError
+	var missing *preflight.MissingError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitCancelled
+	case errors.As(err, &classified):
+		return classified.code
+	case errors.As(err, &missing):
+		return exitEnvironment
+	default:
+		return exitFailed
+	}
+}
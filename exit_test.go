@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoder/preflight"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	missing := &preflight.MissingError{Missing: []string{"video codec: no encoder"}}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"unclassified", errors.New("concat failed"), exitFailed},
+		{"classified", withExitCode(exitInput, errors.New("probe failed")), exitInput},
+		{"wrapped class", fmt.Errorf("job: %w", withExitCode(exitConfig, errors.New("bad crf"))), exitConfig},
+		{"canceled", context.Canceled, exitCancelled},
+		{"wrapped canceled", fmt.Errorf("chunk 3: %w", context.Canceled), exitCancelled},
+		{"canceled wins over class", withExitCode(exitFailed, context.Canceled), exitCancelled},
+		{"missing ffmpeg features", missing, exitEnvironment},
+		{"wrapped missing features", fmt.Errorf("preflight: %w", missing), exitEnvironment},
+		{"class wins over missing features", withExitCode(exitConfig, missing), exitConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitNames(t *testing.T) {
+	for _, code := range []int{exitOK, exitFailed, exitConfig, exitInput, exitEnvironment, exitCancelled} {
+		if exitNames[code] == "" {
+			t.Errorf("Exit code %d has no name", code)
+		}
+	}
+}
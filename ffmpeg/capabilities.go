@@ -0,0 +1,437 @@
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"encoder/codec"
+)
+
+// EncoderInfo describes one entry of "ffmpeg -encoders".
+type EncoderInfo struct {
+	Type        string `json:"type"` // "video", "audio" or "subtitle"
+	Description string `json:"description"`
+}
+
+// Capabilities lists what the local ffmpeg build can do.
+type Capabilities struct {
+	Binary     string                 `json:"binary"`
+	Hash       string                 `json:"hash"` // sha256 of the binary
+	Version    string                 `json:"version"`
+	Encoders   map[string]EncoderInfo `json:"encoders"`
+	Filters    map[string]string      `json:"filters"`
+	Muxers     map[string]string      `json:"muxers"`
+	DetectedAt time.Time              `json:"detected_at"`
+	FromCache  bool                   `json:"-"`
+}
+
+// detected caches capabilities per resolved binary path for the lifetime of the process.
+var (
+	detectedMu sync.Mutex
+	detected   = make(map[string]*Capabilities)
+)
+
+// DetectCapabilities probes the ffmpeg binary (looked up in PATH if needed).
+//
+// Results are cached in memory for the rest of the run and on disk under the
+// user cache directory, keyed by the sha256 of the binary, so a rebuilt or
+// upgraded ffmpeg is probed again.
+func DetectCapabilities(binary string) (*Capabilities, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	detectedMu.Lock()
+	defer detectedMu.Unlock()
+
+	if caps, ok := detected[path]; ok {
+		return caps, nil
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	cachePath := capabilitiesCachePath(hash)
+	if caps, err := readCapabilitiesCache(cachePath); err == nil && caps.Hash == hash {
+		caps.Binary = path
+		caps.FromCache = true
+		detected[path] = caps
+		return caps, nil
+	}
+
+	caps, err := probeCapabilities(path)
+	if err != nil {
+		return nil, err
+	}
+	caps.Hash = hash
+
+	// The disk cache is an optimisation; failing to write it is not an error
+	if cachePath != "" {
+		if data, err := json.Marshal(caps); err == nil {
+			if os.MkdirAll(filepath.Dir(cachePath), 0755) == nil {
+				os.WriteFile(cachePath, data, 0644)
+			}
+		}
+	}
+
+	detected[path] = caps
+	return caps, nil
+}
+
+// probeCapabilities runs ffmpeg -version/-encoders/-filters/-muxers.
+func probeCapabilities(path string) (*Capabilities, error) {
+	run := func(arg string) (string, error) {
+		out, err := exec.Command(path, "-hide_banner", arg).Output()
+		if err != nil {
+			return "", fmt.Errorf("ffmpeg %s failed: %w", arg, err)
+		}
+		return string(out), nil
+	}
+
+	version, err := run("-version")
+	if err != nil {
+		return nil, err
+	}
+	encoders, err := run("-encoders")
+	if err != nil {
+		return nil, err
+	}
+	filters, err := run("-filters")
+	if err != nil {
+		return nil, err
+	}
+	muxers, err := run("-muxers")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Capabilities{
+		Binary:     path,
+		Version:    ParseVersion(version),
+		Encoders:   ParseEncoders(encoders),
+		Filters:    ParseFilters(filters),
+		Muxers:     ParseMuxers(muxers),
+		DetectedAt: time.Now(),
+	}, nil
+}
+
+// ParseVersion extracts the version from "ffmpeg -version" output
+// ("ffmpeg version 6.1.1-3ubuntu5 Copyright ..." -> "6.1.1-3ubuntu5").
+func ParseVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "ffmpeg" && fields[1] == "version" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// ParseEncoders parses "ffmpeg -encoders" output.
+//
+//	V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC (codec h264)
+//	A....D aac                  AAC (Advanced Audio Coding)
+func ParseEncoders(output string) map[string]EncoderInfo {
+	types := map[byte]string{'V': "video", 'A': "audio", 'S': "subtitle"}
+	encoders := make(map[string]EncoderInfo)
+
+	for _, line := range linesAfterSeparator(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[0]) != 6 {
+			continue
+		}
+		typ, ok := types[fields[0][0]]
+		if !ok {
+			continue
+		}
+		encoders[fields[1]] = EncoderInfo{
+			Type:        typ,
+			Description: strings.Join(fields[2:], " "),
+		}
+	}
+
+	return encoders
+}
+
+// ParseFilters parses "ffmpeg -filters" output.
+//
+//	... loudnorm          A->A       EBU R128 loudness normalization
+//	TSC scale             V->V       Scale the input video size and/or convert the image format.
+func ParseFilters(output string) map[string]string {
+	filters := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		filters[fields[1]] = strings.Join(fields[3:], " ")
+	}
+
+	return filters
+}
+
+// ParseMuxers parses "ffmpeg -muxers" output.
+//
+//	E matroska        Matroska
+//	E mp4             MP4 (MPEG-4 Part 14)
+func ParseMuxers(output string) map[string]string {
+	muxers := make(map[string]string)
+
+	for _, line := range linesAfterSeparator(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.Contains(fields[0], "E") {
+			continue
+		}
+		description := strings.Join(fields[2:], " ")
+		for _, name := range strings.Split(fields[1], ",") {
+			muxers[name] = description
+		}
+	}
+
+	return muxers
+}
+
+// linesAfterSeparator returns the lines after the " ------" or " --" legend separator.
+func linesAfterSeparator(output string) []string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && strings.Trim(trimmed, "-") == "" {
+			return lines[i+1:]
+		}
+	}
+	return nil
+}
+
+// HasEncoder reports whether the build includes the encoder.
+func (c *Capabilities) HasEncoder(name string) bool {
+	_, ok := c.Encoders[name]
+	return ok
+}
+
+// HasFilter reports whether the build includes the filter.
+func (c *Capabilities) HasFilter(name string) bool {
+	_, ok := c.Filters[name]
+	return ok
+}
+
+// HasMuxer reports whether the build includes the muxer.
+func (c *Capabilities) HasMuxer(name string) bool {
+	_, ok := c.Muxers[name]
+	return ok
+}
+
+// CheckEncoder returns an error naming the nearest available alternative
+// if the encoder is missing.
+func (c *Capabilities) CheckEncoder(name string) error {
+	if c.HasEncoder(name) {
+		return nil
+	}
+	return c.missing("encoder", name, c.SuggestEncoder(name))
+}
+
+// CheckFilter returns an error naming the nearest available alternative
+// if the filter is missing.
+func (c *Capabilities) CheckFilter(name string) error {
+	if c.HasFilter(name) {
+		return nil
+	}
+	return c.missing("filter", name, c.SuggestFilter(name))
+}
+
+// CheckContainer checks that a muxer exists for the output file's extension.
+// Extensions without a known muxer are not checked.
+func (c *Capabilities) CheckContainer(path string) error {
+	muxer, ok := MuxerForExtension(codec.ContainerOf(path))
+	if !ok || c.HasMuxer(muxer) {
+		return nil
+	}
+	return c.missing("muxer", muxer, "")
+}
+
+func (c *Capabilities) missing(kind, name, suggestion string) error {
+	msg := fmt.Sprintf("ffmpeg %s (%s) has no %s %q", c.Version, c.Binary, kind, name)
+	if suggestion != "" {
+		msg += fmt.Sprintf(" - did you mean %q?", suggestion)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// SuggestEncoder returns the closest available encoder: one for the same
+// format (software first), otherwise the most similar name of the same type.
+func (c *Capabilities) SuggestEncoder(name string) string {
+	typ := ""
+	if info, ok := codec.Lookup(name); ok {
+		typ = string(info.Kind)
+
+		var software, hardware []string
+		for _, candidate := range codec.Names(info.Kind) {
+			other, _ := codec.Lookup(candidate)
+			if candidate == name || other.Family != info.Family || !c.HasEncoder(candidate) {
+				continue
+			}
+			if other.Hardware {
+				hardware = append(hardware, candidate)
+			} else {
+				software = append(software, candidate)
+			}
+		}
+		if len(software) > 0 {
+			return software[0]
+		}
+		if len(hardware) > 0 {
+			return hardware[0]
+		}
+	}
+
+	var candidates []string
+	for candidate, enc := range c.Encoders {
+		if typ == "" || enc.Type == typ {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return closest(name, candidates)
+}
+
+// filterAlternatives maps filters commonly missing from minimal builds to
+// built-in filters that do a similar job.
+var filterAlternatives = map[string][]string{
+	"zscale":    {"scale", "colorspace"},
+	"libvmaf":   {"ssim", "psnr"},
+	"loudnorm":  {"dynaudnorm", "volume"},
+	"bwdif":     {"yadif", "w3fdif"},
+	"nnedi":     {"bwdif", "yadif"},
+	"tonemap":   {"tonemap_opencl", "tonemap_vaapi"},
+	"subtitles": {"ass"},
+}
+
+// SuggestFilter returns the closest available filter.
+func (c *Capabilities) SuggestFilter(name string) string {
+	for _, alt := range filterAlternatives[name] {
+		if c.HasFilter(alt) {
+			return alt
+		}
+	}
+
+	candidates := make([]string, 0, len(c.Filters))
+	for candidate := range c.Filters {
+		candidates = append(candidates, candidate)
+	}
+	return closest(name, candidates)
+}
+
+// closest returns the candidate with the smallest edit distance, if it is
+// close enough to be a plausible typo or variant.
+func closest(name string, candidates []string) string {
+	sort.Strings(candidates) // deterministic tie-break
+	best, bestDist := "", len(name)/2+2
+	for _, candidate := range candidates {
+		if d := levenshtein(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// muxerByExtension maps output file extensions to ffmpeg muxer names.
+var muxerByExtension = map[string]string{
+	"mkv":  "matroska",
+	"mka":  "matroska",
+	"webm": "webm",
+	"mp4":  "mp4",
+	"m4v":  "mp4",
+	"m4a":  "ipod",
+	"mov":  "mov",
+	"ts":   "mpegts",
+	"flv":  "flv",
+	"avi":  "avi",
+	"ogg":  "ogg",
+	"opus": "opus",
+	"flac": "flac",
+	"mp3":  "mp3",
+	"ivf":  "ivf",
+	"m3u8": "hls",
+	"mpd":  "dash",
+}
+
+// MuxerForExtension returns the muxer ffmpeg picks for a file extension.
+func MuxerForExtension(ext string) (string, bool) {
+	muxer, ok := muxerByExtension[strings.ToLower(ext)]
+	return muxer, ok
+}
+
+// capabilitiesCachePath returns the on-disk cache file for a binary hash.
+func capabilitiesCachePath(hash string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "encoder", "ffmpeg-"+hash[:16]+".json")
+}
+
+func readCapabilitiesCache(path string) (*Capabilities, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var caps Capabilities
+	if err := json.Unmarshal(data, &caps); err != nil {
+		return nil, err
+	}
+	return &caps, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,227 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const sampleVersion = `ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers
+built with gcc 13 (Ubuntu 13.2.0-23ubuntu3)
+configuration: --prefix=/usr --enable-gpl --enable-libx264
+`
+
+const sampleEncoders = `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ..S... = Slice-level multithreading
+ ...X.. = Codec is experimental
+ ....B. = Supports draw_horiz_band
+ .....D = Supports direct rendering method 1
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libaom-av1           libaom AV1 (codec av1)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A....D libopus              libopus Opus (codec opus)
+ S..... srt                  SubRip subtitle
+`
+
+const sampleFilters = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ ... dynaudnorm        A->A       Dynamic Audio Normalizer.
+ T.C equalizer         A->A       Apply two-pole peaking equalization (EQ) filter.
+ ... pan               A->A       Remix channels with coefficients (panning).
+ TSC scale             V->V       Scale the input video size and/or convert the image format.
+ ..C ssim              VV->V      Calculate the SSIM between two video streams.
+ TS. yadif             V->V       Deinterlace the input image.
+`
+
+const sampleMuxers = `Muxers:
+ D. = Demuxing supported
+ .E = Muxing supported
+ --
+  E matroska        Matroska
+  E mp4             MP4 (MPEG-4 Part 14)
+  E webm            WebM
+`
+
+func TestParseVersion(t *testing.T) {
+	if got := ParseVersion(sampleVersion); got != "6.1.1-3ubuntu5" {
+		t.Errorf("ParseVersion = %q, want 6.1.1-3ubuntu5", got)
+	}
+	if got := ParseVersion("garbage"); got != "" {
+		t.Errorf("ParseVersion(garbage) = %q, want empty", got)
+	}
+}
+
+func TestParseEncoders(t *testing.T) {
+	encoders := ParseEncoders(sampleEncoders)
+
+	if len(encoders) != 6 {
+		t.Fatalf("Expected 6 encoders, got %d: %v", len(encoders), encoders)
+	}
+	if encoders["libx264"].Type != "video" {
+		t.Errorf("Expected libx264 to be video, got %q", encoders["libx264"].Type)
+	}
+	if encoders["aac"].Type != "audio" {
+		t.Errorf("Expected aac to be audio, got %q", encoders["aac"].Type)
+	}
+	if encoders["srt"].Type != "subtitle" {
+		t.Errorf("Expected srt to be subtitle, got %q", encoders["srt"].Type)
+	}
+	if _, ok := encoders["V....."]; ok {
+		t.Error("Legend lines must not be parsed as encoders")
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	filters := ParseFilters(sampleFilters)
+
+	for _, name := range []string{"dynaudnorm", "equalizer", "pan", "scale", "ssim", "yadif"} {
+		if _, ok := filters[name]; !ok {
+			t.Errorf("Expected filter %s", name)
+		}
+	}
+	if len(filters) != 6 {
+		t.Errorf("Expected 6 filters, got %d: %v", len(filters), filters)
+	}
+}
+
+func TestParseMuxers(t *testing.T) {
+	muxers := ParseMuxers(sampleMuxers)
+	if len(muxers) != 3 || muxers["matroska"] != "Matroska" {
+		t.Errorf("Unexpected muxers: %v", muxers)
+	}
+}
+
+func TestCapabilities_Suggestions(t *testing.T) {
+	caps := sampleCapabilities()
+
+	tests := []struct {
+		name    string
+		check   func(string) error
+		item    string
+		wantErr string
+	}{
+		{"present encoder", caps.CheckEncoder, "libx264", ""},
+		{"same family software first", caps.CheckEncoder, "libsvtav1", `did you mean "libaom-av1"`},
+		{"same family prefers software", caps.CheckEncoder, "h264_qsv", `did you mean "libx264"`},
+		{"no family member, closest name", caps.CheckEncoder, "libx265", `did you mean "libx264"`},
+		{"typo", caps.CheckEncoder, "libopuss", `did you mean "libopus"`},
+		{"present filter", caps.CheckFilter, "pan", ""},
+		{"known alternative", caps.CheckFilter, "loudnorm", `did you mean "dynaudnorm"`},
+		{"libvmaf", caps.CheckFilter, "libvmaf", `did you mean "ssim"`},
+		{"filter typo", caps.CheckFilter, "yadiff", `did you mean "yadif"`},
+		{"container", caps.CheckContainer, "out.mov", `has no muxer "mov"`},
+		{"container present", caps.CheckContainer, "out.mkv", ""},
+		{"unknown container", caps.CheckContainer, "out.xyz", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(tt.item)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDetectCapabilities_FakeBinaryAndCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script fake ffmpeg requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+
+	for name, content := range map[string]string{
+		"version.txt":  sampleVersion,
+		"encoders.txt": sampleEncoders,
+		"filters.txt":  sampleFilters,
+		"muxers.txt":   sampleMuxers,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := `#!/bin/sh
+echo probe >> "` + dir + `/calls"
+case "$2" in
+  -version) cat "` + dir + `/version.txt" ;;
+  -encoders) cat "` + dir + `/encoders.txt" ;;
+  -filters) cat "` + dir + `/filters.txt" ;;
+  -muxers) cat "` + dir + `/muxers.txt" ;;
+esac
+`
+	binary := filepath.Join(dir, "fake-ffmpeg")
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	caps, err := DetectCapabilities(binary)
+	if err != nil {
+		t.Fatalf("DetectCapabilities failed: %v", err)
+	}
+	if caps.Version != "6.1.1-3ubuntu5" || !caps.HasEncoder("libopus") || !caps.HasFilter("scale") {
+		t.Errorf("Unexpected capabilities: %+v", caps)
+	}
+	if caps.FromCache {
+		t.Error("First detection should not come from cache")
+	}
+
+	// Second call in the same run is served from memory
+	again, _ := DetectCapabilities(binary)
+	if again != caps {
+		t.Error("Expected in-memory cache hit")
+	}
+
+	// A new run reads the disk cache without invoking the binary
+	detectedMu.Lock()
+	delete(detected, binary)
+	detectedMu.Unlock()
+	os.Remove(filepath.Join(dir, "calls"))
+
+	cached, err := DetectCapabilities(binary)
+	if err != nil {
+		t.Fatalf("DetectCapabilities (cached) failed: %v", err)
+	}
+	if !cached.FromCache || !cached.HasEncoder("libx264") {
+		t.Errorf("Expected disk cache hit, got %+v", cached)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "calls")); !os.IsNotExist(err) {
+		t.Error("Expected binary not to be invoked on cache hit")
+	}
+}
+
+func TestDetectCapabilities_NotFound(t *testing.T) {
+	if _, err := DetectCapabilities("/nonexistent/ffmpeg"); err == nil {
+		t.Error("Expected error for missing binary")
+	}
+}
+
+func sampleCapabilities() *Capabilities {
+	return &Capabilities{
+		Binary:   "/usr/bin/ffmpeg",
+		Version:  ParseVersion(sampleVersion),
+		Encoders: ParseEncoders(sampleEncoders),
+		Filters:  ParseFilters(sampleFilters),
+		Muxers:   ParseMuxers(sampleMuxers),
+	}
+}
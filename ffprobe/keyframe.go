@@ -0,0 +1,44 @@
+package ffprobe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// StartsWithKeyframe reports whether the first video packet of path is a
+// keyframe, which a chunk needs to join the previous one without glitches.
+func StartsWithKeyframe(path string) (bool, error) {
+	if path == "" {
+		return false, fmt.Errorf("path cannot be empty")
+	}
+
+	// -read_intervals %+#1: stop after the first packet
+	args := []string{
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-read_intervals", "%+#1",
+		"-show_entries", "packet=flags",
+		"-of", "json",
+		path,
+	}
+
+	output, err := exec.Command("ffprobe", args...).Output()
+	if err != nil {
+		return false, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result struct {
+		Packets []struct {
+			Flags string `json:"flags"`
+		} `json:"packets"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return false, fmt.Errorf("failed to parse ffprobe JSON output: %w", err)
+	}
+	if len(result.Packets) == 0 {
+		return false, fmt.Errorf("no video packets in %s", path)
+	}
+	return strings.Contains(result.Packets[0].Flags, "K"), nil
+}
@@ -0,0 +1,51 @@
+package ffprobe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// installFakeFFprobe puts an ffprobe on PATH that prints output.
+func installFakeFFprobe(t *testing.T, output string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncat <<'JSON'\n" + output + "\nJSON\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestStartsWithKeyframe(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    bool
+		wantErr bool
+	}{
+		{"keyframe", `{"packets": [{"flags": "K__"}]}`, true, false},
+		{"not a keyframe", `{"packets": [{"flags": "___"}]}`, false, false},
+		{"no packets", `{"packets": []}`, false, true},
+		{"bad json", `not json`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installFakeFFprobe(t, tt.output)
+			got, err := StartsWithKeyframe("chunk.mkv")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartsWithKeyframe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("StartsWithKeyframe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartsWithKeyframe_EmptyPath(t *testing.T) {
+	if _, err := StartsWithKeyframe(""); err == nil {
+		t.Error("Expected error for empty path")
+	}
+}
@@ -23,16 +23,50 @@ type Chapter struct {
 }
 
 // Stream represents a media stream (audio, video, subtitle, etc.)
+//
+// Field names follow ffprobe's JSON output; see stream.go for the helpers
+// that interpret them (FrameRate, BitDepth, IsHDR, IsInterlaced, Language, ...).
 type Stream struct {
 	Index         int    `json:"index"`
 	CodecName     string `json:"codec_name"`
 	CodecType     string `json:"codec_type"`
 	CodecLongName string `json:"codec_long_name"`
+	CodecTag      string `json:"codec_tag_string,omitempty"`
+	Profile       string `json:"profile,omitempty"`
+	Level         int    `json:"level,omitempty"` // Codec level, e.g. 40 for H.264 4.0, 120 for HEVC 4.0
 	Width         int    `json:"width,omitempty"`
 	Height        int    `json:"height,omitempty"`
 	SampleRate    string `json:"sample_rate,omitempty"`
 	Channels      int    `json:"channels,omitempty"`
 	Duration      string `json:"duration,omitempty"`
+
+	// Timing
+	RFrameRate   Rational `json:"r_frame_rate,omitempty"`   // Lowest rate that represents all timestamps
+	AvgFrameRate Rational `json:"avg_frame_rate,omitempty"` // Average frame rate
+	TimeBase     Rational `json:"time_base,omitempty"`
+	StartTime    string   `json:"start_time,omitempty"`
+	NbFrames     string   `json:"nb_frames,omitempty"`
+	BitRate      string   `json:"bit_rate,omitempty"`
+
+	// Video picture properties
+	PixFmt             string `json:"pix_fmt,omitempty"`
+	BitsPerRawSample   string `json:"bits_per_raw_sample,omitempty"`
+	ColorRange         string `json:"color_range,omitempty"`     // "tv" (limited) or "pc" (full)
+	ColorSpace         string `json:"color_space,omitempty"`     // Matrix coefficients, e.g. "bt2020nc"
+	ColorTransfer      string `json:"color_transfer,omitempty"`  // e.g. "smpte2084" (PQ), "arib-std-b67" (HLG)
+	ColorPrimaries     string `json:"color_primaries,omitempty"` // e.g. "bt709", "bt2020"
+	ChromaLocation     string `json:"chroma_location,omitempty"`
+	FieldOrder         string `json:"field_order,omitempty"` // "progressive", "tt", "bb", "tb", "bt"
+	SampleAspectRatio  string `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string `json:"display_aspect_ratio,omitempty"`
+
+	// Audio properties
+	SampleFmt     string `json:"sample_fmt,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"` // e.g. "stereo", "5.1(side)"
+
+	Disposition Disposition       `json:"disposition,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	SideData    []SideData        `json:"side_data_list,omitempty"`
 }
 
 // Format represents the container format information.
@@ -125,6 +159,17 @@ func (pr *ProbeResult) GetAudioStreams() []Stream {
 	return audioStreams
 }
 
+// PrimaryVideoStream returns the first video stream that is not cover art,
+// or nil if the file has none.
+func (pr *ProbeResult) PrimaryVideoStream() *Stream {
+	for i := range pr.Streams {
+		if pr.Streams[i].IsVideo() {
+			return &pr.Streams[i]
+		}
+	}
+	return nil
+}
+
 // Probe analyzes a media file and extracts its metadata using ffprobe.
 //
 // The function executes ffprobe with JSON output format and parses the result
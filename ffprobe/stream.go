@@ -0,0 +1,406 @@
+package ffprobe
+
+import (
+	"encoder/models"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rational is a fraction as printed by ffprobe ("24000/1001", "1/1000").
+// The zero value and "0/0" both mean "unknown".
+type Rational struct {
+	Num int64
+	Den int64
+}
+
+// ParseRational parses "num/den" (or a plain integer) into a Rational.
+func ParseRational(s string) (Rational, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Rational{}, nil
+	}
+
+	numStr, denStr, found := strings.Cut(s, "/")
+	if !found {
+		denStr = "1"
+	}
+
+	num, err := strconv.ParseInt(numStr, 10, 64)
+	if err != nil {
+		return Rational{}, fmt.Errorf("invalid rational %q: %w", s, err)
+	}
+	den, err := strconv.ParseInt(denStr, 10, 64)
+	if err != nil {
+		return Rational{}, fmt.Errorf("invalid rational %q: %w", s, err)
+	}
+
+	return Rational{Num: num, Den: den}, nil
+}
+
+// UnmarshalJSON accepts the "num/den" strings ffprobe emits.
+func (r *Rational) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseRational(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// MarshalJSON writes the rational back as "num/den".
+func (r Rational) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// IsZero reports whether the value is unknown (0/0, 0/1 or x/0).
+func (r Rational) IsZero() bool {
+	return r.Num == 0 || r.Den == 0
+}
+
+// Float returns the value as a float64 (0 if unknown).
+func (r Rational) Float() float64 {
+	if r.IsZero() {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+// Scale multiplies the value by num/den and reduces the result,
+// e.g. 30000/1001 scaled by 4/5 is 24000/1001.
+func (r Rational) Scale(num, den int64) Rational {
+	out := Rational{Num: r.Num * num, Den: r.Den * den}
+	if g := gcd(out.Num, out.Den); g > 1 {
+		out.Num /= g
+		out.Den /= g
+	}
+	return out
+}
+
+func gcd(a, b int64) int64 {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// String formats the value as "num/den".
+func (r Rational) String() string {
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+// Disposition holds the stream disposition flags (1 = set).
+type Disposition struct {
+	Default         int `json:"default"`
+	Dub             int `json:"dub"`
+	Original        int `json:"original"`
+	Comment         int `json:"comment"`
+	Forced          int `json:"forced"`
+	HearingImpaired int `json:"hearing_impaired"`
+	VisualImpaired  int `json:"visual_impaired"`
+	CleanEffects    int `json:"clean_effects"`
+	AttachedPic     int `json:"attached_pic"`
+	Captions        int `json:"captions"`
+	Descriptions    int `json:"descriptions"`
+}
+
+// Side data types reported by ffprobe.
+const (
+	SideDataMasteringDisplay = "Mastering display metadata"
+	SideDataContentLight     = "Content light level metadata"
+	SideDataDisplayMatrix    = "Display Matrix"
+	SideDataDOVIConfig       = "DOVI configuration record"
+)
+
+// SideData is one entry of a stream's side_data_list. Only the fields
+// belonging to Type are set.
+type SideData struct {
+	Type string `json:"side_data_type"`
+
+	// Mastering display metadata (SMPTE ST 2086)
+	RedX         Rational `json:"red_x,omitempty"`
+	RedY         Rational `json:"red_y,omitempty"`
+	GreenX       Rational `json:"green_x,omitempty"`
+	GreenY       Rational `json:"green_y,omitempty"`
+	BlueX        Rational `json:"blue_x,omitempty"`
+	BlueY        Rational `json:"blue_y,omitempty"`
+	WhitePointX  Rational `json:"white_point_x,omitempty"`
+	WhitePointY  Rational `json:"white_point_y,omitempty"`
+	MinLuminance Rational `json:"min_luminance,omitempty"`
+	MaxLuminance Rational `json:"max_luminance,omitempty"`
+
+	// Content light level metadata (MaxCLL / MaxFALL)
+	MaxContent int `json:"max_content,omitempty"`
+	MaxAverage int `json:"max_average,omitempty"`
+
+	// Display matrix
+	Rotation float64 `json:"rotation,omitempty"`
+
+	// Dolby Vision configuration record
+	DVProfile int `json:"dv_profile,omitempty"`
+	DVLevel   int `json:"dv_level,omitempty"`
+}
+
+// IsVideo reports whether the stream is a video stream (excluding cover art).
+func (s *Stream) IsVideo() bool {
+	return s.CodecType == "video" && s.Disposition.AttachedPic == 0
+}
+
+// IsAudio reports whether the stream is an audio stream.
+func (s *Stream) IsAudio() bool {
+	return s.CodecType == "audio"
+}
+
+// IsSubtitle reports whether the stream is a subtitle stream.
+func (s *Stream) IsSubtitle() bool {
+	return s.CodecType == "subtitle"
+}
+
+// FrameRate returns the stream's frame rate: the average rate if known,
+// otherwise the base rate. Zero for non-video streams.
+func (s *Stream) FrameRate() Rational {
+	if !s.AvgFrameRate.IsZero() {
+		return s.AvgFrameRate
+	}
+	return s.RFrameRate
+}
+
+// IsVariableFrameRate reports whether the average rate differs from the base rate.
+func (s *Stream) IsVariableFrameRate() bool {
+	if s.AvgFrameRate.IsZero() || s.RFrameRate.IsZero() {
+		return false
+	}
+	return math.Abs(s.AvgFrameRate.Float()-s.RFrameRate.Float()) > 0.01
+}
+
+// BitDepth returns the bits per sample of a video stream, from
+// bits_per_raw_sample or else from the pixel format. Zero if unknown.
+func (s *Stream) BitDepth() int {
+	if n, err := strconv.Atoi(s.BitsPerRawSample); err == nil && n > 0 {
+		return n
+	}
+	return pixFmtBitDepth(s.PixFmt)
+}
+
+// pixFmtBitDepth derives the bit depth from names like "yuv420p10le" or "p010le".
+func pixFmtBitDepth(pixFmt string) int {
+	if pixFmt == "" {
+		return 0
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(pixFmt, "le"), "be")
+	for _, depth := range []int{16, 14, 12, 10, 9} {
+		suffix := strconv.Itoa(depth)
+		if strings.HasSuffix(name, "p"+suffix) || strings.HasPrefix(name, "p0"+suffix) ||
+			strings.HasPrefix(name, "gray"+suffix) {
+			return depth
+		}
+	}
+	return 8
+}
+
+// IsHDR reports whether the stream uses an HDR transfer function (PQ or HLG)
+// or carries Dolby Vision metadata.
+func (s *Stream) IsHDR() bool {
+	return s.HDRFormat() != ""
+}
+
+// HDRFormat returns "dolby-vision", "hdr10", "hlg" or "" for SDR.
+func (s *Stream) HDRFormat() string {
+	if s.sideData(SideDataDOVIConfig) != nil {
+		return models.HDRFormatDolbyVision
+	}
+	switch s.ColorTransfer {
+	case "smpte2084":
+		return models.HDRFormatHDR10
+	case "arib-std-b67":
+		return models.HDRFormatHLG
+	}
+	return ""
+}
+
+// IsInterlaced reports whether the container signals interlaced fields.
+// Streams with unknown field order are treated as progressive.
+func (s *Stream) IsInterlaced() bool {
+	switch s.FieldOrder {
+	case "tt", "bb", "tb", "bt":
+		return true
+	}
+	return false
+}
+
+// Language returns the ISO 639 language tag, or "und" if none is set.
+func (s *Stream) Language() string {
+	if lang := strings.ToLower(s.tag("language")); lang != "" {
+		return lang
+	}
+	return "und"
+}
+
+// Title returns the stream title tag.
+func (s *Stream) Title() string {
+	return s.tag("title")
+}
+
+// IsDefault reports whether the stream carries the default disposition.
+func (s *Stream) IsDefault() bool {
+	return s.Disposition.Default == 1
+}
+
+// IsForced reports whether the stream carries the forced disposition.
+func (s *Stream) IsForced() bool {
+	return s.Disposition.Forced == 1
+}
+
+// BitRateBps returns the stream bitrate in bits per second, using the
+// Matroska statistics tag (BPS) when ffprobe reports none. Zero if unknown.
+func (s *Stream) BitRateBps() int64 {
+	for _, value := range []string{s.BitRate, s.tag("BPS"), s.tag("BPS-eng")} {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// SampleRateHz returns the audio sample rate in Hz (0 if unknown).
+func (s *Stream) SampleRateHz() int {
+	n, _ := strconv.Atoi(s.SampleRate)
+	return n
+}
+
+// Rotation returns the display rotation in degrees (e.g. -90, 90, 180), from
+// the display matrix side data or the legacy "rotate" tag.
+func (s *Stream) Rotation() int {
+	if sd := s.sideData(SideDataDisplayMatrix); sd != nil {
+		return int(math.Round(sd.Rotation))
+	}
+	if n, err := strconv.Atoi(s.tag("rotate")); err == nil {
+		return n
+	}
+	return 0
+}
+
+// MasteringDisplay returns the HDR mastering display metadata, if present.
+func (s *Stream) MasteringDisplay() *SideData {
+	return s.sideData(SideDataMasteringDisplay)
+}
+
+// ContentLightLevel returns the MaxCLL/MaxFALL metadata, if present.
+func (s *Stream) ContentLightLevel() *SideData {
+	return s.sideData(SideDataContentLight)
+}
+
+// HDRMetadata returns the stream's HDR signalling, or nil for SDR streams.
+// Missing color tags default to the BT.2020 values HDR10/HLG require.
+func (s *Stream) HDRMetadata() *models.HDRMetadata {
+	format := s.HDRFormat()
+	if format == "" {
+		return nil
+	}
+
+	meta := &models.HDRMetadata{
+		Format:         format,
+		ColorPrimaries: orDefault(s.ColorPrimaries, "bt2020"),
+		ColorTransfer:  orDefault(s.ColorTransfer, "smpte2084"),
+		ColorSpace:     orDefault(s.ColorSpace, "bt2020nc"),
+		ColorRange:     orDefault(s.ColorRange, "tv"),
+	}
+
+	if md := s.MasteringDisplay(); md != nil {
+		meta.MasteringDisplay = &models.MasteringDisplay{
+			RedX: md.RedX.Float(), RedY: md.RedY.Float(),
+			GreenX: md.GreenX.Float(), GreenY: md.GreenY.Float(),
+			BlueX: md.BlueX.Float(), BlueY: md.BlueY.Float(),
+			WhiteX: md.WhitePointX.Float(), WhiteY: md.WhitePointY.Float(),
+			MinLuminance: md.MinLuminance.Float(),
+			MaxLuminance: md.MaxLuminance.Float(),
+		}
+	}
+	if cll := s.ContentLightLevel(); cll != nil {
+		meta.MaxCLL = cll.MaxContent
+		meta.MaxFALL = cll.MaxAverage
+	}
+
+	return meta
+}
+
+func orDefault(value, def string) string {
+	if value == "" || value == "unknown" {
+		return def
+	}
+	return value
+}
+
+func (s *Stream) sideData(typ string) *SideData {
+	for i := range s.SideData {
+		if s.SideData[i].Type == typ {
+			return &s.SideData[i]
+		}
+	}
+	return nil
+}
+
+// tag looks up a tag case-insensitively (containers differ in tag case).
+func (s *Stream) tag(key string) string {
+	if value, ok := s.Tags[key]; ok {
+		return value
+	}
+	for k, value := range s.Tags {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Summary returns a one-line description such as
+// "hevc 3840x2160 yuv420p10le 23.976 fps hdr10" or "eac3 48000 Hz 5.1(side) eng".
+func (s *Stream) Summary() string {
+	parts := []string{s.CodecName}
+
+	switch s.CodecType {
+	case "video":
+		parts = append(parts, fmt.Sprintf("%dx%d", s.Width, s.Height))
+		if s.PixFmt != "" {
+			parts = append(parts, s.PixFmt)
+		}
+		if fps := s.FrameRate().Float(); fps > 0 {
+			parts = append(parts, strconv.FormatFloat(fps, 'f', -1, 64)+" fps")
+		}
+		if s.IsInterlaced() {
+			parts = append(parts, "interlaced")
+		}
+		if hdr := s.HDRFormat(); hdr != "" {
+			parts = append(parts, hdr)
+		}
+		if rot := s.Rotation(); rot != 0 {
+			parts = append(parts, fmt.Sprintf("rotated %d°", rot))
+		}
+	case "audio":
+		if s.SampleRate != "" {
+			parts = append(parts, s.SampleRate+" Hz")
+		}
+		if s.ChannelLayout != "" {
+			parts = append(parts, s.ChannelLayout)
+		} else if s.Channels > 0 {
+			parts = append(parts, fmt.Sprintf("%d ch", s.Channels))
+		}
+		parts = append(parts, s.Language())
+	default:
+		parts = append(parts, s.Language())
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,277 @@
+package ffprobe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// sampleStreamsJSON is trimmed ffprobe -show_streams output for an HDR10
+// Matroska file with two audio tracks and cover art.
+const sampleStreamsJSON = `{
+  "streams": [
+    {
+      "index": 0,
+      "codec_name": "hevc",
+      "codec_type": "video",
+      "profile": "Main 10",
+      "width": 3840,
+      "height": 2160,
+      "pix_fmt": "yuv420p10le",
+      "color_range": "tv",
+      "color_space": "bt2020nc",
+      "color_transfer": "smpte2084",
+      "color_primaries": "bt2020",
+      "field_order": "progressive",
+      "r_frame_rate": "24000/1001",
+      "avg_frame_rate": "24000/1001",
+      "time_base": "1/1000",
+      "disposition": {"default": 1, "forced": 0, "attached_pic": 0},
+      "tags": {"language": "eng", "BPS": "45000000"},
+      "side_data_list": [
+        {
+          "side_data_type": "Mastering display metadata",
+          "red_x": "34000/50000", "red_y": "16000/50000",
+          "green_x": "13250/50000", "green_y": "34500/50000",
+          "blue_x": "7500/50000", "blue_y": "3000/50000",
+          "white_point_x": "15635/50000", "white_point_y": "16450/50000",
+          "min_luminance": "50/10000", "max_luminance": "40000000/10000"
+        },
+        {"side_data_type": "Content light level metadata", "max_content": 1000, "max_average": 400}
+      ]
+    },
+    {
+      "index": 1,
+      "codec_name": "eac3",
+      "codec_type": "audio",
+      "sample_rate": "48000",
+      "channels": 6,
+      "channel_layout": "5.1(side)",
+      "bit_rate": "640000",
+      "r_frame_rate": "0/0",
+      "avg_frame_rate": "0/0",
+      "disposition": {"default": 1, "forced": 0},
+      "tags": {"LANGUAGE": "GER", "title": "Deutsch"}
+    },
+    {
+      "index": 2,
+      "codec_name": "aac",
+      "codec_type": "audio",
+      "sample_rate": "44100",
+      "channels": 2,
+      "disposition": {"default": 0, "forced": 1}
+    },
+    {
+      "index": 3,
+      "codec_name": "mjpeg",
+      "codec_type": "video",
+      "width": 600,
+      "height": 600,
+      "disposition": {"default": 0, "attached_pic": 1}
+    }
+  ]
+}`
+
+func parseSampleStreams(t *testing.T) []Stream {
+	t.Helper()
+	var out ffprobeOutput
+	if err := json.Unmarshal([]byte(sampleStreamsJSON), &out); err != nil {
+		t.Fatalf("Failed to parse sample JSON: %v", err)
+	}
+	return out.Streams
+}
+
+func TestStream_ParseRichMetadata(t *testing.T) {
+	streams := parseSampleStreams(t)
+	video, audio, aac := streams[0], streams[1], streams[2]
+
+	if fps := video.FrameRate(); fps != (Rational{24000, 1001}) {
+		t.Errorf("FrameRate = %v, want 24000/1001", fps)
+	}
+	if video.BitDepth() != 10 {
+		t.Errorf("BitDepth = %d, want 10", video.BitDepth())
+	}
+	if !video.IsHDR() || video.HDRFormat() != "hdr10" {
+		t.Errorf("Expected hdr10, got %q", video.HDRFormat())
+	}
+	if video.IsInterlaced() {
+		t.Error("Progressive stream reported as interlaced")
+	}
+	if video.BitRateBps() != 45000000 {
+		t.Errorf("BitRateBps = %d, want BPS tag value", video.BitRateBps())
+	}
+
+	md := video.MasteringDisplay()
+	if md == nil || md.MaxLuminance.Float() != 4000 || md.MinLuminance.Float() != 0.005 {
+		t.Errorf("Unexpected mastering display: %+v", md)
+	}
+	if cll := video.ContentLightLevel(); cll == nil || cll.MaxContent != 1000 || cll.MaxAverage != 400 {
+		t.Errorf("Unexpected content light level: %+v", cll)
+	}
+
+	if audio.Language() != "ger" || audio.Title() != "Deutsch" {
+		t.Errorf("Expected case-insensitive tags, got %q/%q", audio.Language(), audio.Title())
+	}
+	if audio.ChannelLayout != "5.1(side)" || audio.BitRateBps() != 640000 || audio.SampleRateHz() != 48000 {
+		t.Errorf("Unexpected audio fields: %+v", audio)
+	}
+	if !audio.FrameRate().IsZero() {
+		t.Errorf("Expected 0/0 frame rate for audio, got %v", audio.FrameRate())
+	}
+	if !audio.IsDefault() || aac.IsDefault() || !aac.IsForced() {
+		t.Error("Unexpected disposition flags")
+	}
+	if aac.Language() != "und" {
+		t.Errorf("Expected und for untagged stream, got %q", aac.Language())
+	}
+}
+
+func TestStream_HDRMetadata(t *testing.T) {
+	streams := parseSampleStreams(t)
+
+	meta := streams[0].HDRMetadata()
+	if meta == nil {
+		t.Fatal("Expected HDR metadata for HDR10 stream")
+	}
+	if meta.Format != "hdr10" || meta.ColorPrimaries != "bt2020" || meta.ColorSpace != "bt2020nc" {
+		t.Errorf("Unexpected color metadata: %+v", meta)
+	}
+	md := meta.MasteringDisplay
+	if md == nil || md.GreenX != 0.265 || md.WhiteY != 0.329 || md.MaxLuminance != 4000 {
+		t.Errorf("Unexpected mastering display: %+v", md)
+	}
+	if meta.MaxCLL != 1000 || meta.MaxFALL != 400 {
+		t.Errorf("Expected MaxCLL/MaxFALL 1000/400, got %d/%d", meta.MaxCLL, meta.MaxFALL)
+	}
+
+	if streams[1].HDRMetadata() != nil {
+		t.Error("Expected nil HDR metadata for SDR/audio stream")
+	}
+
+	// HLG without explicit primaries gets the BT.2020 defaults
+	hlg := Stream{ColorTransfer: "arib-std-b67", ColorPrimaries: "unknown"}
+	if m := hlg.HDRMetadata(); m == nil || m.ColorPrimaries != "bt2020" || m.ColorTransfer != "arib-std-b67" {
+		t.Errorf("Unexpected HLG metadata: %+v", m)
+	}
+}
+
+func TestProbeResult_PrimaryVideoStream_SkipsCoverArt(t *testing.T) {
+	streams := parseSampleStreams(t)
+
+	pr := &ProbeResult{Streams: []Stream{streams[3], streams[1], streams[0]}}
+	if v := pr.PrimaryVideoStream(); v == nil || v.CodecName != "hevc" {
+		t.Errorf("Expected hevc primary stream, got %+v", v)
+	}
+
+	pr = &ProbeResult{Streams: []Stream{streams[3]}}
+	if v := pr.PrimaryVideoStream(); v != nil {
+		t.Errorf("Expected no primary video for cover art only, got %+v", v)
+	}
+}
+
+func TestStream_HDRFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream Stream
+		want   string
+	}{
+		{"sdr", Stream{ColorTransfer: "bt709"}, ""},
+		{"pq", Stream{ColorTransfer: "smpte2084"}, "hdr10"},
+		{"hlg", Stream{ColorTransfer: "arib-std-b67"}, "hlg"},
+		{"dolby vision", Stream{SideData: []SideData{{Type: SideDataDOVIConfig, DVProfile: 8}}}, "dolby-vision"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.stream.HDRFormat(); got != tt.want {
+			t.Errorf("%s: HDRFormat = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStream_IsInterlaced(t *testing.T) {
+	for order, want := range map[string]bool{
+		"":            false,
+		"progressive": false,
+		"unknown":     false,
+		"tt":          true,
+		"bb":          true,
+		"tb":          true,
+		"bt":          true,
+	} {
+		s := Stream{FieldOrder: order}
+		if got := s.IsInterlaced(); got != want {
+			t.Errorf("IsInterlaced(%q) = %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestStream_BitDepth(t *testing.T) {
+	tests := []struct {
+		stream Stream
+		want   int
+	}{
+		{Stream{PixFmt: "yuv420p"}, 8},
+		{Stream{PixFmt: "yuv420p10le"}, 10},
+		{Stream{PixFmt: "yuv444p12le"}, 12},
+		{Stream{PixFmt: "p010le"}, 10},
+		{Stream{PixFmt: "gray10le"}, 10},
+		{Stream{PixFmt: "yuv420p", BitsPerRawSample: "10"}, 10},
+		{Stream{}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.stream.BitDepth(); got != tt.want {
+			t.Errorf("BitDepth(%q, %q) = %d, want %d", tt.stream.PixFmt, tt.stream.BitsPerRawSample, got, tt.want)
+		}
+	}
+}
+
+func TestStream_Rotation(t *testing.T) {
+	matrix := Stream{SideData: []SideData{{Type: SideDataDisplayMatrix, Rotation: -90}}}
+	if matrix.Rotation() != -90 {
+		t.Errorf("Rotation from display matrix = %d, want -90", matrix.Rotation())
+	}
+
+	legacy := Stream{Tags: map[string]string{"rotate": "180"}}
+	if legacy.Rotation() != 180 {
+		t.Errorf("Rotation from tag = %d, want 180", legacy.Rotation())
+	}
+}
+
+func TestParseRational(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rational
+		wantErr bool
+	}{
+		{"30000/1001", Rational{30000, 1001}, false},
+		{"25/1", Rational{25, 1}, false},
+		{"0/0", Rational{0, 0}, false},
+		{"50", Rational{50, 1}, false},
+		{"", Rational{}, false},
+		{"abc/1", Rational{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRational(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRational(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRational(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if f := (Rational{30000, 1001}).Float(); f < 29.97 || f > 29.98 {
+		t.Errorf("Float = %f, want ~29.97", f)
+	}
+	if (Rational{0, 0}).Float() != 0 {
+		t.Error("Expected 0 for unknown rational")
+	}
+	if got := (Rational{30000, 1001}).Scale(4, 5); got != (Rational{24000, 1001}) {
+		t.Errorf("Scale(4, 5) = %v, want 24000/1001", got)
+	}
+	if got := (Rational{50, 1}).Scale(1, 2); got != (Rational{25, 1}) {
+		t.Errorf("Scale(1, 2) = %v, want 25/1", got)
+	}
+}
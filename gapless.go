@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoder/codec"
+	"encoder/command/audio"
+	"encoder/config"
+	"encoder/models"
+)
+
+// audioChunkCodec returns the encoder of the audio chunks.
+func audioChunkCodec(cfg *config.Config) string {
+	if cfg.Audio.Gapless {
+		return "flac"
+	}
+	return cfg.Audio.Codec
+}
+
+// audioChunkExt returns the file extension of the encoded audio chunks: the
+// chunk encoder's own container.
+func audioChunkExt(cfg *config.Config) string {
+	return codec.IntermediateContainer(audioChunkCodec(cfg))
+}
+
+// newChunkAudioBuilder creates the audio encode of a chunk. Gapless chunks
+// are cut at exact sample positions and encoded to FLAC, which has no encoder
+// delay; the configured codec is applied once to the joined track (see
+// newFinalAudioBuilder). Otherwise each chunk is encoded with the codec.
+func newChunkAudioBuilder(cfg *config.Config, chunk *models.Chunk, outputPath string) *audio.AudioBuilder {
+	builder := audio.NewAudioBuilder(chunk, outputPath)
+	builder.SetSampleRate(cfg.Audio.SampleRate).
+		SetChannels(cfg.Audio.Channels)
+	builder.SetCodec(audioChunkCodec(cfg))
+	if cfg.Audio.Gapless {
+		builder.SetSampleAccurate(true)
+	} else {
+		builder.SetBitrate(cfg.Audio.Bitrate)
+	}
+	return builder
+}
+
+// newFinalAudioBuilder creates the encode of the joined gapless chunks with
+// the configured codec.
+func newFinalAudioBuilder(cfg *config.Config, inputPath, outputPath string) *audio.FinalBuilder {
+	return audio.NewFinalBuilder(inputPath, outputPath).
+		SetCodec(cfg.Audio.Codec).
+		SetBitrate(cfg.Audio.Bitrate).
+		SetSampleRate(cfg.Audio.SampleRate)
+}
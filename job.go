@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"encoder/chunker"
+	"encoder/command/thumbnail"
+	"encoder/config"
+	"encoder/ffprobe"
+	"encoder/logging"
+	"encoder/models"
+	"encoder/orchestrator"
+	"encoder/quality"
+	"encoder/workdir"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// encodeJob is one input's trip through the pipeline: its work directory,
+// what the analysis found and the files each stage produced. runPipeline
+// runs the stages of a single job; a batch runs many jobs' stages around one
+// shared orchestrator.
+type encodeJob struct {
+	cfg *config.Config
+	out io.Writer    // Progress lines of the stages (io.Discard in a batch)
+	log *slog.Logger // Log of the job's stages
+
+	wd         *workdir.WorkDir
+	tmpDir     string
+	segmentDir string
+	audioDir   string
+	videoDir   string
+
+	probeResult *ffprobe.ProbeResult
+	duration    float64
+	hasAudio    bool
+	hasVideo    bool
+	src         *sourceAnalysis
+	chunks      []*models.Chunk
+	useChapters bool
+
+	renditions        []*videoRendition
+	audioFiles        []string
+	finalAudioPath    string
+	thumbnailBuilders []*thumbnail.ThumbnailBuilder
+	thumbnails        []string
+	metricsReport     *quality.MetricsReport
+	packages          []string
+}
+
+// openJob acquires the job's work directory (locked against concurrent jobs)
+// and creates its subdirectories. The caller releases it with close.
+func openJob(cfg *config.Config, out io.Writer, log *slog.Logger) (*encodeJob, error) {
+	wd, err := workdir.Acquire(workdir.Resolve(cfg.WorkDir, cfg.Input, cfg.Output), cfg.Input, cfg.Output)
+	if err != nil {
+		return nil, withExitCode(exitEnvironment, fmt.Errorf("failed to acquire work directory: %w", err))
+	}
+	log.Info("work directory acquired", "path", wd.Path)
+
+	j := &encodeJob{
+		cfg:        cfg,
+		out:        out,
+		log:        log,
+		wd:         wd,
+		tmpDir:     wd.Path,
+		segmentDir: filepath.Join(wd.Path, "segments"),
+		audioDir:   filepath.Join(wd.Path, "audio"),
+		videoDir:   filepath.Join(wd.Path, "video"),
+	}
+	for _, dir := range []string{j.tmpDir, j.segmentDir, j.audioDir, j.videoDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			j.close()
+			return nil, withExitCode(exitEnvironment, fmt.Errorf("failed to create directory %s: %w", dir, err))
+		}
+	}
+	return j, nil
+}
+
+// close releases the job's work directory.
+func (j *encodeJob) close() {
+	if err := j.wd.Release(); err != nil {
+		j.log.Warn("failed to release work directory", "path", j.wd.Path, "error", err)
+	}
+}
+
+// analyze probes the input and analyzes its video (Phase 1). With a target
+// size, the job's config gets the bitrate it resolves to.
+func (j *encodeJob) analyze(ctx context.Context) error {
+	probeResult, err := ffprobe.Probe(j.cfg.Input)
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("media analysis failed: %w", err))
+	}
+
+	duration, err := probeResult.GetDuration()
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("failed to get media duration: %w", err))
+	}
+	j.probeResult = probeResult
+	j.duration = duration
+
+	j.hasAudio = len(probeResult.GetAudioStreams()) > 0
+	j.hasVideo = len(probeResult.GetVideoStreams()) > 0
+
+	fmt.Fprintf(j.out, "  Duration:       %.2f seconds\n", duration)
+	fmt.Fprintf(j.out, "  Format:         %s\n", probeResult.Format.FormatLongName)
+	fmt.Fprintf(j.out, "  Audio streams:  %d\n", len(probeResult.GetAudioStreams()))
+	fmt.Fprintf(j.out, "  Video streams:  %d\n", len(probeResult.GetVideoStreams()))
+	if probeResult.GetChapterCount() > 0 {
+		fmt.Fprintf(j.out, "  Chapters:       %d\n", probeResult.GetChapterCount())
+	}
+	for _, stream := range probeResult.Streams {
+		fmt.Fprintf(j.out, "    #%d %-8s %s\n", stream.Index, stream.CodecType, stream.Summary())
+	}
+	j.src = analyzeSource(ctx, j.cfg, probeResult, j.out, j.log)
+	if j.hasVideo {
+		if j.cfg, err = resolveTargetSize(j.cfg, duration, j.hasAudio, j.out, j.log); err != nil {
+			return withExitCode(exitConfig, err)
+		}
+	}
+
+	if !j.hasAudio && !j.hasVideo {
+		return withExitCode(exitInput, fmt.Errorf("no audio or video streams found in input file"))
+	}
+	return nil
+}
+
+// chunk plans the chunks of the input (Phase 2): chapters first, then
+// time-based.
+func (j *encodeJob) chunk() error {
+	chunkCreator := chunker.NewChunker(j.cfg.Input)
+
+	// Determine chunking strategy: chapters first, then time-based
+	j.useChapters = j.probeResult.GetChapterCount() > 0
+
+	if j.useChapters {
+		fmt.Fprintf(j.out, "  Strategy:   Chapter-based (%d chapters detected)\n", j.probeResult.GetChapterCount())
+		chunkCreator.SetUseChapters(true)
+	} else {
+		fmt.Fprintf(j.out, "  Strategy:   Time-based (%.1f second chunks)\n", float64(j.cfg.ChunkDuration))
+		chunkCreator.SetChunkDuration(float64(j.cfg.ChunkDuration)).SetUseChapters(false)
+	}
+
+	chunks, err := chunkCreator.CreateChunks(j.probeResult)
+	if err != nil {
+		return fmt.Errorf("chunking failed: %w", err)
+	}
+
+	if err := chunker.ValidateChunks(chunks); err != nil {
+		return fmt.Errorf("chunk validation failed: %w", err)
+	}
+	j.chunks = chunks
+
+	// Calculate average chunk duration
+	avgDuration := 0.0
+	if len(chunks) > 0 {
+		for _, chunk := range chunks {
+			avgDuration += chunk.EndTime - chunk.StartTime
+		}
+		avgDuration /= float64(len(chunks))
+	}
+
+	fmt.Fprintf(j.out, "  Created:    %d chunks (avg %.1fs each)\n", len(chunks), avgDuration)
+	if j.src.FrameRate != "" {
+		// Inverse telecine changes the frame rate; chunks keep source timing
+		for _, chunk := range chunks {
+			chunk.FrameRate = j.src.FrameRate
+		}
+		fmt.Fprintf(j.out, "  Frame rate: %s (after %s)\n", j.src.FrameRate, j.src.Interlace.Type)
+	}
+
+	// Every rendition of the ladder encodes the same chunks; the first one
+	// is the primary output that metrics and the summary describe
+	j.renditions = newVideoRenditions(j.cfg, j.videoDir, j.tmpDir)
+	return nil
+}
+
+// preSplits reports whether the input is split into segments before
+// encoding (Phase 3, for performance).
+func (j *encodeJob) preSplits() bool {
+	return j.cfg.PreSplit && j.useChapters
+}
+
+// preSplit splits the input into chapter segments (Phase 3).
+func (j *encodeJob) preSplit() error {
+	if err := preSplitSegmentsWithCache(j.cfg, j.probeResult, j.chunks, j.segmentDir, j.out, j.log); err != nil {
+		return fmt.Errorf("segment splitting failed: %w", err)
+	}
+	return nil
+}
+
+// addThumbnailTasks adds the job's thumbnail tasks to orch, to run alongside
+// its video chunks.
+func (j *encodeJob) addThumbnailTasks(orch *orchestrator.DAGOrchestrator, prefix string) error {
+	j.thumbnailBuilders = newThumbnailBuilders(j.cfg, j.probeResult, j.src, j.duration, j.log)
+	if len(j.thumbnailBuilders) == 0 {
+		return nil
+	}
+	fmt.Fprintf(j.out, "  Thumbnails: %s → %s\n", j.cfg.Thumbnails.Outputs, thumbnailDir(j.cfg))
+	j.log.Info("generating thumbnails", "outputs", j.cfg.Thumbnails.Outputs, "dir", thumbnailDir(j.cfg))
+	if err := addThumbnailTasks(j.thumbnailBuilders, orch, prefix); err != nil {
+		return fmt.Errorf("thumbnail setup failed: %w", err)
+	}
+	return nil
+}
+
+// collectThumbnails records the thumbnail outputs once their tasks ran.
+func (j *encodeJob) collectThumbnails() {
+	j.thumbnails = thumbnailOutputs(j.thumbnailBuilders, j.out, j.log)
+	if len(j.thumbnails) > 0 {
+		fmt.Fprintf(j.out, "  ✓ Thumbnails: %d output(s) in %s\n", len(j.thumbnails), thumbnailDir(j.cfg))
+	}
+}
+
+// videoFiles returns the encoded chunks of the primary rendition.
+func (j *encodeJob) videoFiles() []string {
+	if !j.hasVideo {
+		return nil
+	}
+	return j.renditions[0].files
+}
+
+// concat joins the encoded chunks of the audio and of every rendition
+// (Phase 7). Gapless audio is encoded with the audio codec once joined.
+func (j *encodeJob) concat() error {
+	cfg := j.cfg
+	concatStart := time.Now()
+
+	if len(j.audioFiles) > 0 {
+		j.finalAudioPath = filepath.Join(j.tmpDir, "final_audio."+audioExt(cfg))
+		joinedAudioPath := j.finalAudioPath
+		if cfg.Audio.Gapless {
+			joinedAudioPath = filepath.Join(j.tmpDir, "joined_audio.flac")
+		}
+		j.log.Info("concatenating audio", "chunks", len(j.audioFiles))
+		audioConcatStart := time.Now()
+		if err := concatenateFiles(j.audioFiles, joinedAudioPath, cfg.StrictMode, "", j.log); err != nil {
+			j.log.Error("audio concatenation failed", "error", err)
+			return fmt.Errorf("audio concatenation failed: %w", err)
+		}
+		elapsed := time.Since(audioConcatStart).Seconds()
+		j.log.Info("audio concatenated", "chunks", len(j.audioFiles), "seconds", elapsed)
+		fmt.Fprintf(j.out, "  ✓ Audio concatenated (%.2fs)\n", elapsed)
+
+		// Gapless chunks are lossless: encode the joined track once
+		if cfg.Audio.Gapless {
+			audioEncodeStart := time.Now()
+			finalBuilder := newFinalAudioBuilder(cfg, joinedAudioPath, j.finalAudioPath)
+			if cmd, err := finalBuilder.DryRun(); err == nil {
+				j.log.Debug("ffmpeg command", "args", cmd)
+			}
+			if err := finalBuilder.Run(); err != nil {
+				j.log.Error("audio encoding failed", "error", err)
+				return fmt.Errorf("audio encoding failed: %w", err)
+			}
+			elapsed := time.Since(audioEncodeStart).Seconds()
+			j.log.Info("audio encoded", "codec", cfg.Audio.Codec, "seconds", elapsed)
+			fmt.Fprintf(j.out, "  ✓ Audio encoded (%.2fs)\n", elapsed)
+		}
+	}
+
+	if len(j.videoFiles()) > 0 {
+		for _, r := range j.renditions {
+			log := r.logger(j.log)
+			log.Info("concatenating video", "chunks", len(r.files))
+			videoConcatStart := time.Now()
+			if err := concatenateFiles(r.files, r.Final, cfg.StrictMode, j.src.FrameRate, log); err != nil {
+				log.Error("video concatenation failed", "error", err)
+				return fmt.Errorf("video concatenation failed%s: %w", r.label(), err)
+			}
+			elapsed := time.Since(videoConcatStart).Seconds()
+			log.Info("video concatenated", "chunks", len(r.files), "seconds", elapsed)
+			fmt.Fprintf(j.out, "  ✓ Video concatenated%s (%.2fs)\n", r.label(), elapsed)
+		}
+	}
+
+	j.log.Info("concatenation complete", "seconds", time.Since(concatStart).Seconds())
+	return nil
+}
+
+// mux writes the outputs (Phase 8): every rendition muxed with the audio,
+// or the single stream the input has. Single-stream outputs are remuxed too:
+// the intermediate container differs from the output's, and subtitles and
+// fast start are added.
+func (j *encodeJob) mux() error {
+	cfg := j.cfg
+	switch {
+	case j.hasAudio && j.hasVideo:
+		// Every rendition is muxed with the same audio encode
+		for _, r := range j.renditions {
+			j.log.Info("muxing audio and video", "output", r.Output)
+			mixStart := time.Now()
+
+			if err := muxOutput(cfg, r.Final, j.finalAudioPath, r.Output, j.probeResult, j.log); err != nil {
+				j.log.Error("mux failed", "output", r.Output, "error", err)
+				return fmt.Errorf("mixing failed%s: %w", r.label(), err)
+			}
+			elapsed := time.Since(mixStart).Seconds()
+			j.log.Info("mux complete", "output", r.Output, "seconds", elapsed)
+			fmt.Fprintf(j.out, "  ✓ Mixed output%s (%.2fs)\n", r.label(), elapsed)
+		}
+	case j.hasAudio:
+		// Audio only
+		j.log.Info("writing audio output", "output", cfg.Output)
+		if err := muxOutput(cfg, "", j.finalAudioPath, cfg.Output, j.probeResult, j.log); err != nil {
+			j.log.Error("failed to write audio output", "output", cfg.Output, "error", err)
+			return fmt.Errorf("failed to write audio to output: %w", err)
+		}
+		j.log.Info("audio output written", "output", cfg.Output)
+		fmt.Fprintf(j.out, "  ✓ Output: %s\n", cfg.Output)
+	case j.hasVideo:
+		// Video only
+		for _, r := range j.renditions {
+			j.log.Info("writing video output", "output", r.Output)
+			if err := muxOutput(cfg, r.Final, "", r.Output, j.probeResult, j.log); err != nil {
+				j.log.Error("failed to write video output", "output", r.Output, "error", err)
+				return fmt.Errorf("failed to write video to output: %w", err)
+			}
+			j.log.Info("video output written", "output", r.Output)
+			fmt.Fprintf(j.out, "  ✓ Output: %s\n", r.Output)
+		}
+	}
+	return nil
+}
+
+// measures reports whether quality metrics are computed (Phase 9).
+func (j *encodeJob) measures() bool {
+	return j.cfg.Metrics.Enabled && len(j.videoFiles()) > 0
+}
+
+// measure compares the primary rendition to the source (Phase 9). Metrics are
+// informational: the output is already written, so failures are warnings.
+func (j *encodeJob) measure() {
+	cfg := j.cfg
+
+	// Metrics are computed on the CPU in every mode
+	metricsOrch := orchestrator.NewDAGOrchestrator([]orchestrator.ResourceConstraint{
+		{Type: orchestrator.ResourceCPU, MaxSlots: cfg.Workers},
+	})
+	metricsOrch.SetLogger(j.log)
+	report, err := measureOutput(j.renditions[0].Config, j.chunks, j.src, j.videoFiles(), filepath.Join(j.tmpDir, "metrics"), metricsOrch, j.out, j.log)
+	if err != nil {
+		j.log.Warn("quality metrics failed", "error", err)
+		fmt.Fprintf(j.out, "  ⚠️  Metrics failed: %v\n", err)
+		return
+	}
+
+	j.metricsReport = report
+	for _, id := range report.Failed {
+		j.log.Warn("chunk could not be measured", logging.KeyChunkID, id)
+	}
+	reportPath := metricsReportPath(cfg)
+	if err := report.WriteJSON(reportPath); err != nil {
+		j.log.Warn("failed to write metrics report", "error", err)
+	} else {
+		j.log.Info("metrics report written", "path", reportPath)
+		fmt.Fprintf(j.out, "  ✓ Report: %s\n", reportPath)
+	}
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(j.out, "  ⚠️  %d chunks could not be measured\n", len(report.Failed))
+	}
+}
+
+// packs reports whether the outputs are packaged for streaming (Phase 10).
+func (j *encodeJob) packs() bool {
+	return j.cfg.Packaging.Enabled() && len(j.videoFiles()) > 0
+}
+
+// pack packages every rendition and the audio for adaptive streaming
+// (Phase 10).
+func (j *encodeJob) pack() error {
+	j.log.Info("packaging", "formats", j.cfg.Packaging.Formats, "dir", packagingDir(j.cfg))
+
+	packageOrch := orchestrator.NewDAGOrchestrator([]orchestrator.ResourceConstraint{
+		{Type: orchestrator.ResourceIO, MaxSlots: 4},
+	})
+	packageOrch.SetLogger(j.log)
+	packages, err := packageOutput(j.cfg, j.renditions, j.finalAudioPath, j.probeResult, packageOrch, j.log)
+	if err != nil {
+		j.log.Error("packaging failed", "error", err)
+		return fmt.Errorf("packaging failed: %w", err)
+	}
+	j.packages = packages
+	for _, path := range packages {
+		fmt.Fprintf(j.out, "  ✓ %s\n", path)
+	}
+	return nil
+}
+
+// finish runs the stages after encoding: concatenation, mux, and the
+// optional metrics and packaging (Phases 7-10), without phase banners.
+func (j *encodeJob) finish() error {
+	if err := j.concat(); err != nil {
+		return err
+	}
+	if err := j.mux(); err != nil {
+		return err
+	}
+	if j.measures() {
+		j.measure()
+	}
+	if j.packs() {
+		return j.pack()
+	}
+	return nil
+}
+
+// outputSize returns the size of the primary output (0 if missing).
+func (j *encodeJob) outputSize() int64 {
+	info, err := os.Stat(j.cfg.Output)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// jobSummary is the result of a finished job, as logged and reported in the
+// "result" event of -output-format json.
+type jobSummary struct {
+	Output          string   `json:"output"`
+	SizeBytes       int64    `json:"size_bytes"`
+	DurationSeconds float64  `json:"duration_seconds"` // Of the input
+	BitrateKbps     float64  `json:"bitrate_kbps"`
+	ElapsedSeconds  float64  `json:"elapsed_seconds"`
+	Speed           float64  `json:"speed"` // Multiple of realtime
+	Chunks          int      `json:"chunks"`
+	AudioChunks     int      `json:"audio_chunks"`
+	VideoChunks     int      `json:"video_chunks"`
+	Renditions      []string `json:"renditions,omitempty"` // Outputs besides Output
+	Quality         string   `json:"quality,omitempty"`
+	Metrics         []string `json:"metrics,omitempty"`
+	Packages        []string `json:"packages,omitempty"`
+	Thumbnails      []string `json:"thumbnails,omitempty"`
+}
+
+// summary returns the result of the job, finished after elapsed.
+func (j *encodeJob) summary(elapsed time.Duration) *jobSummary {
+	outputSize := j.outputSize()
+	s := &jobSummary{
+		Output:          j.cfg.Output,
+		SizeBytes:       outputSize,
+		DurationSeconds: j.duration,
+		BitrateKbps:     float64(outputSize*8) / j.duration / 1000,
+		ElapsedSeconds:  elapsed.Seconds(),
+		Speed:           j.duration / elapsed.Seconds(),
+		Chunks:          len(j.chunks),
+		AudioChunks:     len(j.audioFiles),
+		VideoChunks:     len(j.videoFiles()),
+		Packages:        j.packages,
+		Thumbnails:      j.thumbnails,
+	}
+	if len(j.videoFiles()) > 0 {
+		for _, r := range j.renditions[1:] {
+			s.Renditions = append(s.Renditions, r.Output)
+		}
+	}
+	if j.hasVideo && j.renditions[0].report != nil {
+		s.Quality = j.renditions[0].report.Summary()
+	}
+	if j.metricsReport != nil {
+		s.Metrics = j.metricsReport.Lines()
+	}
+	return s
+}
+
+// logSummary logs the result of the finished job.
+func (j *encodeJob) logSummary(elapsed time.Duration) {
+	s := j.summary(elapsed)
+	j.log.Info("encoding complete",
+		"output", s.Output,
+		"size_bytes", s.SizeBytes,
+		"duration_seconds", s.DurationSeconds,
+		"bitrate_kbps", s.BitrateKbps,
+		"elapsed_seconds", s.ElapsedSeconds,
+		"speed", s.Speed,
+		"chunks", s.Chunks,
+		"audio_chunks", s.AudioChunks,
+		"video_chunks", s.VideoChunks)
+	if len(j.videoFiles()) > 0 {
+		for _, r := range j.renditions[1:] {
+			j.log.Info("rendition written", "rendition", r.Name, "output", r.Output)
+		}
+	}
+	if s.Quality != "" {
+		j.log.Info("quality", "summary", s.Quality)
+	}
+	for _, line := range s.Metrics {
+		j.log.Info("metrics", "summary", line)
+	}
+	for _, path := range s.Packages {
+		j.log.Info("streaming package written", "path", path)
+	}
+	for _, path := range s.Thumbnails {
+		j.log.Info("thumbnails written", "path", path)
+	}
+}
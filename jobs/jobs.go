@@ -0,0 +1,294 @@
+// Package jobs keeps a queue of encoding jobs in a JSON file.
+//
+// The daemons ("encoder watch", "encoder serve") add a job for every input they accept and
+// record its progress through the queue, so a restarted daemon picks up the
+// jobs it had not finished. Every change is written to disk before the
+// method making it returns.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"   // Waiting for a worker
+	StatusRunning  Status = "running"  // Being encoded
+	StatusDone     Status = "done"     // Encoded successfully
+	StatusFailed   Status = "failed"   // Encoding failed
+	StatusCanceled Status = "canceled" // Canceled before it finished
+)
+
+// Finished reports whether the job has left the queue for good.
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
+var (
+	// ErrNotFound is returned for an unknown job ID.
+	ErrNotFound = errors.New("job not found")
+
+	// ErrFinished is returned by Cancel for a job that already finished.
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is one input to encode and its progress through the queue.
+type Job struct {
+	ID        string            `json:"id"`
+	Input     string            `json:"input"`
+	Output    string            `json:"output"`
+	Profile   string            `json:"profile,omitempty"`
+	Overrides map[string]string `json:"overrides,omitempty"` // Config keys, e.g. "video.crf": "24"
+
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`    // Why the job failed
+	Attempts int    `json:"attempts"`           // Times the job was started
+	MovedTo  string `json:"moved_to,omitempty"` // Where the source was moved once finished (watch folder)
+
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// clone returns a copy of the job that shares no state with it.
+func (j *Job) clone() *Job {
+	c := *j
+	if j.Overrides != nil {
+		c.Overrides = make(map[string]string, len(j.Overrides))
+		for key, value := range j.Overrides {
+			c.Overrides[key] = value
+		}
+	}
+	return &c
+}
+
+// Store is a job queue persisted to a JSON file. It is safe for concurrent
+// use; the jobs it returns are copies.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	nextID int
+	jobs   []*Job // In creation order
+}
+
+// storeFile is the on-disk layout of a Store.
+type storeFile struct {
+	NextID int    `json:"next_id"`
+	Jobs   []*Job `json:"jobs"`
+}
+
+// Open loads the store at path, creating its directory if needed. Jobs that
+// were running when the previous process stopped are queued again.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, nextID: 1}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse job store %s: %w", path, err)
+	}
+	s.jobs = file.Jobs
+	s.nextID = max(file.NextID, 1)
+
+	requeued := false
+	for _, job := range s.jobs {
+		if job.Status == StatusRunning {
+			job.Status = StatusQueued
+			requeued = true
+		}
+	}
+	if requeued {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Path returns the file the store is kept in.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add queues a job for input and output, assigning its ID. Only the input,
+// output, profile and overrides of job are used.
+func (s *Store) Add(job Job) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := (&Job{
+		ID:        fmt.Sprintf("%06d", s.nextID),
+		Input:     job.Input,
+		Output:    job.Output,
+		Profile:   job.Profile,
+		Overrides: job.Overrides,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}).clone()
+	s.nextID++
+	s.jobs = append(s.jobs, added)
+	if err := s.save(); err != nil {
+		s.jobs = s.jobs[:len(s.jobs)-1]
+		s.nextID--
+		return nil, err
+	}
+	return added.clone(), nil
+}
+
+// Get returns the job with the given ID.
+func (s *Store) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return job.clone(), nil
+}
+
+// List returns all jobs in creation order.
+func (s *Store) List() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*Job, len(s.jobs))
+	for i, job := range s.jobs {
+		list[i] = job.clone()
+	}
+	return list
+}
+
+// Pending returns the queued or running job of input, if there is one.
+func (s *Store) Pending(input string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Input == input && !job.Status.Finished() {
+			return job.clone(), true
+		}
+	}
+	return nil, false
+}
+
+// Claim marks the oldest queued job as running and returns it, or returns
+// nil if no job is queued.
+func (s *Store) Claim() (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Status != StatusQueued {
+			continue
+		}
+		prev := *job
+		job.Status = StatusRunning
+		job.Attempts++
+		job.StartedAt = time.Now()
+		job.Error = ""
+		if err := s.save(); err != nil {
+			*job = prev
+			return nil, err
+		}
+		return job.clone(), nil
+	}
+	return nil, nil
+}
+
+// Finish records the outcome of a running job: done if err is nil, failed
+// otherwise.
+func (s *Store) Finish(id string, err error) error {
+	return s.Update(id, func(job *Job) {
+		job.Status = StatusDone
+		job.Error = ""
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+		job.FinishedAt = time.Now()
+	})
+}
+
+// Cancel marks a queued or running job as canceled and returns it. Stopping
+// a running job is up to the caller.
+func (s *Store) Cancel(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if job.Status.Finished() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrFinished, id, job.Status)
+	}
+	prev := *job
+	job.Status = StatusCanceled
+	job.FinishedAt = time.Now()
+	if err := s.save(); err != nil {
+		*job = prev
+		return nil, err
+	}
+	return job.clone(), nil
+}
+
+// Update applies fn to the job with the given ID and saves the store. The
+// change is undone if it cannot be saved.
+func (s *Store) Update(id string, fn func(job *Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	prev := job.clone()
+	fn(job)
+	if err := s.save(); err != nil {
+		*job = *prev
+		return err
+	}
+	return nil
+}
+
+// find returns the stored job with the given ID. The caller holds s.mu.
+func (s *Store) find(id string) *Job {
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// save writes the store via a temp file and rename, so a crash never leaves
+// a truncated file behind. The caller holds s.mu.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(storeFile{NextID: s.nextID, Jobs: s.jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
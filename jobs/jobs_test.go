@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_Lifecycle(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "state", "jobs.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	a, err := s.Add(Job{Input: "/in/a.mkv", Output: "/out/a.mkv", Overrides: map[string]string{"video.crf": "24"}})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b, _ := s.Add(Job{Input: "/in/b.mkv", Output: "/out/b.mkv", Profile: "web"})
+	if a.ID == b.ID || a.Status != StatusQueued {
+		t.Errorf("Expected distinct queued jobs, got %+v and %+v", a, b)
+	}
+	if _, ok := s.Pending("/in/a.mkv"); !ok {
+		t.Error("Expected a pending job for a.mkv")
+	}
+
+	claimed, err := s.Claim()
+	if err != nil || claimed == nil || claimed.ID != a.ID {
+		t.Fatalf("Expected to claim %s first, got %+v (%v)", a.ID, claimed, err)
+	}
+	if claimed.Status != StatusRunning || claimed.Attempts != 1 {
+		t.Errorf("Expected a running first attempt, got %s attempt %d", claimed.Status, claimed.Attempts)
+	}
+
+	if err := s.Finish(a.ID, errors.New("encoder crashed")); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	got, _ := s.Get(a.ID)
+	if got.Status != StatusFailed || got.Error != "encoder crashed" || got.FinishedAt.IsZero() {
+		t.Errorf("Expected a failed job, got %+v", got)
+	}
+	if _, ok := s.Pending("/in/a.mkv"); ok {
+		t.Error("Expected no pending job for a.mkv once it failed")
+	}
+
+	if _, err := s.Get("999999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_Cancel(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "jobs.json"))
+	a, _ := s.Add(Job{Input: "/in/a.mkv"})
+	b, _ := s.Add(Job{Input: "/in/b.mkv"})
+
+	canceled, err := s.Cancel(a.ID)
+	if err != nil || canceled.Status != StatusCanceled {
+		t.Fatalf("Expected a canceled job, got %+v (%v)", canceled, err)
+	}
+	if _, err := s.Cancel(a.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished for a canceled job, got %v", err)
+	}
+
+	// Canceled jobs are never claimed
+	if claimed, _ := s.Claim(); claimed == nil || claimed.ID != b.ID {
+		t.Errorf("Expected to claim %s, got %+v", b.ID, claimed)
+	}
+}
+
+func TestStore_ReturnsCopies(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "jobs.json"))
+	job, _ := s.Add(Job{Input: "/in/a.mkv", Overrides: map[string]string{"video.crf": "24"}})
+
+	job.Status = StatusDone
+	job.Overrides["video.crf"] = "30"
+	got, _ := s.Get(job.ID)
+	if got.Status != StatusQueued || got.Overrides["video.crf"] != "24" {
+		t.Errorf("Expected the stored job to be unchanged, got %+v", got)
+	}
+}
+
+func TestOpen_RequeuesRunningJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	s, _ := Open(path)
+	a, _ := s.Add(Job{Input: "/in/a.mkv"})
+	b, _ := s.Add(Job{Input: "/in/b.mkv"})
+	s.Claim()
+	s.Finish(a.ID, nil)
+	s.Claim() // b is running when the process stops
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got, _ := reopened.Get(a.ID); got.Status != StatusDone {
+		t.Errorf("Expected %s to stay done, got %s", a.ID, got.Status)
+	}
+	got, _ := reopened.Get(b.ID)
+	if got.Status != StatusQueued || got.Attempts != 1 {
+		t.Errorf("Expected %s queued again after 1 attempt, got %s after %d", b.ID, got.Status, got.Attempts)
+	}
+
+	// IDs keep counting after a restart
+	c, _ := reopened.Add(Job{Input: "/in/c.mkv"})
+	if c.ID == a.ID || c.ID == b.ID {
+		t.Errorf("Expected a new ID, got %s", c.ID)
+	}
+}
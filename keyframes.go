@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoder/ffprobe"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+)
+
+// verifyChunkKeyframes checks that every encoded chunk begins with a
+// keyframe, so the chunks join without artifacts at the boundaries. Chunks
+// that cannot be checked are logged and skipped. In strict mode a chunk
+// that does not start with a keyframe is an error; otherwise a warning.
+func verifyChunkKeyframes(files []string, strictMode bool, out io.Writer, log *slog.Logger) error {
+	var bad []string
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		ok, err := ffprobe.StartsWithKeyframe(path)
+		if err != nil {
+			log.Warn("could not check first frame of chunk", "path", path, "error", err)
+			continue
+		}
+		if !ok {
+			bad = append(bad, filepath.Base(path))
+		}
+	}
+	if len(bad) == 0 {
+		log.Info("all chunks start with a keyframe", "chunks", len(files))
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d chunk(s) do not start with a keyframe: %v", len(bad), bad)
+	if strictMode {
+		return fmt.Errorf("%s", msg)
+	}
+	log.Warn(msg)
+	fmt.Fprintf(out, "  ⚠️  %s\n", msg)
+	return nil
+}
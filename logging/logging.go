@@ -0,0 +1,74 @@
+// Package logging builds the structured loggers of the encoder on log/slog.
+//
+// Log records carry their context as attributes rather than in the message:
+// the job they belong to, the chunk and task type of an encoder command, and
+// the attempt of a job restarted by a daemon. The Key constants name these
+// attributes so every package spells them the same way.
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Attribute keys shared by every logger
+const (
+	KeyJobID    = "job_id"    // Batch, watch or serve job
+	KeyAttempt  = "attempt"   // Attempt of a daemon job (1 = first run)
+	KeyTaskID   = "task_id"   // Orchestrator task
+	KeyTaskType = "task_type" // command.TaskType of a task or builder
+	KeyChunkID  = "chunk_id"  // Chunk an encoder command works on
+)
+
+// Log formats
+const (
+	FormatText = "text" // key=value lines
+	FormatJSON = "json" // One JSON object per line
+)
+
+// StderrTailLines is how many trailing lines of a failed ffmpeg's stderr are
+// logged at debug level.
+const StderrTailLines = 20
+
+// New returns a logger writing records at level and above to w, as text or
+// JSON.
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// Discard returns a logger dropping every record, for code run without a log.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// ParseLevel parses a level name: debug, info, warn or error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q, must be one of: debug, info, warn, error", name)
+	}
+	return level, nil
+}
+
+// Tail returns the last n non-empty lines of output, such as an ffmpeg's
+// stderr. Progress lines ffmpeg ends with a carriage return count as lines.
+func Tail(output []byte, n int) string {
+	output = bytes.ReplaceAll(output, []byte("\r"), []byte("\n"))
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, FormatJSON, slog.LevelInfo).With(KeyJobID, "000042", KeyAttempt, 2)
+	logger.Debug("ffmpeg command", "args", "-i in.mkv")
+	logger.Info("chunk completed", KeyChunkID, 7)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the info record, got %q", buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", lines[0], err)
+	}
+	if record["msg"] != "chunk completed" || record[KeyJobID] != "000042" || record[KeyAttempt] != 2.0 || record[KeyChunkID] != 7.0 {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	buf.Reset()
+	New(&buf, FormatText, slog.LevelDebug).Debug("ffmpeg command", KeyTaskType, "video")
+	if !strings.Contains(buf.String(), "level=DEBUG") || !strings.Contains(buf.String(), "task_type=video") {
+		t.Errorf("Expected a debug text record, got %q", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	stderr := []byte("ffmpeg version 7.1\nInput #0\nframe=  10 fps=0.0\rframe=  20 fps=9.5\r\n\n[libx264] error\nConversion failed!\n")
+	if got, want := Tail(stderr, 3), "frame=  20 fps=9.5\n[libx264] error\nConversion failed!"; got != want {
+		t.Errorf("Tail = %q, want %q", got, want)
+	}
+	if got := Tail([]byte("one line"), 20); got != "one line" {
+		t.Errorf("Expected short output unchanged, got %q", got)
+	}
+}
@@ -2,20 +2,24 @@ package main
 
 import (
 	"context"
-	"encoder/chunker"
-	"encoder/command/audio"
-	"encoder/command/mixing"
-	"encoder/command/segment"
+	"encoder/command"
 	"encoder/command/video"
 	"encoder/concatenator"
 	"encoder/config"
 	"encoder/ffprobe"
+	"encoder/logging"
 	"encoder/models"
 	"encoder/orchestrator"
+	"encoder/preflight"
+	"encoder/quality"
+	"encoder/quality/metric"
+	"encoder/workdir"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -25,109 +29,263 @@ import (
 	"time"
 )
 
-var (
-	logger  *log.Logger
-	logFile *os.File
-)
-
-func initLogger(outputPath string) error {
-	// Create log file in the same directory as output
-	logPath := outputPath + ".log"
-	var err error
-	logFile, err = os.Create(logPath)
+// openLog creates the log file base.log (next to the output for an encode)
+// with the log_format and log_level of cfg, and tells out where it is. The
+// caller closes it with the returned function.
+func openLog(base string, cfg *config.Config, out io.Writer) (*slog.Logger, func(), error) {
+	level, err := logging.ParseLevel(cfg.EffectiveLogLevel())
 	if err != nil {
-		return fmt.Errorf("failed to create log file: %w", err)
+		return nil, nil, err
+	}
+	logPath := base + ".log"
+	file, err := os.Create(logPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create log file: %w", err)
 	}
 
-	// Create logger with timestamp
-	logger = log.New(logFile, "", log.LstdFlags)
-	logger.Printf("===== ENCODING SESSION STARTED =====")
-
-	fmt.Printf("📝 Logging to: %s\n", logPath)
-	return nil
+	log := logging.New(file, cfg.LogFormat, level)
+	log.Info("encoding session started")
+	fmt.Fprintf(out, "📝 Logging to: %s\n", logPath)
+	return log, func() {
+		log.Info("encoding session ended")
+		file.Close()
+	}, nil
 }
 
-func closeLogger() {
-	if logger != nil {
-		logger.Printf("===== ENCODING SESSION ENDED =====")
-	}
-	if logFile != nil {
-		logFile.Close()
+func main() {
+	// Subcommands (e.g. "encoder workdirs gc") are handled before flag parsing
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:]))
 	}
-}
 
-func main() {
-	// Step 1: Load configuration (CLI flags > config file > defaults)
+	// Step 1: Load configuration (CLI flags > environment > config file > defaults)
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Configuration error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitConfig)
+	}
+
+	if cfg.ShowSources {
+		cfg.PrintSources()
+		return
+	}
+
+	// With -output-format json stdout carries only events; everything
+	// printed for people goes to stderr instead
+	var out io.Writer = os.Stdout
+	var events *eventWriter
+	if cfg.JSONOutput() {
+		events = newEventWriter(os.Stdout)
+		out = os.Stderr
+	}
+
+	// Check the configuration against the local ffmpeg build; a dry run
+	// reports what is missing with the commands instead
+	if err := checkFFmpeg(cfg); err != nil && !cfg.DryRun {
+		code := exitCode(err)
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		events.result(code, err)
+		os.Exit(code)
 	}
 
 	// Step 2: Handle dry-run mode
 	if cfg.DryRun {
-		fmt.Println("═══════════════════════════════════════════════════════════")
-		fmt.Println("                      DRY RUN MODE")
-		fmt.Println("═══════════════════════════════════════════════════════════")
-		cfg.PrintConfig()
+		fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+		fmt.Fprintln(out, "                      DRY RUN MODE")
+		fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+		cfg.PrintConfig(out)
+		log := logging.Discard() // Nothing is logged without an encode
+		var commands []dryRunCommand
+		addCommand := func(step, name, output, cmd string, err error) {
+			c := dryRunCommand{Step: step, Name: name, Command: cmd, Output: output}
+			if err != nil {
+				c.Error = err.Error()
+			}
+			commands = append(commands, c)
+		}
 
 		// Show sample commands that would be generated
-		fmt.Println("\n📋 Sample Commands That Would Be Generated:")
-		fmt.Println("───────────────────────────────────────────────────────────")
+		fmt.Fprintln(out, "\n📋 Sample Commands That Would Be Generated:")
+		fmt.Fprintln(out, "───────────────────────────────────────────────────────────")
+
+		// Show where intermediate files would be written
+		jobDir := workdir.Resolve(cfg.WorkDir, cfg.Input, cfg.Output)
+		fmt.Fprintf(out, "\n📁 Work Directory: %s\n", jobDir)
 
 		// Create a dummy chunk for demonstration
 		dummyChunk := &models.Chunk{
 			ChunkID:    1,
-			SourcePath: "tmp/segments/segment_000.mkv",
+			SourcePath: newSegmentBuilder(cfg, filepath.Join(jobDir, "segments"), nil).GetSegmentPath(0),
 			StartTime:  0.0,
 			EndTime:    300.0,
 		}
 
 		// Audio command
-		fmt.Println("\n🎵 Audio Encoding Command:")
-		audioBuilder := audio.NewAudioBuilder(dummyChunk, "tmp/audio/audio_chunk_001.opus")
-		audioBuilder.SetCodec(cfg.Audio.Codec).
-			SetBitrate(cfg.Audio.Bitrate).
-			SetSampleRate(cfg.Audio.SampleRate).
-			SetChannels(cfg.Audio.Channels)
-		if audioCmd, err := audioBuilder.DryRun(); err == nil {
-			fmt.Printf("  %s\n", audioCmd)
+		fmt.Fprintln(out, "\n🎵 Audio Encoding Command:")
+		audioBuilder := newChunkAudioBuilder(cfg, dummyChunk, filepath.Join(jobDir, "audio", "audio_chunk_001."+audioChunkExt(cfg)))
+		audioCmd, err := audioBuilder.DryRun()
+		if err == nil {
+			fmt.Fprintf(out, "  %s\n", audioCmd)
+		} else {
+			fmt.Fprintf(out, "  ❌ %v\n", err)
+		}
+		addCommand("audio", "", audioBuilder.GetOutputPath(), audioCmd, err)
+		if cfg.Audio.Gapless {
+			fmt.Fprintln(out, "\n🎵 Final Audio Encoding Command (joined chunks):")
+			finalPath := filepath.Join(jobDir, "final_audio."+audioExt(cfg))
+			finalBuilder := newFinalAudioBuilder(cfg, filepath.Join(jobDir, "joined_audio.flac"), finalPath)
+			finalCmd, err := finalBuilder.DryRun()
+			if err == nil {
+				fmt.Fprintf(out, "  %s\n", finalCmd)
+			} else {
+				fmt.Fprintf(out, "  ❌ %v\n", err)
+			}
+			addCommand("final_audio", "", finalPath, finalCmd, err)
 		}
 
-		// Video command
-		fmt.Println("\n🎬 Video Encoding Command:")
-		videoBuilder := video.NewVideoBuilder(dummyChunk, "tmp/video/video_chunk_001.mkv")
-		videoBuilder.SetCodec(cfg.Video.Codec).
-			SetCRF(cfg.Video.CRF).
-			SetPreset(cfg.Video.Preset)
-		if cfg.Video.Bitrate != "" {
-			videoBuilder.SetBitrate(cfg.Video.Bitrate)
+		// Deinterlacing, crop and HDR handling depend on the source; analyze it if ffprobe is available
+		var src *sourceAnalysis
+		probeResult, probeErr := ffprobe.Probe(cfg.Input)
+		if probeErr == nil {
+			src = analyzeSource(context.Background(), cfg, probeResult, out, log)
+			dummyChunk.FrameRate = src.FrameRate
+			if duration, err := probeResult.GetDuration(); err == nil {
+				if resolved, err := resolveTargetSize(cfg, duration, len(probeResult.GetAudioStreams()) > 0, out, log); err == nil {
+					cfg = resolved
+				} else {
+					fmt.Fprintf(out, "  ❌ %v\n", err)
+				}
+			}
+		} else if cfg.Video.Crop == "auto" {
+			fmt.Fprintf(out, "\n  ⚠️  Source could not be probed, crop detection skipped: %v\n", probeErr)
+		} else {
+			// A manual crop does not need the source
+			crop, _ := resolveCrop(context.Background(), cfg, nil, 0, out, log)
+			src = &sourceAnalysis{Crop: crop}
+		}
+		if probeErr != nil {
+			probeResult = &ffprobe.ProbeResult{}
+		}
+
+		// Video commands, one per rendition of the ladder
+		renditions := newVideoRenditions(cfg, filepath.Join(jobDir, "video"), jobDir)
+		capabilityCommands := []command.Command{audioBuilder}
+		for _, r := range renditions {
+			if r.Name == "" {
+				fmt.Fprintln(out, "\n🎬 Video Encoding Command:")
+			} else {
+				fmt.Fprintf(out, "\n🎬 Video Encoding Command (%s → %s):\n", r.Name, r.Output)
+			}
+			rcfg := r.Config
+			videoBuilder := video.NewVideoBuilder(dummyChunk, filepath.Join(r.Dir, "video_chunk_001."+videoExt(rcfg)))
+			videoBuilder.SetCodec(rcfg.Video.Codec).
+				SetCRF(rcfg.Video.CRF).
+				SetPreset(rcfg.Video.Preset)
+			if rcfg.Video.FrameRate > 0 {
+				videoBuilder.SetFrameRate(rcfg.Video.FrameRate)
+			}
+
+			videoBuilder.SetEncoderParams(rcfg.Video.EncoderParams)
+			videoBuilder.SetKeyframeInterval(keyframeInterval(rcfg))
+			applySourceAnalysis(videoBuilder, rcfg, src)
+			applyRateControl(videoBuilder, rcfg)
+			capabilityCommands = append(capabilityCommands, videoBuilder)
+
+			passes := []*video.VideoBuilder{videoBuilder}
+			if twoPass(rcfg) {
+				first := videoBuilder.TwoPass(filepath.Join(r.Dir, "passlogs", "chunk_001"))
+				passes = []*video.VideoBuilder{first, videoBuilder}
+			}
+			for _, builder := range passes {
+				videoCmd, err := builder.DryRun()
+				if err == nil {
+					fmt.Fprintf(out, "  %s\n", videoCmd)
+				} else {
+					fmt.Fprintf(out, "  ❌ %v\n", err)
+				}
+				addCommand("video", r.Name, builder.GetOutputPath(), videoCmd, err)
+			}
+		}
+		if cfg.Video.TargetQuality > 0 {
+			metric, target := resolveQualityTarget(cfg, out, log)
+			fmt.Fprintf(out, "  Target quality: %s %s, probing CRF %v on %d×%.0fs windows per chunk\n",
+				metric, metric.Format(target), qualityProbeCRFs(cfg), quality.DefaultWindows, quality.DefaultWindowLength)
 		}
-		if cfg.Video.FrameRate > 0 {
-			videoBuilder.SetFrameRate(cfg.Video.FrameRate)
+		if cfg.Metrics.Enabled {
+			fmt.Fprintf(out, "  Metrics: %v per chunk, report to %s\n", resolveMetrics(cfg, out, log), metricsReportPath(cfg))
 		}
 
-		// Add SVT-AV1 specific parameters to reduce memory usage
-		if cfg.Video.Codec == "libsvtav1" {
-			videoBuilder.AddExtraArgs(
-				"-svtav1-params", "lp=4:pin=1",
-			)
+		// Mux commands, one per output
+		fmt.Fprintln(out, "\n🎞️  Mux Commands:")
+		for _, r := range renditions {
+			builder := newMuxBuilder(cfg, r.Final, filepath.Join(jobDir, "final_audio."+audioExt(cfg)), r.Output, probeResult, log)
+			muxCmd, err := builder.DryRun()
+			if err == nil {
+				fmt.Fprintf(out, "  %s\n", muxCmd)
+			}
+			addCommand("mux", r.Name, r.Output, muxCmd, err)
 		}
 
-		if videoCmd, err := videoBuilder.DryRun(); err == nil {
-			fmt.Printf("  %s\n", videoCmd)
+		// Packaging commands
+		if cfg.Packaging.Enabled() {
+			fmt.Fprintln(out, "\n📦 Packaging Commands:")
+			for _, builder := range newPackagingBuilders(cfg, renditions, filepath.Join(jobDir, "final_audio."+audioExt(cfg)), probeResult) {
+				packageCmd, err := builder.DryRun()
+				if err == nil {
+					fmt.Fprintf(out, "  %s\n  → %s\n", packageCmd, builder.GetOutputPath())
+				} else {
+					fmt.Fprintf(out, "  ❌ %v\n", err)
+				}
+				addCommand("packaging", "", builder.GetOutputPath(), packageCmd, err)
+			}
 		}
 
-		fmt.Println("\n✓ Configuration is valid. No encoding will be performed.")
+		// Thumbnail commands
+		if cfg.Thumbnails.Enabled() {
+			fmt.Fprintln(out, "\n🖼️  Thumbnail Commands:")
+			duration, _ := probeResult.GetDuration()
+			for _, builder := range newThumbnailBuilders(cfg, probeResult, src, duration, log) {
+				thumbnailCmd, err := builder.DryRun()
+				if err == nil {
+					fmt.Fprintf(out, "  %s\n  → %s\n", thumbnailCmd, builder.GetOutputPath())
+				} else {
+					fmt.Fprintf(out, "  ❌ %v\n", err)
+				}
+				addCommand("thumbnail", "", builder.GetOutputPath(), thumbnailCmd, err)
+				capabilityCommands = append(capabilityCommands, builder)
+			}
+		}
+
+		// Check the generated commands against the local ffmpeg build
+		fmt.Fprintln(out, "\n🔧 FFmpeg Capabilities:")
+		missing := printCapabilities(cfg, out, capabilityCommands...)
+		events.dryRun(jobDir, commands, missing)
+		if missing > 0 {
+			err := fmt.Errorf("%d required feature(s) missing from the local ffmpeg build", missing)
+			fmt.Fprintf(out, "\n❌ %v.\n", err)
+			events.result(exitEnvironment, err)
+			os.Exit(exitEnvironment)
+		}
+
+		fmt.Fprintln(out, "\n✓ Configuration is valid. No encoding will be performed.")
+		events.result(exitOK, nil)
 		return
 	}
 
-	// Step 3: Initialize logger
-	if err := initLogger(cfg.Output); err != nil {
+	// Step 3: Open the log next to the output
+	log, closeLog, err := openLog(cfg.Output, cfg, out)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Logger initialization error: %v\n", err)
-		os.Exit(1)
+		events.result(exitEnvironment, err)
+		os.Exit(exitEnvironment)
+	}
+	defer closeLog()
+	if err := enableMetrics(cfg.MetricsAddr, out); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		events.result(exitEnvironment, err)
+		closeLog()
+		os.Exit(exitEnvironment)
 	}
-	defer closeLogger()
 
 	// Step 4: Set up context with cancellation for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -138,286 +296,298 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		fmt.Println("\n\n⚠️  Interrupt received, cleaning up...")
-		logger.Println("INTERRUPT: User cancelled encoding")
+		fmt.Fprintln(out, "\n\n⚠️  Interrupt received, cleaning up...")
+		log.Warn("interrupted, cancelling encoding")
 		cancel()
 	}()
 
 	// Step 6: Run the encoding pipeline
-	if err := runPipeline(ctx, cfg); err != nil {
+	events.started(cfg.Input, cfg.Output, cfg.Mode)
+	if err := runPipeline(ctx, cfg, out, log, events.monitor()); err != nil {
+		// os.Exit skips deferred calls
+		log.Error("pipeline failed", "error", err)
+		closeLog()
 		// Check if it was a cancellation
+		code := exitCode(err)
 		if ctx.Err() == context.Canceled {
-			fmt.Println("\n⚠️  Encoding cancelled by user")
-			os.Exit(130) // Standard exit code for SIGINT
+			fmt.Fprintln(out, "\n⚠️  Encoding cancelled by user")
+			code = exitCancelled
+		} else {
+			fmt.Fprintf(os.Stderr, "\n❌ Pipeline error: %v\n", err)
 		}
-		fmt.Fprintf(os.Stderr, "\n❌ Pipeline error: %v\n", err)
-		os.Exit(1)
+		events.result(code, err)
+		os.Exit(code)
 	}
 
-	fmt.Println("\n✅ Encoding completed successfully!")
+	fmt.Fprintln(out, "\n✅ Encoding completed successfully!")
+	events.result(exitOK, nil)
 }
 
-// runPipeline executes the complete encoding workflow
-func runPipeline(ctx context.Context, cfg *config.Config) error {
-	startTime := time.Now()
+// checkFFmpeg probes the local ffmpeg build and checks the configuration
+// against it. Without a usable ffmpeg the checks are skipped.
+func checkFFmpeg(cfg *config.Config) error {
+	caps, _ := preflight.Detect()
+	return preflight.Check(cfg, caps)
+}
 
-	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
-	fmt.Println("║                   ENCODER - PIPELINE START                     ║")
-	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
-	fmt.Printf("Input:  %s\n", cfg.Input)
-	fmt.Printf("Output: %s\n", cfg.Output)
-	fmt.Printf("Mode:   %s\n", cfg.Mode)
-	fmt.Println()
-
-	// Create tmp directory next to output file with subdirectories
-	outputDir := filepath.Dir(cfg.Output)
-	tmpDir := filepath.Join(outputDir, "tmp")
-	segmentDir := filepath.Join(tmpDir, "segments")
-	audioDir := filepath.Join(tmpDir, "audio")
-	videoDir := filepath.Join(tmpDir, "video")
-
-	for _, dir := range []string{tmpDir, segmentDir, audioDir, videoDir} {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+// printCapabilities reports the detected ffmpeg build to out and checks the
+// configuration and the filters the given commands use against it. Returns
+// the number of missing features.
+func printCapabilities(cfg *config.Config, out io.Writer, cmds ...command.Command) int {
+	caps, _ := preflight.Detect()
+	if caps == nil {
+		fmt.Fprintln(out, "  ⚠️  ffmpeg not found in PATH - encoder and filter checks skipped")
+		return 0
+	}
+
+	source := "probed"
+	if caps.FromCache {
+		source = "cached"
+	}
+	fmt.Fprintf(out, "  ffmpeg %s (%s, %s)\n", caps.Version, caps.Binary, source)
+	fmt.Fprintf(out, "  %d encoders, %d filters, %d muxers\n", len(caps.Encoders), len(caps.Filters), len(caps.Muxers))
+
+	missing := 0
+	var missingErr *preflight.MissingError
+	if errors.As(preflight.Check(cfg, caps), &missingErr) {
+		for _, feature := range missingErr.Missing {
+			fmt.Fprintf(out, "  ❌ %s\n", feature)
+			missing++
 		}
 	}
-
-	// PHASE 1: Media Analysis
-	fmt.Println("📊 Phase 1: Media Analysis")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-
-	probeResult, err := ffprobe.Probe(cfg.Input)
-	if err != nil {
-		return fmt.Errorf("media analysis failed: %w", err)
-	}
-
-	duration, err := probeResult.GetDuration()
-	if err != nil {
-		return fmt.Errorf("failed to get media duration: %w", err)
+	reported := make(map[string]bool) // Filters preflight.Check already listed
+	for _, filter := range preflight.RequiredFilters(cfg) {
+		reported[filter] = true
 	}
-
-	hasAudio := len(probeResult.GetAudioStreams()) > 0
-	hasVideo := len(probeResult.GetVideoStreams()) > 0
-
-	fmt.Printf("  Duration:       %.2f seconds\n", duration)
-	fmt.Printf("  Format:         %s\n", probeResult.Format.FormatLongName)
-	fmt.Printf("  Audio streams:  %d\n", len(probeResult.GetAudioStreams()))
-	fmt.Printf("  Video streams:  %d\n", len(probeResult.GetVideoStreams()))
-	if probeResult.GetChapterCount() > 0 {
-		fmt.Printf("  Chapters:       %d\n", probeResult.GetChapterCount())
+	for _, cmd := range cmds {
+		fr, ok := cmd.(command.FilterRequirer)
+		if !ok {
+			continue
+		}
+		for _, filter := range fr.RequiredFilters() {
+			if reported[filter] {
+				continue
+			}
+			if err := caps.CheckFilter(filter); err != nil {
+				fmt.Fprintf(out, "  ❌ %s: %v\n", cmd.GetTaskType(), err)
+				missing++
+			}
+		}
 	}
-	fmt.Println()
-
-	if !hasAudio && !hasVideo {
-		return fmt.Errorf("no audio or video streams found in input file")
+	if missing == 0 {
+		fmt.Fprintf(out, "  ✓ Encoders %s, %s and all filters available\n", strings.Join(cfg.Video.Codecs(), ", "), cfg.Audio.Codec)
 	}
+	return missing
+}
 
-	// PHASE 2: Chunking
-	fmt.Println("✂️  Phase 2: Chunking")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-
-	chunkCreator := chunker.NewChunker(cfg.Input)
+// runPipeline executes the complete encoding workflow, printing its progress
+// to out and logging to log. monitor (optional) follows its phases and tasks;
+// ctx cancels it.
+func runPipeline(ctx context.Context, cfg *config.Config, out io.Writer, log *slog.Logger, monitor *pipelineMonitor) error {
+	startTime := time.Now()
 
-	// Determine chunking strategy: chapters first, then time-based
-	hasChapters := probeResult.GetChapterCount() > 0
-	useChapters := hasChapters
+	fmt.Fprintln(out, "╔════════════════════════════════════════════════════════════════╗")
+	fmt.Fprintln(out, "║                   ENCODER - PIPELINE START                     ║")
+	fmt.Fprintln(out, "╚════════════════════════════════════════════════════════════════╝")
+	fmt.Fprintf(out, "Input:  %s\n", cfg.Input)
+	fmt.Fprintf(out, "Output: %s\n", cfg.Output)
+	fmt.Fprintf(out, "Mode:   %s\n", cfg.Mode)
 
-	if useChapters {
-		fmt.Printf("  Strategy:   Chapter-based (%d chapters detected)\n", probeResult.GetChapterCount())
-		chunkCreator.SetUseChapters(true)
-	} else {
-		fmt.Printf("  Strategy:   Time-based (%.1f second chunks)\n", float64(cfg.ChunkDuration))
-		chunkCreator.SetChunkDuration(float64(cfg.ChunkDuration)).SetUseChapters(false)
+	// Acquire a per-job work directory (locked against concurrent jobs)
+	job, err := openJob(cfg, out, log)
+	if err != nil {
+		return err
 	}
+	defer job.close()
+	fmt.Fprintf(out, "Work:   %s\n", job.tmpDir)
+	fmt.Fprintln(out)
 
-	chunks, err := chunkCreator.CreateChunks(probeResult)
+	// PHASE 1: Media Analysis
+	monitor.phase(phaseAnalysis)
+	fmt.Fprintln(out, "📊 Phase 1: Media Analysis")
+	fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	err = job.analyze(ctx)
+	fmt.Fprintln(out)
 	if err != nil {
-		return fmt.Errorf("chunking failed: %w", err)
+		return err
 	}
+	cfg = job.cfg
 
-	if err := chunker.ValidateChunks(chunks); err != nil {
-		return fmt.Errorf("chunk validation failed: %w", err)
-	}
+	// PHASE 2: Chunking
+	monitor.phase(phaseChunking)
+	fmt.Fprintln(out, "✂️  Phase 2: Chunking")
+	fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-	// Calculate average chunk duration
-	avgDuration := 0.0
-	if len(chunks) > 0 {
-		for _, chunk := range chunks {
-			avgDuration += chunk.EndTime - chunk.StartTime
-		}
-		avgDuration /= float64(len(chunks))
+	if err := job.chunk(); err != nil {
+		return err
 	}
-
-	fmt.Printf("  Created:    %d chunks (avg %.1fs each)\n", len(chunks), avgDuration)
-	fmt.Println()
+	monitor.plan(job.chunks, job.useChapters)
+	fmt.Fprintln(out)
 
 	// PHASE 3: Pre-split segments (optional, for performance)
-	if cfg.PreSplit && useChapters {
-		fmt.Println("✂️  Phase 3: Pre-splitting Segments")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if job.preSplits() {
+		monitor.phase(phasePreSplit)
+		fmt.Fprintln(out, "✂️  Phase 3: Pre-splitting Segments")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-		if err := preSplitSegmentsWithCache(cfg, probeResult, chunks, segmentDir); err != nil {
-			return fmt.Errorf("segment splitting failed: %w", err)
+		if err := job.preSplit(); err != nil {
+			return err
 		}
-		fmt.Println()
+		fmt.Fprintln(out)
 	}
 
 	// PHASE 4: Set up DAG Orchestrator
-	fmt.Println("⚙️  Phase 4: Orchestrator Setup")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	monitor.phase(phaseSetup)
+	fmt.Fprintln(out, "⚙️  Phase 4: Orchestrator Setup")
+	fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	constraints := buildResourceConstraints(cfg)
 	orch := orchestrator.NewDAGOrchestrator(constraints)
+	orch.SetContext(ctx)
+	orch.SetLogger(log)
 
-	fmt.Printf("  Mode:      %s\n", cfg.Mode)
-	fmt.Printf("  Workers:   %d\n", cfg.Workers)
-	fmt.Println()
+	fmt.Fprintf(out, "  Mode:      %s\n", cfg.Mode)
+	fmt.Fprintf(out, "  Workers:   %d\n", cfg.Workers)
+	fmt.Fprintln(out)
 
 	// PHASE 5: Audio Encoding
-	var audioFiles []string
-	if hasAudio {
-		fmt.Println("🎵 Phase 5: Audio Encoding")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if job.hasAudio {
+		monitor.phase(phaseAudio)
+		fmt.Fprintln(out, "🎵 Phase 5: Audio Encoding")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-		audioFiles, err = encodeAudio(cfg, chunks, audioDir, orch)
+		job.audioFiles, err = encodeAudio(cfg, job.chunks, job.audioDir, orch, out, log, monitor)
 		if err != nil {
 			return fmt.Errorf("audio encoding failed: %w", err)
 		}
-		fmt.Println()
+		fmt.Fprintln(out)
 	}
 
 	// PHASE 6: Video Encoding
-	var videoFiles []string
-	if hasVideo {
-		fmt.Println("🎬 Phase 6: Video Encoding")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if job.hasVideo {
+		monitor.phase(phaseVideo)
+		fmt.Fprintln(out, "🎬 Phase 6: Video Encoding")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		if len(cfg.Video.Renditions) > 0 {
+			names := make([]string, len(job.renditions))
+			for i, r := range job.renditions {
+				names[i] = r.Name
+			}
+			fmt.Fprintf(out, "  Renditions: %s\n", strings.Join(names, ", "))
+		}
 
-		// Create a new orchestrator for video encoding
+		// Create a new orchestrator for video encoding; thumbnails are
+		// generated from the source in the same run
 		videoOrch := orchestrator.NewDAGOrchestrator(constraints)
-		videoFiles, err = encodeVideo(cfg, chunks, videoDir, videoOrch)
-		if err != nil {
+		videoOrch.SetContext(ctx)
+		videoOrch.SetLogger(log)
+		if err := job.addThumbnailTasks(videoOrch, ""); err != nil {
+			return err
+		}
+		if err := encodeVideo(job.renditions, job.chunks, job.src, videoOrch, out, log, monitor); err != nil {
 			return fmt.Errorf("video encoding failed: %w", err)
 		}
-		fmt.Println()
+		job.collectThumbnails()
+		fmt.Fprintln(out)
 	}
 
 	// PHASE 7: Concatenation
-	fmt.Println("🔗 Phase 7: Concatenation")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	monitor.phase(phaseConcat)
+	fmt.Fprintln(out, "🔗 Phase 7: Concatenation")
+	fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-	var finalAudioPath, finalVideoPath string
-	concatStart := time.Now()
+	if err := job.concat(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
 
-	if len(audioFiles) > 0 {
-		finalAudioPath = filepath.Join(tmpDir, "final_audio.opus")
-		logger.Printf("CONCAT: Starting audio concatenation of %d chunks", len(audioFiles))
-		audioConcatStart := time.Now()
-		if err := concatenateFiles(audioFiles, finalAudioPath, cfg.StrictMode); err != nil {
-			logger.Printf("CONCAT: Audio concatenation failed: %v", err)
-			return fmt.Errorf("audio concatenation failed: %w", err)
-		}
-		elapsed := time.Since(audioConcatStart).Seconds()
-		logger.Printf("CONCAT: Audio concatenated %d chunks in %.2fs", len(audioFiles), elapsed)
-		fmt.Printf("  ✓ Audio concatenated (%.2fs)\n", elapsed)
+	// PHASE 8: Mixing (if both audio and video)
+	monitor.phase(phaseMux)
+	if job.hasAudio && job.hasVideo {
+		fmt.Fprintln(out, "🎞️  Phase 8: Mixing Audio + Video")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	}
+	if err := job.mux(); err != nil {
+		return err
 	}
+	fmt.Fprintln(out)
 
-	if len(videoFiles) > 0 {
-		// Use .mkv for final video (better AV1 compatibility)
-		finalVideoPath = filepath.Join(tmpDir, "final_video.mkv")
-		logger.Printf("CONCAT: Starting video concatenation of %d chunks", len(videoFiles))
-		videoConcatStart := time.Now()
-		if err := concatenateFiles(videoFiles, finalVideoPath, cfg.StrictMode); err != nil {
-			logger.Printf("CONCAT: Video concatenation failed: %v", err)
-			return fmt.Errorf("video concatenation failed: %w", err)
-		}
-		elapsed := time.Since(videoConcatStart).Seconds()
-		logger.Printf("CONCAT: Video concatenated %d chunks in %.2fs", len(videoFiles), elapsed)
-		fmt.Printf("  ✓ Video concatenated (%.2fs)\n", elapsed)
+	// PHASE 9: Quality Metrics (optional)
+	if job.measures() {
+		monitor.phase(phaseMetrics)
+		fmt.Fprintln(out, "📏 Phase 9: Quality Metrics")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		job.measure()
+		fmt.Fprintln(out)
 	}
 
-	totalConcatTime := time.Since(concatStart).Seconds()
-	logger.Printf("CONCAT: Total concatenation time: %.2fs", totalConcatTime)
-	fmt.Println()
+	// PHASE 10: Streaming Packaging (optional)
+	if job.packs() {
+		monitor.phase(phasePackaging)
+		fmt.Fprintln(out, "📦 Phase 10: Streaming Packaging")
+		fmt.Fprintln(out, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		if err := job.pack(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
 
-	// PHASE 8: Mixing (if both audio and video)
-	if hasAudio && hasVideo {
-		fmt.Println("🎞️  Phase 8: Mixing Audio + Video")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		logger.Printf("MIXING: Starting audio/video mux to %s", cfg.Output)
-		mixStart := time.Now()
-
-		if err := mixAudioVideo(finalAudioPath, finalVideoPath, cfg.Output); err != nil {
-			logger.Printf("MIXING: Failed: %v", err)
-			return fmt.Errorf("mixing failed: %w", err)
-		}
-		elapsed := time.Since(mixStart).Seconds()
-		logger.Printf("MIXING: Complete in %.2fs", elapsed)
-		fmt.Printf("  ✓ Mixed output (%.2fs)\n", elapsed)
-		fmt.Println()
-	} else if hasAudio {
-		// Audio only - copy to output
-		logger.Printf("FINALIZE: Copying audio to output: %s", cfg.Output)
-		if err := copyFile(finalAudioPath, cfg.Output); err != nil {
-			logger.Printf("FINALIZE: Failed to copy audio: %v", err)
-			return fmt.Errorf("failed to copy audio to output: %w", err)
-		}
-		logger.Printf("FINALIZE: Audio output written to %s", cfg.Output)
-		fmt.Printf("  ✓ Output: %s\n", cfg.Output)
-		fmt.Println()
-	} else if hasVideo {
-		// Video only - copy to output
-		logger.Printf("FINALIZE: Copying video to output: %s", cfg.Output)
-		if err := copyFile(finalVideoPath, cfg.Output); err != nil {
-			logger.Printf("FINALIZE: Failed to copy video: %v", err)
-			return fmt.Errorf("failed to copy video to output: %w", err)
-		}
-		logger.Printf("FINALIZE: Video output written to %s", cfg.Output)
-		fmt.Printf("  ✓ Output: %s\n", cfg.Output)
-		fmt.Println()
-	}
-
-	// PHASE 8: Final Report with bitrate info
+	// PHASE 11: Final Report with bitrate info
 	elapsed := time.Since(startTime)
+	outputSize := job.outputSize()
+	overallSpeed := job.duration / elapsed.Seconds()
+	job.logSummary(elapsed)
+	monitor.done(job.summary(elapsed))
 
-	// Get output file info
-	outputInfo, err := os.Stat(cfg.Output)
-	outputSize := int64(0)
-	if err == nil {
-		outputSize = outputInfo.Size()
+	// Minimal terminal output
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+	fmt.Fprintln(out, "                     ✅ SUCCESS!")
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
+	fmt.Fprintf(out, "  Output:      %s\n", cfg.Output)
+	fmt.Fprintf(out, "  Size:        %.2f MB\n", float64(outputSize)/(1024*1024))
+	fmt.Fprintf(out, "  Duration:    %.2fs\n", job.duration)
+	fmt.Fprintf(out, "  Total time:  %.2fs (%.2fx realtime)\n", elapsed.Seconds(), overallSpeed)
+	fmt.Fprintf(out, "  Chunks:      %d\n", len(job.chunks))
+	if len(job.videoFiles()) > 0 {
+		for i, r := range job.renditions[1:] {
+			label := ""
+			if i == 0 {
+				label = "Renditions:"
+			}
+			fmt.Fprintf(out, "  %-12s %s\n", label, r.Output)
+		}
+		if report := job.renditions[0].report; report != nil {
+			fmt.Fprintf(out, "  Quality:     %s\n", report.Summary())
+		}
 	}
-
-	// Calculate bitrate (bits per second)
-	bitrateBps := float64(outputSize*8) / duration
-	bitrateKbps := bitrateBps / 1000
-	overallSpeed := duration / elapsed.Seconds()
-
-	// Log final summary
-	logger.Printf("===== ENCODING COMPLETE =====")
-	logger.Printf("Output: %s", cfg.Output)
-	logger.Printf("Size: %.2f MB", float64(outputSize)/(1024*1024))
-	logger.Printf("Duration: %.2fs", duration)
-	logger.Printf("Bitrate: %.0f kbps", bitrateKbps)
-	logger.Printf("Total time: %.2fs", elapsed.Seconds())
-	logger.Printf("Speed: %.2fx realtime", overallSpeed)
-	logger.Printf("Chunks: %d", len(chunks))
-	if len(audioFiles) > 0 {
-		logger.Printf("Audio: %d chunks encoded", len(audioFiles))
+	if job.metricsReport != nil {
+		for i, line := range job.metricsReport.Lines() {
+			label := ""
+			if i == 0 {
+				label = "Metrics:"
+			}
+			fmt.Fprintf(out, "  %-12s %s\n", label, line)
+		}
 	}
-	if len(videoFiles) > 0 {
-		logger.Printf("Video: %d chunks encoded", len(videoFiles))
+	for i, path := range job.packages {
+		label := ""
+		if i == 0 {
+			label = "Streaming:"
+		}
+		fmt.Fprintf(out, "  %-12s %s\n", label, path)
 	}
-
-	// Minimal terminal output
-	fmt.Println("═══════════════════════════════════════════════════════════")
-	fmt.Println("                     ✅ SUCCESS!")
-	fmt.Println("═══════════════════════════════════════════════════════════")
-	fmt.Printf("  Output:      %s\n", cfg.Output)
-	fmt.Printf("  Size:        %.2f MB\n", float64(outputSize)/(1024*1024))
-	fmt.Printf("  Duration:    %.2fs\n", duration)
-	fmt.Printf("  Total time:  %.2fs (%.2fx realtime)\n", elapsed.Seconds(), overallSpeed)
-	fmt.Printf("  Chunks:      %d\n", len(chunks))
-	fmt.Println("═══════════════════════════════════════════════════════════")
+	for i, path := range job.thumbnails {
+		label := ""
+		if i == 0 {
+			label = "Thumbnails:"
+		}
+		fmt.Fprintf(out, "  %-12s %s\n", label, path)
+	}
+	fmt.Fprintln(out, "═══════════════════════════════════════════════════════════")
 
 	return nil
 }
@@ -449,8 +619,7 @@ func buildResourceConstraints(cfg *config.Config) []orchestrator.ResourceConstra
 }
 
 // encodeAudio encodes all audio chunks in parallel
-func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orch *orchestrator.DAGOrchestrator) ([]string, error) {
-	outputFiles := make([]string, len(chunks))
+func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orch *orchestrator.DAGOrchestrator, out io.Writer, log *slog.Logger, monitor *pipelineMonitor) ([]string, error) {
 	startTime := time.Now()
 
 	// Calculate total duration to encode
@@ -459,23 +628,6 @@ func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 		totalDuration += chunk.EndTime - chunk.StartTime
 	}
 
-	// Try to load cached audio encoding manifest
-	cachedManifest := (*EncodingManifest)(nil)
-	cachedChunks := make(map[uint]string) // ChunkID -> OutputPath
-	_, err := os.Stat(cfg.Input)
-	if err == nil {
-		cachedManifest, err = loadEncodingManifest(tempDir, "audio")
-		if err == nil && validateEncodingManifest(cfg, cachedManifest, len(chunks), "audio") {
-			// Use cached manifest
-			for chunkID, path := range cachedManifest.EncodedChunks {
-				if id, err := strconv.ParseUint(chunkID, 10, 32); err == nil {
-					cachedChunks[uint(id)] = path
-				}
-			}
-			logger.Printf("AUDIO: Using cached manifest with %d already-encoded chunks", len(cachedChunks))
-		}
-	}
-
 	// Progress tracking via channel - no race conditions!
 	// This is the proper Go way to coordinate between goroutines
 	type progressUpdate struct {
@@ -493,7 +645,7 @@ func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 	var latestEncoderFrame int64
 	var latestEncoderTime string
 
-	logger.Printf("AUDIO: Starting encoding of %d chunks (%.2f seconds total)", len(chunks), totalDuration)
+	log.Info("audio encoding started", "chunks", len(chunks), "duration_seconds", totalDuration)
 
 	// Function to log progress - reads from channel
 	logProgress := func(completed int) {
@@ -515,19 +667,19 @@ func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 		}
 
 		// Log detailed progress with frame/time info
+		attrs := []interface{}{"completed", completed, "total", len(chunks), "rate", rate,
+			"overall_speed", overallSpeed, "speed", latestEncoderSpeed, "eta_seconds", eta}
 		if latestEncoderTime != "" {
-			logger.Printf("AUDIO: chunk=%d/%d rate=%.1f/s overall=%.2fx current=%.2fx time=%s frame=%d eta=%.0fs",
-				completed, len(chunks), rate, overallSpeed, latestEncoderSpeed, latestEncoderTime, latestEncoderFrame, eta)
-		} else {
-			logger.Printf("AUDIO: chunk=%d/%d rate=%.1f/s overall=%.2fx current=%.2fx eta=%.0fs",
-				completed, len(chunks), rate, overallSpeed, latestEncoderSpeed, eta)
+			attrs = append(attrs, "time", latestEncoderTime, "frame", latestEncoderFrame)
 		}
+		log.Info("audio progress", attrs...)
 	}
 
 	// Set callback for when chunks complete and progress updates
 	orch.SetProgressCallback(func(completedCount, total int, task *orchestrator.Task) {
-		logger.Printf("AUDIO: Completed chunk %d/%d (task: %s)", completedCount, total, task.ID)
+		log.Info("audio chunk completed", logging.KeyTaskID, task.ID, "completed", completedCount, "total", total)
 		logProgress(completedCount)
+		monitor.task(task)
 	})
 
 	// Start a ticker to log progress every 2 seconds
@@ -549,67 +701,33 @@ func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 	}()
 
 	// Create encoding tasks
-	resourceType := orchestrator.ResourceCPU
-	if cfg.Mode == "gpu-only" {
-		resourceType = orchestrator.ResourceGPUEncode
-	}
-
-	tasksAdded := 0
-	for i, chunk := range chunks {
-		outputPath := filepath.Join(tempDir, fmt.Sprintf("audio_chunk_%03d.opus", chunk.ChunkID))
-		outputFiles[i] = outputPath
-
-		// Skip if already cached and file exists
-		if cachedPath, exists := cachedChunks[chunk.ChunkID]; exists {
-			if _, err := os.Stat(cachedPath); err == nil {
-				logger.Printf("AUDIO: Skipping chunk %d (using cached: %s)", chunk.ChunkID, cachedPath)
-				outputFiles[i] = cachedPath
-				continue
-			}
-		}
-
-		// Capture chunk reference and index in closure (by value)
-		localChunk := chunk
-		builder := audio.NewAudioBuilder(localChunk, outputPath)
-		builder.SetCodec(cfg.Audio.Codec).
-			SetBitrate(cfg.Audio.Bitrate).
-			SetSampleRate(cfg.Audio.SampleRate).
-			SetChannels(cfg.Audio.Channels).
-			SetProgressCallback(func(progress *models.EncodingProgress) {
-				// Safely update encoder stats (these are only read during logging)
-				// No race condition here because we're not using these for control flow
-				latestEncoderSpeed = progress.Speed
-				latestEncoderFrame = progress.Frame
-				latestEncoderTime = progress.CurrentTime
-			})
-
-		task := &orchestrator.Task{
-			ID:           fmt.Sprintf("audio_%d", localChunk.ChunkID),
-			Command:      builder,
-			Dependencies: []string{},
-			Resource:     resourceType,
-		}
-
-		if err := orch.AddTask(task); err != nil {
-			return nil, fmt.Errorf("failed to add task: %w", err)
-		}
-		tasksAdded++
+	outputFiles, tasks := audioTasks(cfg, chunks, tempDir, "", log, func(taskID string, progress *models.EncodingProgress) {
+		// Safely update encoder stats (these are only read during logging)
+		// No race condition here because we're not using these for control flow
+		latestEncoderSpeed = progress.Speed
+		latestEncoderFrame = progress.Frame
+		latestEncoderTime = progress.CurrentTime
+		monitor.progress(taskID, progress)
+	})
+	if err := addTasks(orch, tasks); err != nil {
+		close(done)
+		return nil, err
 	}
 
 	// Execute all tasks (only if there are tasks to execute)
 	var results []*models.EncoderResult
 
-	if tasksAdded > 0 {
+	if len(tasks) > 0 {
 		var err error
-		results, err = orch.Execute()
+		results, err = monitor.execute(orch)
 		close(done) // Stop the ticker goroutine
 		if err != nil {
-			logger.Printf("AUDIO: Encoding failed: %v", err)
+			log.Error("audio encoding failed", "error", err)
 			return nil, err
 		}
 	} else {
 		close(done) // Stop the ticker goroutine
-		logger.Printf("AUDIO: All chunks cached - skipping execution")
+		log.Info("all audio chunks cached, skipping execution")
 		// Create results from cached output files
 		results = make([]*models.EncoderResult, len(outputFiles))
 		for i, outputPath := range outputFiles {
@@ -622,69 +740,145 @@ func encodeAudio(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 
 	elapsed := time.Since(startTime).Seconds()
 	rate := float64(len(chunks)) / elapsed
-	logger.Printf("AUDIO: Completed all %d chunks in %.2fs (%.1f chunks/s)", len(chunks), elapsed, rate)
-	fmt.Printf("  ✓ Audio encoding complete\n")
+	log.Info("audio encoding complete", "chunks", len(chunks), "seconds", elapsed, "rate", rate)
+	fmt.Fprintf(out, "  ✓ Audio encoding complete\n")
 
 	// Check for failed tasks
 	if cfg.StrictMode && len(results) != len(chunks) {
 		return nil, fmt.Errorf("expected %d results, got %d", len(chunks), len(results))
 	}
 
-	// Save audio encoding manifest for future runs
-	fileInfo, err := os.Stat(cfg.Input)
-	if err == nil {
-		audioManifest := &EncodingManifest{
-			InputPath:     cfg.Input,
-			InputSize:     fileInfo.Size(),
-			InputModTime:  fileInfo.ModTime().Unix(),
-			ChunkCount:    len(chunks),
-			AudioBitrate:  cfg.Audio.Bitrate,
-			CreatedAt:     time.Now().Unix(),
-			EncodedChunks: make(map[string]string),
+	saveAudioManifest(cfg, chunks, tempDir, outputFiles, log)
+
+	return outputFiles, nil
+}
+
+// audioTasks returns the audio chunk files of a job and the tasks encoding
+// those not cached by an earlier run. Task IDs start with prefix; the
+// encoders log to log; progress (optional) receives the encoder progress of
+// every task.
+func audioTasks(cfg *config.Config, chunks []*models.Chunk, tempDir, prefix string, log *slog.Logger, progress func(taskID string, progress *models.EncodingProgress)) ([]string, []*orchestrator.Task) {
+	outputFiles := make([]string, len(chunks))
+
+	// Try to load cached audio encoding manifest
+	cachedChunks := make(map[uint]string) // ChunkID -> OutputPath
+	if _, err := os.Stat(cfg.Input); err == nil {
+		cachedManifest, err := loadEncodingManifest(tempDir, "audio")
+		if err == nil && validateEncodingManifest(cfg, cachedManifest, len(chunks), "audio", log) {
+			// Use cached manifest
+			for chunkID, path := range cachedManifest.EncodedChunks {
+				if id, err := strconv.ParseUint(chunkID, 10, 32); err == nil {
+					cachedChunks[uint(id)] = path
+				}
+			}
+			log.Info("using cached audio manifest", "chunks", len(cachedChunks))
 		}
+	}
 
-		// Add all encoded chunks to manifest
-		for i, chunk := range chunks {
-			audioManifest.EncodedChunks[fmt.Sprintf("%d", chunk.ChunkID)] = outputFiles[i]
+	resourceType := orchestrator.ResourceCPU
+	if cfg.Mode == "gpu-only" {
+		resourceType = orchestrator.ResourceGPUEncode
+	}
+
+	var tasks []*orchestrator.Task
+	for i, chunk := range chunks {
+		outputPath := filepath.Join(tempDir, fmt.Sprintf("audio_chunk_%03d.%s", chunk.ChunkID, audioChunkExt(cfg)))
+		outputFiles[i] = outputPath
+
+		// Skip if already cached and file exists
+		if cachedPath, exists := cachedChunks[chunk.ChunkID]; exists {
+			if _, err := os.Stat(cachedPath); err == nil {
+				log.Debug("audio chunk cached", logging.KeyChunkID, chunk.ChunkID, "path", cachedPath)
+				outputFiles[i] = cachedPath
+				continue
+			}
 		}
 
-		if err := saveEncodingManifest(tempDir, "audio", audioManifest); err != nil {
-			logger.Printf("AUDIO: Warning: Failed to save audio manifest: %v", err)
-		} else {
-			logger.Printf("AUDIO: Saved encoding manifest for %d chunks", len(chunks))
+		taskID := fmt.Sprintf("%saudio_%d", prefix, chunk.ChunkID)
+		builder := newChunkAudioBuilder(cfg, chunk, outputPath)
+		builder.SetLogger(log.With(logging.KeyChunkID, chunk.ChunkID, logging.KeyTaskType, builder.GetTaskType()))
+		if progress != nil {
+			builder.SetProgressCallback(func(p *models.EncodingProgress) { progress(taskID, p) })
 		}
+
+		tasks = append(tasks, &orchestrator.Task{
+			ID:           taskID,
+			Command:      builder,
+			Dependencies: []string{},
+			Resource:     resourceType,
+		})
 	}
+	return outputFiles, tasks
+}
 
-	return outputFiles, nil
+// addTasks adds tasks to orch.
+func addTasks(orch *orchestrator.DAGOrchestrator, tasks []*orchestrator.Task) error {
+	for _, task := range tasks {
+		if err := orch.AddTask(task); err != nil {
+			return fmt.Errorf("failed to add task: %w", err)
+		}
+	}
+	return nil
 }
 
-// encodeVideo encodes all video chunks in parallel
-func encodeVideo(cfg *config.Config, chunks []*models.Chunk, tempDir string, orch *orchestrator.DAGOrchestrator) ([]string, error) {
-	outputFiles := make([]string, len(chunks))
+// saveAudioManifest records the encoded audio chunks for future runs.
+func saveAudioManifest(cfg *config.Config, chunks []*models.Chunk, tempDir string, outputFiles []string, log *slog.Logger) {
+	fileInfo, err := os.Stat(cfg.Input)
+	if err != nil {
+		return
+	}
+	audioManifest := &EncodingManifest{
+		InputPath:     cfg.Input,
+		InputSize:     fileInfo.Size(),
+		InputModTime:  fileInfo.ModTime().Unix(),
+		ChunkCount:    len(chunks),
+		AudioCodec:    audioChunkCodec(cfg),
+		AudioBitrate:  cfg.Audio.Bitrate,
+		AudioGapless:  cfg.Audio.Gapless,
+		CreatedAt:     time.Now().Unix(),
+		EncodedChunks: make(map[string]string),
+	}
+
+	// Add all encoded chunks to manifest
+	for i, chunk := range chunks {
+		audioManifest.EncodedChunks[fmt.Sprintf("%d", chunk.ChunkID)] = outputFiles[i]
+	}
+
+	if err := saveEncodingManifest(tempDir, "audio", audioManifest); err != nil {
+		log.Warn("failed to save audio manifest", "error", err)
+	} else {
+		log.Info("saved audio manifest", "chunks", len(chunks))
+	}
+}
+
+// newChunkVideoBuilder creates the video encode of a chunk with the configured
+// settings and the per-source decisions.
+func newChunkVideoBuilder(cfg *config.Config, src *sourceAnalysis, chunk *models.Chunk, outputPath string) *video.VideoBuilder {
+	builder := video.NewVideoBuilder(chunk, outputPath)
+	builder.SetCodec(cfg.Video.Codec).
+		SetCRF(cfg.Video.CRF).
+		SetPreset(cfg.Video.Preset)
+
+	builder.SetEncoderParams(cfg.Video.EncoderParams)
+	builder.SetKeyframeInterval(keyframeInterval(cfg))
+	applyRateControl(builder, cfg)
+	applySourceAnalysis(builder, cfg, src)
+	return builder
+}
+
+// encodeVideo encodes all video chunks of every rendition in parallel, in one
+// orchestrator run from the same segments. Each rendition's chunk files (and
+// in target-quality mode, the report of its CRF search) are stored on it.
+func encodeVideo(renditions []*videoRendition, chunks []*models.Chunk, src *sourceAnalysis, orch *orchestrator.DAGOrchestrator, out io.Writer, log *slog.Logger, monitor *pipelineMonitor) error {
 	startTime := time.Now()
+	totalChunks := len(chunks) * len(renditions)
 
 	// Calculate total duration to encode
 	totalDuration := 0.0
 	for _, chunk := range chunks {
 		totalDuration += chunk.EndTime - chunk.StartTime
 	}
-
-	// Try to load cached video encoding manifest
-	cachedManifest := (*EncodingManifest)(nil)
-	cachedChunks := make(map[uint]string) // ChunkID -> OutputPath
-	_, err := os.Stat(cfg.Input)
-	if err == nil {
-		cachedManifest, err = loadEncodingManifest(tempDir, "video")
-		if err == nil && validateEncodingManifest(cfg, cachedManifest, len(chunks), "video") {
-			// Use cached manifest
-			for chunkID, path := range cachedManifest.EncodedChunks {
-				if id, err := strconv.ParseUint(chunkID, 10, 32); err == nil {
-					cachedChunks[uint(id)] = path
-				}
-			}
-			logger.Printf("VIDEO: Using cached manifest with %d already-encoded chunks", len(cachedChunks))
-		}
-	}
+	totalDuration *= float64(len(renditions))
 
 	// Progress tracking via channel - no race conditions!
 	// This is the proper Go way to coordinate between goroutines
@@ -703,7 +897,7 @@ func encodeVideo(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 	var latestEncoderFrame int64
 	var latestEncoderTime string
 
-	logger.Printf("VIDEO: Starting encoding of %d chunks (%.2f seconds total)", len(chunks), totalDuration)
+	log.Info("video encoding started", "chunks", len(chunks), "renditions", len(renditions), "duration_seconds", totalDuration)
 
 	// Function to log progress - reads from channel
 	logProgress := func(completed int) {
@@ -714,30 +908,49 @@ func encodeVideo(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 
 		// Calculate metrics
 		rate := float64(completed) / elapsed
-		encodedDuration := (totalDuration / float64(len(chunks))) * float64(completed)
+		encodedDuration := (totalDuration / float64(totalChunks)) * float64(completed)
 		overallSpeed := encodedDuration / elapsed
 
 		// Calculate ETA
-		remaining := len(chunks) - completed
+		remaining := totalChunks - completed
 		eta := 0.0
 		if rate > 0 {
 			eta = float64(remaining) / rate
 		}
 
 		// Log detailed progress with frame/time info
+		attrs := []interface{}{"completed", completed, "total", totalChunks, "rate", rate,
+			"overall_speed", overallSpeed, "speed", latestEncoderSpeed, "eta_seconds", eta}
 		if latestEncoderTime != "" {
-			logger.Printf("VIDEO: chunk=%d/%d rate=%.1f/s overall=%.2fx current=%.2fx time=%s frame=%d eta=%.0fs",
-				completed, len(chunks), rate, overallSpeed, latestEncoderSpeed, latestEncoderTime, latestEncoderFrame, eta)
-		} else {
-			logger.Printf("VIDEO: chunk=%d/%d rate=%.1f/s overall=%.2fx current=%.2fx eta=%.0fs",
-				completed, len(chunks), rate, overallSpeed, latestEncoderSpeed, eta)
+			attrs = append(attrs, "time", latestEncoderTime, "frame", latestEncoderFrame)
 		}
+		log.Info("video progress", attrs...)
 	}
 
-	// Set callback for when chunks complete and progress updates
+	// Set callback for when chunks complete and progress updates; probe,
+	// first-pass and thumbnail tasks are not chunks
+	chunksCompleted := 0
 	orch.SetProgressCallback(func(completedCount, total int, task *orchestrator.Task) {
-		logger.Printf("VIDEO: Completed chunk %d/%d (task: %s)", completedCount, total, task.ID)
-		logProgress(completedCount)
+		monitor.task(task)
+		if task.Command.GetTaskType() == command.TaskTypeThumbnail {
+			if task.Error != nil {
+				log.Warn("thumbnail task failed", logging.KeyTaskID, task.ID, "error", task.Error)
+			} else {
+				log.Info("thumbnails generated", logging.KeyTaskID, task.ID, "path", task.Command.GetOutputPath(), "tasks_completed", completedCount, "tasks", total)
+			}
+			return
+		}
+		if task.Command.GetTaskType() == command.TaskTypeQuality {
+			log.Info("quality probe completed", logging.KeyTaskID, task.ID, "tasks_completed", completedCount, "tasks", total)
+			return
+		}
+		if b, ok := task.Command.(*video.VideoBuilder); ok && b.Pass() == 1 {
+			log.Info("first pass completed", logging.KeyTaskID, task.ID, "tasks_completed", completedCount, "tasks", total)
+			return
+		}
+		chunksCompleted++
+		log.Info("video chunk completed", logging.KeyTaskID, task.ID, "completed", chunksCompleted, "total", totalChunks)
+		logProgress(chunksCompleted)
 	})
 
 	// Start a ticker to log progress every 2 seconds
@@ -759,126 +972,266 @@ func encodeVideo(cfg *config.Config, chunks []*models.Chunk, tempDir string, orc
 	}()
 
 	// Create encoding tasks
+	jobs, tasks, err := videoTasks(renditions, chunks, src, "", out, log, func(taskID string, progress *models.EncodingProgress) {
+		// Safely update encoder stats (these are only read during logging)
+		// No race condition here because we're not using these for control flow
+		latestEncoderSpeed = progress.Speed
+		latestEncoderFrame = progress.Frame
+		latestEncoderTime = progress.CurrentTime
+		monitor.progress(taskID, progress)
+	})
+	if err == nil {
+		err = addTasks(orch, tasks)
+	}
+	if err != nil {
+		close(done)
+		return err
+	}
+
+	// Execute all tasks (only if there are tasks to execute); thumbnail
+	// tasks already in orch run alongside the chunks
+	var results []*models.EncoderResult
+
+	if orch.TaskCount() > 0 {
+		var err error
+		results, err = monitor.execute(orch)
+		close(done) // Stop the ticker goroutine
+		if err != nil {
+			log.Error("video encoding failed", "error", err)
+			return err
+		}
+	} else {
+		close(done) // Stop the ticker goroutine
+		log.Info("all video chunks cached, skipping execution")
+	}
+
+	elapsed := time.Since(startTime).Seconds()
+	rate := float64(totalChunks) / elapsed
+	log.Info("video encoding complete", "chunks", totalChunks, "seconds", elapsed, "rate", rate)
+	fmt.Fprintf(out, "  ✓ Video encoding complete\n")
+
+	return finishVideo(renditions, jobs, chunks, results, out, log)
+}
+
+// renditionEncode is the state of a rendition's encode between adding its
+// tasks and collecting their results: the target-quality search and how
+// many chunks the cache already had.
+type renditionEncode struct {
+	metric   metric.Metric
+	target   float64
+	searches []*quality.Search
+	cached   int
+}
+
+// videoTasks stores the chunk files of every rendition on it and returns
+// the tasks encoding those not cached by an earlier run, with their
+// target-quality probes and first passes. Task IDs start with prefix;
+// warnings are printed to out and the encoders log to log; progress
+// (optional) receives the encoder progress of every chunk encode.
+func videoTasks(renditions []*videoRendition, chunks []*models.Chunk, src *sourceAnalysis, prefix string, out io.Writer, log *slog.Logger, progress func(taskID string, progress *models.EncodingProgress)) ([]*renditionEncode, []*orchestrator.Task, error) {
 	resourceType := orchestrator.ResourceCPU
-	if cfg.Mode == "gpu-only" {
+	if renditions[0].Config.Mode == "gpu-only" {
 		resourceType = orchestrator.ResourceGPUEncode
 	}
 
-	tasksAdded := 0
-	for i, chunk := range chunks {
-		// Use .mkv format for intermediate video chunks (better AV1 compatibility)
-		outputPath := filepath.Join(tempDir, fmt.Sprintf("video_chunk_%03d.mkv", chunk.ChunkID))
-		outputFiles[i] = outputPath
+	jobs := make([]*renditionEncode, len(renditions))
+	var tasks []*orchestrator.Task
 
-		// Skip if already cached and file exists
-		if cachedPath, exists := cachedChunks[chunk.ChunkID]; exists {
-			if _, err := os.Stat(cachedPath); err == nil {
-				logger.Printf("VIDEO: Skipping chunk %d (using cached: %s)", chunk.ChunkID, cachedPath)
-				outputFiles[i] = cachedPath
-				continue
+	for ri, r := range renditions {
+		cfg := r.Config
+		rlog := r.logger(log)
+		job := &renditionEncode{}
+		jobs[ri] = job
+		r.files = make([]string, len(chunks))
+		if err := os.MkdirAll(r.Dir, 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create video directory: %w", err)
+		}
+
+		// Try to load cached video encoding manifest
+		cachedChunks := make(map[uint]string) // ChunkID -> OutputPath
+		if _, err := os.Stat(cfg.Input); err == nil {
+			cachedManifest, err := loadEncodingManifest(r.Dir, "video")
+			if err == nil && validateEncodingManifest(cfg, cachedManifest, len(chunks), "video", rlog) {
+				// Use cached manifest
+				for chunkID, path := range cachedManifest.EncodedChunks {
+					if id, err := strconv.ParseUint(chunkID, 10, 32); err == nil {
+						cachedChunks[uint(id)] = path
+					}
+				}
+				rlog.Info("using cached video manifest", "chunks", len(cachedChunks))
 			}
 		}
 
-		// Capture chunk reference and index in closure (by value)
-		localChunk := chunk
-		builder := video.NewVideoBuilder(localChunk, outputPath)
-		builder.SetCodec(cfg.Video.Codec).
-			SetCRF(cfg.Video.CRF).
-			SetPreset(cfg.Video.Preset)
+		// Target-quality mode
+		job.metric, job.target = resolveQualityTarget(cfg, out, rlog)
+		probeDir := filepath.Join(r.Dir, "probes")
+		if job.target > 0 {
+			if err := os.MkdirAll(probeDir, 0755); err != nil {
+				return nil, nil, fmt.Errorf("failed to create probe directory: %w", err)
+			}
+		}
 
-		// Add SVT-AV1 specific parameters to reduce memory usage
-		if cfg.Video.Codec == "libsvtav1" {
-			builder.AddExtraArgs(
-				"-svtav1-params", "lp=4:pin=1", // lp=4 (reduce lookahead), pin=1 (logical core pinning)
-			)
+		// Two-pass mode: each chunk keeps its own pass log so parallel chunks
+		// don't overwrite each other's stats
+		passDir := filepath.Join(r.Dir, "passlogs")
+		if twoPass(cfg) {
+			if err := os.MkdirAll(passDir, 0755); err != nil {
+				return nil, nil, fmt.Errorf("failed to create pass log directory: %w", err)
+			}
 		}
 
-		builder.SetProgressCallback(func(progress *models.EncodingProgress) {
-			// Safely update encoder stats (these are only read during logging)
-			// No race condition here because we're not using these for control flow
-			latestEncoderSpeed = progress.Speed
-			latestEncoderFrame = progress.Frame
-			latestEncoderTime = progress.CurrentTime
-		})
+		for i, chunk := range chunks {
+			// Intermediate chunks use the encoder's intermediate container
+			outputPath := filepath.Join(r.Dir, fmt.Sprintf("video_chunk_%03d.%s", chunk.ChunkID, videoExt(cfg)))
+			r.files[i] = outputPath
+
+			// Skip if already cached and file exists
+			if cachedPath, exists := cachedChunks[chunk.ChunkID]; exists {
+				if _, err := os.Stat(cachedPath); err == nil {
+					rlog.Debug("video chunk cached", logging.KeyChunkID, chunk.ChunkID, "path", cachedPath)
+					r.files[i] = cachedPath
+					job.cached++
+					continue
+				}
+			}
 
-		task := &orchestrator.Task{
-			ID:           fmt.Sprintf("video_%d", localChunk.ChunkID),
-			Command:      builder,
-			Dependencies: []string{},
-			Resource:     resourceType,
-		}
+			// Capture chunk reference and index in closure (by value)
+			localChunk := chunk
+			taskID := prefix + r.taskID("video_%d", localChunk.ChunkID)
+			builder := newChunkVideoBuilder(cfg, src, localChunk, outputPath)
+			builder.SetLogger(rlog.With(logging.KeyChunkID, localChunk.ChunkID, logging.KeyTaskType, builder.GetTaskType()))
+			if progress != nil {
+				builder.SetProgressCallback(func(p *models.EncodingProgress) { progress(taskID, p) })
+			}
 
-		if err := orch.AddTask(task); err != nil {
-			return nil, fmt.Errorf("failed to add task: %w", err)
+			// Target-quality mode: the encode waits for the chunk's probe encodes
+			var cmd command.Command = builder
+			dependencies := []string{}
+			if job.target > 0 {
+				search, probes := newQualitySearch(cfg, src, localChunk, builder, job.metric, job.target, probeDir)
+				for _, probe := range probes {
+					probeTask := &orchestrator.Task{
+						ID:       prefix + r.taskID("probe_%d_crf%d", localChunk.ChunkID, probe.CRF()),
+						Command:  probe,
+						Resource: resourceType,
+					}
+					tasks = append(tasks, probeTask)
+					dependencies = append(dependencies, probeTask.ID)
+				}
+				job.searches = append(job.searches, search)
+				cmd = quality.NewTargetCommand(builder, search)
+			}
+
+			// Two-pass mode: the encode is the second pass and waits for the first
+			if twoPass(cfg) {
+				first := builder.TwoPass(filepath.Join(passDir, fmt.Sprintf("chunk_%03d", localChunk.ChunkID)))
+				firstTask := &orchestrator.Task{
+					ID:       prefix + r.taskID("pass1_%d", localChunk.ChunkID),
+					Command:  first,
+					Resource: resourceType,
+				}
+				tasks = append(tasks, firstTask)
+				dependencies = append(dependencies, firstTask.ID)
+			}
+
+			tasks = append(tasks, &orchestrator.Task{
+				ID:           taskID,
+				Command:      cmd,
+				Dependencies: dependencies,
+				Resource:     resourceType,
+			})
 		}
-		tasksAdded++
 	}
 
-	// Execute all tasks (only if there are tasks to execute)
-	var results []*models.EncoderResult
+	return jobs, tasks, nil
+}
 
-	if tasksAdded > 0 {
-		var err error
-		results, err = orch.Execute()
-		close(done) // Stop the ticker goroutine
-		if err != nil {
-			logger.Printf("VIDEO: Encoding failed: %v", err)
-			return nil, err
-		}
-	} else {
-		close(done) // Stop the ticker goroutine
-		logger.Printf("VIDEO: All chunks cached - skipping execution")
-		// Create results from cached output files
-		results = make([]*models.EncoderResult, len(outputFiles))
-		for i, outputPath := range outputFiles {
-			results[i] = &models.EncoderResult{
-				ChunkID:    chunks[i].ChunkID,
-				OutputPath: outputPath,
+// finishVideo checks the results of the renditions' chunk encodes, writes
+// their target-quality reports to the log and out, and records the encoded
+// chunks for future runs. results may hold results of other tasks, which are
+// ignored.
+func finishVideo(renditions []*videoRendition, jobs []*renditionEncode, chunks []*models.Chunk, results []*models.EncoderResult, out io.Writer, log *slog.Logger) error {
+	for ri, r := range renditions {
+		cfg := r.Config
+		rlog := r.logger(log)
+		job := jobs[ri]
+
+		// Probe and first-pass results are not chunks: they are written to
+		// subdirectories of the rendition's directory
+		encoded := job.cached
+		for _, result := range results {
+			if filepath.Dir(result.OutputPath) == r.Dir {
+				encoded++
 			}
 		}
-	}
 
-	elapsed := time.Since(startTime).Seconds()
-	rate := float64(len(chunks)) / elapsed
-	logger.Printf("VIDEO: Completed all %d chunks in %.2fs (%.1f chunks/s)", len(chunks), elapsed, rate)
-	fmt.Printf("  ✓ Video encoding complete\n")
+		// Check for failed tasks
+		if cfg.StrictMode && encoded != len(chunks) {
+			return fmt.Errorf("video%s: expected %d results, got %d", r.label(), len(chunks), encoded)
+		}
 
-	// Check for failed tasks
-	if cfg.StrictMode && len(results) != len(chunks) {
-		return nil, fmt.Errorf("expected %d results, got %d", len(chunks), len(results))
-	}
+		if err := verifyChunkKeyframes(r.files, cfg.StrictMode, out, rlog); err != nil {
+			return err
+		}
 
-	// Save video encoding manifest for future runs
-	fileInfo, err := os.Stat(cfg.Input)
-	if err == nil {
-		videoManifest := &EncodingManifest{
-			InputPath:     cfg.Input,
-			InputSize:     fileInfo.Size(),
-			InputModTime:  fileInfo.ModTime().Unix(),
-			ChunkCount:    len(chunks),
-			VideoCodec:    cfg.Video.Codec,
-			VideoCRF:      cfg.Video.CRF,
-			CreatedAt:     time.Now().Unix(),
-			EncodedChunks: make(map[string]string),
-		}
-
-		// Add all encoded chunks to manifest
-		for i, chunk := range chunks {
-			videoManifest.EncodedChunks[fmt.Sprintf("%d", chunk.ChunkID)] = outputFiles[i]
+		if len(job.searches) > 0 {
+			r.report = quality.NewReport(job.metric, job.target, job.searches)
+			for _, c := range r.report.Chunks {
+				rlog.Info("chunk quality", logging.KeyChunkID, c.ChunkID, "crf", c.CRF, "score", c.Score, "measured", c.Measured, "probes", c.Probes)
+				for _, e := range c.Errors {
+					rlog.Warn("chunk quality search", logging.KeyChunkID, c.ChunkID, "warning", e)
+				}
+			}
+			reportPath := filepath.Join(r.Dir, "quality_report.json")
+			if err := r.report.WriteJSON(reportPath); err != nil {
+				rlog.Warn("failed to write quality report", "error", err)
+			} else {
+				rlog.Info("quality report written", "path", reportPath)
+			}
+			fmt.Fprintf(out, "  ✓ Quality%s: %s\n", r.label(), r.report.Summary())
 		}
 
-		if err := saveEncodingManifest(tempDir, "video", videoManifest); err != nil {
-			logger.Printf("VIDEO: Warning: Failed to save video manifest: %v", err)
-		} else {
-			logger.Printf("VIDEO: Saved encoding manifest for %d chunks", len(chunks))
+		// Save video encoding manifest for future runs
+		fileInfo, err := os.Stat(cfg.Input)
+		if err == nil {
+			videoManifest := &EncodingManifest{
+				InputPath:        cfg.Input,
+				InputSize:        fileInfo.Size(),
+				InputModTime:     fileInfo.ModTime().Unix(),
+				ChunkCount:       len(chunks),
+				VideoCodec:       cfg.Video.Codec,
+				VideoCRF:         cfg.Video.CRF,
+				VideoHDR:         cfg.Video.HDR,
+				VideoCrop:        cfg.Video.Crop,
+				VideoDeinterlace: cfg.Video.Deinterlace,
+				VideoResolution:  cfg.Video.Resolution,
+				VideoTarget:      cfg.Video.TargetQuality,
+				VideoRate:        rateControlKey(cfg),
+				VideoParams:      encoderParamsKey(cfg),
+				VideoKeyframes:   keyframeInterval(cfg),
+				CreatedAt:        time.Now().Unix(),
+				EncodedChunks:    make(map[string]string),
+			}
+
+			// Add all encoded chunks to manifest
+			for i, chunk := range chunks {
+				videoManifest.EncodedChunks[fmt.Sprintf("%d", chunk.ChunkID)] = r.files[i]
+			}
+
+			if err := saveEncodingManifest(r.Dir, "video", videoManifest); err != nil {
+				rlog.Warn("failed to save video manifest", "error", err)
+			} else {
+				rlog.Info("saved video manifest", "chunks", len(chunks))
+			}
 		}
 	}
 
-	return outputFiles, nil
+	return nil
 }
 
-// concatenateFiles concatenates files using the concatenator
-func concatenateFiles(files []string, outputPath string, strictMode bool) error {
+// concatenateFiles concatenates files using the concatenator. A non-empty
+// frameRate is recorded on the output video stream.
+func concatenateFiles(files []string, outputPath string, strictMode bool, frameRate string, log *slog.Logger) error {
 	// Convert file list to EncoderResult format (with pointers)
 	results := make([]*models.EncoderResult, len(files))
 	for i, file := range files {
@@ -897,12 +1250,12 @@ func concatenateFiles(files []string, outputPath string, strictMode bool) error
 				chunkID = uint(id)
 			} else {
 				chunkID = uint(i)
-				logger.Printf("CONCAT: Warning: could not parse ChunkID from filename '%s', using index %d", file, i)
+				log.Warn("could not parse chunk ID from filename, using index", "path", file, "index", i)
 			}
 		} else {
 			// Fall back to using loop index if filename doesn't match expected pattern
 			chunkID = uint(i)
-			logger.Printf("CONCAT: Warning: unexpected filename format '%s', using index %d", file, i)
+			log.Warn("unexpected chunk filename format, using index", "path", file, "index", i)
 		}
 
 		// Check if file exists (marks success)
@@ -918,42 +1271,14 @@ func concatenateFiles(files []string, outputPath string, strictMode bool) error
 		}
 	}
 
-	concat := concatenator.NewConcatenator(strictMode)
+	concat := concatenator.NewConcatenator(strictMode).SetFrameRate(frameRate).SetLogger(log)
 	if err := concat.Concatenate(results, outputPath); err != nil {
 		return err
 	}
 
-	return nil
-} // mixAudioVideo mixes audio and video streams into final output
-func mixAudioVideo(audioPath, videoPath, outputPath string) error {
-	// NewMixingBuilder takes (videoInput, outputPath)
-	builder := mixing.NewMixingBuilder(videoPath, outputPath)
-	builder.AddAudioTrack(audioPath).
-		SetCopyAudio(true).
-		SetCopyVideo(true)
-
-	if err := builder.Run(); err != nil {
-		return fmt.Errorf("mixing failed: %w", err)
-	}
-
 	return nil
 }
 
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
-	input, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-
-	// Create output directory if needed
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return err
-	}
-
-	return os.WriteFile(dst, input, 0644)
-}
-
 // SplitManifest tracks cached segment splits to avoid re-splitting
 type SplitManifest struct {
 	InputPath    string            `json:"input_path"`
@@ -1002,7 +1327,7 @@ func saveManifest(tempDir string, manifest *SplitManifest) error {
 }
 
 // validateManifest checks if cached segments are still valid
-func validateManifest(cfg *config.Config, manifest *SplitManifest, expectedChapterCount int, expectedSegmentCount int) bool {
+func validateManifest(cfg *config.Config, manifest *SplitManifest, expectedChapterCount int, expectedSegmentCount int, log *slog.Logger) bool {
 	// Check if input file still exists and hasn't changed
 	fileInfo, err := os.Stat(cfg.Input)
 	if err != nil {
@@ -1010,34 +1335,34 @@ func validateManifest(cfg *config.Config, manifest *SplitManifest, expectedChapt
 	}
 
 	if fileInfo.Size() != manifest.InputSize {
-		logger.Printf("SPLIT: Cache invalid - input size changed from %d to %d", manifest.InputSize, fileInfo.Size())
+		log.Info("segment cache invalid: input size changed", "cached_size", manifest.InputSize, "size", fileInfo.Size())
 		return false
 	}
 
 	if fileInfo.ModTime().Unix() != manifest.InputModTime {
-		logger.Printf("SPLIT: Cache invalid - input modification time changed")
+		log.Info("segment cache invalid: input modification time changed")
 		return false
 	}
 
 	if manifest.ChapterCount != expectedChapterCount || manifest.SegmentCount != expectedSegmentCount {
-		logger.Printf("SPLIT: Cache invalid - chapter/segment count mismatch")
+		log.Info("segment cache invalid: chapter or segment count changed")
 		return false
 	}
 
 	// Check if all cached segment files still exist
 	for i, segPath := range manifest.SegmentPaths {
 		if _, err := os.Stat(segPath); err != nil {
-			logger.Printf("SPLIT: Cache invalid - segment file missing: %s (chunk %s)", segPath, i)
+			log.Info("segment cache invalid: segment missing", "path", segPath, "index", i)
 			return false
 		}
 	}
 
-	logger.Printf("SPLIT: Cache validated - using %d cached segments", len(manifest.SegmentPaths))
+	log.Info("segment cache valid", "segments", len(manifest.SegmentPaths))
 	return true
 }
 
 // preSplitSegmentsWithCache checks for cached splits before performing new split
-func preSplitSegmentsWithCache(cfg *config.Config, probeResult *ffprobe.ProbeResult, chunks []*models.Chunk, tempDir string) error {
+func preSplitSegmentsWithCache(cfg *config.Config, probeResult *ffprobe.ProbeResult, chunks []*models.Chunk, tempDir string, out io.Writer, log *slog.Logger) error {
 	chapters := probeResult.GetChapters()
 	if len(chapters) == 0 {
 		return fmt.Errorf("no chapters found for splitting")
@@ -1045,29 +1370,29 @@ func preSplitSegmentsWithCache(cfg *config.Config, probeResult *ffprobe.ProbeRes
 
 	// Try to load cached manifest
 	manifest, err := loadManifest(tempDir)
-	if err == nil && validateManifest(cfg, manifest, len(chapters), len(chunks)) {
+	if err == nil && validateManifest(cfg, manifest, len(chapters), len(chunks), log) {
 		// Cache is valid - use it
-		fmt.Printf("  Strategy:   Using cached segments (skipping re-split)\n")
+		fmt.Fprintf(out, "  Strategy:   Using cached segments (skipping re-split)\n")
 		for i, chunk := range chunks {
 			if segPath, ok := manifest.SegmentPaths[fmt.Sprintf("%d", i)]; ok {
 				chunk.SegmentPath = segPath
-				logger.Printf("SPLIT: Chunk %d -> %s (cached)", i, segPath)
+				log.Debug("cached segment", "index", i, "path", segPath)
 			}
 		}
 		elapsed := time.Since(time.Unix(manifest.CreatedAt, 0)).Seconds()
-		fmt.Printf("  ✓ Loaded %d cached segments (created %.0fs ago)\n", len(chunks), elapsed)
+		fmt.Fprintf(out, "  ✓ Loaded %d cached segments (created %.0fs ago)\n", len(chunks), elapsed)
 		return nil
 	}
 
 	// Cache invalid or doesn't exist - perform new split
 	if err != nil {
-		logger.Printf("SPLIT: No valid cache found - performing new split")
+		log.Info("no segment cache, splitting")
 	} else {
-		logger.Printf("SPLIT: Cache validation failed - re-splitting")
+		log.Info("segment cache invalid, re-splitting")
 	}
 
 	// Perform the split
-	if err := preSplitSegments(cfg, probeResult, chunks, tempDir); err != nil {
+	if err := preSplitSegments(cfg, probeResult, chunks, tempDir, out, log); err != nil {
 		return err
 	}
 
@@ -1089,7 +1414,7 @@ func preSplitSegmentsWithCache(cfg *config.Config, probeResult *ffprobe.ProbeRes
 	}
 
 	if err := saveManifest(tempDir, newManifest); err != nil {
-		logger.Printf("SPLIT: Warning - failed to save manifest: %v", err)
+		log.Warn("failed to save segment manifest", "error", err)
 		// Don't fail the entire process if we can't save manifest
 	}
 
@@ -1098,10 +1423,10 @@ func preSplitSegmentsWithCache(cfg *config.Config, probeResult *ffprobe.ProbeRes
 
 // preSplitSegments splits the input file into segments using -c copy (no re-encoding)
 // Updates chunks to reference segment files instead of using -ss/-to seeking
-func preSplitSegments(cfg *config.Config, probeResult *ffprobe.ProbeResult, chunks []*models.Chunk, tempDir string) error {
-	logger.Printf("SPLIT: Starting segment split using -c copy (no re-encoding)")
+func preSplitSegments(cfg *config.Config, probeResult *ffprobe.ProbeResult, chunks []*models.Chunk, tempDir string, out io.Writer, log *slog.Logger) error {
+	log.Info("splitting segments with stream copy")
 
-	fmt.Printf("  Strategy:   Fast stream copy (no re-encoding)\n")
+	fmt.Fprintf(out, "  Strategy:   Fast stream copy (no re-encoding)\n")
 
 	chapters := probeResult.GetChapters()
 	if len(chapters) == 0 {
@@ -1111,11 +1436,9 @@ func preSplitSegments(cfg *config.Config, probeResult *ffprobe.ProbeResult, chun
 	splitStart := time.Now()
 
 	// Build segment splitter
-	splitter := segment.NewSegmentBuilder(cfg.Input, tempDir, chapters)
+	splitter := newSegmentBuilder(cfg, tempDir, chapters)
 
-	// Show dry-run command
-	cmd := splitter.DryRun()
-	logger.Printf("SPLIT: Command: %s", cmd)
+	log.Debug("ffmpeg command", "args", splitter.DryRun())
 
 	// Run the split
 	if err := splitter.Run(); err != nil {
@@ -1126,27 +1449,48 @@ func preSplitSegments(cfg *config.Config, probeResult *ffprobe.ProbeResult, chun
 	for i, chunk := range chunks {
 		segmentPath := splitter.GetSegmentPath(i)
 		chunk.SegmentPath = segmentPath
-		logger.Printf("SPLIT: Chunk %d -> %s", i, segmentPath)
+		log.Debug("segment", "index", i, "path", segmentPath)
 	}
 
 	elapsed := time.Since(splitStart).Seconds()
-	logger.Printf("SPLIT: Split complete in %.2fs", elapsed)
-	fmt.Printf("  ✓ Split %d segments (%.2fs)\n", len(chunks), elapsed)
+	log.Info("split complete", "segments", len(chunks), "seconds", elapsed)
+	fmt.Fprintf(out, "  ✓ Split %d segments (%.2fs)\n", len(chunks), elapsed)
 
 	return nil
 }
 
 // EncodingManifest tracks which chunks have been encoded to avoid re-encoding
 type EncodingManifest struct {
-	InputPath     string            `json:"input_path"`
-	InputSize     int64             `json:"input_size"`
-	InputModTime  int64             `json:"input_mod_time"`
-	ChunkCount    int               `json:"chunk_count"`
-	AudioBitrate  string            `json:"audio_bitrate"`
-	VideoCodec    string            `json:"video_codec"`
-	VideoCRF      int               `json:"video_crf"`
-	CreatedAt     int64             `json:"created_at"`
-	EncodedChunks map[string]string `json:"encoded_chunks"` // chunk index -> output path
+	InputPath        string            `json:"input_path"`
+	InputSize        int64             `json:"input_size"`
+	InputModTime     int64             `json:"input_mod_time"`
+	ChunkCount       int               `json:"chunk_count"`
+	AudioCodec       string            `json:"audio_codec"`
+	AudioBitrate     string            `json:"audio_bitrate"`
+	AudioGapless     bool              `json:"audio_gapless"`
+	VideoCodec       string            `json:"video_codec"`
+	VideoCRF         int               `json:"video_crf"`
+	VideoHDR         string            `json:"video_hdr"`
+	VideoCrop        string            `json:"video_crop"`
+	VideoDeinterlace string            `json:"video_deinterlace"`
+	VideoResolution  string            `json:"video_resolution"`
+	VideoTarget      float64           `json:"video_target_quality"`
+	VideoRate        string            `json:"video_rate_control"`
+	VideoParams      string            `json:"video_encoder_params"`
+	VideoKeyframes   float64           `json:"video_keyframe_interval"`
+	CreatedAt        int64             `json:"created_at"`
+	EncodedChunks    map[string]string `json:"encoded_chunks"` // chunk index -> output path
+}
+
+// encoderParamsKey describes the tuning of the selected encoder for cache
+// validation.
+func encoderParamsKey(cfg *config.Config) string {
+	params, args := cfg.Video.EncoderParams.Options(cfg.Video.Codec)
+	entries := make([]string, 0, len(params))
+	for _, p := range params {
+		entries = append(entries, p.Key+"="+p.Value)
+	}
+	return fmt.Sprintf("params=%s args=%s ten_bit=%v", strings.Join(entries, ":"), strings.Join(args, " "), cfg.Video.EncoderParams.TenBit(cfg.Video.Codec))
 }
 
 // getEncodingManifestPath returns the path to the encoding manifest file
@@ -1186,7 +1530,8 @@ func saveEncodingManifest(workDir, encodingType string, manifest *EncodingManife
 }
 
 // validateEncodingManifest checks if cached encodings are still valid
-func validateEncodingManifest(cfg *config.Config, manifest *EncodingManifest, expectedChunkCount int, encodingType string) bool {
+func validateEncodingManifest(cfg *config.Config, manifest *EncodingManifest, expectedChunkCount int, encodingType string, log *slog.Logger) bool {
+	log = log.With("manifest", encodingType)
 	// Check if input file still exists and hasn't changed
 	fileInfo, err := os.Stat(cfg.Input)
 	if err != nil {
@@ -1194,39 +1539,39 @@ func validateEncodingManifest(cfg *config.Config, manifest *EncodingManifest, ex
 	}
 
 	if fileInfo.Size() != manifest.InputSize {
-		logger.Printf("ENCODING: Cache invalid - input size changed")
+		log.Info("encoding cache invalid: input size changed")
 		return false
 	}
 
 	if fileInfo.ModTime().Unix() != manifest.InputModTime {
-		logger.Printf("ENCODING: Cache invalid - input modification time changed")
+		log.Info("encoding cache invalid: input modification time changed")
 		return false
 	}
 
 	if manifest.ChunkCount != expectedChunkCount {
-		logger.Printf("ENCODING: Cache invalid - chunk count mismatch")
+		log.Info("encoding cache invalid: chunk count changed")
 		return false
 	}
 
 	// Check encoding parameters haven't changed
-	if encodingType == "audio" && manifest.AudioBitrate != cfg.Audio.Bitrate {
-		logger.Printf("ENCODING: Cache invalid - audio bitrate changed")
+	if encodingType == "audio" && (manifest.AudioCodec != audioChunkCodec(cfg) || manifest.AudioBitrate != cfg.Audio.Bitrate || manifest.AudioGapless != cfg.Audio.Gapless) {
+		log.Info("encoding cache invalid: audio settings changed")
 		return false
 	}
 
-	if encodingType == "video" && (manifest.VideoCodec != cfg.Video.Codec || manifest.VideoCRF != cfg.Video.CRF) {
-		logger.Printf("ENCODING: Cache invalid - video parameters changed")
+	if encodingType == "video" && (manifest.VideoCodec != cfg.Video.Codec || manifest.VideoCRF != cfg.Video.CRF || manifest.VideoHDR != cfg.Video.HDR || manifest.VideoCrop != cfg.Video.Crop || manifest.VideoDeinterlace != cfg.Video.Deinterlace || manifest.VideoResolution != cfg.Video.Resolution || manifest.VideoTarget != cfg.Video.TargetQuality || manifest.VideoRate != rateControlKey(cfg) || manifest.VideoParams != encoderParamsKey(cfg) || manifest.VideoKeyframes != keyframeInterval(cfg)) {
+		log.Info("encoding cache invalid: video settings changed")
 		return false
 	}
 
 	// Check if all cached files still exist
 	for i, path := range manifest.EncodedChunks {
 		if _, err := os.Stat(path); err != nil {
-			logger.Printf("ENCODING: Cache invalid - encoded file missing: %s (chunk %s)", path, i)
+			log.Info("encoding cache invalid: chunk missing", "path", path, logging.KeyChunkID, i)
 			return false
 		}
 	}
 
-	logger.Printf("ENCODING: Cache validated - found %d cached encoded chunks", len(manifest.EncodedChunks))
+	log.Info("encoding cache valid", "chunks", len(manifest.EncodedChunks))
 	return true
 }
@@ -0,0 +1,187 @@
+// Package metrics collects the metrics of encoder runs and serves them in the
+// Prometheus text exposition format, for scraping on an encoding farm.
+//
+// A Registry follows the orchestrators it tracks (task counts and resource
+// slots, read at scrape time) and counts the tasks reported to it (durations,
+// bytes written, failures). All methods are safe on a nil *Registry, so
+// callers need not check whether metrics are enabled.
+package metrics
+
+import (
+	"context"
+	"encoder/command"
+	"encoder/models"
+	"encoder/orchestrator"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the task duration
+// histogram buckets.
+var DurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// Failure classes of ErrorClass
+const (
+	ClassCanceled   = "canceled"       // The run was interrupted
+	ClassTimeout    = "timeout"        // A deadline passed
+	ClassDependency = "dependency"     // A task it depends on failed
+	ClassMissing    = "missing_binary" // ffmpeg or another tool is not installed
+	ClassEncoder    = "encoder"        // The encoder exited with an error
+	ClassIO         = "io"             // A file could not be read or written
+	ClassOther      = "other"
+)
+
+// Registry holds the metrics of every run in the process.
+type Registry struct {
+	mu sync.Mutex
+
+	orchs    map[*orchestrator.DAGOrchestrator]int // Tracked orchestrators (tracking count)
+	speeds   map[string]float64                    // Task ID -> latest speed of running encodes
+	finished map[taskKey]float64                   // Finished tasks by type and status
+	duration map[command.TaskType]*histogram
+	bytes    map[command.TaskType]float64
+	failures map[string]float64 // Failed tasks by error class
+	restarts float64            // Jobs restarted by watch or serve
+}
+
+// taskKey labels the finished task counter.
+type taskKey struct {
+	taskType command.TaskType
+	status   orchestrator.TaskStatus
+}
+
+// histogram is a cumulative Prometheus histogram with DurationBuckets.
+type histogram struct {
+	counts []float64 // Observations <= DurationBuckets[i]
+	sum    float64
+	count  float64
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{
+		orchs:    make(map[*orchestrator.DAGOrchestrator]int),
+		speeds:   make(map[string]float64),
+		finished: make(map[taskKey]float64),
+		duration: make(map[command.TaskType]*histogram),
+		bytes:    make(map[command.TaskType]float64),
+		failures: make(map[string]float64),
+	}
+}
+
+// Track reports the tasks and resource slots of orch until the returned
+// function is called.
+func (r *Registry) Track(orch *orchestrator.DAGOrchestrator) (untrack func()) {
+	if r == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	r.orchs[orch]++
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.orchs[orch]--; r.orchs[orch] <= 0 {
+			delete(r.orchs, orch)
+		}
+	}
+}
+
+// Progress records the encoder progress of a task.
+func (r *Registry) Progress(taskID string, progress *models.EncodingProgress) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch progress.State {
+	case models.ProgressStateCompleted, models.ProgressStateFailed, models.ProgressStateCancelled:
+		delete(r.speeds, taskID)
+	default:
+		r.speeds[taskID] = progress.Speed
+	}
+}
+
+// TaskDone records a finished task: its duration, the size of its output if
+// it completed, or the class of its error if it failed.
+func (r *Registry) TaskDone(task *orchestrator.Task) {
+	if r == nil {
+		return
+	}
+	taskType := task.Command.GetTaskType()
+	var size int64
+	if task.Status == orchestrator.TaskCompleted {
+		if info, err := os.Stat(task.Command.GetOutputPath()); err == nil && info.Mode().IsRegular() {
+			size = info.Size()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.speeds, task.ID)
+	r.finished[taskKey{taskType, task.Status}]++
+	if !task.StartTime.IsZero() && !task.EndTime.IsZero() {
+		h := r.duration[taskType]
+		if h == nil {
+			h = &histogram{counts: make([]float64, len(DurationBuckets))}
+			r.duration[taskType] = h
+		}
+		h.observe(task.EndTime.Sub(task.StartTime).Seconds())
+	}
+	r.bytes[taskType] += float64(size)
+	if task.Status == orchestrator.TaskFailed {
+		r.failures[ErrorClass(task.Error)]++
+	}
+}
+
+// Restart records a queued job that watch or serve starts again after an
+// interrupted attempt, e.g. when the daemon was stopped while encoding it.
+// Tasks are never retried within a run, so this is the only kind of retry.
+func (r *Registry) Restart() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.restarts++
+	r.mu.Unlock()
+}
+
+// ErrorClass returns the failure class of a task error.
+func ErrorClass(err error) string {
+	var exitErr *exec.ExitError
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.Is(err, orchestrator.ErrDependencyFailed):
+		return ClassDependency
+	case errors.Is(err, exec.ErrNotFound):
+		return ClassMissing
+	case errors.As(err, &exitErr):
+		return ClassEncoder
+	case errors.As(err, &pathErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassIO
+	default:
+		return ClassOther
+	}
+}
+
+// observe adds a value to the histogram.
+func (h *histogram) observe(v float64) {
+	for i, bound := range DurationBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
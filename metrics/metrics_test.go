@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"context"
+	"encoder/command"
+	"encoder/models"
+	"encoder/orchestrator"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCommand is a command of a given type writing nothing.
+type fakeCommand struct {
+	taskType command.TaskType
+	output   string
+	err      error
+}
+
+func (c *fakeCommand) Run() error                               { return c.err }
+func (c *fakeCommand) GetOutputPath() string                    { return c.output }
+func (c *fakeCommand) GetInputPath() string                     { return "input.mkv" }
+func (c *fakeCommand) DryRun() (string, error)                  { return "ffmpeg", nil }
+func (c *fakeCommand) BuildArgs() []string                      { return nil }
+func (c *fakeCommand) GetPriority() int                         { return 0 }
+func (c *fakeCommand) SetPriority(priority int) command.Command { return c }
+func (c *fakeCommand) GetTaskType() command.TaskType            { return c.taskType }
+
+func finishedTask(id string, cmd *fakeCommand, seconds float64) *orchestrator.Task {
+	start := time.Now()
+	task := &orchestrator.Task{
+		ID:        id,
+		Command:   cmd,
+		Status:    orchestrator.TaskCompleted,
+		StartTime: start,
+		EndTime:   start.Add(time.Duration(seconds * float64(time.Second))),
+	}
+	if cmd.err != nil {
+		task.Status = orchestrator.TaskFailed
+		task.Error = cmd.err
+	}
+	return task
+}
+
+func TestErrorClass(t *testing.T) {
+	exitErr := exec.Command("false").Run()
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.Canceled, ClassCanceled},
+		{fmt.Errorf("probe: %w", context.DeadlineExceeded), ClassTimeout},
+		{orchestrator.ErrDependencyFailed, ClassDependency},
+		{fmt.Errorf("ffmpeg failed: %w", exec.ErrNotFound), ClassMissing},
+		{fmt.Errorf("ffmpeg command failed: %w", exitErr), ClassEncoder},
+		{&os.PathError{Op: "open", Path: "/in.mkv", Err: os.ErrNotExist}, ClassIO},
+		{errors.New("no video stream"), ClassOther},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry_WriteText(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "chunk_0000.mkv")
+	if err := os.WriteFile(output, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.Progress("video_0001", &models.EncodingProgress{Speed: 1.5, State: models.ProgressStateEncoding})
+	r.Progress("video_0002", &models.EncodingProgress{Speed: 2, State: models.ProgressStateEncoding})
+	r.TaskDone(finishedTask("video_0000", &fakeCommand{taskType: command.TaskTypeVideo, output: output}, 42))
+	r.TaskDone(finishedTask("video_0002", &fakeCommand{taskType: command.TaskTypeVideo, err: context.Canceled}, 3))
+	r.TaskDone(finishedTask("audio_0000", &fakeCommand{taskType: command.TaskTypeAudio, err: orchestrator.ErrDependencyFailed}, 0))
+	r.Restart()
+
+	orch := orchestrator.NewDAGOrchestrator([]orchestrator.ResourceConstraint{{Type: orchestrator.ResourceCPU, MaxSlots: 4}})
+	orch.AddTask(&orchestrator.Task{ID: "a", Command: &fakeCommand{taskType: command.TaskTypeVideo}, Resource: orchestrator.ResourceCPU})
+	untrack := r.Track(orch)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	text := b.String()
+
+	for _, want := range []string{
+		"# TYPE encoder_tasks gauge\n",
+		`encoder_tasks{status="pending"} 1`,
+		`encoder_resource_slots{resource="cpu"} 4`,
+		`encoder_resource_utilization_ratio{resource="cpu"} 0`,
+		"encoder_speed 1.5\n", // video_0002 stopped
+		"encoder_encodes_running 1\n",
+		`encoder_tasks_finished_total{task_type="video",status="completed"} 1`,
+		`encoder_tasks_finished_total{task_type="video",status="failed"} 1`,
+		"# TYPE encoder_task_duration_seconds histogram\n",
+		`encoder_task_duration_seconds_bucket{task_type="video",le="30"} 1`,
+		`encoder_task_duration_seconds_bucket{task_type="video",le="60"} 2`,
+		`encoder_task_duration_seconds_bucket{task_type="video",le="+Inf"} 2`,
+		`encoder_task_duration_seconds_sum{task_type="video"} 45`,
+		`encoder_bytes_written_total{task_type="video"} 1000`,
+		`encoder_task_failures_total{class="canceled"} 1`,
+		`encoder_task_failures_total{class="dependency"} 1`,
+		"encoder_job_restarts_total 1\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+
+	untrack()
+	b.Reset()
+	r.WriteText(&b)
+	if strings.Contains(b.String(), `resource="cpu"`) {
+		t.Errorf("Expected no slots once the orchestrator is untracked, got:\n%s", b.String())
+	}
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var r *Registry
+	r.Track(nil)()
+	r.Progress("video_0000", &models.EncodingProgress{})
+	r.TaskDone(nil)
+	r.Restart()
+	if err := r.WriteText(&strings.Builder{}); err != nil {
+		t.Errorf("Expected a nil registry to write nothing, got %v", err)
+	}
+}
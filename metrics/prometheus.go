@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"bufio"
+	"encoder/orchestrator"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// taskStatuses are the statuses reported by encoder_tasks, in output order.
+var taskStatuses = []orchestrator.TaskStatus{
+	orchestrator.TaskPending,
+	orchestrator.TaskReady,
+	orchestrator.TaskRunning,
+	orchestrator.TaskCompleted,
+	orchestrator.TaskFailed,
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+	out := bufio.NewWriter(w)
+	r.writeOrchestrators(out)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	family(out, "encoder_speed", "gauge", "Summed realtime speed of the running encodes (2 = twice realtime).")
+	speed := 0.0
+	for _, s := range r.speeds {
+		speed += s
+	}
+	sample(out, "encoder_speed", nil, speed)
+	family(out, "encoder_encodes_running", "gauge", "Encodes reporting progress.")
+	sample(out, "encoder_encodes_running", nil, float64(len(r.speeds)))
+
+	family(out, "encoder_tasks_finished_total", "counter", "Finished tasks by task type and status.")
+	keys := make([]taskKey, 0, len(r.finished))
+	for key := range r.finished {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].taskType != keys[j].taskType {
+			return keys[i].taskType < keys[j].taskType
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		sample(out, "encoder_tasks_finished_total", []string{"task_type", string(key.taskType), "status", key.status.String()}, r.finished[key])
+	}
+
+	family(out, "encoder_task_duration_seconds", "histogram", "Run time of finished tasks by task type.")
+	for _, taskType := range sortedKeys(r.duration) {
+		h := r.duration[taskType]
+		for i, bound := range DurationBuckets {
+			sample(out, "encoder_task_duration_seconds_bucket", []string{"task_type", string(taskType), "le", formatFloat(bound)}, h.counts[i])
+		}
+		sample(out, "encoder_task_duration_seconds_bucket", []string{"task_type", string(taskType), "le", "+Inf"}, h.count)
+		sample(out, "encoder_task_duration_seconds_sum", []string{"task_type", string(taskType)}, h.sum)
+		sample(out, "encoder_task_duration_seconds_count", []string{"task_type", string(taskType)}, h.count)
+	}
+
+	family(out, "encoder_bytes_written_total", "counter", "Size of the outputs of completed tasks by task type.")
+	for _, taskType := range sortedKeys(r.bytes) {
+		sample(out, "encoder_bytes_written_total", []string{"task_type", string(taskType)}, r.bytes[taskType])
+	}
+
+	family(out, "encoder_task_failures_total", "counter", "Failed tasks by error class.")
+	for _, class := range sortedKeys(r.failures) {
+		sample(out, "encoder_task_failures_total", []string{"class", class}, r.failures[class])
+	}
+
+	family(out, "encoder_job_restarts_total", "counter", "Queued jobs started again by watch or serve after an interrupted attempt.")
+	sample(out, "encoder_job_restarts_total", nil, r.restarts)
+
+	return out.Flush()
+}
+
+// writeOrchestrators writes the task and slot gauges of the tracked
+// orchestrators, read without holding r.mu so that tasks finishing meanwhile
+// are not held up.
+func (r *Registry) writeOrchestrators(out io.Writer) {
+	r.mu.Lock()
+	orchs := make([]*orchestrator.DAGOrchestrator, 0, len(r.orchs))
+	for orch := range r.orchs {
+		orchs = append(orchs, orch)
+	}
+	r.mu.Unlock()
+
+	tasks := make(map[string]int)
+	active := make(map[orchestrator.ResourceType]int)
+	slots := make(map[orchestrator.ResourceType]int)
+	for _, orch := range orchs {
+		for status, count := range orch.GetStats() {
+			if n, ok := count.(int); ok {
+				tasks[status] += n
+			}
+		}
+		for _, usage := range orch.ResourceUsage() {
+			active[usage.Type] += usage.Active
+			slots[usage.Type] += usage.MaxSlots
+		}
+	}
+
+	family(out, "encoder_tasks", "gauge", "Tasks of the running orchestrators by status.")
+	for _, status := range taskStatuses {
+		sample(out, "encoder_tasks", []string{"status", status.String()}, float64(tasks[status.String()]))
+	}
+
+	family(out, "encoder_resource_slots_active", "gauge", "Resource slots held by running tasks.")
+	for _, resource := range sortedKeys(slots) {
+		sample(out, "encoder_resource_slots_active", []string{"resource", string(resource)}, float64(active[resource]))
+	}
+	family(out, "encoder_resource_slots", "gauge", "Resource slots available to tasks.")
+	for _, resource := range sortedKeys(slots) {
+		sample(out, "encoder_resource_slots", []string{"resource", string(resource)}, float64(slots[resource]))
+	}
+	family(out, "encoder_resource_utilization_ratio", "gauge", "Share of the resource slots held by running tasks.")
+	for _, resource := range sortedKeys(slots) {
+		ratio := 0.0
+		if slots[resource] > 0 {
+			ratio = float64(active[resource]) / float64(slots[resource])
+		}
+		sample(out, "encoder_resource_utilization_ratio", []string{"resource", string(resource)}, ratio)
+	}
+}
+
+// family writes the HELP and TYPE lines of a metric.
+func family(out io.Writer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample; labels alternate names and values.
+func sample(out io.Writer, name string, labels []string, value float64) {
+	io.WriteString(out, name)
+	if len(labels) > 0 {
+		io.WriteString(out, "{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				io.WriteString(out, ",")
+			}
+			fmt.Fprintf(out, "%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		io.WriteString(out, "}")
+	}
+	fmt.Fprintf(out, " %s\n", formatFloat(value))
+}
+
+// labelEscaper escapes label values as the text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat formats a sample value or bucket bound.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of a map with string-like keys in order.
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
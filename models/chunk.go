@@ -19,12 +19,17 @@ import (
 //
 // SegmentPath is used when the input file has been pre-split into segments.
 // When set, encoders use this file directly without seeking, avoiding overhead.
+//
+// FrameRate is set when filtering changes the frame rate (inverse telecine
+// turns 29.97 fps into 23.976 fps) so every chunk is encoded on the same
+// timestamp grid and concatenates without drift.
 type Chunk struct {
 	ChunkID     uint    `json:"chunk_id"`
 	StartTime   float64 `json:"start_time"`
 	EndTime     float64 `json:"end_time"`
 	SourcePath  string  `json:"source_path"`
 	SegmentPath string  `json:"segment_path,omitempty"` // Optional: pre-split segment file
+	FrameRate   string  `json:"frame_rate,omitempty"`   // Optional: output video frame rate, e.g. "24000/1001" after inverse telecine
 }
 
 // NewChunk creates a new Chunk with validation.
@@ -0,0 +1,42 @@
+package models
+
+// HDR formats reported by HDRMetadata.Format.
+const (
+	HDRFormatHDR10       = "hdr10"
+	HDRFormatHLG         = "hlg"
+	HDRFormatDolbyVision = "dolby-vision"
+)
+
+// HDRMetadata describes the high dynamic range signalling of a video stream.
+//
+// It is filled from the source probe and used by the video builder to either
+// carry the signalling over to the encoded stream or tone-map to SDR.
+// Color fields use ffmpeg's names (e.g. "bt2020", "smpte2084", "bt2020nc").
+type HDRMetadata struct {
+	Format         string `json:"format"` // HDRFormatHDR10, HDRFormatHLG or HDRFormatDolbyVision
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	ColorTransfer  string `json:"color_transfer,omitempty"`
+	ColorSpace     string `json:"color_space,omitempty"`
+	ColorRange     string `json:"color_range,omitempty"`
+
+	// Static metadata (SMPTE ST 2086 and CTA-861.3), nil/0 if the source has none
+	MasteringDisplay *MasteringDisplay `json:"mastering_display,omitempty"`
+	MaxCLL           int               `json:"max_cll,omitempty"`  // Maximum content light level (cd/m²)
+	MaxFALL          int               `json:"max_fall,omitempty"` // Maximum frame-average light level (cd/m²)
+}
+
+// MasteringDisplay holds the mastering display color volume: CIE 1931 xy
+// chromaticity coordinates and luminance in cd/m².
+type MasteringDisplay struct {
+	RedX, RedY     float64
+	GreenX, GreenY float64
+	BlueX, BlueY   float64
+	WhiteX, WhiteY float64
+	MinLuminance   float64
+	MaxLuminance   float64
+}
+
+// HasContentLight reports whether MaxCLL/MaxFALL are known.
+func (h *HDRMetadata) HasContentLight() bool {
+	return h.MaxCLL > 0 || h.MaxFALL > 0
+}
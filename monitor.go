@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoder/models"
+	"encoder/orchestrator"
+	"time"
+)
+
+// Phases of a pipeline run, as reported to a pipelineMonitor
+const (
+	phaseAnalysis  = "analysis"
+	phaseChunking  = "chunking"
+	phasePreSplit  = "pre-split"
+	phaseSetup     = "setup"
+	phaseAudio     = "audio"
+	phaseVideo     = "video"
+	phaseConcat    = "concat"
+	phaseMux       = "mux"
+	phaseMetrics   = "metrics"
+	phasePackaging = "packaging"
+)
+
+// pipelineMonitor follows a pipeline run for something other than the
+// terminal, such as the job API of "encoder serve" or the events of
+// -output-format json. Every hook is optional and may be called from several
+// goroutines at once; all methods are safe on a nil monitor and also report
+// to encoderMetrics.
+type pipelineMonitor struct {
+	OnPhase    func(phase string)                                     // A phase starts
+	OnPlan     func(chunks []*models.Chunk, useChapters bool)         // The input was split into chunks
+	OnProgress func(taskID string, progress *models.EncodingProgress) // Encoder progress of a chunk task
+	OnTask     func(task *orchestrator.Task)                          // A task completed or failed
+	OnStats    func(stats map[string]interface{})                     // Orchestrator stats, every second while tasks run
+	OnDone     func(summary *jobSummary)                              // The pipeline succeeded
+}
+
+// phase reports the start of a phase.
+func (m *pipelineMonitor) phase(name string) {
+	if m != nil && m.OnPhase != nil {
+		m.OnPhase(name)
+	}
+}
+
+// plan reports the chunks of the input.
+func (m *pipelineMonitor) plan(chunks []*models.Chunk, useChapters bool) {
+	if m != nil && m.OnPlan != nil {
+		m.OnPlan(chunks, useChapters)
+	}
+}
+
+// progress reports the encoder progress of a task.
+func (m *pipelineMonitor) progress(taskID string, progress *models.EncodingProgress) {
+	encoderMetrics.Progress(taskID, progress)
+	if m != nil && m.OnProgress != nil {
+		m.OnProgress(taskID, progress)
+	}
+}
+
+// task reports a finished task.
+func (m *pipelineMonitor) task(task *orchestrator.Task) {
+	encoderMetrics.TaskDone(task)
+	if m != nil && m.OnTask != nil {
+		m.OnTask(task)
+	}
+}
+
+// done reports the result of a successful pipeline.
+func (m *pipelineMonitor) done(summary *jobSummary) {
+	if m != nil && m.OnDone != nil {
+		m.OnDone(summary)
+	}
+}
+
+// execute runs orch, reporting its stats every second while it runs and
+// once when it is done.
+func (m *pipelineMonitor) execute(orch *orchestrator.DAGOrchestrator) ([]*models.EncoderResult, error) {
+	defer encoderMetrics.Track(orch)()
+	if m == nil || m.OnStats == nil {
+		return orch.Execute()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.OnStats(orch.GetStats())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	results, err := orch.Execute()
+	close(done)
+	m.OnStats(orch.GetStats())
+	return results, err
+}
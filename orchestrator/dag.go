@@ -1,9 +1,14 @@
 package orchestrator
 
 import (
+	"context"
 	"encoder/command"
+	"encoder/logging"
 	"encoder/models"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 )
@@ -42,12 +47,41 @@ const (
 	TaskFailed
 )
 
+// ErrDependencyFailed is the error of tasks that never ran because a task
+// they depend on failed
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// String returns the status name, as used by GetStats
+func (s TaskStatus) String() string {
+	switch s {
+	case TaskPending:
+		return "pending"
+	case TaskReady:
+		return "ready"
+	case TaskRunning:
+		return "running"
+	case TaskCompleted:
+		return "completed"
+	case TaskFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("TaskStatus(%d)", int(s))
+	}
+}
+
 // ResourceConstraint defines limits for a resource type
 type ResourceConstraint struct {
 	Type     ResourceType
 	MaxSlots int // Maximum concurrent tasks for this resource
 }
 
+// ResourceUsage is the slot usage of a constrained resource type
+type ResourceUsage struct {
+	Type     ResourceType
+	Active   int // Slots held by running tasks
+	MaxSlots int
+}
+
 // DAGOrchestrator manages task execution with dependencies and resource constraints
 type DAGOrchestrator struct {
 	tasks       map[string]*Task
@@ -63,6 +97,16 @@ type DAGOrchestrator struct {
 
 	// Progress tracking
 	onProgress func(completed, total int, task *Task)
+
+	// Cancellation (nil = tasks always run to completion)
+	ctx context.Context
+
+	// Tasks added while Execute runs (see KeepOpen); guarded by tasksMutex
+	running  bool
+	open     bool
+	closedCh chan struct{} // Closed by Close
+
+	log *slog.Logger
 }
 
 // NewDAGOrchestrator creates a new orchestrator with resource constraints
@@ -77,6 +121,7 @@ func NewDAGOrchestrator(constraints []ResourceConstraint) *DAGOrchestrator {
 		constraints: constraintMap,
 		activeSlots: make(map[ResourceType]int),
 		completeCh:  make(chan string, 100),
+		log:         logging.Discard(),
 	}
 }
 
@@ -88,27 +133,102 @@ func (o *DAGOrchestrator) AddTask(task *Task) error {
 	if _, exists := o.tasks[task.ID]; exists {
 		return fmt.Errorf("task %s already exists", task.ID)
 	}
+	if o.running {
+		if !o.open {
+			return fmt.Errorf("task %s added after the orchestrator was closed", task.ID)
+		}
+		// Depending only on existing tasks keeps the graph acyclic
+		for _, depID := range task.Dependencies {
+			if _, exists := o.tasks[depID]; !exists {
+				return fmt.Errorf("task %s depends on non-existent task %s", task.ID, depID)
+			}
+		}
+	}
 
 	task.Status = TaskPending
 	o.tasks[task.ID] = task
 	return nil
 }
 
+// TaskCount returns the number of tasks added
+func (o *DAGOrchestrator) TaskCount() int {
+	o.tasksMutex.RLock()
+	defer o.tasksMutex.RUnlock()
+	return len(o.tasks)
+}
+
+// KeepOpen makes Execute wait for tasks added while it runs until Close is
+// called, so work can be scheduled as soon as it is known. Tasks added while
+// Execute runs may only depend on tasks added before them. Call before
+// Execute.
+func (o *DAGOrchestrator) KeepOpen() {
+	o.tasksMutex.Lock()
+	defer o.tasksMutex.Unlock()
+	o.open = true
+	o.closedCh = make(chan struct{})
+}
+
+// Close ends KeepOpen: no more tasks are added, and Execute returns once the
+// tasks added so far are done.
+func (o *DAGOrchestrator) Close() {
+	o.tasksMutex.Lock()
+	defer o.tasksMutex.Unlock()
+	if !o.open {
+		return
+	}
+	o.open = false
+	close(o.closedCh)
+}
+
+// isOpen reports whether tasks may still be added (see KeepOpen).
+func (o *DAGOrchestrator) isOpen() bool {
+	o.tasksMutex.RLock()
+	defer o.tasksMutex.RUnlock()
+	return o.open
+}
+
+// finished reports whether completed tasks are all the tasks there will be.
+func (o *DAGOrchestrator) finished(completed int) bool {
+	o.tasksMutex.RLock()
+	defer o.tasksMutex.RUnlock()
+	return !o.open && completed == len(o.tasks)
+}
+
 // SetProgressCallback sets a callback for progress updates
 func (o *DAGOrchestrator) SetProgressCallback(callback func(completed, total int, task *Task)) {
 	o.onProgress = callback
 }
 
-// Execute runs all tasks respecting dependencies and resource constraints
+// SetLogger sets the logger of task starts and ends (debug level) and task
+// failures (warn level). Records are discarded by default.
+func (o *DAGOrchestrator) SetLogger(log *slog.Logger) {
+	o.log = log
+}
+
+// SetContext makes Execute stop early once ctx is done: tasks that have not
+// started fail with the context's error, and running commands that implement
+// command.ContextRunner are stopped.
+func (o *DAGOrchestrator) SetContext(ctx context.Context) {
+	o.ctx = ctx
+}
+
+// Execute runs all tasks respecting dependencies and resource constraints.
+// Failed tasks are reported through their status and results; the error is
+// only set for an invalid graph or a canceled context (see SetContext).
+// With KeepOpen it also runs the tasks added meanwhile, until Close.
 func (o *DAGOrchestrator) Execute() ([]*models.EncoderResult, error) {
 	// Validate DAG (no cycles, all dependencies exist)
 	if err := o.validateDAG(); err != nil {
 		return nil, err
 	}
 
-	totalTasks := len(o.tasks)
-	resultsCh := make(chan *models.EncoderResult, totalTasks) // Channel for results - no race condition!
-	completionCh := make(chan struct{})                       // Signal when all tasks are done
+	o.tasksMutex.Lock()
+	o.running = true
+	closedCh := o.closedCh // nil without KeepOpen: never ready
+	o.tasksMutex.Unlock()
+
+	resultsCh := make(chan *models.EncoderResult, 100) // Channel for results - no race condition!
+	completionCh := make(chan struct{})                // Signal when all tasks are done
 
 	// Completion handler goroutine
 	var wg sync.WaitGroup
@@ -117,7 +237,7 @@ func (o *DAGOrchestrator) Execute() ([]*models.EncoderResult, error) {
 		defer wg.Done()
 
 		completedCount := 0
-		for {
+		for !o.finished(completedCount) {
 			select {
 			case result := <-resultsCh:
 				completedCount++
@@ -131,18 +251,17 @@ func (o *DAGOrchestrator) Execute() ([]*models.EncoderResult, error) {
 						break
 					}
 				}
+				totalTasks := len(o.tasks)
 				o.tasksMutex.RUnlock()
 
 				if task != nil && o.onProgress != nil {
 					o.onProgress(completedCount, totalTasks, task)
 				}
-
-				if completedCount == totalTasks {
-					close(completionCh)
-					return
-				}
+			case <-closedCh:
+				closedCh = nil
 			}
 		}
+		close(completionCh)
 	}()
 
 	// Start scheduler goroutine
@@ -173,8 +292,8 @@ func (o *DAGOrchestrator) Execute() ([]*models.EncoderResult, error) {
 	wg.Wait()
 
 	// Collect all results
-	results := make([]*models.EncoderResult, 0, totalTasks)
 	o.tasksMutex.RLock()
+	results := make([]*models.EncoderResult, 0, len(o.tasks))
 	for _, task := range o.tasks {
 		if task.Result != nil {
 			results = append(results, task.Result)
@@ -182,6 +301,9 @@ func (o *DAGOrchestrator) Execute() ([]*models.EncoderResult, error) {
 	}
 	o.tasksMutex.RUnlock()
 
+	if o.ctx != nil && o.ctx.Err() != nil {
+		return results, o.ctx.Err()
+	}
 	return results, nil
 }
 
@@ -194,11 +316,20 @@ func (o *DAGOrchestrator) scheduler() {
 	const minSleep = 1 * time.Millisecond
 
 	for {
-		// Check if all tasks are done or blocked
-		if o.allTasksCompleteOrBlocked() {
+		// Check if all tasks are done or blocked, and no more are coming
+		// (read first: once closed, no task can be added before the check)
+		open := o.isOpen()
+		if o.allTasksCompleteOrBlocked() && !open {
 			return
 		}
 
+		// Canceled: start nothing more and wait for running tasks
+		if o.ctx != nil && o.ctx.Err() != nil {
+			o.cancelWaiting(o.ctx.Err())
+			time.Sleep(maxSleep)
+			continue
+		}
+
 		// Find ready tasks
 		readyTasks := o.getReadyTasks()
 
@@ -232,7 +363,8 @@ func (o *DAGOrchestrator) scheduler() {
 	}
 }
 
-// getReadyTasks returns tasks that are ready to execute
+// getReadyTasks returns tasks that are ready to execute, highest command
+// priority first so low-priority work only takes slots the rest leaves free
 func (o *DAGOrchestrator) getReadyTasks() []*Task {
 	o.tasksMutex.Lock()
 	defer o.tasksMutex.Unlock()
@@ -251,6 +383,15 @@ func (o *DAGOrchestrator) getReadyTasks() []*Task {
 		}
 	}
 
+	// Map order is random: break priority ties by ID for a stable order
+	sort.Slice(ready, func(i, j int) bool {
+		pi, pj := ready[i].Command.GetPriority(), ready[j].Command.GetPriority()
+		if pi != pj {
+			return pi > pj
+		}
+		return ready[i].ID < ready[j].ID
+	})
+
 	return ready
 }
 
@@ -307,8 +448,16 @@ func (o *DAGOrchestrator) executeTask(task *Task) {
 	task.StartTime = time.Now()
 	o.tasksMutex.Unlock()
 
+	log := o.log.With(logging.KeyTaskID, task.ID, logging.KeyTaskType, task.Command.GetTaskType())
+	log.Debug("task started", "resource", task.Resource)
+
 	// Execute the command
-	err := task.Command.Run()
+	var err error
+	if runner, ok := task.Command.(command.ContextRunner); ok && o.ctx != nil {
+		err = runner.RunContext(o.ctx)
+	} else {
+		err = task.Command.Run()
+	}
 
 	// Update status based on result
 	o.tasksMutex.Lock()
@@ -329,8 +478,15 @@ func (o *DAGOrchestrator) executeTask(task *Task) {
 			Success:    true,
 		}
 	}
+	duration := task.EndTime.Sub(task.StartTime)
 	o.tasksMutex.Unlock()
 
+	if err != nil {
+		log.Warn("task failed", "duration", duration, "error", err)
+	} else {
+		log.Debug("task completed", "duration", duration)
+	}
+
 	// Notify completion
 	o.completeCh <- task.ID
 }
@@ -350,7 +506,8 @@ func (o *DAGOrchestrator) allTasksCompleteOrBlocked() bool {
 			if o.hasFailedDependency(task) {
 				// Mark as failed due to dependency and notify
 				task.Status = TaskFailed
-				task.Error = fmt.Errorf("dependency failed")
+				task.Error = ErrDependencyFailed
+				o.log.Debug("task skipped", logging.KeyTaskID, task.ID, "error", task.Error)
 				task.Result = &models.EncoderResult{
 					OutputPath: task.Command.GetOutputPath(),
 					Success:    false,
@@ -374,6 +531,29 @@ func (o *DAGOrchestrator) allTasksCompleteOrBlocked() bool {
 	return true
 }
 
+// cancelWaiting fails every task that has not started with err
+func (o *DAGOrchestrator) cancelWaiting(err error) {
+	o.tasksMutex.Lock()
+	defer o.tasksMutex.Unlock()
+
+	for _, task := range o.tasks {
+		if task.Status != TaskPending && task.Status != TaskReady {
+			continue
+		}
+		task.Status = TaskFailed
+		task.Error = err
+		task.Result = &models.EncoderResult{
+			OutputPath: task.Command.GetOutputPath(),
+			Success:    false,
+			Error:      err,
+		}
+		o.log.Debug("task canceled", logging.KeyTaskID, task.ID, "error", err)
+		go func(id string) {
+			o.completeCh <- id
+		}(task.ID)
+	}
+}
+
 // hasFailedDependency checks if any dependency has failed
 func (o *DAGOrchestrator) hasFailedDependency(task *Task) bool {
 	for _, depID := range task.Dependencies {
@@ -452,6 +632,24 @@ func (o *DAGOrchestrator) GetTaskStatus(taskID string) (TaskStatus, error) {
 	return task.Status, nil
 }
 
+// ResourceUsage returns the active and maximum slots of each constrained
+// resource, sorted by resource type
+func (o *DAGOrchestrator) ResourceUsage() []ResourceUsage {
+	o.slotsMutex.RLock()
+	defer o.slotsMutex.RUnlock()
+
+	usage := make([]ResourceUsage, 0, len(o.constraints))
+	for resourceType, constraint := range o.constraints {
+		usage = append(usage, ResourceUsage{
+			Type:     resourceType,
+			Active:   o.activeSlots[resourceType],
+			MaxSlots: constraint.MaxSlots,
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Type < usage[j].Type })
+	return usage
+}
+
 // GetStats returns execution statistics
 func (o *DAGOrchestrator) GetStats() map[string]interface{} {
 	o.tasksMutex.RLock()
@@ -1,10 +1,16 @@
 package orchestrator
 
 import (
+	"bytes"
+	"context"
 	"encoder/command"
+	"encoder/logging"
 	"encoder/models"
 	"errors"
 	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -221,6 +227,74 @@ func TestDAGOrchestrator_ResourceConstraint(t *testing.T) {
 	}
 }
 
+func TestDAGOrchestrator_ResourceUsage(t *testing.T) {
+	orch := NewDAGOrchestrator([]ResourceConstraint{
+		{Type: ResourceIO, MaxSlots: 4},
+		{Type: ResourceCPU, MaxSlots: 2},
+	})
+	orch.AddTask(&Task{
+		ID:       "A",
+		Command:  &MockCommand{id: "A", outputPath: "/tmp/a.mp4", duration: 60 * time.Millisecond},
+		Resource: ResourceCPU,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		orch.Execute()
+		close(done)
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	want := []ResourceUsage{
+		{Type: ResourceCPU, Active: 1, MaxSlots: 2},
+		{Type: ResourceIO, Active: 0, MaxSlots: 4},
+	}
+	if got := orch.ResourceUsage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected usage %+v while A runs, got %+v", want, got)
+	}
+
+	<-done
+	if got := orch.ResourceUsage(); got[0].Active != 0 {
+		t.Errorf("Expected the CPU slot to be released, got %+v", got)
+	}
+}
+
+func TestDAGOrchestrator_Priority(t *testing.T) {
+	// One slot: ready tasks run highest priority first, ties by ID
+	orch := NewDAGOrchestrator([]ResourceConstraint{
+		{Type: ResourceCPU, MaxSlots: 1},
+	})
+
+	tasks := []*Task{
+		{ID: "thumbs", Command: &MockCommand{id: "thumbs", outputPath: "/tmp/t.jpg", duration: 5 * time.Millisecond, priority: command.PriorityLow}},
+		{ID: "video_2", Command: &MockCommand{id: "video_2", outputPath: "/tmp/2.mkv", duration: 5 * time.Millisecond, priority: command.PriorityNormal}},
+		{ID: "video_1", Command: &MockCommand{id: "video_1", outputPath: "/tmp/1.mkv", duration: 5 * time.Millisecond, priority: command.PriorityNormal}},
+		{ID: "final", Command: &MockCommand{id: "final", outputPath: "/tmp/f.mkv", duration: 5 * time.Millisecond, priority: command.PriorityHigh}},
+	}
+	for _, task := range tasks {
+		task.Resource = ResourceCPU
+		if err := orch.AddTask(task); err != nil {
+			t.Fatalf("Failed to add task %s: %v", task.ID, err)
+		}
+	}
+	if orch.TaskCount() != len(tasks) {
+		t.Errorf("Expected %d tasks, got %d", len(tasks), orch.TaskCount())
+	}
+
+	var order []string
+	orch.SetProgressCallback(func(completed, total int, task *Task) {
+		order = append(order, task.ID)
+	})
+	if _, err := orch.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := []string{"final", "video_1", "video_2", "thumbs"}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("Expected execution order %v, got %v", want, order)
+	}
+}
+
 func TestDAGOrchestrator_MixedResources(t *testing.T) {
 	// Create orchestrator with different resource limits
 	orch := NewDAGOrchestrator([]ResourceConstraint{
@@ -392,6 +466,9 @@ func TestDAGOrchestrator_FailedTask(t *testing.T) {
 	if taskC.Status == TaskCompleted {
 		t.Errorf("Task C should not complete since B failed")
 	}
+	if !errors.Is(taskC.Error, ErrDependencyFailed) {
+		t.Errorf("Task C should fail with ErrDependencyFailed, got %v", taskC.Error)
+	}
 
 	// Should have results for A and B (B's result has error)
 	if len(results) < 2 {
@@ -414,6 +491,34 @@ func TestDAGOrchestrator_FailedTask(t *testing.T) {
 	}
 }
 
+func TestDAGOrchestrator_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	orch := NewDAGOrchestrator([]ResourceConstraint{
+		{Type: ResourceCPU, MaxSlots: 1},
+	})
+	orch.SetLogger(logging.New(&buf, logging.FormatText, slog.LevelInfo))
+	orch.AddTask(&Task{
+		ID:       "A",
+		Command:  &MockCommand{id: "A", outputPath: "/tmp/a.mp4"},
+		Resource: ResourceCPU,
+	})
+	orch.AddTask(&Task{
+		ID:       "B",
+		Command:  &MockCommand{id: "B", outputPath: "/tmp/b.mp4", shouldFail: true},
+		Resource: ResourceCPU,
+	})
+
+	if _, err := orch.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// Starts and completions are debug records; only the failure is logged
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "level=WARN") ||
+		!strings.Contains(lines[0], "task_id=B") || !strings.Contains(lines[0], "task_type=video") {
+		t.Errorf("Expected one warning for task B, got %q", buf.String())
+	}
+}
+
 func TestDAGOrchestrator_ProgressCallback(t *testing.T) {
 	orch := NewDAGOrchestrator([]ResourceConstraint{
 		{Type: ResourceCPU, MaxSlots: 2},
@@ -503,3 +608,103 @@ func TestDAGOrchestrator_GetStats(t *testing.T) {
 		t.Errorf("Expected 1 pending task, got %d", stats["pending"].(int))
 	}
 }
+
+// ContextMockCommand is a MockCommand that stops when its context is done
+type ContextMockCommand struct {
+	MockCommand
+	stopped bool
+}
+
+func (m *ContextMockCommand) RunContext(ctx context.Context) error {
+	select {
+	case <-time.After(m.duration):
+		m.executed = true
+		return nil
+	case <-ctx.Done():
+		m.stopped = true
+		return ctx.Err()
+	}
+}
+
+func TestDAGOrchestrator_Cancel(t *testing.T) {
+	orch := NewDAGOrchestrator([]ResourceConstraint{
+		{Type: ResourceCPU, MaxSlots: 1},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	orch.SetContext(ctx)
+
+	// A runs until canceled; B waits for the only CPU slot; C depends on A
+	slow := &ContextMockCommand{MockCommand: MockCommand{id: "A", outputPath: "/tmp/a.mp4", duration: 10 * time.Second}}
+	orch.AddTask(&Task{ID: "A", Command: slow, Resource: ResourceCPU, Dependencies: []string{}})
+	orch.AddTask(&Task{ID: "B", Command: &MockCommand{id: "B", outputPath: "/tmp/b.mp4"}, Resource: ResourceCPU, Dependencies: []string{}})
+	orch.AddTask(&Task{ID: "C", Command: &MockCommand{id: "C", outputPath: "/tmp/c.mp4"}, Resource: ResourceCPU, Dependencies: []string{"A"}})
+
+	var calls int
+	orch.SetProgressCallback(func(completed, total int, task *Task) {
+		calls++
+	})
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := orch.Execute()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected Execute to stop early, took %v", elapsed)
+	}
+	if !slow.stopped {
+		t.Error("Expected the running command to be stopped")
+	}
+	for _, id := range []string{"A", "B", "C"} {
+		if status, _ := orch.GetTaskStatus(id); status != TaskFailed {
+			t.Errorf("Expected task %s to fail, got status %d", id, status)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Expected a progress callback per task, got %d", calls)
+	}
+}
+
+func TestDAGOrchestrator_KeepOpen(t *testing.T) {
+	orch := NewDAGOrchestrator([]ResourceConstraint{
+		{Type: ResourceCPU, MaxSlots: 2},
+	})
+	orch.KeepOpen()
+	orch.AddTask(&Task{ID: "A", Command: &MockCommand{id: "A", outputPath: "/tmp/a.mp4", duration: 10 * time.Millisecond}, Resource: ResourceCPU})
+
+	done := make(chan []*models.EncoderResult)
+	go func() {
+		results, err := orch.Execute()
+		if err != nil {
+			t.Errorf("Execute failed: %v", err)
+		}
+		done <- results
+	}()
+
+	// Added while A runs or after it finished: Execute keeps waiting
+	time.Sleep(50 * time.Millisecond)
+	if err := orch.AddTask(&Task{ID: "B", Command: &MockCommand{id: "B", outputPath: "/tmp/b.mp4"}, Resource: ResourceCPU, Dependencies: []string{"A"}}); err != nil {
+		t.Fatalf("AddTask failed: %v", err)
+	}
+	if err := orch.AddTask(&Task{ID: "C", Command: &MockCommand{id: "C", outputPath: "/tmp/c.mp4"}, Resource: ResourceCPU, Dependencies: []string{"missing"}}); err == nil {
+		t.Error("Expected error for a dependency on a task not added yet")
+	}
+	select {
+	case <-done:
+		t.Fatal("Execute returned before Close")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	orch.Close()
+	results := <-done
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+	if status, _ := orch.GetTaskStatus("B"); status != TaskCompleted {
+		t.Errorf("Expected B to be completed, got %v", status)
+	}
+	if err := orch.AddTask(&Task{ID: "D", Command: &MockCommand{id: "D"}, Resource: ResourceCPU}); err == nil {
+		t.Error("Expected error for a task added after Close")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoder/codec"
+	"encoder/command/packaging"
+	"encoder/config"
+	"encoder/ffprobe"
+	"encoder/logging"
+	"encoder/orchestrator"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// textSubtitleCodecs are the subtitle codecs that convert to WebVTT.
+var textSubtitleCodecs = []string{"subrip", "ass", "ssa", "webvtt", "mov_text", "text"}
+
+// packagingDir returns the directory the streaming package is written to.
+func packagingDir(cfg *config.Config) string {
+	if cfg.Packaging.Dir != "" {
+		return cfg.Packaging.Dir
+	}
+	return strings.TrimSuffix(cfg.Output, filepath.Ext(cfg.Output)) + "_stream"
+}
+
+// segmentDuration returns the packaging segment length in seconds.
+func segmentDuration(cfg *config.Config) float64 {
+	if cfg.Packaging.SegmentDuration > 0 {
+		return cfg.Packaging.SegmentDuration
+	}
+	return packaging.DefaultSegmentDuration
+}
+
+// keyframeInterval returns the forced keyframe interval of the video chunks:
+// the segment duration when packaging, so segments can be cut on keyframes.
+func keyframeInterval(cfg *config.Config) float64 {
+	if !cfg.Packaging.Enabled() {
+		return 0
+	}
+	return segmentDuration(cfg)
+}
+
+// newPackagingBuilders creates a builder per configured format, each writing
+// to its own subdirectory. Every video rendition is a variant sharing the
+// audio rendition, which the source's first audio stream names; the source's
+// text subtitles become WebVTT renditions.
+func newPackagingBuilders(cfg *config.Config, renditions []*videoRendition, audioPath string, probeResult *ffprobe.ProbeResult) []*packaging.PackagingBuilder {
+	segmentType := packaging.SegmentType(cfg.Packaging.HLSSegmentType)
+	if segmentType == "" {
+		segmentType = packaging.SegmentFMP4
+	}
+
+	var builders []*packaging.PackagingBuilder
+	for _, name := range cfg.Packaging.FormatNames() {
+		builder := packaging.NewPackagingBuilder(packaging.Format(name), renditions[0].Final, filepath.Join(packagingDir(cfg), name)).
+			SetSegmentDuration(segmentDuration(cfg)).
+			SetSegmentType(segmentType).
+			SetVideoTag(videoTag(renditions[0].Config))
+		for _, r := range renditions[1:] {
+			builder.AddVideoTrack(packaging.VideoTrack{Path: r.Final, Tag: videoTag(r.Config)})
+		}
+
+		if audioPath != "" {
+			track := packaging.AudioTrack{Path: audioPath, Default: true}
+			if streams := probeResult.GetAudioStreams(); len(streams) > 0 {
+				track.Language = streams[0].Language()
+				track.Name = streams[0].Title()
+			}
+			builder.AddAudioTrack(track)
+		}
+
+		if cfg.Packaging.Subtitles {
+			index := 0
+			for _, stream := range probeResult.Streams {
+				if !stream.IsSubtitle() {
+					continue
+				}
+				if slices.Contains(textSubtitleCodecs, stream.CodecName) {
+					builder.AddSubtitleTrack(packaging.SubtitleTrack{
+						Path:     cfg.Input,
+						Stream:   index,
+						Language: stream.Language(),
+						Name:     stream.Title(),
+						Default:  stream.IsDefault(),
+						Forced:   stream.IsForced(),
+					})
+				}
+				index++
+			}
+		}
+		builders = append(builders, builder)
+	}
+	return builders
+}
+
+// videoTag returns the sample entry of the encoded video in the package:
+// hvc1 for HEVC, which Apple players require, else the muxer default.
+func videoTag(cfg *config.Config) string {
+	if c, ok := codec.Lookup(cfg.Video.Codec); ok && c.Family == "hevc" {
+		return "hvc1"
+	}
+	return ""
+}
+
+// packageOutput segments the concatenated videos and audio for adaptive
+// streaming, one orchestrator task per format, and returns the entry points
+// (master playlist, manifest) that were written.
+func packageOutput(cfg *config.Config, renditions []*videoRendition, audioPath string, probeResult *ffprobe.ProbeResult, orch *orchestrator.DAGOrchestrator, log *slog.Logger) ([]string, error) {
+	startTime := time.Now()
+	builders := newPackagingBuilders(cfg, renditions, audioPath, probeResult)
+	for _, builder := range builders {
+		if cmd, err := builder.DryRun(); err == nil {
+			log.Debug("ffmpeg command", logging.KeyTaskType, builder.GetTaskType(), "args", cmd)
+		}
+		task := &orchestrator.Task{
+			ID:       "package_" + filepath.Base(filepath.Dir(builder.GetOutputPath())),
+			Command:  builder,
+			Resource: orchestrator.ResourceIO,
+		}
+		if err := orch.AddTask(task); err != nil {
+			return nil, fmt.Errorf("failed to add task: %w", err)
+		}
+	}
+
+	var failed []string
+	orch.SetProgressCallback(func(completedCount, total int, task *orchestrator.Task) {
+		if task.Error != nil {
+			log.Error("packaging task failed", logging.KeyTaskID, task.ID, "error", task.Error)
+			failed = append(failed, fmt.Sprintf("%s: %v", task.ID, task.Error))
+		}
+		encoderMetrics.TaskDone(task)
+	})
+	defer encoderMetrics.Track(orch)()
+	if _, err := orch.Execute(); err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+
+	outputs := make([]string, len(builders))
+	for i, builder := range builders {
+		outputs[i] = builder.GetOutputPath()
+	}
+	log.Info("packaging complete", "outputs", outputs, "seconds", time.Since(startTime).Seconds())
+	return outputs, nil
+}
@@ -0,0 +1,161 @@
+// Package preflight checks a configuration against the local ffmpeg build
+// before anything is encoded: the encoders, filters and muxers the
+// configured pipeline needs.
+package preflight
+
+import (
+	"encoder/command"
+	"encoder/command/audio"
+	"encoder/command/thumbnail"
+	"encoder/command/video"
+	"encoder/config"
+	"encoder/ffmpeg"
+	"encoder/quality/metric"
+	"fmt"
+	"strings"
+)
+
+// MissingError lists the features the configuration needs that the local
+// ffmpeg build lacks.
+type MissingError struct {
+	Missing []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("local ffmpeg build is missing required features:\n  - %s", strings.Join(e.Missing, "\n  - "))
+}
+
+// Detect probes the local ffmpeg build for Check. The result is cached for
+// the rest of the run, so later stages call Detect again instead of carrying
+// the capabilities around. A missing or unusable ffmpeg is not fatal: the
+// capabilities are nil, Check skips its checks and the error is returned for
+// callers that want to report it.
+func Detect() (*ffmpeg.Capabilities, error) {
+	return ffmpeg.DetectCapabilities("ffmpeg")
+}
+
+// Check checks the configuration against the detected ffmpeg build caps.
+// Returns a *MissingError listing what is missing, or nil if nothing is or
+// the build is unknown (nil caps).
+func Check(cfg *config.Config, caps *ffmpeg.Capabilities) error {
+	// Skip if detection failed or its output could not be parsed
+	if caps == nil || len(caps.Encoders) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, name := range cfg.Video.Codecs() {
+		if err := caps.CheckEncoder(name); err != nil {
+			missing = append(missing, "video codec: "+err.Error())
+		}
+	}
+	if cfg.Audio.Codec != "" {
+		if err := caps.CheckEncoder(cfg.Audio.Codec); err != nil {
+			missing = append(missing, "audio codec: "+err.Error())
+		}
+	}
+	if name := previewEncoder(cfg); name != "" {
+		if err := caps.CheckEncoder(name); err != nil {
+			missing = append(missing, "thumbnails preview: "+err.Error())
+		}
+	}
+	for _, filter := range RequiredFilters(cfg) {
+		if err := caps.CheckFilter(filter); err != nil {
+			missing = append(missing, "filter: "+err.Error())
+		}
+	}
+	if cfg.Output != "" {
+		if err := caps.CheckContainer(cfg.Output); err != nil {
+			missing = append(missing, "output: "+err.Error())
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
+
+// RequiredFilters returns the ffmpeg filters the configured pipeline uses.
+// Crop, deinterlacing and scaling filters are included when enabled.
+// Tone-mapping filters are included when HDR sources would be tone-mapped,
+// whether configured or as the fallback for encoders without 10-bit output.
+// Target-quality mode and the metrics stage add their metric filters, and
+// thumbnail outputs the filters of their builders.
+func RequiredFilters(cfg *config.Config) []string {
+	filters := command.FilterNames(audio.NormalizationFilters...)
+	switch cfg.Video.Crop {
+	case "", "off":
+	case "auto":
+		filters = append(filters, "cropdetect", "crop")
+	default:
+		filters = append(filters, "crop")
+	}
+	deinterlacer := cfg.Video.Deinterlacer
+	if deinterlacer == "" {
+		deinterlacer = "bwdif"
+	}
+	switch cfg.Video.Deinterlace {
+	case "", "auto":
+		filters = append(filters, "idet", deinterlacer, "fieldmatch", "decimate")
+	case "deinterlace":
+		filters = append(filters, deinterlacer)
+	case "ivtc":
+		filters = append(filters, "fieldmatch", deinterlacer, "decimate")
+	}
+	if tonemapsHDR(cfg) {
+		filters = append(filters, "zscale", "tonemap")
+	}
+	for _, v := range cfg.Video.Ladder() {
+		if v.Resolution != "" {
+			filters = append(filters, "scale")
+			break
+		}
+	}
+	for _, builder := range thumbnailBuilders(cfg) {
+		filters = append(filters, builder.RequiredFilters()...)
+	}
+	if cfg.Metrics.Enabled {
+		// libvmaf is optional: vmaf is skipped without it
+		for _, name := range cfg.Metrics.MetricNames() {
+			if name != string(metric.VMAF) {
+				filters = append(filters, metric.Metric(name).Filter())
+			}
+		}
+	}
+	if cfg.Video.TargetQuality > 0 {
+		// libvmaf is optional: without it the search falls back to ssim
+		m, _ := metric.Resolve(metric.Metric(cfg.Video.QualityMetric), false)
+		filters = append(filters, m.Filter())
+	}
+	return filters
+}
+
+// thumbnailBuilders returns builders for the selected thumbnail outputs,
+// configured enough to report the filters they need.
+func thumbnailBuilders(cfg *config.Config) []*thumbnail.ThumbnailBuilder {
+	var builders []*thumbnail.ThumbnailBuilder
+	for _, name := range cfg.Thumbnails.OutputNames() {
+		builders = append(builders, thumbnail.NewThumbnailBuilder(thumbnail.Kind(name), cfg.Input, cfg.Thumbnails.Dir).
+			SetSelection(thumbnail.Selection{Mode: thumbnail.Mode(cfg.Thumbnails.Selection)}).
+			SetPreviewFormat(thumbnail.PreviewFormat(cfg.Thumbnails.PreviewFormat)))
+	}
+	return builders
+}
+
+// previewEncoder returns the encoder of the animated preview, if one is
+// generated and needs a library encoder.
+func previewEncoder(cfg *config.Config) string {
+	for _, name := range cfg.Thumbnails.OutputNames() {
+		if name == "preview" {
+			return thumbnail.PreviewFormat(cfg.Thumbnails.PreviewFormat).Encoder()
+		}
+	}
+	return ""
+}
+
+// tonemapsHDR reports whether HDR sources would be tone-mapped to SDR.
+func tonemapsHDR(cfg *config.Config) bool {
+	mode, _ := video.ResolveHDRMode(video.HDRMode(cfg.Video.HDR), cfg.Video.Codec)
+	return mode == video.HDRTonemap
+}
@@ -0,0 +1,92 @@
+package preflight
+
+import (
+	"encoder/config"
+	"encoder/ffmpeg"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output = "out.mkv"
+	caps := &ffmpeg.Capabilities{
+		Binary:  "/usr/bin/ffmpeg",
+		Version: "6.1",
+		Encoders: map[string]ffmpeg.EncoderInfo{
+			"libaom-av1": {Type: "video"},
+			"libopus":    {Type: "audio"},
+		},
+		Filters: map[string]string{"pan": "", "equalizer": "", "dynaudnorm": ""},
+		Muxers:  map[string]string{"matroska": ""},
+	}
+
+	err := Check(cfg, caps)
+	var missing *MissingError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected *MissingError for missing encoder and filter, got: %v", err)
+	}
+	for _, want := range []string{
+		`has no encoder "libsvtav1" - did you mean "libaom-av1"?`,
+		`has no filter "loudnorm" - did you mean "dynaudnorm"?`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	// Everything present
+	caps.Encoders["libsvtav1"] = ffmpeg.EncoderInfo{Type: "video"}
+	caps.Filters["loudnorm"] = ""
+	for _, name := range []string{"idet", "bwdif", "fieldmatch", "decimate"} {
+		caps.Filters[name] = ""
+	}
+	if err := Check(cfg, caps); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Missing muxer for the output container
+	cfg.Output = "out.webm"
+	if err := Check(cfg, caps); err == nil || !strings.Contains(err.Error(), `has no muxer "webm"`) {
+		t.Errorf("Expected muxer error, got: %v", err)
+	}
+
+	// Unknown capabilities skip the checks
+	if err := Check(cfg, nil); err != nil {
+		t.Errorf("Unexpected error without capabilities: %v", err)
+	}
+}
+
+func TestRequiredFilters(t *testing.T) {
+	filters := RequiredFilters(config.DefaultConfig())
+	for _, want := range []string{"pan", "loudnorm", "equalizer"} {
+		if !slices.Contains(filters, want) {
+			t.Errorf("Expected %s in required filters %v", want, filters)
+		}
+	}
+}
+
+func TestRequiredFilters_Tonemap(t *testing.T) {
+	hasZscale := func(cfg *config.Config) bool {
+		return slices.Contains(RequiredFilters(cfg), "zscale")
+	}
+
+	cfg := config.DefaultConfig()
+	if hasZscale(cfg) {
+		t.Error("Preserving HDR with libsvtav1 should not need zscale")
+	}
+
+	cfg.Video.HDR = "tonemap"
+	if !hasZscale(cfg) {
+		t.Error("Expected zscale for tonemap mode")
+	}
+
+	// h264_nvenc cannot keep 10-bit HDR, so preserve falls back to tone-mapping
+	cfg.Video.HDR = "preserve"
+	cfg.Video.Codec = "h264_nvenc"
+	if !hasZscale(cfg) {
+		t.Error("Expected zscale when preserve falls back to tonemap")
+	}
+}
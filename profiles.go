@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoder/config"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runProfilesCommand lists the profiles from the config file and the drop-in
+// profiles directory.
+//
+//	encoder profiles [-config FILE]
+func runProfilesCommand(args []string) int {
+	fs := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file (default: search standard locations)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *configPath == "" {
+		*configPath = os.Getenv(config.EnvConfigPath)
+	}
+	if *configPath == "" {
+		*configPath = config.FindConfigFile()
+	}
+
+	cfg := config.DefaultConfig()
+	if *configPath != "" {
+		fileCfg, err := config.LoadConfigFile(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to load config file %s: %v\n", *configPath, err)
+			return 1
+		}
+		cfg = fileCfg
+	}
+	if err := cfg.LoadProfiles(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		fmt.Println("No profiles defined (add profiles: to the config file or files to ~/.encoder/profiles.d/)")
+		return 0
+	}
+
+	fmt.Println("Available profiles:")
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		fmt.Printf("\n  %s\n", name)
+		if p.Description != "" {
+			fmt.Printf("    %s\n", p.Description)
+		}
+		if p.Inherits != "" {
+			fmt.Printf("    Inherits: %s\n", p.Inherits)
+		}
+		if keys := p.Keys(); len(keys) > 0 {
+			fmt.Printf("    Sets:     %s\n", strings.Join(keys, ", "))
+		}
+		fmt.Printf("    From:     %s\n", p.Origin)
+	}
+	return 0
+}
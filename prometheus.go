@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoder/metrics"
+	"encoder/models"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// encoderMetrics collects the metrics of every run in the process. It is nil
+// unless a metrics endpoint is enabled, which turns all reporting into no-ops.
+var encoderMetrics *metrics.Registry
+
+// enableMetrics starts collecting metrics and serves them at
+// http://addr/metrics in the background. Does nothing if addr is empty.
+func enableMetrics(addr string, out io.Writer) error {
+	if addr == "" {
+		return nil
+	}
+	if encoderMetrics == nil {
+		encoderMetrics = metrics.New()
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("metrics endpoint: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", encoderMetrics)
+	go http.Serve(listener, mux)
+	fmt.Fprintf(out, "📈 Metrics: http://%s/metrics\n", listener.Addr())
+	return nil
+}
+
+// metricsProgress returns the progress callback of encoder tasks feeding
+// encoderMetrics, or nil when metrics are off so no progress is parsed.
+func metricsProgress() func(taskID string, progress *models.EncodingProgress) {
+	if encoderMetrics == nil {
+		return nil
+	}
+	return encoderMetrics.Progress
+}
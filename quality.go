@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoder/codec"
+	"encoder/command/video"
+	"encoder/config"
+	"encoder/logging"
+	"encoder/models"
+	"encoder/orchestrator"
+	"encoder/preflight"
+	"encoder/quality"
+	"encoder/quality/metric"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// resolveQualityTarget returns the metric and target score for
+// target-quality mode, or a zero target when CRF is fixed. A VMAF target is
+// translated to SSIM when the ffmpeg build lacks libvmaf.
+func resolveQualityTarget(cfg *config.Config, out io.Writer, log *slog.Logger) (metric.Metric, float64) {
+	if cfg.Video.TargetQuality <= 0 {
+		return "", 0
+	}
+
+	m, reason := metric.Resolve(metric.Metric(cfg.Video.QualityMetric), hasLibvmaf())
+	target := cfg.Video.TargetQuality
+	if reason != "" {
+		target = metric.EquivalentTarget(target, m)
+		fmt.Fprintf(out, "  ⚠️  VMAF unavailable (%s): targeting %s %s instead\n", reason, m, m.Format(target))
+		log.Warn("VMAF target translated", "vmaf", cfg.Video.TargetQuality, "metric", m, "target", m.Format(target), "reason", reason)
+	}
+	return m, target
+}
+
+// hasLibvmaf reports whether the local ffmpeg build has the libvmaf filter.
+// It is assumed present when the build could not be probed; a failed
+// measurement falls back to the configured CRF.
+func hasLibvmaf() bool {
+	caps, _ := preflight.Detect()
+	return caps == nil || len(caps.Filters) == 0 || caps.HasFilter("libvmaf")
+}
+
+// qualityProbeCRFs returns the CRFs probed for each chunk.
+func qualityProbeCRFs(cfg *config.Config) []int {
+	minCRF, maxCRF := codec.QualityRange(cfg.Video.Codec)
+	return quality.ProbeCRFs(cfg.Video.CRF, quality.DefaultProbeStep(minCRF, maxCRF), cfg.Video.QualityProbes, minCRF, maxCRF)
+}
+
+// newQualitySearch sets up the CRF search for a chunk: one probe command per
+// probed CRF, each encoding the chunk's sample windows with the same settings
+// as the chunk encode. Probe files are written to probeDir.
+func newQualitySearch(cfg *config.Config, src *sourceAnalysis, chunk *models.Chunk, builder *video.VideoBuilder, metric metric.Metric, target float64, probeDir string) (*quality.Search, []*quality.ProbeCommand) {
+	minCRF, maxCRF := codec.QualityRange(cfg.Video.Codec)
+	search := &quality.Search{
+		ChunkID:    chunk.ChunkID,
+		ChunkStart: chunk.StartTime,
+		Source:     chunk.SourcePath,
+		Windows:    quality.SampleWindows(chunk.StartTime, chunk.EndTime, quality.DefaultWindows, quality.DefaultWindowLength),
+		Filters:    builder.CPUFilters(),
+		Metric:     metric,
+		Target:     target,
+		DefaultCRF: cfg.Video.CRF,
+		MinCRF:     minCRF,
+		MaxCRF:     maxCRF,
+	}
+
+	var probes []*quality.ProbeCommand
+	for _, crf := range qualityProbeCRFs(cfg) {
+		samples := make([]*video.VideoBuilder, len(search.Windows))
+		for i, w := range search.Windows {
+			// Cut the window from the source, not from a pre-split segment
+			window := *chunk
+			window.StartTime, window.EndTime = w.Start, w.Start+w.Duration
+			window.SegmentPath = ""
+			output := filepath.Join(probeDir, fmt.Sprintf("chunk_%03d_crf%02d_%d.%s", chunk.ChunkID, crf, i, videoExt(cfg)))
+			samples[i] = newChunkVideoBuilder(cfg, src, &window, output)
+		}
+		probes = append(probes, quality.NewProbeCommand(search, crf, samples))
+	}
+	return search, probes
+}
+
+// resolveMetrics returns the metrics computed by the metrics stage. VMAF is
+// skipped when the ffmpeg build lacks libvmaf.
+func resolveMetrics(cfg *config.Config, out io.Writer, log *slog.Logger) []metric.Metric {
+	vmaf := hasLibvmaf()
+
+	var metrics []metric.Metric
+	for _, name := range cfg.Metrics.MetricNames() {
+		m := metric.Metric(name)
+		if m == metric.VMAF && !vmaf {
+			fmt.Fprintln(out, "  ⚠️  VMAF unavailable (ffmpeg was built without libvmaf): skipping")
+			log.Warn("skipping vmaf: ffmpeg was built without libvmaf")
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// metricsReportPath returns where the metrics report is written.
+func metricsReportPath(cfg *config.Config) string {
+	if cfg.Metrics.Report != "" {
+		return cfg.Metrics.Report
+	}
+	return cfg.Output + ".metrics.json"
+}
+
+// measureOutput compares each encoded video chunk with the source, in
+// parallel through the orchestrator, and aggregates the per-frame scores.
+// Chunks that cannot be measured are listed as failed in the report.
+func measureOutput(cfg *config.Config, chunks []*models.Chunk, src *sourceAnalysis, videoFiles []string, tempDir string, orch *orchestrator.DAGOrchestrator, out io.Writer, log *slog.Logger) (*quality.MetricsReport, error) {
+	metrics := resolveMetrics(cfg, out, log)
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics to compute")
+	}
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", tempDir, err)
+	}
+
+	startTime := time.Now()
+	cmds := make([]*quality.MetricsCommand, 0, len(chunks))
+	for i, chunk := range chunks {
+		if i >= len(videoFiles) || videoFiles[i] == "" {
+			continue
+		}
+		cmd := quality.NewMetricsCommand(chunk.ChunkID, quality.Comparison{
+			Distorted:        videoFiles[i],
+			Reference:        cfg.Input,
+			ReferenceStart:   chunk.StartTime,
+			Duration:         chunk.EndTime - chunk.StartTime,
+			ReferenceFilters: newChunkVideoBuilder(cfg, src, chunk, videoFiles[i]).CPUFilters(),
+		}, metrics, tempDir)
+		cmds = append(cmds, cmd)
+
+		task := &orchestrator.Task{
+			ID:       fmt.Sprintf("metrics_%d", chunk.ChunkID),
+			Command:  cmd,
+			Resource: orchestrator.ResourceCPU,
+		}
+		if err := orch.AddTask(task); err != nil {
+			return nil, fmt.Errorf("failed to add task: %w", err)
+		}
+	}
+
+	orch.SetProgressCallback(func(completedCount, total int, task *orchestrator.Task) {
+		if task.Error != nil {
+			log.Warn("metrics task failed", logging.KeyTaskID, task.ID, "error", task.Error)
+		}
+		fmt.Fprintf(out, "\r  Measuring: %d/%d chunks", completedCount, total)
+		encoderMetrics.TaskDone(task)
+	})
+
+	log.Info("computing metrics", "metrics", metrics, "chunks", len(cmds))
+	defer encoderMetrics.Track(orch)()
+	if _, err := orch.Execute(); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(out)
+
+	report := quality.NewMetricsReport(cmds)
+	log.Info("metrics complete", "chunks", len(cmds), "seconds", time.Since(startTime).Seconds())
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package quality
+
+import (
+	"math"
+	"sort"
+)
+
+// Probe is the score measured for one CRF.
+type Probe struct {
+	CRF   int     `json:"crf"`
+	Score float64 `json:"score"`
+}
+
+// ProbeCRFs returns n CRFs around center (included) and step apart, clamped to
+// [minCRF, maxCRF] without duplicates, in ascending order.
+func ProbeCRFs(center, step, n, minCRF, maxCRF int) []int {
+	if n <= 0 {
+		n = 1
+	}
+	if step <= 0 {
+		step = 1
+	}
+
+	first := center - step*((n-1)/2)
+	seen := make(map[int]bool, n)
+	var crfs []int
+	for i := 0; i < n; i++ {
+		crf := min(max(first+step*i, minCRF), maxCRF)
+		if !seen[crf] {
+			seen[crf] = true
+			crfs = append(crfs, crf)
+		}
+	}
+	return crfs
+}
+
+// DefaultProbeStep is the CRF spacing between probes for an encoder whose
+// quality scale spans [minCRF, maxCRF]: about an eighth of the range.
+func DefaultProbeStep(minCRF, maxCRF int) int {
+	return max((maxCRF-minCRF)/8, 1)
+}
+
+// InterpolateCRF returns the highest CRF expected to reach target, assuming
+// the score falls as CRF rises. Between probes the CRF is interpolated
+// linearly; outside them the nearest two probes are extrapolated. The result
+// is rounded towards better quality and clamped to [minCRF, maxCRF]. ok is
+// false if there are no probes.
+func InterpolateCRF(probes []Probe, target float64, minCRF, maxCRF int) (crf int, ok bool) {
+	if len(probes) == 0 {
+		return 0, false
+	}
+
+	sorted := append([]Probe{}, probes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CRF < sorted[j].CRF })
+
+	if len(sorted) == 1 {
+		// Nothing to interpolate: keep the CRF if it reaches the target
+		c := sorted[0].CRF
+		if sorted[0].Score < target {
+			c--
+		}
+		return min(max(c, minCRF), maxCRF), true
+	}
+
+	// Segment that brackets the target, or the outermost one to extrapolate
+	lo, hi := sorted[0], sorted[1]
+	if target < sorted[len(sorted)-1].Score {
+		lo, hi = sorted[len(sorted)-2], sorted[len(sorted)-1]
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Score >= target && sorted[i].Score <= target {
+			lo, hi = sorted[i-1], sorted[i]
+			break
+		}
+	}
+
+	var estimate float64
+	if lo.Score == hi.Score {
+		// Flat segment (e.g. static content): any CRF in it scores the same
+		if lo.Score >= target {
+			estimate = float64(hi.CRF)
+		} else {
+			estimate = float64(lo.CRF)
+		}
+	} else {
+		estimate = float64(lo.CRF) + (lo.Score-target)*float64(hi.CRF-lo.CRF)/(lo.Score-hi.Score)
+	}
+
+	return min(max(int(math.Floor(estimate+1e-9)), minCRF), maxCRF), true
+}
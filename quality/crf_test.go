@@ -0,0 +1,66 @@
+package quality
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProbeCRFs(t *testing.T) {
+	tests := []struct {
+		center, step, n, min, max int
+		want                      []int
+	}{
+		{28, 7, 3, 0, 63, []int{21, 28, 35}},
+		{28, 6, 4, 0, 51, []int{22, 28, 34, 40}},
+		{60, 7, 3, 0, 63, []int{53, 60, 63}},
+		{2, 7, 3, 0, 63, []int{0, 2, 9}},
+		{1, 6, 3, 0, 3, []int{0, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		got := ProbeCRFs(tt.center, tt.step, tt.n, tt.min, tt.max)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ProbeCRFs(%d, %d, %d) = %v, want %v", tt.center, tt.step, tt.n, got, tt.want)
+		}
+	}
+
+	if step := DefaultProbeStep(0, 63); step != 7 {
+		t.Errorf("DefaultProbeStep(0, 63) = %d, want 7", step)
+	}
+}
+
+func TestInterpolateCRF(t *testing.T) {
+	probes := []Probe{{CRF: 35, Score: 88}, {CRF: 21, Score: 97}, {CRF: 28, Score: 93}}
+
+	tests := []struct {
+		name   string
+		probes []Probe
+		target float64
+		want   int
+	}{
+		{"exact probe", probes, 93, 28},
+		{"between probes rounds to better quality", probes, 95, 24},
+		{"above all probes extrapolates down", probes, 99, 17},
+		{"below all probes extrapolates up", probes, 80, 46},
+		{"clamped to range", probes, 60, 63},
+		{"single probe reaching target", []Probe{{CRF: 30, Score: 95}}, 93, 30},
+		{"single probe missing target", []Probe{{CRF: 30, Score: 90}}, 93, 29},
+		{"flat scores keep the highest CRF", []Probe{{CRF: 20, Score: 99}, {CRF: 30, Score: 99}}, 95, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := InterpolateCRF(tt.probes, tt.target, 0, 63)
+			if !ok {
+				t.Fatal("Expected a CRF")
+			}
+			if got != tt.want {
+				t.Errorf("InterpolateCRF = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := InterpolateCRF(nil, 93, 0, 63); ok {
+		t.Error("Expected no CRF without probes")
+	}
+}
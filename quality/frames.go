@@ -0,0 +1,109 @@
+package quality
+
+import (
+	"bufio"
+	"encoder/quality/metric"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxPSNR replaces the infinite PSNR of identical frames so that averages
+// stay finite.
+const maxPSNR = 100.0
+
+// ReadFrameScores reads the per-frame log the metric's filter wrote to path
+// and returns one score per frame in frame order.
+func ReadFrameScores(m metric.Metric, path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch m {
+	case metric.VMAF:
+		return ParseVMAFLog(f)
+	case metric.SSIM:
+		return ParseSSIMStats(f)
+	case metric.PSNR:
+		return ParsePSNRStats(f)
+	}
+	return nil, fmt.Errorf("unknown metric %q", m)
+}
+
+// ParseVMAFLog parses a libvmaf JSON log (log_fmt=json).
+func ParseVMAFLog(r io.Reader) ([]float64, error) {
+	var log struct {
+		Frames []struct {
+			FrameNum int                `json:"frameNum"`
+			Metrics  map[string]float64 `json:"metrics"`
+		} `json:"frames"`
+	}
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, fmt.Errorf("invalid libvmaf log: %w", err)
+	}
+
+	scores := make([]float64, 0, len(log.Frames))
+	for _, frame := range log.Frames {
+		score, ok := frame.Metrics["vmaf"]
+		if !ok {
+			return nil, fmt.Errorf("libvmaf log frame %d has no vmaf score", frame.FrameNum)
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// ParseSSIMStats parses an ssim filter stats_file.
+//
+//	n:1 Y:0.987623 U:0.991230 V:0.990981 All:0.988734 (19.478362)
+func ParseSSIMStats(r io.Reader) ([]float64, error) {
+	return parseStatsFile(r, "All")
+}
+
+// ParsePSNRStats parses a psnr filter stats_file.
+//
+//	n:1 mse_avg:2.57 mse_y:3.12 mse_u:1.41 mse_v:1.52 psnr_avg:44.03 psnr_y:43.19 psnr_u:46.64 psnr_v:46.31
+func ParsePSNRStats(r io.Reader) ([]float64, error) {
+	return parseStatsFile(r, "psnr_avg")
+}
+
+// parseStatsFile reads the key:value field named key from each line of an
+// ssim or psnr stats file.
+func parseStatsFile(r io.Reader, key string) ([]float64, error) {
+	var scores []float64
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		found := false
+		for _, field := range strings.Fields(text) {
+			name, value, ok := strings.Cut(field, ":")
+			if !ok || name != key {
+				continue
+			}
+			if value == "inf" {
+				scores = append(scores, maxPSNR)
+			} else {
+				score, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid %s value %q", line, key, value)
+				}
+				scores = append(scores, min(score, maxPSNR))
+			}
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("line %d: no %s value", line, key)
+		}
+	}
+	return scores, scanner.Err()
+}
@@ -0,0 +1,84 @@
+package quality
+
+import (
+	"encoder/quality/metric"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseVMAFLog(t *testing.T) {
+	log := `{
+  "version": "2.3.1",
+  "frames": [
+    {"frameNum": 0, "metrics": {"integer_adm2": 0.98, "vmaf": 95.5}},
+    {"frameNum": 1, "metrics": {"integer_adm2": 0.97, "vmaf": 91.25}}
+  ],
+  "pooled_metrics": {"vmaf": {"mean": 93.375}}
+}`
+	scores, err := ParseVMAFLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseVMAFLog failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 95.5 || scores[1] != 91.25 {
+		t.Errorf("Expected [95.5 91.25], got %v", scores)
+	}
+
+	if _, err := ParseVMAFLog(strings.NewReader(`{"frames": [{"frameNum": 0, "metrics": {}}]}`)); err == nil {
+		t.Error("Expected error for frame without vmaf score")
+	}
+	if _, err := ParseVMAFLog(strings.NewReader("not json")); err == nil {
+		t.Error("Expected error for invalid log")
+	}
+}
+
+func TestParseSSIMStats(t *testing.T) {
+	stats := "n:1 Y:0.987623 U:0.991230 V:0.990981 All:0.988734 (19.478362)\n" +
+		"n:2 Y:0.981000 U:0.990000 V:0.990000 All:0.984500 (18.079000)\n\n"
+	scores, err := ParseSSIMStats(strings.NewReader(stats))
+	if err != nil {
+		t.Fatalf("ParseSSIMStats failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 0.988734 || scores[1] != 0.9845 {
+		t.Errorf("Expected [0.988734 0.9845], got %v", scores)
+	}
+
+	if _, err := ParseSSIMStats(strings.NewReader("n:1 Y:0.98\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected error naming line 1, got %v", err)
+	}
+}
+
+func TestParsePSNRStats(t *testing.T) {
+	stats := "n:1 mse_avg:2.57 mse_y:3.12 mse_u:1.41 mse_v:1.52 psnr_avg:44.03 psnr_y:43.19 psnr_u:46.64 psnr_v:46.31\n" +
+		"n:2 mse_avg:0.00 mse_y:0.00 mse_u:0.00 mse_v:0.00 psnr_avg:inf psnr_y:inf psnr_u:inf psnr_v:inf\n"
+	scores, err := ParsePSNRStats(strings.NewReader(stats))
+	if err != nil {
+		t.Fatalf("ParsePSNRStats failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 44.03 || scores[1] != maxPSNR {
+		t.Errorf("Expected [44.03 %v], got %v", maxPSNR, scores)
+	}
+
+	if _, err := ParsePSNRStats(strings.NewReader("n:1 psnr_avg:abc\n")); err == nil {
+		t.Error("Expected error for invalid psnr value")
+	}
+}
+
+func TestReadFrameScores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssim.log")
+	if err := os.WriteFile(path, []byte("n:1 All:0.95 (13.0)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scores, err := ReadFrameScores(metric.SSIM, path)
+	if err != nil || len(scores) != 1 || scores[0] != 0.95 {
+		t.Errorf("Expected [0.95], got %v (err %v)", scores, err)
+	}
+	if _, err := ReadFrameScores(metric.PSNR, filepath.Join(t.TempDir(), "missing.log")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := ReadFrameScores("butteraugli", path); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+}
@@ -0,0 +1,203 @@
+// Package quality measures encoded video against the source and searches for
+// the CRF that reaches a target quality.
+//
+// Scores are computed by ffmpeg's libvmaf, ssim or psnr filters on short
+// sample windows of a chunk. In target-quality mode each chunk is probed at a
+// few CRFs, the CRF that hits the target score is interpolated from the
+// probes, and the chunk is encoded at that CRF. The metrics stage compares
+// each finished chunk with the source and aggregates the per-frame scores
+// into a MetricsReport.
+package quality
+
+import (
+	"context"
+	"encoder/quality/metric"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	vmafScore = regexp.MustCompile(`VMAF score[:=]\s*([0-9.]+)`)
+	ssimScore = regexp.MustCompile(`SSIM .*All:\s*([0-9.]+)`)
+	psnrScore = regexp.MustCompile(`PSNR .*average:\s*([0-9.]+|inf)`)
+)
+
+// ParseScore extracts the summary score the metric's filter prints at the
+// end of ffmpeg's log output.
+func ParseScore(m metric.Metric, output string) (float64, error) {
+	var re *regexp.Regexp
+	switch m {
+	case metric.VMAF:
+		re = vmafScore
+	case metric.SSIM:
+		re = ssimScore
+	case metric.PSNR:
+		re = psnrScore
+	default:
+		return 0, fmt.Errorf("unknown metric %q", m)
+	}
+
+	matches := re.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("no %s score in ffmpeg output", m)
+	}
+	value := matches[len(matches)-1][1]
+	if value == "inf" {
+		// Identical frames
+		return 100, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// Default sample windows per chunk and their length in seconds.
+const (
+	DefaultWindows      = 2
+	DefaultWindowLength = 5.0
+)
+
+// Window is a sample of a chunk in source time (seconds).
+type Window struct {
+	Start    float64
+	Duration float64
+}
+
+// SampleWindows returns n windows of the given length spread evenly across
+// [start, end). A range too short for n windows is covered by one window.
+func SampleWindows(start, end float64, n int, length float64) []Window {
+	span := end - start
+	if span <= 0 {
+		return nil
+	}
+	if n <= 0 || length <= 0 || span <= float64(n)*length {
+		return []Window{{Start: start, Duration: span}}
+	}
+
+	windows := make([]Window, n)
+	step := span / float64(n)
+	for i := range windows {
+		// Centre each window in its slice of the range
+		windows[i] = Window{Start: start + step*float64(i) + (step-length)/2, Duration: length}
+	}
+	return windows
+}
+
+// Comparison describes one measurement: a window of the encoded file and the
+// matching window of the source.
+type Comparison struct {
+	Distorted      string  // Encoded file
+	DistortedStart float64 // Offset of the window in the encoded file
+	Reference      string  // Source file
+	ReferenceStart float64 // Offset of the window in the source
+	Duration       float64
+
+	// ReferenceFilters are applied to the source before comparison so it
+	// matches what the encoder saw (deinterlacing, crop, tone-mapping).
+	ReferenceFilters []string
+}
+
+// Args returns the ffmpeg arguments that compute metric for the comparison.
+func (c Comparison) Args(metric metric.Metric) []string {
+	graph := fmt.Sprintf("[0:v]setpts=PTS-STARTPTS[dist];[1:v]%s[ref];[dist][ref]%s",
+		c.referenceChain(), metric.Filter())
+	return c.args(graph)
+}
+
+// StatsArgs returns the ffmpeg arguments that compute all metrics in one
+// pass, each writing its per-frame scores to the matching path in logs
+// (a libvmaf JSON log, or an ssim/psnr stats file).
+func (c Comparison) StatsArgs(metrics []metric.Metric, logs []string) []string {
+	n := len(metrics)
+	var dist, ref, compare []string
+	for i, m := range metrics {
+		dist = append(dist, fmt.Sprintf("[d%d]", i))
+		ref = append(ref, fmt.Sprintf("[r%d]", i))
+
+		path := escapeFilterValue(logs[i])
+		filter := fmt.Sprintf("%s=stats_file=%s", m.Filter(), path)
+		if m == metric.VMAF {
+			filter = fmt.Sprintf("libvmaf=log_fmt=json:log_path=%s", path)
+		}
+		compare = append(compare, fmt.Sprintf("[d%d][r%d]%s", i, i, filter))
+	}
+
+	graph := fmt.Sprintf("[0:v]setpts=PTS-STARTPTS,split=%d%s;[1:v]%s,split=%d%s;%s",
+		n, strings.Join(dist, ""), c.referenceChain(), n, strings.Join(ref, ""), strings.Join(compare, ";"))
+	return c.args(graph)
+}
+
+// referenceChain is the filter chain that prepares the source for comparison.
+func (c Comparison) referenceChain() string {
+	ref := append(append([]string{}, c.ReferenceFilters...), "setpts=PTS-STARTPTS")
+	return strings.Join(ref, ",")
+}
+
+func (c Comparison) args(graph string) []string {
+	return []string{
+		"-hide_banner", "-nostats",
+		"-ss", formatSeconds(c.DistortedStart), "-t", formatSeconds(c.Duration), "-i", c.Distorted,
+		"-ss", formatSeconds(c.ReferenceStart), "-t", formatSeconds(c.Duration), "-i", c.Reference,
+		"-lavfi", graph,
+		"-f", "null", "-",
+	}
+}
+
+// escapeFilterValue escapes a filter option value (e.g. a path) for use in
+// a filtergraph.
+func escapeFilterValue(s string) string {
+	return strings.NewReplacer(`\`, `\\\\`, `:`, `\\:`, `'`, `\\\'`, `,`, `\,`, `;`, `\;`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// Measure runs ffmpeg to compute metric for the comparison.
+func Measure(ctx context.Context, c Comparison, metric metric.Metric) (float64, error) {
+	output, err := exec.CommandContext(ctx, "ffmpeg", c.Args(metric)...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("%s measurement failed: %w (output: %s)", metric, err, lastLines(string(output), 5))
+	}
+	return ParseScore(metric, string(output))
+}
+
+// MeasureWindows measures each window of the encoded file against the source
+// and returns the mean score. encodedStart is the source time at which the
+// encoded file begins.
+func MeasureWindows(ctx context.Context, encoded string, encodedStart float64, source string, windows []Window, filters []string, metric metric.Metric) (float64, error) {
+	if len(windows) == 0 {
+		return 0, fmt.Errorf("no windows to measure")
+	}
+
+	total := 0.0
+	for _, w := range windows {
+		score, err := Measure(ctx, Comparison{
+			Distorted:        encoded,
+			DistortedStart:   math.Max(0, w.Start-encodedStart),
+			Reference:        source,
+			ReferenceStart:   w.Start,
+			Duration:         w.Duration,
+			ReferenceFilters: filters,
+		}, metric)
+		if err != nil {
+			return 0, err
+		}
+		total += score
+	}
+	return total / float64(len(windows)), nil
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
+
+// lastLines returns at most n trailing lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,98 @@
+// Package metric names the objective video quality metrics (VMAF, SSIM,
+// PSNR), their ffmpeg filters and valid target scores.
+//
+// It is split from package quality, which runs ffmpeg and builds probe
+// commands, so that config can validate quality settings on its own.
+package metric
+
+import "fmt"
+
+// Metric is an objective video quality metric.
+type Metric string
+
+const (
+	VMAF Metric = "vmaf" // 0-100, needs an ffmpeg build with libvmaf
+	SSIM Metric = "ssim" // 0-1
+	PSNR Metric = "psnr" // dB
+)
+
+// Filter returns the ffmpeg filter that computes the metric.
+func (m Metric) Filter() string {
+	if m == VMAF {
+		return "libvmaf"
+	}
+	return string(m)
+}
+
+// Format formats a score with the metric's usual precision.
+func (m Metric) Format(score float64) string {
+	switch m {
+	case SSIM:
+		return fmt.Sprintf("%.4f", score)
+	case PSNR:
+		return fmt.Sprintf("%.2f dB", score)
+	}
+	return fmt.Sprintf("%.2f", score)
+}
+
+// ValidTarget reports whether target is a reachable score for the metric.
+func (m Metric) ValidTarget(target float64) bool {
+	switch m {
+	case SSIM:
+		return target > 0 && target <= 1
+	case VMAF, PSNR:
+		return target > 0 && target <= 100
+	}
+	return false
+}
+
+// Resolve returns the metric to use given whether the ffmpeg build has
+// libvmaf. VMAF falls back to SSIM and reason explains why. An empty metric
+// means VMAF.
+func Resolve(metric Metric, hasLibvmaf bool) (resolved Metric, reason string) {
+	if metric == "" {
+		metric = VMAF
+	}
+	if metric == VMAF && !hasLibvmaf {
+		return SSIM, "ffmpeg was built without libvmaf"
+	}
+	return metric, ""
+}
+
+// vmafEquivalents maps VMAF scores to roughly equivalent SSIM and PSNR
+// scores for typical 1080p content, used to translate a VMAF target when
+// falling back to another metric.
+var vmafEquivalents = []struct{ vmaf, ssim, psnr float64 }{
+	{70, 0.930, 33},
+	{80, 0.955, 36},
+	{90, 0.975, 39.5},
+	{95, 0.985, 42},
+	{98, 0.992, 45},
+	{100, 1.000, 50},
+}
+
+// EquivalentTarget translates a VMAF target into an approximately equivalent
+// score for metric. The mapping is content-dependent and only a rough guide.
+func EquivalentTarget(vmaf float64, metric Metric) float64 {
+	pick := func(i int) float64 {
+		if metric == PSNR {
+			return vmafEquivalents[i].psnr
+		}
+		return vmafEquivalents[i].ssim
+	}
+	if metric == VMAF {
+		return vmaf
+	}
+
+	if vmaf <= vmafEquivalents[0].vmaf {
+		return pick(0)
+	}
+	for i := 1; i < len(vmafEquivalents); i++ {
+		lo, hi := vmafEquivalents[i-1], vmafEquivalents[i]
+		if vmaf <= hi.vmaf {
+			t := (vmaf - lo.vmaf) / (hi.vmaf - lo.vmaf)
+			return pick(i-1) + t*(pick(i)-pick(i-1))
+		}
+	}
+	return pick(len(vmafEquivalents) - 1)
+}
@@ -0,0 +1,33 @@
+package metric
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	if m, reason := Resolve("", true); m != VMAF || reason != "" {
+		t.Errorf("Expected vmaf by default, got %s (%s)", m, reason)
+	}
+	if m, reason := Resolve(VMAF, false); m != SSIM || reason == "" {
+		t.Errorf("Expected ssim fallback with a reason, got %s (%q)", m, reason)
+	}
+	if m, _ := Resolve(PSNR, false); m != PSNR {
+		t.Errorf("Expected psnr to be kept, got %s", m)
+	}
+}
+
+func TestEquivalentTarget(t *testing.T) {
+	if got := EquivalentTarget(95, SSIM); got != 0.985 {
+		t.Errorf("SSIM for VMAF 95 = %v, want 0.985", got)
+	}
+	if got := EquivalentTarget(85, PSNR); math.Abs(got-37.75) > 1e-9 {
+		t.Errorf("PSNR for VMAF 85 = %v, want 37.75", got)
+	}
+	if got := EquivalentTarget(50, SSIM); got != 0.930 {
+		t.Errorf("Expected lowest entry below the table, got %v", got)
+	}
+	if got := EquivalentTarget(93, VMAF); got != 93 {
+		t.Errorf("VMAF target must be unchanged, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package quality
+
+import (
+	"encoder/quality/metric"
+	"strings"
+	"testing"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		metric metric.Metric
+		output string
+		want   float64
+	}{
+		{metric.VMAF, "[Parsed_libvmaf_4 @ 0x5600c0] VMAF score: 94.871263\n", 94.871263},
+		{metric.SSIM, "[Parsed_ssim_4 @ 0x5600c0] SSIM Y:0.981234 (17.26) U:0.990000 (20.00) V:0.991000 (20.46) All:0.985128 (18.28)\n", 0.985128},
+		{metric.PSNR, "[Parsed_psnr_4 @ 0x5600c0] PSNR y:41.23 u:44.01 v:44.50 average:42.05 min:38.10 max:47.77\n", 42.05},
+		{metric.PSNR, "[Parsed_psnr_4 @ 0x5600c0] PSNR y:inf u:inf v:inf average:inf min:inf max:inf\n", 100},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseScore(tt.metric, tt.output)
+		if err != nil {
+			t.Errorf("ParseScore(%s) failed: %v", tt.metric, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseScore(%s) = %v, want %v", tt.metric, got, tt.want)
+		}
+	}
+
+	if _, err := ParseScore(metric.VMAF, "frame=  120 fps=0.0"); err == nil {
+		t.Error("Expected error for output without a score")
+	}
+}
+
+func TestSampleWindows(t *testing.T) {
+	windows := SampleWindows(100, 140, 2, 5)
+	want := []Window{{107.5, 5}, {127.5, 5}}
+	if len(windows) != len(want) {
+		t.Fatalf("Expected %d windows, got %v", len(want), windows)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d = %v, want %v", i, windows[i], want[i])
+		}
+	}
+
+	// Short chunk: one window covering it
+	if got := SampleWindows(10, 16, 2, 5); len(got) != 1 || got[0] != (Window{10, 6}) {
+		t.Errorf("Expected whole-chunk window, got %v", got)
+	}
+	if SampleWindows(10, 10, 2, 5) != nil {
+		t.Error("Expected no windows for an empty range")
+	}
+}
+
+func TestComparison_Args(t *testing.T) {
+	c := Comparison{
+		Distorted:        "chunk.mkv",
+		DistortedStart:   7.5,
+		Reference:        "source.mkv",
+		ReferenceStart:   107.5,
+		Duration:         5,
+		ReferenceFilters: []string{"crop=1920:800:0:140"},
+	}
+
+	args := strings.Join(c.Args(metric.VMAF), " ")
+	for _, want := range []string{
+		"-ss 7.500 -t 5.000 -i chunk.mkv",
+		"-ss 107.500 -t 5.000 -i source.mkv",
+		"[1:v]crop=1920:800:0:140,setpts=PTS-STARTPTS[ref];[dist][ref]libvmaf",
+		"-f null -",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in %s", want, args)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package quality
+
+import (
+	"encoder/command"
+	"encoder/quality/metric"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MetricsCommand compares an encoded chunk with the source and reads the
+// per-frame scores of every requested metric. All metrics are computed in a
+// single ffmpeg pass.
+type MetricsCommand struct {
+	chunkID    uint
+	comparison Comparison
+	metrics    []metric.Metric
+	statsDir   string
+	priority   int
+
+	frames map[metric.Metric][]FrameScore
+}
+
+// NewMetricsCommand creates the comparison of one chunk. The per-frame logs
+// are written to statsDir.
+func NewMetricsCommand(chunkID uint, comparison Comparison, metrics []metric.Metric, statsDir string) *MetricsCommand {
+	return &MetricsCommand{
+		chunkID:    chunkID,
+		comparison: comparison,
+		metrics:    metrics,
+		statsDir:   statsDir,
+		priority:   command.PriorityLow,
+	}
+}
+
+// statsPath returns the per-frame log file of a metric.
+func (m *MetricsCommand) statsPath(kind metric.Metric) string {
+	ext := "log"
+	if kind == metric.VMAF {
+		ext = "json"
+	}
+	return filepath.Join(m.statsDir, fmt.Sprintf("chunk_%03d_%s.%s", m.chunkID, kind, ext))
+}
+
+// BuildArgs returns the ffmpeg arguments of the comparison.
+func (m *MetricsCommand) BuildArgs() []string {
+	logs := make([]string, len(m.metrics))
+	for i, metric := range m.metrics {
+		logs[i] = m.statsPath(metric)
+	}
+	return m.comparison.StatsArgs(m.metrics, logs)
+}
+
+// Run computes the metrics and reads the per-frame scores. Frame times are
+// spread evenly over the compared window of the source.
+func (m *MetricsCommand) Run() error {
+	output, err := exec.Command("ffmpeg", m.BuildArgs()...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("metrics for chunk %d failed: %w (output: %s)", m.chunkID, err, lastLines(string(output), 5))
+	}
+
+	frames := make(map[metric.Metric][]FrameScore, len(m.metrics))
+	for _, metric := range m.metrics {
+		scores, err := ReadFrameScores(metric, m.statsPath(metric))
+		if err != nil {
+			return fmt.Errorf("metrics for chunk %d: %s: %w", m.chunkID, metric, err)
+		}
+		step := 0.0
+		if len(scores) > 0 {
+			step = m.comparison.Duration / float64(len(scores))
+		}
+		for i, score := range scores {
+			frames[metric] = append(frames[metric], FrameScore{
+				Time:  m.comparison.ReferenceStart + float64(i)*step,
+				Score: score,
+			})
+		}
+	}
+	m.frames = frames
+	return nil
+}
+
+// Frames returns the per-frame scores read by Run, by metric.
+func (m *MetricsCommand) Frames() map[metric.Metric][]FrameScore {
+	return m.frames
+}
+
+// DryRun returns the command that would be executed without running it
+func (m *MetricsCommand) DryRun() (string, error) {
+	return "ffmpeg " + strings.Join(m.BuildArgs(), " "), nil
+}
+
+// GetPriority returns the task priority
+func (m *MetricsCommand) GetPriority() int {
+	return m.priority
+}
+
+// SetPriority sets the task priority
+func (m *MetricsCommand) SetPriority(priority int) command.Command {
+	m.priority = priority
+	return m
+}
+
+// GetTaskType returns the task type identifier
+func (m *MetricsCommand) GetTaskType() command.TaskType {
+	return command.TaskTypeQuality
+}
+
+// GetInputPath returns the encoded chunk
+func (m *MetricsCommand) GetInputPath() string {
+	return m.comparison.Distorted
+}
+
+// GetOutputPath returns the first metric's per-frame log
+func (m *MetricsCommand) GetOutputPath() string {
+	if len(m.metrics) == 0 {
+		return ""
+	}
+	return m.statsPath(m.metrics[0])
+}
+
+// ChunkMetrics are the mean scores of one chunk.
+type ChunkMetrics struct {
+	ChunkID uint                      `json:"chunk_id"`
+	Start   float64                   `json:"start"`
+	End     float64                   `json:"end"`
+	Scores  map[metric.Metric]float64 `json:"scores"`
+}
+
+// MetricsReport aggregates the metrics of all chunks of an encode.
+type MetricsReport struct {
+	Metrics map[metric.Metric]Stats `json:"metrics"`
+	Chunks  []ChunkMetrics          `json:"chunks"`
+	Failed  []uint                  `json:"failed_chunks,omitempty"` // Chunks that could not be measured
+}
+
+// NewMetricsReport aggregates the results of the metrics commands. Commands
+// that did not run successfully are listed as failed.
+func NewMetricsReport(cmds []*MetricsCommand) *MetricsReport {
+	r := &MetricsReport{Metrics: make(map[metric.Metric]Stats)}
+	all := make(map[metric.Metric][]FrameScore)
+
+	for _, cmd := range cmds {
+		if cmd.frames == nil {
+			r.Failed = append(r.Failed, cmd.chunkID)
+			continue
+		}
+		chunk := ChunkMetrics{
+			ChunkID: cmd.chunkID,
+			Start:   cmd.comparison.ReferenceStart,
+			End:     cmd.comparison.ReferenceStart + cmd.comparison.Duration,
+			Scores:  make(map[metric.Metric]float64),
+		}
+		for metric, frames := range cmd.frames {
+			all[metric] = append(all[metric], frames...)
+			chunk.Scores[metric] = Summarize(frames, DefaultSegmentLength, 0).Mean
+		}
+		r.Chunks = append(r.Chunks, chunk)
+	}
+
+	for metric, frames := range all {
+		sort.Slice(frames, func(i, j int) bool { return frames[i].Time < frames[j].Time })
+		r.Metrics[metric] = Summarize(frames, DefaultSegmentLength, DefaultWorstSegments)
+	}
+	sort.Slice(r.Chunks, func(i, j int) bool { return r.Chunks[i].ChunkID < r.Chunks[j].ChunkID })
+	sort.Slice(r.Failed, func(i, j int) bool { return r.Failed[i] < r.Failed[j] })
+	return r
+}
+
+// WriteJSON writes the report to path.
+func (r *MetricsReport) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics report: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Lines describes each metric in one line, e.g.
+// "vmaf  mean 94.21  harmonic 94.05  p1 88.10  worst 00:12:04 (81.30)".
+func (r *MetricsReport) Lines() []string {
+	var lines []string
+	for _, metric := range []metric.Metric{metric.VMAF, metric.SSIM, metric.PSNR} {
+		s, ok := r.Metrics[metric]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("%-4s  mean %s  harmonic %s  p1 %s", metric,
+			metric.Format(s.Mean), metric.Format(s.HarmonicMean), metric.Format(s.P1))
+		if len(s.Worst) > 0 {
+			line += fmt.Sprintf("  worst %s (%s)", formatTimestamp(s.Worst[0].Start), metric.Format(s.Worst[0].Score))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatTimestamp formats seconds as HH:MM:SS.
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+}
@@ -0,0 +1,118 @@
+package quality
+
+import (
+	"encoder/quality/metric"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMetricsFFmpeg writes four frames to every stats file and log named in
+// the filtergraph. Comparisons of a file named "broken" fail.
+const fakeMetricsFFmpeg = `#!/bin/sh
+case "$*" in
+*broken*) echo "Invalid data found when processing input" >&2; exit 1 ;;
+esac
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-lavfi" ]; then graph="$2"; fi
+	shift
+done
+for path in $(echo "$graph" | tr ';' '\n' | sed -n 's/.*stats_file=//p'); do
+	case "$graph" in
+	*"psnr=stats_file=$path"*) f=psnr_avg; s1=40.0; s2=30.0 ;;
+	*) f=All; s1=0.99; s2=0.90 ;;
+	esac
+	for s in $s1 $s1 $s1 $s2; do echo "n:1 $f:$s" >> "$path"; done
+done
+for path in $(echo "$graph" | tr ';' '\n' | sed -n 's/.*log_path=//p'); do
+	echo '{"frames":[{"frameNum":0,"metrics":{"vmaf":96}},{"frameNum":1,"metrics":{"vmaf":96}},{"frameNum":2,"metrics":{"vmaf":96}},{"frameNum":3,"metrics":{"vmaf":80}}]}' > "$path"
+done
+`
+
+func TestComparison_StatsArgs(t *testing.T) {
+	c := Comparison{
+		Distorted:        "chunk.mkv",
+		Reference:        "source.mkv",
+		ReferenceStart:   600,
+		Duration:         60,
+		ReferenceFilters: []string{"crop=1920:800:0:140"},
+	}
+	args := c.StatsArgs([]metric.Metric{metric.VMAF, metric.PSNR}, []string{"/tmp/a:b.json", "/tmp/c.log"})
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"-ss 600.000 -t 60.000 -i source.mkv",
+		"[0:v]setpts=PTS-STARTPTS,split=2[d0][d1]",
+		"[1:v]crop=1920:800:0:140,setpts=PTS-STARTPTS,split=2[r0][r1]",
+		`[d0][r0]libvmaf=log_fmt=json:log_path=/tmp/a\\:b.json`,
+		"[d1][r1]psnr=stats_file=/tmp/c.log",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in args: %s", want, joined)
+		}
+	}
+}
+
+func TestMetricsCommand_Run(t *testing.T) {
+	installFakeFFmpeg(t, fakeMetricsFFmpeg)
+	dir := t.TempDir()
+	metrics := []metric.Metric{metric.VMAF, metric.SSIM, metric.PSNR}
+
+	good := NewMetricsCommand(1, Comparison{Distorted: "chunk_001.mkv", Reference: "source.mkv", ReferenceStart: 10, Duration: 2}, metrics, dir)
+	if err := good.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	vmaf := good.Frames()[metric.VMAF]
+	if len(vmaf) != 4 {
+		t.Fatalf("Expected 4 vmaf frames, got %v", vmaf)
+	}
+	// Frames are spread over the compared window of the source
+	if vmaf[0].Time != 10 || vmaf[3].Time != 11.5 || vmaf[3].Score != 80 {
+		t.Errorf("Unexpected frame times or scores: %v", vmaf)
+	}
+
+	broken := NewMetricsCommand(2, Comparison{Distorted: "broken.mkv", Reference: "source.mkv", ReferenceStart: 12, Duration: 2}, metrics, dir)
+	if err := broken.Run(); err == nil || !strings.Contains(err.Error(), "Invalid data") {
+		t.Errorf("Expected error with ffmpeg output, got %v", err)
+	}
+
+	report := NewMetricsReport([]*MetricsCommand{broken, good})
+	if len(report.Failed) != 1 || report.Failed[0] != 2 {
+		t.Errorf("Expected chunk 2 to be listed as failed, got %v", report.Failed)
+	}
+	if len(report.Chunks) != 1 || report.Chunks[0].Scores[metric.VMAF] != 92 {
+		t.Errorf("Expected chunk 1 with mean vmaf 92, got %+v", report.Chunks)
+	}
+	if s := report.Metrics[metric.PSNR]; s.Frames != 4 || s.P1 != 30 {
+		t.Errorf("Expected 4 psnr frames with p1 30, got %+v", s)
+	}
+
+	lines := report.Lines()
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "vmaf  mean 92.00") || !strings.Contains(lines[0], "worst 00:00:10 (92.00)") {
+		t.Errorf("Unexpected summary lines: %q", lines)
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := report.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded MetricsReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if decoded.Metrics[metric.SSIM].Frames != 4 {
+		t.Errorf("Expected ssim stats in JSON, got %s", data)
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	if got := formatTimestamp(3725.9); got != "01:02:05" {
+		t.Errorf("Expected 01:02:05, got %s", got)
+	}
+}
@@ -0,0 +1,318 @@
+package quality
+
+import (
+	"context"
+	"encoder/command"
+	"encoder/command/video"
+	"encoder/quality/metric"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Search picks the CRF for one chunk. Probe commands record the score of
+// each probed CRF; the chunk's TargetCommand then interpolates the CRF that
+// reaches Target and measures the encoded chunk on the same windows.
+type Search struct {
+	ChunkID    uint
+	ChunkStart float64  // Source time at which the chunk starts
+	Source     string   // Source file the chunk is cut from
+	Windows    []Window // Sample windows probed and measured
+	Filters    []string // Encoder filters, replayed on the source for comparison
+
+	Metric     metric.Metric
+	Target     float64
+	DefaultCRF int // Used when no probe succeeds
+	MinCRF     int
+	MaxCRF     int
+
+	mu       sync.Mutex
+	probes   []Probe
+	errors   []string
+	crf      int
+	measured float64
+	done     bool
+}
+
+// record stores a probe result; safe for concurrent probes.
+func (s *Search) record(p Probe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes = append(s.probes, p)
+}
+
+// fail stores a non-fatal error; the search continues without the step.
+func (s *Search) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, err.Error())
+}
+
+// CRF returns the CRF interpolated from the recorded probes, or DefaultCRF
+// if none succeeded.
+func (s *Search) CRF() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	crf, ok := InterpolateCRF(s.probes, s.Target, s.MinCRF, s.MaxCRF)
+	if !ok {
+		crf = s.DefaultCRF
+	}
+	s.crf = crf
+	return crf
+}
+
+// Result returns the outcome of the search.
+func (s *Search) Result() ChunkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	probes := append([]Probe{}, s.probes...)
+	sort.Slice(probes, func(i, j int) bool { return probes[i].CRF < probes[j].CRF })
+	return ChunkResult{
+		ChunkID:  s.ChunkID,
+		CRF:      s.crf,
+		Score:    s.measured,
+		Measured: s.done,
+		Probes:   probes,
+		Errors:   append([]string{}, s.errors...),
+	}
+}
+
+// ProbeCommand encodes the sample windows of a chunk at one CRF and records
+// their mean score in the chunk's Search. Failures are recorded rather than
+// returned, so the chunk is still encoded (at the default CRF if needed).
+type ProbeCommand struct {
+	search   *Search
+	crf      int
+	samples  []*video.VideoBuilder // One encode per search window
+	priority int
+}
+
+// NewProbeCommand creates a probe of search at crf. samples must hold one
+// encode per search window, cut to that window.
+func NewProbeCommand(search *Search, crf int, samples []*video.VideoBuilder) *ProbeCommand {
+	for _, sample := range samples {
+		sample.SetCRF(crf)
+	}
+	return &ProbeCommand{
+		search:   search,
+		crf:      crf,
+		samples:  samples,
+		priority: command.PriorityNormal,
+	}
+}
+
+// CRF returns the probed CRF.
+func (p *ProbeCommand) CRF() int {
+	return p.crf
+}
+
+// comparison returns the measurement of sample i against the source.
+func (p *ProbeCommand) comparison(i int) Comparison {
+	w := p.search.Windows[i]
+	return Comparison{
+		Distorted:        p.samples[i].GetOutputPath(),
+		Reference:        p.search.Source,
+		ReferenceStart:   w.Start,
+		Duration:         w.Duration,
+		ReferenceFilters: p.search.Filters,
+	}
+}
+
+// BuildArgs returns the arguments of the first sample encode.
+func (p *ProbeCommand) BuildArgs() []string {
+	if len(p.samples) == 0 {
+		return nil
+	}
+	return p.samples[0].BuildArgs()
+}
+
+// Run encodes and measures every sample, then removes the sample files.
+func (p *ProbeCommand) Run() error {
+	if len(p.samples) != len(p.search.Windows) {
+		p.search.fail(fmt.Errorf("crf %d: %d samples for %d windows", p.crf, len(p.samples), len(p.search.Windows)))
+		return nil
+	}
+
+	total := 0.0
+	for i, sample := range p.samples {
+		err := sample.Run()
+		if err == nil {
+			var score float64
+			score, err = Measure(context.Background(), p.comparison(i), p.search.Metric)
+			total += score
+		}
+		os.Remove(sample.GetOutputPath())
+		if err != nil {
+			p.search.fail(fmt.Errorf("crf %d probe: %w", p.crf, err))
+			return nil
+		}
+	}
+
+	p.search.record(Probe{CRF: p.crf, Score: total / float64(len(p.samples))})
+	return nil
+}
+
+// DryRun returns the sample encodes and measurements, one per line.
+func (p *ProbeCommand) DryRun() (string, error) {
+	var lines []string
+	for i, sample := range p.samples {
+		cmd, err := sample.DryRun()
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, cmd)
+		if i < len(p.search.Windows) {
+			lines = append(lines, "ffmpeg "+strings.Join(p.comparison(i).Args(p.search.Metric), " "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// GetPriority returns the task priority
+func (p *ProbeCommand) GetPriority() int {
+	return p.priority
+}
+
+// SetPriority sets the task priority
+func (p *ProbeCommand) SetPriority(priority int) command.Command {
+	p.priority = priority
+	return p
+}
+
+// GetTaskType returns the task type identifier
+func (p *ProbeCommand) GetTaskType() command.TaskType {
+	return command.TaskTypeQuality
+}
+
+// GetInputPath returns the source path
+func (p *ProbeCommand) GetInputPath() string {
+	return p.search.Source
+}
+
+// GetOutputPath returns the first sample's output path
+func (p *ProbeCommand) GetOutputPath() string {
+	if len(p.samples) == 0 {
+		return ""
+	}
+	return p.samples[0].GetOutputPath()
+}
+
+// TargetCommand encodes a chunk at the CRF chosen by its Search and then
+// measures the encoded chunk on the search windows. Run it after the
+// chunk's probes.
+type TargetCommand struct {
+	*video.VideoBuilder
+	search *Search
+}
+
+// NewTargetCommand wraps the chunk's encode with its search.
+func NewTargetCommand(builder *video.VideoBuilder, search *Search) *TargetCommand {
+	return &TargetCommand{VideoBuilder: builder, search: search}
+}
+
+// Run encodes the chunk at the searched CRF. A failed measurement of the
+// result is recorded, not returned.
+func (t *TargetCommand) Run() error {
+	return t.RunContext(context.Background())
+}
+
+// RunContext is Run, stopping the encode and measurement once ctx is done.
+func (t *TargetCommand) RunContext(ctx context.Context) error {
+	t.VideoBuilder.SetCRF(t.search.CRF())
+	if err := t.VideoBuilder.RunContext(ctx); err != nil {
+		return err
+	}
+
+	s := t.search
+	score, err := MeasureWindows(ctx, t.GetOutputPath(), s.ChunkStart, s.Source, s.Windows, s.Filters, s.Metric)
+	if err != nil {
+		s.fail(fmt.Errorf("measuring crf %d: %w", s.crf, err))
+		return nil
+	}
+
+	s.mu.Lock()
+	s.measured, s.done = score, true
+	s.mu.Unlock()
+	return nil
+}
+
+// SetPriority sets the task priority
+func (t *TargetCommand) SetPriority(priority int) command.Command {
+	t.VideoBuilder.SetPriority(priority)
+	return t
+}
+
+// ChunkResult is the outcome of a chunk's CRF search.
+type ChunkResult struct {
+	ChunkID  uint     `json:"chunk_id"`
+	CRF      int      `json:"crf"`   // CRF the chunk was encoded at
+	Score    float64  `json:"score"` // Measured score of the encoded chunk
+	Measured bool     `json:"measured"`
+	Probes   []Probe  `json:"probes"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Report records the CRF search of every encoded chunk.
+type Report struct {
+	Metric metric.Metric `json:"metric"`
+	Target float64       `json:"target"`
+	Chunks []ChunkResult `json:"chunks"`
+}
+
+// NewReport collects the results of searches, ordered by chunk.
+func NewReport(metric metric.Metric, target float64, searches []*Search) *Report {
+	r := &Report{Metric: metric, Target: target, Chunks: make([]ChunkResult, 0, len(searches))}
+	for _, s := range searches {
+		r.Chunks = append(r.Chunks, s.Result())
+	}
+	sort.Slice(r.Chunks, func(i, j int) bool { return r.Chunks[i].ChunkID < r.Chunks[j].ChunkID })
+	return r
+}
+
+// WriteJSON writes the report to path.
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quality report: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Summary describes the chosen CRFs and measured scores in one line, e.g.
+// "vmaf target 93.00: CRF 24-35, score 92.10-94.05 (mean 93.20)".
+func (r *Report) Summary() string {
+	if len(r.Chunks) == 0 {
+		return fmt.Sprintf("%s target %s: no chunks encoded", r.Metric, r.Metric.Format(r.Target))
+	}
+
+	minCRF, maxCRF := r.Chunks[0].CRF, r.Chunks[0].CRF
+	var scores []float64
+	for _, c := range r.Chunks {
+		minCRF, maxCRF = min(minCRF, c.CRF), max(maxCRF, c.CRF)
+		if c.Measured {
+			scores = append(scores, c.Score)
+		}
+	}
+
+	summary := fmt.Sprintf("%s target %s: CRF %d", r.Metric, r.Metric.Format(r.Target), minCRF)
+	if maxCRF != minCRF {
+		summary += fmt.Sprintf("-%d", maxCRF)
+	}
+	if len(scores) == 0 {
+		return summary + ", not measured"
+	}
+	lo, hi, sum := scores[0], scores[0], 0.0
+	for _, s := range scores {
+		lo, hi, sum = min(lo, s), max(hi, s), sum+s
+	}
+	if lo == hi {
+		return fmt.Sprintf("%s, score %s", summary, r.Metric.Format(lo))
+	}
+	return fmt.Sprintf("%s, score %s-%s (mean %s)", summary,
+		r.Metric.Format(lo), r.Metric.Format(hi), r.Metric.Format(sum/float64(len(scores))))
+}
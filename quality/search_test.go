@@ -0,0 +1,145 @@
+package quality
+
+import (
+	"encoder/command"
+	"encoder/command/video"
+	"encoder/models"
+	"encoder/quality/metric"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeFFmpeg creates every output file it is asked to encode and reports an
+// SSIM score that falls with the CRF in the distorted file's name.
+const fakeFFmpeg = `#!/bin/sh
+for last; do :; done
+case "$*" in
+*-lavfi*)
+	case "$*" in
+	*crf20*) s=0.990 ;;
+	*crf28*) s=0.970 ;;
+	*crf36*) s=0.950 ;;
+	*) s=0.981 ;;
+	esac
+	echo "[Parsed_ssim_2 @ 0x1] SSIM Y:$s (20.0) U:$s (20.0) V:$s (20.0) All:$s (20.0)" >&2
+	;;
+*)
+	: > "$last"
+	;;
+esac
+`
+
+func installFakeFFmpeg(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script fake ffmpeg requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newTestSearch(chunk *models.Chunk) *Search {
+	return &Search{
+		ChunkID:    chunk.ChunkID,
+		ChunkStart: chunk.StartTime,
+		Source:     chunk.SourcePath,
+		Windows:    SampleWindows(chunk.StartTime, chunk.EndTime, 2, 5),
+		Metric:     metric.SSIM,
+		Target:     0.98,
+		DefaultCRF: 28,
+		MinCRF:     0,
+		MaxCRF:     51,
+	}
+}
+
+func TestSearch_ProbeAndEncode(t *testing.T) {
+	installFakeFFmpeg(t, fakeFFmpeg)
+
+	dir := t.TempDir()
+	chunk := &models.Chunk{ChunkID: 1, StartTime: 60, EndTime: 120, SourcePath: "/input/source.mkv"}
+	search := newTestSearch(chunk)
+
+	for _, crf := range []int{20, 28, 36} {
+		samples := make([]*video.VideoBuilder, len(search.Windows))
+		for i, w := range search.Windows {
+			window := &models.Chunk{ChunkID: 1, StartTime: w.Start, EndTime: w.Start + w.Duration, SourcePath: chunk.SourcePath}
+			samples[i] = video.NewVideoBuilder(window, filepath.Join(dir, fmt.Sprintf("chunk_001_crf%02d_%d.mkv", crf, i)))
+		}
+		probe := NewProbeCommand(search, crf, samples)
+		if probe.GetTaskType() != command.TaskTypeQuality {
+			t.Errorf("Expected quality task type, got %s", probe.GetTaskType())
+		}
+		if err := probe.Run(); err != nil {
+			t.Fatalf("Probe at CRF %d failed: %v", crf, err)
+		}
+		for _, sample := range samples {
+			if _, err := os.Stat(sample.GetOutputPath()); !os.IsNotExist(err) {
+				t.Errorf("Expected probe sample %s to be removed", sample.GetOutputPath())
+			}
+		}
+	}
+
+	builder := video.NewVideoBuilder(chunk, filepath.Join(dir, "video_chunk_001.mkv"))
+	if err := NewTargetCommand(builder, search).Run(); err != nil {
+		t.Fatalf("Target encode failed: %v", err)
+	}
+
+	result := search.Result()
+	// 0.98 lies halfway between CRF 20 (0.99) and CRF 28 (0.97)
+	if result.CRF != 24 {
+		t.Errorf("Expected CRF 24, got %d (probes %v)", result.CRF, result.Probes)
+	}
+	if !result.Measured || result.Score != 0.981 {
+		t.Errorf("Expected measured score 0.981, got %v (measured=%v)", result.Score, result.Measured)
+	}
+	if len(result.Probes) != 3 || len(result.Errors) != 0 {
+		t.Errorf("Unexpected probes %v, errors %v", result.Probes, result.Errors)
+	}
+	if !strings.Contains(strings.Join(builder.BuildArgs(), " "), "-crf 24") {
+		t.Errorf("Expected chunk to be encoded at CRF 24: %v", builder.BuildArgs())
+	}
+
+	report := NewReport(metric.SSIM, 0.98, []*Search{search})
+	if got := report.Summary(); got != "ssim target 0.9800: CRF 24, score 0.9810" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+	path := filepath.Join(dir, "quality_report.json")
+	if err := report.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Chunks) != 1 || decoded.Chunks[0].CRF != 24 {
+		t.Errorf("Unexpected report JSON: %s", data)
+	}
+}
+
+func TestSearch_FailedProbesUseDefaultCRF(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\necho 'Conversion failed!' >&2\nexit 1\n")
+
+	chunk := &models.Chunk{ChunkID: 2, StartTime: 0, EndTime: 60, SourcePath: "/input/source.mkv"}
+	search := newTestSearch(chunk)
+
+	samples := []*video.VideoBuilder{
+		video.NewVideoBuilder(chunk, filepath.Join(t.TempDir(), "a.mkv")),
+		video.NewVideoBuilder(chunk, filepath.Join(t.TempDir(), "b.mkv")),
+	}
+	if err := NewProbeCommand(search, 20, samples).Run(); err != nil {
+		t.Fatalf("Probe failures must not fail the task: %v", err)
+	}
+
+	if crf := search.CRF(); crf != 28 {
+		t.Errorf("Expected default CRF 28, got %d", crf)
+	}
+	if result := search.Result(); len(result.Errors) != 1 || len(result.Probes) != 0 {
+		t.Errorf("Expected one recorded error, got %+v", result)
+	}
+}
@@ -0,0 +1,112 @@
+package quality
+
+import (
+	"math"
+	"sort"
+)
+
+// Defaults for Summarize: segment length in seconds and segments reported.
+const (
+	DefaultSegmentLength = 2.0
+	DefaultWorstSegments = 5
+)
+
+// FrameScore is the score of one frame at its time in the source.
+type FrameScore struct {
+	Time  float64
+	Score float64
+}
+
+// Segment is a stretch of the output with its mean score.
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Score float64 `json:"score"`
+}
+
+// Stats aggregates per-frame scores of one metric.
+type Stats struct {
+	Frames       int       `json:"frames"`
+	Mean         float64   `json:"mean"`
+	HarmonicMean float64   `json:"harmonic_mean"`
+	P1           float64   `json:"p1"` // 1st percentile: 99% of frames score at least this
+	Min          float64   `json:"min"`
+	Max          float64   `json:"max"`
+	Worst        []Segment `json:"worst_segments"` // Lowest-scoring segments, worst first
+}
+
+// Summarize aggregates frame scores. Frames are grouped into segments of
+// segmentLength seconds and the worst lowest-scoring segments are reported.
+//
+// The harmonic mean is taken over score+1, as libvmaf does, so a single
+// zero-score frame does not make it undefined; it weights bad frames more
+// heavily than the mean.
+func Summarize(frames []FrameScore, segmentLength float64, worst int) Stats {
+	if len(frames) == 0 {
+		return Stats{}
+	}
+	if segmentLength <= 0 {
+		segmentLength = DefaultSegmentLength
+	}
+
+	scores := make([]float64, len(frames))
+	sum, inverse := 0.0, 0.0
+	for i, f := range frames {
+		scores[i] = f.Score
+		sum += f.Score
+		inverse += 1 / (f.Score + 1)
+	}
+	sort.Float64s(scores)
+
+	n := float64(len(frames))
+	return Stats{
+		Frames:       len(frames),
+		Mean:         sum / n,
+		HarmonicMean: n/inverse - 1,
+		P1:           percentile(scores, 1),
+		Min:          scores[0],
+		Max:          scores[len(scores)-1],
+		Worst:        worstSegments(frames, segmentLength, worst),
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// worstSegments groups frames into fixed segments of the timeline and
+// returns the n with the lowest mean score, worst first.
+func worstSegments(frames []FrameScore, length float64, n int) []Segment {
+	type bucket struct {
+		sum   float64
+		count int
+	}
+	buckets := make(map[int]*bucket)
+	for _, f := range frames {
+		i := int(math.Floor(f.Time / length))
+		if buckets[i] == nil {
+			buckets[i] = &bucket{}
+		}
+		buckets[i].sum += f.Score
+		buckets[i].count++
+	}
+
+	segments := make([]Segment, 0, len(buckets))
+	for i, b := range buckets {
+		start := float64(i) * length
+		segments = append(segments, Segment{Start: start, End: start + length, Score: b.sum / float64(b.count)})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].Score != segments[j].Score {
+			return segments[i].Score < segments[j].Score
+		}
+		return segments[i].Start < segments[j].Start
+	})
+
+	if n >= 0 && len(segments) > n {
+		segments = segments[:n]
+	}
+	return segments
+}
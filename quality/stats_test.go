@@ -0,0 +1,77 @@
+package quality
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	// 100 frames at 10 fps: 90 at 95, a bad second at 50 from 4.0s
+	var frames []FrameScore
+	for i := 0; i < 100; i++ {
+		score := 95.0
+		if i >= 40 && i < 50 {
+			score = 50
+		}
+		frames = append(frames, FrameScore{Time: float64(i) / 10, Score: score})
+	}
+
+	s := Summarize(frames, 2, 2)
+	if s.Frames != 100 {
+		t.Errorf("Expected 100 frames, got %d", s.Frames)
+	}
+	if math.Abs(s.Mean-90.5) > 1e-9 {
+		t.Errorf("Expected mean 90.5, got %v", s.Mean)
+	}
+	if s.HarmonicMean >= s.Mean {
+		t.Errorf("Expected harmonic mean %v below mean %v", s.HarmonicMean, s.Mean)
+	}
+	wantHarmonic := 100/(90/96.0+10/51.0) - 1
+	if math.Abs(s.HarmonicMean-wantHarmonic) > 1e-9 {
+		t.Errorf("Expected harmonic mean %v, got %v", wantHarmonic, s.HarmonicMean)
+	}
+	if s.P1 != 50 || s.Min != 50 || s.Max != 95 {
+		t.Errorf("Expected p1 50, min 50, max 95, got %v, %v, %v", s.P1, s.Min, s.Max)
+	}
+
+	if len(s.Worst) != 2 {
+		t.Fatalf("Expected 2 worst segments, got %v", s.Worst)
+	}
+	// The bad second falls in the 4-6s segment, averaging (10×50 + 10×95)/20
+	if s.Worst[0].Start != 4 || s.Worst[0].End != 6 || s.Worst[0].Score != 72.5 {
+		t.Errorf("Expected worst segment 4-6s at 72.5, got %+v", s.Worst[0])
+	}
+	// Ties are broken by time
+	if s.Worst[1].Start != 0 || s.Worst[1].Score != 95 {
+		t.Errorf("Expected second segment at 0s scoring 95, got %+v", s.Worst[1])
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	if s := Summarize(nil, 2, 5); s.Frames != 0 || s.Worst != nil {
+		t.Errorf("Expected empty stats, got %+v", s)
+	}
+}
+
+func TestSummarize_ZeroScores(t *testing.T) {
+	s := Summarize([]FrameScore{{Time: 0, Score: 0}, {Time: 1, Score: 0}}, 2, 0)
+	if s.HarmonicMean != 0 || math.IsNaN(s.HarmonicMean) {
+		t.Errorf("Expected harmonic mean 0 for zero scores, got %v", s.HarmonicMean)
+	}
+	if len(s.Worst) != 0 {
+		t.Errorf("Expected no worst segments when none are requested, got %v", s.Worst)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]float64, 200)
+	for i := range sorted {
+		sorted[i] = float64(i + 1)
+	}
+	if p := percentile(sorted, 1); p != 2 {
+		t.Errorf("Expected 1st percentile 2 of 1..200, got %v", p)
+	}
+	if p := percentile([]float64{7}, 1); p != 7 {
+		t.Errorf("Expected single value, got %v", p)
+	}
+}
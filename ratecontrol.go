@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoder/command/video"
+	"encoder/config"
+	"encoder/units"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// resolveTargetSize returns cfg with the video bitrate that makes the output
+// come out at video.target_size, computed from the duration and the audio
+// bitrate. cfg is returned unchanged when no target size is set.
+func resolveTargetSize(cfg *config.Config, duration float64, hasAudio bool, out io.Writer, log *slog.Logger) (*config.Config, error) {
+	if cfg.Video.TargetSize == "" {
+		return cfg, nil
+	}
+
+	size, err := units.ParseSize(cfg.Video.TargetSize)
+	if err != nil {
+		return nil, err
+	}
+	var audioBitrate int64
+	if hasAudio && cfg.Audio.Bitrate != "" {
+		if audioBitrate, err = units.ParseBitrate(cfg.Audio.Bitrate); err != nil {
+			return nil, fmt.Errorf("audio %w", err)
+		}
+	}
+	bitrate, err := video.TargetBitrate(size, duration, audioBitrate)
+	if err != nil {
+		return nil, fmt.Errorf("target size %s: %w", cfg.Video.TargetSize, err)
+	}
+
+	resolved := cfg.Copy()
+	resolved.Video.Bitrate = units.FormatBitrate(bitrate)
+	fmt.Fprintf(out, "  Target size:    %s → video %s\n", cfg.Video.TargetSize, resolved.Video.Bitrate)
+	log.Info("target size resolved", "target_size", cfg.Video.TargetSize, "duration_seconds", duration, "audio_bps", audioBitrate, "video_bitrate", resolved.Video.Bitrate)
+	return resolved, nil
+}
+
+// applyRateControl sets the bitrate and VBV options. A bitrate (or a target
+// size resolved to one) encodes at an average bitrate instead of CRF.
+func applyRateControl(builder *video.VideoBuilder, cfg *config.Config) {
+	if cfg.Video.Bitrate != "" {
+		builder.SetAverageBitrate(cfg.Video.Bitrate)
+	}
+	if cfg.Video.MaxRate != "" {
+		builder.SetMaxRate(cfg.Video.MaxRate).SetBufSize(cfg.Video.BufSize)
+	}
+}
+
+// twoPass reports whether video chunks are encoded in two passes.
+func twoPass(cfg *config.Config) bool {
+	return cfg.Video.Passes == 2
+}
+
+// rateControlKey describes the rate control settings for cache validation.
+func rateControlKey(cfg *config.Config) string {
+	return fmt.Sprintf("passes=%d bitrate=%s maxrate=%s bufsize=%s size=%s",
+		max(cfg.Video.Passes, 1), cfg.Video.Bitrate, cfg.Video.MaxRate, cfg.Video.BufSize, cfg.Video.TargetSize)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoder/config"
+	"encoder/quality"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// videoRendition is one video output of the job: the video section itself,
+// or one rendition of the ABR ladder. All renditions encode the same chunks
+// and are muxed with the same audio.
+type videoRendition struct {
+	Name   string         // Rendition name (empty without a ladder)
+	Config *config.Config // Job settings with the rendition's video section
+	Dir    string         // Work directory of the encoded chunks
+	Final  string         // Concatenated video
+	Output string         // Muxed output file
+
+	files  []string        // Encoded chunks, in chunk order
+	report *quality.Report // Target-quality report (nil = fixed CRF)
+}
+
+// newVideoRenditions returns the video outputs of the job. Without a ladder
+// the single output keeps the video/ directory and final_video.<ext>; ladder
+// renditions get a subdirectory and final video each. The first rendition
+// is written to the output, the others next to it.
+func newVideoRenditions(cfg *config.Config, videoDir, tmpDir string) []*videoRendition {
+	if len(cfg.Video.Renditions) == 0 {
+		return []*videoRendition{{
+			Config: cfg,
+			Dir:    videoDir,
+			Final:  filepath.Join(tmpDir, "final_video."+videoExt(cfg)),
+			Output: cfg.Output,
+		}}
+	}
+
+	renditions := make([]*videoRendition, len(cfg.Video.Renditions))
+	for i, r := range cfg.Video.Renditions {
+		rcfg := cfg.Copy()
+		rcfg.Video = cfg.Video.Rendition(r)
+		output := cfg.Output
+		if i > 0 {
+			output = renditionOutput(cfg.Output, r.Name)
+		}
+		renditions[i] = &videoRendition{
+			Name:   r.Name,
+			Config: rcfg,
+			Dir:    filepath.Join(videoDir, r.Name),
+			Final:  filepath.Join(tmpDir, fmt.Sprintf("final_video_%s.%s", r.Name, videoExt(rcfg))),
+			Output: output,
+		}
+	}
+	return renditions
+}
+
+// renditionOutput returns the output file of a rendition other than the
+// first: <output name>_<name>.<ext>.
+func renditionOutput(output, name string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "_" + name + ext
+}
+
+// taskID returns a task ID unique across renditions, e.g. "video_3" or
+// "720p_video_3".
+func (r *videoRendition) taskID(format string, args ...interface{}) string {
+	id := fmt.Sprintf(format, args...)
+	if r.Name == "" {
+		return id
+	}
+	return r.Name + "_" + id
+}
+
+// label returns " (NAME)" for messages about a ladder rendition, or nothing
+// without a ladder.
+func (r *videoRendition) label() string {
+	if r.Name == "" {
+		return ""
+	}
+	return " (" + r.Name + ")"
+}
+
+// logger returns log with the rendition attribute of a ladder rendition, or
+// log itself without a ladder.
+func (r *videoRendition) logger(log *slog.Logger) *slog.Logger {
+	if r.Name == "" {
+		return log
+	}
+	return log.With("rendition", r.Name)
+}
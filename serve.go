@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoder/config"
+	"encoder/jobs"
+	"encoder/logging"
+	"encoder/metrics"
+	"encoder/models"
+	"encoder/orchestrator"
+	"encoder/server"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// runServeCommand serves the job API until interrupted.
+//
+//	encoder serve [-config FILE] [-addr HOST:PORT] [-concurrency N] [-state-dir DIR]
+func runServeCommand(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file (default: search standard locations)")
+	addr := fs.String("addr", "", "Listen address, loopback only (default: serve.addr from config)")
+	concurrency := fs.Int("concurrency", -1, "Jobs encoded at once (default: serve.concurrency from config)")
+	stateDir := fs.String("state-dir", "", "Job store and log directory (default: serve.state_dir from config)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: encoder serve [-config FILE] [-addr HOST:PORT] [-concurrency N] [-state-dir DIR]")
+		return 2
+	}
+
+	settings, err := config.LoadSettings(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration error: %v\n", err)
+		return 1
+	}
+	sc := settings.Serve
+	if *addr != "" {
+		sc.Addr = *addr
+	}
+	if *concurrency != -1 {
+		sc.Concurrency = *concurrency
+	}
+	if *stateDir != "" {
+		sc.StateDir = *stateDir
+	}
+	if sc.StateDir == "" {
+		sc.StateDir = config.DefaultServeStateDir()
+	}
+	if err := sc.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration error: serve config: %v\n", err)
+		return 1
+	}
+
+	store, err := jobs.Open(filepath.Join(sc.StateDir, "jobs.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	// Jobs may run concurrently, so they share one log; their records carry
+	// the job ID
+	log, closeLog, err := openLog(filepath.Join(sc.StateDir, "serve"), settings, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Logger initialization error: %v\n", err)
+		return 1
+	}
+	defer closeLog()
+
+	srv := server.New(store, serveRunner(*configPath, log), sc.Concurrency)
+	srv.SetValidator(func(job *jobs.Job) error {
+		if _, err := os.Stat(job.Input); err != nil {
+			return fmt.Errorf("input %s: %w", job.Input, err)
+		}
+		_, err := loadQueuedJobConfig(*configPath, job)
+		return err
+	})
+	// The API serves the metrics too; metrics_addr adds an endpoint for
+	// scrapers on other hosts
+	encoderMetrics = metrics.New()
+	if err := enableMetrics(settings.MetricsAddr, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	api := srv.Handler()
+	mux := http.NewServeMux()
+	mux.Handle("/jobs", api)
+	mux.Handle("/jobs/", api)
+	mux.Handle("GET /metrics", encoderMetrics)
+	httpServer := &http.Server{Addr: sc.Addr, Handler: mux}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\n\n⚠️  Interrupt received, stopping (running jobs resume on the next start)...")
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		httpServer.Shutdown(shutdownCtx)
+		cancel()
+	}()
+
+	queued := 0
+	for _, job := range store.List() {
+		if !job.Status.Finished() {
+			queued++
+		}
+	}
+	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                   ENCODER - SERVE START                        ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("API:      http://%s/jobs\n", sc.Addr)
+	fmt.Printf("Metrics:  http://%s/metrics\n", sc.Addr)
+	fmt.Printf("Jobs:     %s (%d to resume, %d at once)\n", store.Path(), queued, sc.Concurrency)
+	fmt.Println()
+
+	dispatched := make(chan struct{})
+	go func() {
+		srv.Run(ctx)
+		close(dispatched)
+	}()
+
+	err = httpServer.ListenAndServe()
+	cancel()
+	<-dispatched
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Println("\n👋 Server stopped")
+	return 0
+}
+
+// serveRunner returns the server.Runner encoding jobs with the settings of
+// configPath, logging to log and reporting the pipeline's progress to the
+// job API.
+func serveRunner(configPath string, log *slog.Logger) server.Runner {
+	return func(ctx context.Context, job *jobs.Job, p *server.Progress) error {
+		if job.Attempts > 1 {
+			encoderMetrics.Restart()
+		}
+		log := log.With(logging.KeyJobID, job.ID, logging.KeyAttempt, job.Attempts)
+		cfg, err := loadQueuedJobConfig(configPath, job)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.Output), 0755); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		log.Info("job started", "input", job.Input, "output", job.Output)
+		fmt.Printf("▶️  [%s] Encoding %s (attempt %d)\n\n", job.ID, filepath.Base(job.Input), job.Attempts)
+
+		err = runPipeline(ctx, cfg, os.Stdout, log, &pipelineMonitor{
+			OnPhase: p.Phase,
+			OnProgress: func(taskID string, progress *models.EncodingProgress) {
+				p.Chunk(taskID, progress)
+			},
+			OnTask: func(task *orchestrator.Task) {
+				p.TaskDone(task.ID, task.Error)
+			},
+			OnStats: p.Stats,
+		})
+		switch {
+		case ctx.Err() != nil:
+			log.Warn("job stopped", "error", ctx.Err())
+			fmt.Printf("\n⏹️  [%s] %s stopped\n\n", job.ID, filepath.Base(job.Input))
+		case err != nil:
+			log.Error("job failed", "error", err)
+			fmt.Printf("\n❌ [%s] %s failed after %s: %v\n\n", job.ID, filepath.Base(job.Input), time.Since(start).Round(time.Second), err)
+		default:
+			fmt.Printf("\n✅ [%s] %s → %s (%s)\n\n", job.ID, filepath.Base(job.Input), job.Output, time.Since(start).Round(time.Second))
+		}
+		return err
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoder/models"
+	"sort"
+	"sync"
+)
+
+// Progress is the live progress of a running job. A Runner reports to it and
+// it forwards every report to the job's event streams. Its methods are safe
+// for concurrent use.
+type Progress struct {
+	server *Server
+	jobID  string
+
+	mu     sync.Mutex
+	phase  string
+	stats  map[string]interface{}
+	chunks map[string]*ChunkProgress // Task ID -> progress
+}
+
+// ChunkProgress is the encoder progress of one task, as sent in "chunk"
+// events.
+type ChunkProgress struct {
+	Task     string  `json:"task"`
+	State    string  `json:"state"`
+	Progress float64 `json:"progress"` // Percent of the task's duration
+	Frame    int64   `json:"frame"`
+	FPS      float64 `json:"fps"`
+	Speed    float64 `json:"speed"`
+	Time     string  `json:"time,omitempty"`
+	Bitrate  string  `json:"bitrate,omitempty"`
+	Size     string  `json:"size,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Snapshot is the progress of a job at one point in time, as returned by
+// GET /jobs/{id} and sent in "progress" events.
+type Snapshot struct {
+	Phase  string                 `json:"phase,omitempty"`
+	Stats  map[string]interface{} `json:"stats,omitempty"`
+	Chunks []ChunkProgress        `json:"chunks"`
+}
+
+// newProgress returns the progress of job jobID run by s.
+func newProgress(s *Server, jobID string) *Progress {
+	return &Progress{server: s, jobID: jobID, chunks: make(map[string]*ChunkProgress)}
+}
+
+// Phase reports the start of a pipeline phase.
+func (p *Progress) Phase(name string) {
+	p.mu.Lock()
+	p.phase = name
+	p.mu.Unlock()
+	p.publish("phase", map[string]string{"phase": name})
+}
+
+// Stats reports the task counts of the orchestrator, as returned by
+// DAGOrchestrator.GetStats.
+func (p *Progress) Stats(stats map[string]interface{}) {
+	p.mu.Lock()
+	p.stats = stats
+	p.mu.Unlock()
+	p.publish("stats", stats)
+}
+
+// Chunk reports the encoder progress of a task.
+func (p *Progress) Chunk(taskID string, progress *models.EncodingProgress) {
+	chunk := ChunkProgress{
+		Task:     taskID,
+		State:    string(progress.State),
+		Progress: progress.Progress,
+		Frame:    progress.Frame,
+		FPS:      progress.FPS,
+		Speed:    progress.Speed,
+		Time:     progress.CurrentTime,
+		Bitrate:  progress.Bitrate,
+		Size:     progress.Size,
+	}
+
+	p.mu.Lock()
+	p.chunks[taskID] = &chunk
+	p.mu.Unlock()
+	p.publish("chunk", chunk)
+}
+
+// TaskDone reports a task that completed, or failed with err.
+func (p *Progress) TaskDone(taskID string, err error) {
+	event := map[string]string{"task": taskID, "status": "completed"}
+	if err != nil {
+		event["status"] = "failed"
+		event["error"] = err.Error()
+	}
+
+	p.mu.Lock()
+	if chunk := p.chunks[taskID]; chunk != nil {
+		chunk.State = string(models.ProgressStateCompleted)
+		if err != nil {
+			chunk.State = string(models.ProgressStateFailed)
+			chunk.Error = err.Error()
+		} else {
+			chunk.Progress = 100
+		}
+	}
+	p.mu.Unlock()
+	p.publish("task", event)
+}
+
+// Snapshot returns the current progress, with chunks in task ID order.
+func (p *Progress) Snapshot() Snapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot := Snapshot{Phase: p.phase, Stats: p.stats, Chunks: make([]ChunkProgress, 0, len(p.chunks))}
+	for _, chunk := range p.chunks {
+		snapshot.Chunks = append(snapshot.Chunks, *chunk)
+	}
+	sort.Slice(snapshot.Chunks, func(i, j int) bool { return snapshot.Chunks[i].Task < snapshot.Chunks[j].Task })
+	return snapshot
+}
+
+// publish sends an event to the job's streams.
+func (p *Progress) publish(name string, data interface{}) {
+	if p.server != nil {
+		p.server.publish(p.jobID, Event{Name: name, Data: data})
+	}
+}
@@ -0,0 +1,400 @@
+// Package server implements the job API of "encoder serve": a JSON HTTP API
+// to submit, list, inspect and cancel encoding jobs, with their progress
+// streamed as Server-Sent Events.
+//
+// Jobs are kept in a jobs.Store, so queued jobs survive a restart, and are
+// encoded by a Runner, at most a configured number at a time.
+//
+//	POST /jobs               Submit {"input", "output", "profile", "overrides"}
+//	GET  /jobs               List jobs (?status=queued|running|done|failed|canceled)
+//	GET  /jobs/{id}          Inspect a job and, while it runs, its progress
+//	POST /jobs/{id}/cancel   Cancel a queued or running job
+//	GET  /jobs/{id}/events   Stream the job's progress (text/event-stream)
+package server
+
+import (
+	"context"
+	"encoder/batch"
+	"encoder/jobs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+)
+
+// Runner encodes a job, reporting its progress to p. It must return soon
+// after ctx is done.
+type Runner func(ctx context.Context, job *jobs.Job, p *Progress) error
+
+// Validator checks a submitted job before it is queued, e.g. that its
+// profile exists and its overrides are valid settings.
+type Validator func(job *jobs.Job) error
+
+// Server queues submitted jobs and runs them.
+type Server struct {
+	store       *jobs.Store
+	run         Runner
+	validate    Validator
+	concurrency int
+
+	mu      sync.Mutex
+	running map[string]*runningJob         // Job ID -> running job
+	subs    map[string]map[chan Event]bool // Job ID -> event streams
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// runningJob is a job being encoded.
+type runningJob struct {
+	cancel   context.CancelFunc
+	progress *Progress
+	canceled bool // Canceled through the API (not by a shutdown)
+}
+
+// Event is a message of a job's event stream. Name is one of "job" (the job
+// record, sent when its status changes), "phase", "stats", "chunk" and
+// "task"; Data is sent as JSON.
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// New returns a server running the jobs of store with run, concurrency
+// jobs at a time.
+func New(store *jobs.Store, run Runner, concurrency int) *Server {
+	return &Server{
+		store:       store,
+		run:         run,
+		concurrency: max(concurrency, 1),
+		running:     make(map[string]*runningJob),
+		subs:        make(map[string]map[chan Event]bool),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// SetValidator sets the check of submitted jobs (nil = accept any job with
+// an input and an output).
+func (s *Server) SetValidator(v Validator) {
+	s.validate = v
+}
+
+// Run starts queued jobs until ctx is done, then waits for the running jobs
+// to stop. Jobs stopped this way stay running in the store, which queues
+// them again when it is next opened.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		s.startQueued(ctx)
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-s.wake:
+		}
+	}
+}
+
+// notify makes Run look for queued jobs.
+func (s *Server) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// startQueued claims queued jobs while fewer than the concurrency limit run.
+func (s *Server) startQueued(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ctx.Err() == nil && len(s.running) < s.concurrency {
+		job, err := s.store.Claim()
+		if err != nil || job == nil {
+			return
+		}
+
+		jobCtx, cancel := context.WithCancel(ctx)
+		rj := &runningJob{cancel: cancel, progress: newProgress(s, job.ID)}
+		s.running[job.ID] = rj
+		s.publishLocked(job.ID, Event{Name: "job", Data: job})
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			err := s.run(jobCtx, job, rj.progress)
+			cancel()
+			s.finish(ctx, job.ID, rj, err)
+		}()
+	}
+}
+
+// finish records the outcome of a job that stopped running.
+func (s *Server) finish(ctx context.Context, id string, rj *runningJob, err error) {
+	s.mu.Lock()
+	delete(s.running, id)
+	canceled := rj.canceled
+	s.mu.Unlock()
+
+	switch {
+	case canceled:
+		// Cancel already recorded it
+	case ctx.Err() != nil:
+		return // Shutting down: queued again on the next start
+	default:
+		s.store.Finish(id, err)
+	}
+
+	if job, err := s.store.Get(id); err == nil {
+		s.publish(id, Event{Name: "job", Data: job})
+	}
+	s.notify()
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGet)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /jobs/{id}/events", s.handleEvents)
+	return mux
+}
+
+// submitRequest is the body of POST /jobs.
+type submitRequest struct {
+	Input     string                 `json:"input"`
+	Output    string                 `json:"output"`
+	Profile   string                 `json:"profile"`
+	Overrides map[string]interface{} `json:"overrides"` // Config key -> string, number or boolean
+}
+
+// jobView is a job as returned by the API, with its progress while it runs.
+type jobView struct {
+	*jobs.Job
+	Progress *Snapshot `json:"progress,omitempty"`
+}
+
+// handleSubmit queues a job.
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	job := jobs.Job{Input: req.Input, Output: req.Output, Profile: req.Profile}
+	switch {
+	case job.Input == "" || job.Output == "":
+		writeError(w, http.StatusBadRequest, errors.New("input and output are required"))
+		return
+	case !filepath.IsAbs(job.Input) || !filepath.IsAbs(job.Output):
+		writeError(w, http.StatusBadRequest, errors.New("input and output must be absolute paths"))
+		return
+	}
+	for key, value := range req.Overrides {
+		str, err := batch.ScalarString(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("override %s: %w", key, err))
+			return
+		}
+		if job.Overrides == nil {
+			job.Overrides = make(map[string]string)
+		}
+		job.Overrides[key] = str
+	}
+	if s.validate != nil {
+		if err := s.validate(&job); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	added, err := s.store.Add(job)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.notify()
+	w.Header().Set("Location", "/jobs/"+added.ID)
+	writeJSON(w, http.StatusCreated, jobView{Job: added})
+}
+
+// handleList lists the jobs, optionally only those with a given status.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	status := jobs.Status(r.URL.Query().Get("status"))
+	list := make([]jobView, 0)
+	for _, job := range s.store.List() {
+		if status == "" || job.Status == status {
+			list = append(list, jobView{Job: job})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": list})
+}
+
+// handleGet returns a job and its progress.
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	job, err := s.store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.view(job))
+}
+
+// view returns job with the progress of its run, if it is running.
+func (s *Server) view(job *jobs.Job) jobView {
+	s.mu.Lock()
+	rj := s.running[job.ID]
+	s.mu.Unlock()
+
+	view := jobView{Job: job}
+	if rj != nil {
+		snapshot := rj.progress.Snapshot()
+		view.Progress = &snapshot
+	}
+	return view
+}
+
+// handleCancel cancels a queued job or stops a running one.
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	job, err := s.store.Cancel(id)
+	if err == nil {
+		if rj := s.running[id]; rj != nil {
+			rj.canceled = true
+			rj.cancel()
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, jobs.ErrFinished):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		s.publish(id, Event{Name: "job", Data: job})
+		writeJSON(w, http.StatusOK, jobView{Job: job})
+	}
+}
+
+// handleEvents streams a job's events until it finishes or the client goes
+// away. The stream starts with the job record and, while it runs, a
+// "progress" event holding its current progress.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	// Subscribe before reading the job so no status change is missed
+	events := s.subscribe(id)
+	defer s.unsubscribe(id, events)
+
+	job, err := s.store.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	view := s.view(job)
+	writeEvent(w, Event{Name: "job", Data: view.Job})
+	if view.Progress != nil {
+		writeEvent(w, Event{Name: "progress", Data: view.Progress})
+	}
+	flusher.Flush()
+	if job.Status.Finished() {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			writeEvent(w, event)
+			flusher.Flush()
+			if j, ok := event.Data.(*jobs.Job); ok && event.Name == "job" && j.Status.Finished() {
+				return
+			}
+		}
+	}
+}
+
+// subscribe returns a channel receiving the events of job id.
+func (s *Server) subscribe(id string) chan Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Event, 64)
+	if s.subs[id] == nil {
+		s.subs[id] = make(map[chan Event]bool)
+	}
+	s.subs[id][ch] = true
+	return ch
+}
+
+// unsubscribe stops sending events to ch.
+func (s *Server) unsubscribe(id string, ch chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subs[id], ch)
+	if len(s.subs[id]) == 0 {
+		delete(s.subs, id)
+	}
+}
+
+// publish sends an event to the streams of job id. Streams that fall behind
+// miss events rather than slowing the job down.
+func (s *Server) publish(id string, event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publishLocked(id, event)
+}
+
+// publishLocked is publish for callers holding s.mu.
+func (s *Server) publishLocked(id string, event Event) {
+	for ch := range s.subs[id] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// writeEvent writes an event in the text/event-stream format.
+func writeEvent(w http.ResponseWriter, event Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeError writes {"error": "..."} with the given status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoder/jobs"
+	"encoder/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRunner runs jobs until they are released or canceled.
+type fakeRunner struct {
+	started chan string
+	release chan error
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{started: make(chan string, 10), release: make(chan error)}
+}
+
+func (f *fakeRunner) run(ctx context.Context, job *jobs.Job, p *Progress) error {
+	p.Phase("video")
+	p.Chunk("chunk_0000", &models.EncodingProgress{Frame: 48, Progress: 50, State: models.ProgressStateEncoding})
+	f.started <- job.ID
+	select {
+	case err := <-f.release:
+		p.TaskDone("chunk_0000", err)
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newTestServer starts a server with concurrency 1 and returns it with its
+// HTTP test server and runner.
+func newTestServer(t *testing.T) (*Server, *httptest.Server, *fakeRunner) {
+	t.Helper()
+	store, err := jobs.Open(filepath.Join(t.TempDir(), "jobs.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	runner := newFakeRunner()
+	s := New(store, runner.run, 1)
+	s.SetValidator(func(job *jobs.Job) error {
+		if job.Profile == "missing" {
+			return errors.New(`unknown profile "missing"`)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		<-done
+	})
+	return s, ts, runner
+}
+
+func request(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode
+}
+
+// waitStatus polls a job until it has the given status.
+func waitStatus(t *testing.T, ts *httptest.Server, id string, want jobs.Status) *jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job jobs.Job
+		request(t, "GET", ts.URL+"/jobs/"+id, "", &job)
+		if job.Status == want {
+			return &job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %s is %s, want %s", id, job.Status, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_Submit(t *testing.T) {
+	_, ts, runner := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"malformed", `{"input":`, http.StatusBadRequest},
+		{"unknown field", `{"input":"/in/a.mkv","output":"/out/a.mkv","crf":24}`, http.StatusBadRequest},
+		{"missing output", `{"input":"/in/a.mkv"}`, http.StatusBadRequest},
+		{"relative path", `{"input":"a.mkv","output":"/out/a.mkv"}`, http.StatusBadRequest},
+		{"invalid override", `{"input":"/in/a.mkv","output":"/out/a.mkv","overrides":{"video.crf":[24]}}`, http.StatusBadRequest},
+		{"rejected", `{"input":"/in/a.mkv","output":"/out/a.mkv","profile":"missing"}`, http.StatusBadRequest},
+		{"valid", `{"input":"/in/a.mkv","output":"/out/a.mkv","overrides":{"video.crf":24,"video.preset":"slow"}}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job jobs.Job
+			if got := request(t, "POST", ts.URL+"/jobs", tt.body, &job); got != tt.want {
+				t.Fatalf("Expected status %d, got %d", tt.want, got)
+			}
+			if tt.want == http.StatusCreated && job.Overrides["video.crf"] != "24" {
+				t.Errorf("Expected the numeric override as a string, got %v", job.Overrides)
+			}
+		})
+	}
+
+	id := <-runner.started
+	running := waitStatus(t, ts, id, jobs.StatusRunning)
+	runner.release <- nil
+	waitStatus(t, ts, id, jobs.StatusDone)
+	if running.Attempts != 1 {
+		t.Errorf("Expected a first attempt, got %d", running.Attempts)
+	}
+
+	var list struct{ Jobs []jobs.Job }
+	request(t, "GET", ts.URL+"/jobs?status=done", "", &list)
+	if len(list.Jobs) != 1 || list.Jobs[0].ID != id {
+		t.Errorf("Expected only %s to be done, got %+v", id, list.Jobs)
+	}
+	if got := request(t, "GET", ts.URL+"/jobs/999999", "", nil); got != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", got)
+	}
+}
+
+func TestServer_ConcurrencyAndCancel(t *testing.T) {
+	_, ts, runner := newTestServer(t)
+
+	var a, b jobs.Job
+	request(t, "POST", ts.URL+"/jobs", `{"input":"/in/a.mkv","output":"/out/a.mkv"}`, &a)
+	request(t, "POST", ts.URL+"/jobs", `{"input":"/in/b.mkv","output":"/out/b.mkv"}`, &b)
+	<-runner.started
+
+	// Concurrency 1: b waits for a
+	var view struct {
+		jobs.Job
+		Progress *Snapshot
+	}
+	request(t, "GET", ts.URL+"/jobs/"+b.ID, "", &view)
+	if view.Status != jobs.StatusQueued {
+		t.Errorf("Expected %s to stay queued, got %s", b.ID, view.Status)
+	}
+	request(t, "GET", ts.URL+"/jobs/"+a.ID, "", &view)
+	if view.Progress == nil || view.Progress.Phase != "video" || len(view.Progress.Chunks) != 1 {
+		t.Errorf("Expected the progress of the running job, got %+v", view.Progress)
+	}
+
+	// Canceling the running job stops it and starts the next one
+	if got := request(t, "POST", ts.URL+"/jobs/"+a.ID+"/cancel", "", nil); got != http.StatusOK {
+		t.Fatalf("Expected 200 canceling a running job, got %d", got)
+	}
+	if id := <-runner.started; id != b.ID {
+		t.Errorf("Expected %s to start next, got %s", b.ID, id)
+	}
+	waitStatus(t, ts, a.ID, jobs.StatusCanceled)
+	if got := request(t, "POST", ts.URL+"/jobs/"+a.ID+"/cancel", "", nil); got != http.StatusConflict {
+		t.Errorf("Expected 409 canceling a finished job, got %d", got)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	_, ts, runner := newTestServer(t)
+
+	var job jobs.Job
+	request(t, "POST", ts.URL+"/jobs", `{"input":"/in/a.mkv","output":"/out/a.mkv"}`, &job)
+	<-runner.started
+
+	resp, err := http.Get(ts.URL + "/jobs/" + job.ID + "/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+
+	go func() { runner.release <- errors.New("encoder crashed") }()
+
+	// The stream ends once the job finishes
+	var names []string
+	var last string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			last = data
+		}
+	}
+
+	want := []string{"job", "progress", "task", "job"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected events %v, got %v", want, names)
+	}
+	var final jobs.Job
+	json.Unmarshal([]byte(last), &final)
+	if final.Status != jobs.StatusFailed || final.Error != "encoder crashed" {
+		t.Errorf("Expected the stream to end with the failed job, got %s", last)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoder/analysis"
+	"encoder/command/video"
+	"encoder/config"
+	"encoder/crop"
+	"encoder/ffprobe"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+)
+
+// sourceAnalysis holds the per-source video decisions made once before
+// encoding and applied identically to every video chunk.
+type sourceAnalysis struct {
+	Video     *ffprobe.Stream           // Primary video stream (nil if none)
+	Crop      *crop.Rect                // Crop rectangle (nil = no crop)
+	Interlace *analysis.InterlaceResult // Scan type (nil = not analyzed)
+	FrameRate string                    // Output frame rate when filtering changes it (e.g. "24000/1001"), else ""
+}
+
+// analyzeSource inspects the probed input according to the video settings
+// and prints what it decided to out. Analysis failures are reported as warnings;
+// the source is then encoded without the affected step.
+func analyzeSource(ctx context.Context, cfg *config.Config, probeResult *ffprobe.ProbeResult, out io.Writer, log *slog.Logger) *sourceAnalysis {
+	src := &sourceAnalysis{Video: probeResult.PrimaryVideoStream()}
+	if src.Video == nil {
+		return src
+	}
+
+	if src.Video.IsHDR() {
+		mode, reason := video.ResolveHDRMode(video.HDRMode(cfg.Video.HDR), cfg.Video.Codec)
+		if reason != "" {
+			fmt.Fprintf(out, "  HDR:            %s → %s (%s)\n", src.Video.HDRFormat(), mode, reason)
+			log.Info("HDR source will be tone-mapped", "reason", reason)
+		} else {
+			fmt.Fprintf(out, "  HDR:            %s → %s\n", src.Video.HDRFormat(), mode)
+		}
+	}
+
+	duration, _ := probeResult.GetDuration()
+	interlace, err := resolveInterlace(ctx, cfg, src.Video, duration, out, log)
+	if err != nil {
+		fmt.Fprintf(out, "  Scan:           ⚠️  %v (encoding as progressive)\n", err)
+		log.Warn("interlace detection failed", "error", err)
+	}
+	src.Interlace = interlace
+	if interlace != nil {
+		if num, den := interlace.FrameRateFactor(); num != den {
+			in := src.Video.FrameRate()
+			rate := in.Scale(num, den)
+			if !rate.IsZero() {
+				src.FrameRate = rate.String()
+				fmt.Fprintf(out, "  Frame rate:     %.3f → %.3f fps\n", in.Float(), rate.Float())
+			}
+		}
+	}
+
+	rect, err := resolveCrop(ctx, cfg, src.Video, duration, out, log)
+	if err != nil {
+		fmt.Fprintf(out, "  Crop:           ⚠️  %v (encoding without crop)\n", err)
+		log.Warn("crop detection failed", "error", err)
+	}
+	src.Crop = rect
+
+	return src
+}
+
+// resolveCrop returns the crop rectangle for video.crop: nil for "off", the
+// parsed rectangle for a manual value, or the cropdetect result for "auto".
+// stream is only needed for "auto".
+func resolveCrop(ctx context.Context, cfg *config.Config, stream *ffprobe.Stream, duration float64, out io.Writer, log *slog.Logger) (*crop.Rect, error) {
+	switch cfg.Video.Crop {
+	case "", "off":
+		return nil, nil
+	case "auto":
+	default:
+		rect, err := crop.Parse(cfg.Video.Crop)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(out, "  Crop:           %s (manual)\n", rect)
+		return &rect, nil
+	}
+
+	if stream == nil {
+		return nil, fmt.Errorf("no video stream to analyze")
+	}
+
+	// ffmpeg rotates portrait phone clips before filtering, so cropdetect
+	// sees the displayed dimensions
+	width, height := stream.Width, stream.Height
+	if rot := stream.Rotation(); rot == 90 || rot == -90 || rot == 270 || rot == -270 {
+		width, height = height, width
+	}
+
+	result, err := analysis.DetectCrop(ctx, cfg.Input, duration, width, height, analysis.CropOptions{Round: cfg.Video.CropRound})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("cropdetect", "samples", result.Samples, "rejected", result.Rejected, "result", result.Rect)
+
+	if !result.Cropped {
+		fmt.Fprintf(out, "  Crop:           none detected (%d samples)\n", len(result.Samples))
+		return nil, nil
+	}
+	fmt.Fprintf(out, "  Crop:           %s from %dx%d (%d samples, %d outliers rejected)\n",
+		result.Rect, width, height, len(result.Samples), result.Rejected)
+	return &result.Rect, nil
+}
+
+// resolveInterlace classifies the stream's scanning for video.deinterlace.
+// "off" returns nil. "deinterlace" and "ivtc" force the treatment, taking the
+// field order from ffprobe. "auto" runs idet, unless the stream is flagged
+// progressive at a rate where interlacing or pulldown does not occur.
+func resolveInterlace(ctx context.Context, cfg *config.Config, stream *ffprobe.Stream, duration float64, out io.Writer, log *slog.Logger) (*analysis.InterlaceResult, error) {
+	fieldOrder := "tff"
+	if stream.FieldOrder == "bb" || stream.FieldOrder == "bt" {
+		fieldOrder = "bff"
+	}
+
+	switch cfg.Video.Deinterlace {
+	case "off":
+		return nil, nil
+	case "deinterlace":
+		fmt.Fprintf(out, "  Scan:           interlaced %s (forced)\n", fieldOrder)
+		return &analysis.InterlaceResult{Type: analysis.ScanInterlaced, FieldOrder: fieldOrder}, nil
+	case "ivtc":
+		fmt.Fprintf(out, "  Scan:           telecined %s (forced)\n", fieldOrder)
+		return &analysis.InterlaceResult{Type: analysis.ScanTelecined, FieldOrder: fieldOrder}, nil
+	}
+
+	fps := stream.FrameRate().Float()
+	if stream.FieldOrder == "progressive" && !isBroadcastRate(fps) {
+		return &analysis.InterlaceResult{Type: analysis.ScanProgressive}, nil
+	}
+
+	result, err := analysis.DetectInterlace(ctx, cfg.Input, duration, stream.FieldOrder, fps, analysis.InterlaceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("idet", "tff", result.Stats.TFF, "bff", result.Stats.BFF, "progressive", result.Stats.Progressive,
+		"undetermined", result.Stats.Undetermined, "repeated", result.Stats.RepeatedRatio(), "result", result.Type)
+
+	if result.Type == analysis.ScanProgressive {
+		fmt.Fprintf(out, "  Scan:           progressive (%.0f%% combed frames)\n", result.Stats.InterlacedRatio()*100)
+	} else {
+		fmt.Fprintf(out, "  Scan:           %s %s (%.0f%% combed frames)\n", result.Type, result.FieldOrder, result.Stats.InterlacedRatio()*100)
+	}
+	return result, nil
+}
+
+// isBroadcastRate reports whether fps is a PAL or NTSC field-derived rate,
+// where interlaced or telecined content is common.
+func isBroadcastRate(fps float64) bool {
+	for _, rate := range []float64{25, 30000.0 / 1001, 30, 50, 60000.0 / 1001} {
+		if math.Abs(fps-rate) < 0.01 {
+			return true
+		}
+	}
+	return false
+}
+
+// applySourceAnalysis configures a video chunk builder with the per-source
+// decisions. Deinterlacing must see the original field lines, so it comes
+// first; cropping and scaling to the configured resolution follow so later
+// filters work on fewer pixels.
+func applySourceAnalysis(builder *video.VideoBuilder, cfg *config.Config, src *sourceAnalysis) {
+	if src != nil && src.Interlace != nil {
+		if filter := src.Interlace.Filter(cfg.Video.Deinterlacer); filter != "" {
+			builder.AddCPUFilter(filter)
+		}
+	}
+
+	if src != nil && src.Crop != nil {
+		builder.AddCPUFilter(src.Crop.Filter())
+	}
+
+	if width, height, err := video.ParseResolution(cfg.Video.Resolution); err == nil {
+		builder.AddScale(width, height)
+	}
+
+	if src != nil && src.Video != nil && src.Video.IsHDR() {
+		mode, _ := video.ResolveHDRMode(video.HDRMode(cfg.Video.HDR), cfg.Video.Codec)
+		builder.ApplyHDR(src.Video.HDRMetadata(), mode, cfg.Video.Tonemap)
+	}
+}
@@ -0,0 +1,130 @@
+// Package streaming writes the playlists of adaptive streaming packages.
+//
+// ffmpeg's hls muxer segments the streams and writes a media playlist per
+// stream. The master playlist is written here instead, with CODECS strings
+// (RFC 6381) derived from the probed streams and BANDWIDTH measured from the
+// segments ffmpeg wrote, which players and validators rely on to pick a
+// variant.
+package streaming
+
+import (
+	"encoder/ffprobe"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// h264Profiles maps ffprobe's H.264 profile names to profile_idc and the
+// constraint flags byte.
+var h264Profiles = map[string][2]int{
+	"Constrained Baseline":  {66, 0x40},
+	"Baseline":              {66, 0x00},
+	"Main":                  {77, 0x00},
+	"Extended":              {88, 0x00},
+	"High":                  {100, 0x00},
+	"High 10":               {110, 0x00},
+	"High 4:2:2":            {122, 0x00},
+	"High 4:4:4 Predictive": {244, 0x00},
+}
+
+// CodecString returns the RFC 6381 codecs parameter of a stream (e.g.
+// "avc1.640028", "mp4a.40.2"), or "" for codecs HLS has no identifier for.
+// hevcTag is the sample entry HEVC is muxed with ("hvc1" or "hev1").
+func CodecString(s ffprobe.Stream, hevcTag string) string {
+	switch s.CodecName {
+	case "h264":
+		p, ok := h264Profiles[s.Profile]
+		if !ok {
+			p = h264Profiles["High"]
+		}
+		return fmt.Sprintf("avc1.%02x%02x%02x", p[0], p[1], max(s.Level, 0))
+	case "hevc":
+		if hevcTag == "" {
+			hevcTag = "hvc1"
+		}
+		// general_profile_idc and its compatibility flags
+		profile, compat := 1, "6"
+		switch s.Profile {
+		case "Main 10":
+			profile, compat = 2, "4"
+		case "Rext":
+			profile, compat = 4, "10"
+		}
+		// Main tier, progressive frame-only content
+		return fmt.Sprintf("%s.%d.%s.L%d.90", hevcTag, profile, compat, max(s.Level, 0))
+	case "av1":
+		profile := 0
+		switch s.Profile {
+		case "High":
+			profile = 1
+		case "Professional":
+			profile = 2
+		}
+		return fmt.Sprintf("av01.%d.%02dM.%02d", profile, max(s.Level, 0), bitDepth(s))
+	case "vp9":
+		profile := 0
+		if s.BitDepth() > 8 {
+			profile = 2
+		}
+		return fmt.Sprintf("vp09.%02d.%02d.%02d", profile, vp9Level(s.Width, s.Height), bitDepth(s))
+	case "aac":
+		switch s.Profile {
+		case "HE-AAC":
+			return "mp4a.40.5"
+		case "HE-AACv2":
+			return "mp4a.40.29"
+		}
+		return "mp4a.40.2"
+	case "mp3":
+		return "mp4a.40.34"
+	case "ac3":
+		return "ac-3"
+	case "eac3":
+		return "ec-3"
+	case "opus":
+		return "Opus"
+	case "flac":
+		return "fLaC"
+	}
+	return ""
+}
+
+// bitDepth returns the stream's bit depth, assuming 8 when unknown.
+func bitDepth(s ffprobe.Stream) int {
+	if depth := s.BitDepth(); depth > 0 {
+		return depth
+	}
+	return 8
+}
+
+// vp9Level estimates the VP9 level from the picture size; ffprobe does not
+// report it. The estimate assumes up to 30 fps.
+func vp9Level(width, height int) int {
+	switch pixels := width * height; {
+	case pixels <= 0:
+		return 10
+	case pixels <= 640*360:
+		return 21
+	case pixels <= 960*540:
+		return 30
+	case pixels <= 1280*720:
+		return 31
+	case pixels <= 2048*1088:
+		return 40
+	case pixels <= 4096*2176:
+		return 50
+	}
+	return 60
+}
+
+// JoinCodecs joins codecs parameters for a CODECS attribute, dropping empty
+// and duplicate entries.
+func JoinCodecs(codecs ...string) string {
+	var unique []string
+	for _, c := range codecs {
+		if c != "" && !slices.Contains(unique, c) {
+			unique = append(unique, c)
+		}
+	}
+	return strings.Join(unique, ",")
+}
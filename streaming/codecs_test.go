@@ -0,0 +1,43 @@
+package streaming
+
+import (
+	"encoder/ffprobe"
+	"testing"
+)
+
+func TestCodecString(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  ffprobe.Stream
+		hevcTag string
+		want    string
+	}{
+		{"H.264 High 4.0", ffprobe.Stream{CodecName: "h264", Profile: "High", Level: 40}, "", "avc1.640028"},
+		{"H.264 Main 3.1", ffprobe.Stream{CodecName: "h264", Profile: "Main", Level: 31}, "", "avc1.4d001f"},
+		{"H.264 Constrained Baseline", ffprobe.Stream{CodecName: "h264", Profile: "Constrained Baseline", Level: 30}, "", "avc1.42401e"},
+		{"HEVC Main", ffprobe.Stream{CodecName: "hevc", Profile: "Main", Level: 120}, "hvc1", "hvc1.1.6.L120.90"},
+		{"HEVC Main 10 hev1", ffprobe.Stream{CodecName: "hevc", Profile: "Main 10", Level: 150}, "hev1", "hev1.2.4.L150.90"},
+		{"AV1 Main 10-bit", ffprobe.Stream{CodecName: "av1", Profile: "Main", Level: 8, PixFmt: "yuv420p10le"}, "", "av01.0.08M.10"},
+		{"AV1 Main 8-bit", ffprobe.Stream{CodecName: "av1", Profile: "Main", Level: 5, PixFmt: "yuv420p"}, "", "av01.0.05M.08"},
+		{"VP9 1080p", ffprobe.Stream{CodecName: "vp9", Width: 1920, Height: 1080, PixFmt: "yuv420p"}, "", "vp09.00.40.08"},
+		{"AAC LC", ffprobe.Stream{CodecName: "aac", Profile: "LC"}, "", "mp4a.40.2"},
+		{"HE-AAC", ffprobe.Stream{CodecName: "aac", Profile: "HE-AAC"}, "", "mp4a.40.5"},
+		{"Opus", ffprobe.Stream{CodecName: "opus"}, "", "Opus"},
+		{"E-AC-3", ffprobe.Stream{CodecName: "eac3"}, "", "ec-3"},
+		{"unknown", ffprobe.Stream{CodecName: "prores"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodecString(tt.stream, tt.hevcTag); got != tt.want {
+				t.Errorf("CodecString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinCodecs(t *testing.T) {
+	if got := JoinCodecs("av01.0.08M.10", "", "Opus", "Opus"); got != "av01.0.08M.10,Opus" {
+		t.Errorf("JoinCodecs() = %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package streaming
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MediaStats describes the segments of a media playlist.
+type MediaStats struct {
+	Segments         int
+	Duration         float64 // Seconds
+	PeakBandwidth    int64   // Highest segment bit rate (bits per second)
+	AverageBandwidth int64   // Total size over total duration (bits per second)
+}
+
+// ReadMediaStats parses the media playlist at path and measures the segments
+// it lists. Segment sizes are read from disk, relative to the playlist.
+func ReadMediaStats(path string) (*MediaStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := &MediaStats{}
+	var totalBytes int64
+	duration := -1.0 // Duration of the next segment, from #EXTINF
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if duration, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("%s: invalid segment duration %q", path, value)
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		case duration >= 0:
+			info, err := os.Stat(filepath.Join(filepath.Dir(path), line))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if duration > 0 {
+				rate := int64(math.Ceil(float64(info.Size()) * 8 / duration))
+				stats.PeakBandwidth = max(stats.PeakBandwidth, rate)
+			}
+			stats.Segments++
+			stats.Duration += duration
+			totalBytes += info.Size()
+			duration = -1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if stats.Segments == 0 {
+		return nil, fmt.Errorf("%s: no segments", path)
+	}
+	if stats.Duration > 0 {
+		stats.AverageBandwidth = int64(math.Ceil(float64(totalBytes) * 8 / stats.Duration))
+	}
+	return stats, nil
+}
+
+// Rendition is an alternative audio or subtitle track (EXT-X-MEDIA).
+type Rendition struct {
+	Type     string // "AUDIO" or "SUBTITLES"
+	GroupID  string
+	Name     string
+	Language string // ISO 639 code (empty = omitted)
+	Default  bool
+	Forced   bool // SUBTITLES only
+	Channels int  // AUDIO only (0 = omitted)
+	URI      string
+}
+
+// Variant is a playable combination of streams (EXT-X-STREAM-INF).
+type Variant struct {
+	URI              string
+	Bandwidth        int64 // Peak bit rate including the largest rendition of each group
+	AverageBandwidth int64
+	Codecs           string
+	Width, Height    int
+	FrameRate        float64
+	Audio            string // Audio rendition group (empty = none)
+	Subtitles        string // Subtitle rendition group (empty = none)
+}
+
+// MasterPlaylist lists the variants and renditions of an HLS package.
+type MasterPlaylist struct {
+	Version    int
+	Renditions []Rendition
+	Variants   []Variant
+}
+
+// String renders the playlist.
+func (m *MasterPlaylist) String() string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", m.Version)
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	for _, r := range m.Renditions {
+		attrs := []string{
+			"TYPE=" + r.Type,
+			"GROUP-ID=" + quote(r.GroupID),
+			"NAME=" + quote(r.Name),
+		}
+		if r.Language != "" {
+			attrs = append(attrs, "LANGUAGE="+quote(r.Language))
+		}
+		attrs = append(attrs, "DEFAULT="+yesNo(r.Default), "AUTOSELECT=YES")
+		if r.Type == "SUBTITLES" {
+			attrs = append(attrs, "FORCED="+yesNo(r.Forced))
+		}
+		if r.Channels > 0 {
+			attrs = append(attrs, "CHANNELS="+quote(strconv.Itoa(r.Channels)))
+		}
+		attrs = append(attrs, "URI="+quote(r.URI))
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:%s\n", strings.Join(attrs, ","))
+	}
+
+	for _, v := range m.Variants {
+		attrs := []string{"BANDWIDTH=" + strconv.FormatInt(v.Bandwidth, 10)}
+		if v.AverageBandwidth > 0 {
+			attrs = append(attrs, "AVERAGE-BANDWIDTH="+strconv.FormatInt(v.AverageBandwidth, 10))
+		}
+		if v.Codecs != "" {
+			attrs = append(attrs, "CODECS="+quote(v.Codecs))
+		}
+		if v.Width > 0 && v.Height > 0 {
+			attrs = append(attrs, fmt.Sprintf("RESOLUTION=%dx%d", v.Width, v.Height))
+		}
+		if v.FrameRate > 0 {
+			attrs = append(attrs, "FRAME-RATE="+strconv.FormatFloat(v.FrameRate, 'f', 3, 64))
+		}
+		if v.Audio != "" {
+			attrs = append(attrs, "AUDIO="+quote(v.Audio))
+		}
+		if v.Subtitles != "" {
+			attrs = append(attrs, "SUBTITLES="+quote(v.Subtitles))
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:%s\n%s\n", strings.Join(attrs, ","), v.URI)
+	}
+	return b.String()
+}
+
+// WriteFile writes the playlist to path.
+func (m *MasterPlaylist) WriteFile(path string) error {
+	return os.WriteFile(path, []byte(m.String()), 0644)
+}
+
+// SubtitlePlaylist returns a media playlist with a single WebVTT file
+// covering duration seconds.
+func SubtitlePlaylist(uri string, duration float64) string {
+	return fmt.Sprintf("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:%.3f,\n%s\n#EXT-X-ENDLIST\n",
+		int(math.Ceil(duration)), duration, uri)
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
@@ -0,0 +1,113 @@
+package streaming
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadMediaStats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "init.mp4"), 800)
+	writeFile(t, filepath.Join(dir, "segment_00000.m4s"), 750000)  // 1 Mbps over 6s
+	writeFile(t, filepath.Join(dir, "segment_00001.m4s"), 1500000) // 2 Mbps over 6s
+	writeFile(t, filepath.Join(dir, "segment_00002.m4s"), 250000)  // 1 Mbps over 2s
+
+	playlist := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:6.000000,
+segment_00000.m4s
+#EXTINF:6.000000,
+segment_00001.m4s
+#EXTINF:2.000000,
+segment_00002.m4s
+#EXT-X-ENDLIST
+`
+	path := filepath.Join(dir, "index.m3u8")
+	if err := os.WriteFile(path, []byte(playlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ReadMediaStats(path)
+	if err != nil {
+		t.Fatalf("ReadMediaStats() error = %v", err)
+	}
+	if stats.Segments != 3 || stats.Duration != 14 {
+		t.Errorf("Segments = %d, Duration = %v, want 3 and 14", stats.Segments, stats.Duration)
+	}
+	if stats.PeakBandwidth != 2000000 {
+		t.Errorf("PeakBandwidth = %d, want 2000000", stats.PeakBandwidth)
+	}
+	if stats.AverageBandwidth != 1428572 {
+		t.Errorf("AverageBandwidth = %d, want 1428572", stats.AverageBandwidth)
+	}
+}
+
+func TestReadMediaStats_MissingSegment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.m3u8")
+	if err := os.WriteFile(path, []byte("#EXTM3U\n#EXTINF:6.0,\nmissing.ts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMediaStats(path); err == nil {
+		t.Error("expected error for a missing segment")
+	}
+}
+
+func TestMasterPlaylist_String(t *testing.T) {
+	m := &MasterPlaylist{
+		Version: 6,
+		Renditions: []Rendition{
+			{Type: "AUDIO", GroupID: "audio", Name: "English", Language: "eng", Default: true, Channels: 2, URI: "stream_1/index.m3u8"},
+			{Type: "SUBTITLES", GroupID: "subs", Name: "Deutsch", Language: "ger", URI: "subtitles_0.m3u8"},
+		},
+		Variants: []Variant{{
+			URI:              "stream_0/index.m3u8",
+			Bandwidth:        5128000,
+			AverageBandwidth: 3100000,
+			Codecs:           "av01.0.08M.10,Opus",
+			Width:            1920,
+			Height:           1080,
+			FrameRate:        23.976,
+			Audio:            "audio",
+			Subtitles:        "subs",
+		}},
+	}
+
+	want := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="English",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,CHANNELS="2",URI="stream_1/index.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Deutsch",LANGUAGE="ger",DEFAULT=NO,AUTOSELECT=YES,FORCED=NO,URI="subtitles_0.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=5128000,AVERAGE-BANDWIDTH=3100000,CODECS="av01.0.08M.10,Opus",RESOLUTION=1920x1080,FRAME-RATE=23.976,AUDIO="audio",SUBTITLES="subs"
+stream_0/index.m3u8
+`
+	if got := m.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSubtitlePlaylist(t *testing.T) {
+	got := SubtitlePlaylist("subtitles_0.vtt", 5400.5)
+	for _, want := range []string{"#EXT-X-TARGETDURATION:5401\n", "#EXTINF:5400.500,\nsubtitles_0.vtt\n", "#EXT-X-ENDLIST\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SubtitlePlaylist() missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoder/command/thumbnail"
+	"encoder/command/video"
+	"encoder/config"
+	"encoder/ffprobe"
+	"encoder/orchestrator"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// thumbnailDir returns the directory thumbnails are written to.
+func thumbnailDir(cfg *config.Config) string {
+	if cfg.Thumbnails.Dir != "" {
+		return cfg.Thumbnails.Dir
+	}
+	return strings.TrimSuffix(cfg.Output, filepath.Ext(cfg.Output)) + "_thumbs"
+}
+
+// thumbnailSelection returns the configured frame selection. Chapter
+// selection falls back to the interval when the source has no chapters.
+func thumbnailSelection(cfg *config.Config, probeResult *ffprobe.ProbeResult, log *slog.Logger) thumbnail.Selection {
+	switch cfg.Thumbnails.Selection {
+	case "scene":
+		return thumbnail.SceneSelection(cfg.Thumbnails.SceneThreshold)
+	case "chapters":
+		if sel := thumbnail.ChapterSelection(probeResult.Chapters); len(sel.Times) > 0 {
+			return sel
+		}
+		log.Info("source has no chapters, selecting thumbnails by interval", "interval_seconds", cfg.Thumbnails.Interval)
+	}
+	return thumbnail.IntervalSelection(cfg.Thumbnails.Interval)
+}
+
+// newThumbnailBuilders creates a builder per configured thumbnail output.
+// Frames are taken from the source, deinterlaced and cropped like the encode;
+// HDR sources are always tone-mapped since the outputs are 8-bit SDR.
+func newThumbnailBuilders(cfg *config.Config, probeResult *ffprobe.ProbeResult, src *sourceAnalysis, duration float64, log *slog.Logger) []*thumbnail.ThumbnailBuilder {
+	var filters []string
+	if src != nil && src.Interlace != nil {
+		if filter := src.Interlace.Filter(cfg.Video.Deinterlacer); filter != "" {
+			filters = append(filters, filter)
+		}
+	}
+	if src != nil && src.Crop != nil {
+		filters = append(filters, src.Crop.Filter())
+	}
+	if src != nil && src.Video != nil && src.Video.IsHDR() {
+		filters = append(filters, video.ToneMappingFilter(cfg.Video.Tonemap))
+	}
+
+	selection := thumbnailSelection(cfg, probeResult, log)
+	tileWidth, tileHeight, _ := video.ParseResolution(cfg.Thumbnails.SpriteTile)
+
+	var builders []*thumbnail.ThumbnailBuilder
+	for _, name := range cfg.Thumbnails.OutputNames() {
+		kind := thumbnail.Kind(name)
+		builder := thumbnail.NewThumbnailBuilder(kind, cfg.Input, thumbnailDir(cfg)).
+			SetDuration(duration).
+			SetSelection(selection)
+		for _, filter := range filters {
+			builder.AddFilter(filter)
+		}
+
+		switch kind {
+		case thumbnail.KindPoster:
+			builder.SetCount(cfg.Thumbnails.Posters).
+				SetSize(cfg.Thumbnails.PosterWidth, 0)
+		case thumbnail.KindSprite:
+			builder.SetSize(tileWidth, tileHeight).
+				SetGrid(cfg.Thumbnails.SpriteColumns, cfg.Thumbnails.SpriteRows)
+		case thumbnail.KindPreview:
+			builder.SetClips(nil, cfg.Thumbnails.PreviewLength).
+				SetPreviewFormat(thumbnail.PreviewFormat(cfg.Thumbnails.PreviewFormat))
+		}
+		builders = append(builders, builder)
+	}
+	return builders
+}
+
+// addThumbnailTasks adds a low-priority CPU task per thumbnail output to
+// orch, so thumbnails are generated from the source while chunks encode;
+// chunks waiting for a slot start first. Task IDs start with prefix.
+func addThumbnailTasks(builders []*thumbnail.ThumbnailBuilder, orch *orchestrator.DAGOrchestrator, prefix string) error {
+	for _, builder := range builders {
+		task := &orchestrator.Task{
+			ID:       fmt.Sprintf("%sthumbnail_%s", prefix, builder.Kind()),
+			Command:  builder,
+			Resource: orchestrator.ResourceCPU,
+		}
+		if err := orch.AddTask(task); err != nil {
+			return fmt.Errorf("failed to add task: %w", err)
+		}
+	}
+	return nil
+}
+
+// thumbnailOutputs returns the main output of each builder that wrote one.
+// Thumbnails are optional, so a missing output is only a warning.
+func thumbnailOutputs(builders []*thumbnail.ThumbnailBuilder, out io.Writer, log *slog.Logger) []string {
+	var outputs []string
+	for _, builder := range builders {
+		if _, err := os.Stat(builder.GetOutputPath()); err != nil {
+			log.Warn("no thumbnail output", "kind", builder.Kind(), "error", err)
+			fmt.Fprintf(out, "  ⚠️  No %s thumbnails were generated (see log)\n", builder.Kind())
+			continue
+		}
+		outputs = append(outputs, builder.GetOutputPath())
+	}
+	return outputs
+}
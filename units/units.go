@@ -0,0 +1,67 @@
+// Package units parses and formats the bitrates and file sizes used in the
+// configuration ("5M", "1500k", "4.7G").
+//
+// Config validation checks these values with the same parser the video
+// builder's rate control later uses.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseBitrate parses an ffmpeg-style bitrate ("5M", "1500k", "800000") in
+// bits per second. The k and M suffixes are decimal, as in ffmpeg.
+func ParseBitrate(s string) (int64, error) {
+	value, err := parseSuffixed(s, 1000)
+	if err != nil {
+		return 0, fmt.Errorf("bitrate %q: %w", s, err)
+	}
+	return value, nil
+}
+
+// FormatBitrate formats bits per second for ffmpeg, in kbit/s.
+func FormatBitrate(bps int64) string {
+	return fmt.Sprintf("%dk", bps/1000)
+}
+
+// ParseSize parses a file size ("700M", "4.7G", "1500000") in bytes. The
+// K, M and G suffixes are binary (KiB, MiB, GiB), as file managers show them.
+func ParseSize(s string) (int64, error) {
+	value, err := parseSuffixed(s, 1024)
+	if err != nil {
+		return 0, fmt.Errorf("size %q: %w", s, err)
+	}
+	return value, nil
+}
+
+// parseSuffixed parses a positive number with an optional K, M or G suffix
+// of the given base.
+func parseSuffixed(s string, base float64) (int64, error) {
+	s = strings.TrimSpace(s)
+	multiplier := 1.0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			multiplier = base
+		case 'm', 'M':
+			multiplier = base * base
+		case 'g', 'G':
+			multiplier = base * base * base
+		}
+		if multiplier != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("not a number with an optional k, M or G suffix")
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return int64(math.Round(n * multiplier)), nil
+}
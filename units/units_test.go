@@ -0,0 +1,26 @@
+package units
+
+import "testing"
+
+func TestParseBitrate(t *testing.T) {
+	tests := map[string]int64{"5M": 5000000, "1500k": 1500000, "800000": 800000, "2.5M": 2500000}
+	for in, want := range tests {
+		if got, err := ParseBitrate(in); err != nil || got != want {
+			t.Errorf("ParseBitrate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "fast", "-5M", "0"} {
+		if _, err := ParseBitrate(in); err == nil {
+			t.Errorf("Expected ParseBitrate(%q) to fail", in)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if got, err := ParseSize("700M"); err != nil || got != 700*1024*1024 {
+		t.Errorf("ParseSize(700M) = %d, %v", got, err)
+	}
+	if got, err := ParseSize("4.5G"); err != nil || got != 4831838208 {
+		t.Errorf("ParseSize(4.5G) = %d, %v", got, err)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoder/batch"
+	"encoder/config"
+	"encoder/ffprobe"
+	"encoder/jobs"
+	"encoder/logging"
+	"encoder/watch"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxWatchAttempts is how often a job is started before the daemon gives up
+// on it. A job is only started again if the daemon stopped while it ran.
+const maxWatchAttempts = 3
+
+// sourceGone is recorded as a finished job's destination when its source was
+// removed before it could be moved.
+const sourceGone = "(removed)"
+
+// watchDaemon encodes the files dropped into an ingest directory, one at a
+// time, through a job queue that survives restarts.
+type watchDaemon struct {
+	configPath string
+	cfg        config.WatchConfig // With every directory resolved
+	store      *jobs.Store
+	tracker    *watch.Tracker
+}
+
+// runWatchCommand watches an ingest directory and encodes the files dropped
+// into it.
+//
+//	encoder watch [-config FILE] [-dir DIR] [-metrics-addr ADDR]
+func runWatchCommand(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to config file (default: search standard locations)")
+	dir := fs.String("dir", "", "Ingest directory to watch (default: watch.dir from config)")
+	metricsAddr := fs.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9464 (default: metrics_addr from config)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: encoder watch [-config FILE] [-dir DIR] [-metrics-addr ADDR]")
+		return 2
+	}
+
+	settings, err := config.LoadSettings(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration error: %v\n", err)
+		return 1
+	}
+	wc := settings.Watch
+	if *dir != "" {
+		wc.Dir = *dir
+	}
+	if wc.Dir == "" {
+		fmt.Fprintln(os.Stderr, "❌ No directory to watch: pass -dir or set watch.dir in the config file")
+		return 2
+	}
+	if err := wc.Validate(settings.Profiles); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration error: watch config: %v\n", err)
+		return 1
+	}
+
+	d, err := newWatchDaemon(*configPath, wc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if *metricsAddr != "" {
+		settings.MetricsAddr = *metricsAddr
+	}
+	if err := enableMetrics(settings.MetricsAddr, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\n\n⚠️  Interrupt received, stopping (a running job resumes on the next start)...")
+		cancel()
+	}()
+
+	d.run(ctx)
+	fmt.Println("\n👋 Watch stopped")
+	return 0
+}
+
+// newWatchDaemon resolves the directories of wc and opens the job queue.
+func newWatchDaemon(configPath string, wc config.WatchConfig) (*watchDaemon, error) {
+	dir, err := filepath.Abs(wc.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("watch directory %s does not exist", dir)
+	}
+	wc.Dir = dir
+	if wc.OutputTemplate == "" {
+		wc.OutputTemplate = batch.DefaultOutputTemplate
+	}
+	if wc.DoneDir == "" {
+		wc.DoneDir = filepath.Join(dir, "done")
+	}
+	if wc.FailedDir == "" {
+		wc.FailedDir = filepath.Join(dir, "failed")
+	}
+	if wc.StateDir == "" {
+		wc.StateDir = filepath.Join(dir, ".encoder-watch")
+	}
+
+	store, err := jobs.Open(filepath.Join(wc.StateDir, "jobs.json"))
+	if err != nil {
+		return nil, err
+	}
+	return &watchDaemon{
+		configPath: configPath,
+		cfg:        wc,
+		store:      store,
+		tracker:    watch.NewTracker(time.Duration(wc.SettleTime * float64(time.Second))),
+	}, nil
+}
+
+// run scans the directory and works through the queue until ctx is canceled.
+func (d *watchDaemon) run(ctx context.Context) {
+	queued := 0
+	for _, job := range d.store.List() {
+		if !job.Status.Finished() {
+			queued++
+		}
+	}
+
+	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                   ENCODER - WATCH START                        ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("Watching: %s (every %gs, files settle for %gs)\n", d.cfg.Dir, d.cfg.PollInterval, d.cfg.SettleTime)
+	fmt.Printf("Outputs:  %s\n", d.cfg.OutputTemplate)
+	fmt.Printf("Done:     %s\n", d.cfg.DoneDir)
+	fmt.Printf("Failed:   %s\n", d.cfg.FailedDir)
+	fmt.Printf("Queue:    %s (%d jobs to resume)\n", d.store.Path(), queued)
+	fmt.Printf("Rules:    %d\n", len(d.cfg.Rules))
+	fmt.Println()
+
+	poll := time.Duration(d.cfg.PollInterval * float64(time.Second))
+	for {
+		d.moveFinished()
+		d.enqueue()
+		for ctx.Err() == nil && d.runNext(ctx) {
+			d.enqueue() // Files dropped during a long encode
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+	}
+}
+
+// enqueue adds a job for every file that has finished being written and is
+// not queued, running or waiting to be moved already.
+func (d *watchDaemon) enqueue() {
+	ready, err := d.tracker.Scan(d.cfg.Dir)
+	if err != nil {
+		fmt.Printf("⚠️  Scan of %s failed: %v\n", d.cfg.Dir, err)
+		return
+	}
+
+	known := make(map[string]bool)
+	for _, job := range d.store.List() {
+		if !job.Status.Finished() || job.MovedTo == "" {
+			known[job.Input] = true
+		}
+		known[job.Output] = true // Outputs written into the watched directory
+	}
+
+	for _, path := range ready {
+		if known[path] {
+			continue
+		}
+		profile := d.profileFor(path)
+		output := batch.ExpandOutput(d.cfg.OutputTemplate, path, 0, profile) // {index} is rejected by validation
+		job, err := d.store.Add(jobs.Job{Input: path, Output: output, Profile: profile})
+		if err != nil {
+			fmt.Printf("⚠️  Failed to queue %s: %v\n", path, err)
+			continue
+		}
+		known[path] = true
+		if profile == "" {
+			profile = "default settings"
+		}
+		fmt.Printf("📥 [%s] Queued %s (%s) → %s\n", job.ID, filepath.Base(path), profile, output)
+	}
+}
+
+// profileFor returns the profile of the first watch rule matching the file,
+// or "" for the default settings.
+func (d *watchDaemon) profileFor(path string) string {
+	var probe *ffprobe.ProbeResult
+	if watch.NeedsProbe(d.cfg.Rules) {
+		var err error
+		if probe, err = ffprobe.Probe(path); err != nil {
+			fmt.Printf("⚠️  Failed to probe %s, only file name rules apply: %v\n", filepath.Base(path), err)
+		}
+	}
+	if rule := watch.Match(d.cfg.Rules, path, probe); rule != nil {
+		return rule.Profile
+	}
+	return ""
+}
+
+// runNext encodes the oldest queued job and moves its source to the done or
+// failed directory. Returns false if no job was queued. A job interrupted by
+// ctx is left running in the queue, which requeues it on the next start.
+func (d *watchDaemon) runNext(ctx context.Context) bool {
+	job, err := d.store.Claim()
+	if err != nil {
+		fmt.Printf("⚠️  Failed to update the job queue: %v\n", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	start := time.Now()
+	fmt.Printf("▶️  [%s] Encoding %s (attempt %d)\n\n", job.ID, filepath.Base(job.Input), job.Attempts)
+	if job.Attempts > 1 {
+		encoderMetrics.Restart()
+	}
+	if job.Attempts > maxWatchAttempts {
+		err = fmt.Errorf("interrupted %d times, giving up", job.Attempts-1)
+	} else {
+		err = d.encode(ctx, job)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err := d.store.Finish(job.ID, err); err != nil {
+		fmt.Printf("⚠️  Failed to update the job queue: %v\n", err)
+	}
+	if err != nil {
+		fmt.Printf("\n❌ [%s] %s failed after %s: %v\n", job.ID, filepath.Base(job.Input), time.Since(start).Round(time.Second), err)
+	} else {
+		fmt.Printf("\n✅ [%s] %s → %s (%s)\n", job.ID, filepath.Base(job.Input), job.Output, time.Since(start).Round(time.Second))
+	}
+	d.moveFinished()
+	fmt.Println()
+	return true
+}
+
+// encode runs the pipeline for a job with its own log next to its output.
+func (d *watchDaemon) encode(ctx context.Context, job *jobs.Job) error {
+	cfg, err := loadQueuedJobConfig(d.configPath, job)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Output), 0755); err != nil {
+		return err
+	}
+	log, closeLog, err := openLog(cfg.Output, cfg, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("logger initialization error: %w", err)
+	}
+	defer closeLog()
+	log = log.With(logging.KeyJobID, job.ID, logging.KeyAttempt, job.Attempts)
+	log.Info("job started", "input", job.Input, "output", job.Output)
+
+	err = runPipeline(ctx, cfg, os.Stdout, log, nil)
+	if err != nil {
+		log.Error("job failed", "error", err)
+	}
+	return err
+}
+
+// loadQueuedJobConfig loads the configuration of a queued job: its profile
+// and overrides on top of the config file and environment, checked against
+// the local ffmpeg build.
+func loadQueuedJobConfig(configPath string, job *jobs.Job) (*config.Config, error) {
+	overrides := make(map[string]string, len(job.Overrides)+2)
+	for key, value := range job.Overrides {
+		overrides[key] = value
+	}
+	overrides["input"] = job.Input
+	overrides["output"] = job.Output
+	cfg, err := config.LoadJobConfig(configPath, job.Profile, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFFmpeg(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// moveFinished moves the sources of finished jobs that were not moved yet to
+// the done or failed directory.
+func (d *watchDaemon) moveFinished() {
+	for _, job := range d.store.List() {
+		if !job.Status.Finished() || job.MovedTo != "" {
+			continue
+		}
+
+		dest := sourceGone
+		if _, err := os.Stat(job.Input); err == nil {
+			dir := d.cfg.DoneDir
+			if job.Status != jobs.StatusDone {
+				dir = d.cfg.FailedDir
+			}
+			if dest, err = moveFile(job.Input, dir); err != nil {
+				fmt.Printf("⚠️  [%s] Failed to move %s, retrying later: %v\n", job.ID, job.Input, err)
+				continue
+			}
+		}
+		if err := d.store.Update(job.ID, func(j *jobs.Job) { j.MovedTo = dest }); err != nil {
+			fmt.Printf("⚠️  Failed to update the job queue: %v\n", err)
+		}
+	}
+}
+
+// rename renames files for moveFile; tests replace it to take the copy path
+// used across file systems.
+var rename = os.Rename
+
+// moveFile moves src into dir, adding a numeric suffix to its name if dir
+// already holds a file of that name, and returns the new path. Files are
+// copied when dir is on another file system.
+func moveFile(src, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(src)
+	ext := filepath.Ext(base)
+	dest := filepath.Join(dir, base)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dest = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), i, ext))
+	}
+
+	if err := rename(src, dest); err == nil {
+		return dest, nil
+	}
+	if err := copyFile(src, dest); err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	return dest, os.Remove(src)
+}
+
+// copyFile copies src to dest and flushes it to disk.
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
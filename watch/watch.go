@@ -0,0 +1,142 @@
+// Package watch finds the files of an ingest directory that are ready to be
+// encoded and picks the profile of each from the watch rules.
+//
+// The directory is polled rather than watched with inotify: a file is ready
+// once its size and modification time have not changed for the settle time,
+// which polling has to observe anyway, and polling also works on network
+// mounts where change notifications are not delivered.
+package watch
+
+import (
+	"encoder/batch"
+	"encoder/config"
+	"encoder/ffprobe"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Tracker remembers the files seen by previous scans of a directory.
+type Tracker struct {
+	settle time.Duration
+	now    func() time.Time // Clock, replaced in tests
+	files  map[string]fileState
+}
+
+// fileState is what a scan saw of a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // When the file was first seen with this size and mtime
+}
+
+// NewTracker returns a tracker that reports files once they have stayed
+// unchanged for settle.
+func NewTracker(settle time.Duration) *Tracker {
+	return &Tracker{settle: settle, now: time.Now, files: make(map[string]fileState)}
+}
+
+// Scan lists the media files directly in dir and returns, in name order,
+// those whose size and modification time have not changed for the settle
+// time. A file must be seen by two scans at least the settle time apart, so
+// a file is never reported by the scan that first sees it unless the settle
+// time is zero. Hidden files, which copy tools such as rsync write to before
+// renaming them into place, are ignored.
+func (t *Tracker) Scan(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.now()
+	var ready []string
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") || !batch.IsMedia(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // Removed since ReadDir
+		}
+		path := filepath.Join(dir, e.Name())
+		present[path] = true
+
+		state, ok := t.files[path]
+		if !ok || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			state = fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			t.files[path] = state
+		}
+		if now.Sub(state.since) >= t.settle {
+			ready = append(ready, path)
+		}
+	}
+
+	// Forget files that left the directory
+	for path := range t.files {
+		if !present[path] {
+			delete(t.files, path)
+		}
+	}
+	return ready, nil
+}
+
+// NeedsProbe reports whether any rule looks at stream properties.
+func NeedsProbe(rules []config.WatchRule) bool {
+	for i := range rules {
+		if rules[i].NeedsProbe() {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the first rule that matches the file at path, or nil if none
+// does. probe describes the file; rules with stream conditions never match
+// when it is nil.
+func Match(rules []config.WatchRule, path string, probe *ffprobe.ProbeResult) *config.WatchRule {
+	for i := range rules {
+		if matches(&rules[i], path, probe) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// matches reports whether every condition of rule holds for the file.
+func matches(rule *config.WatchRule, path string, probe *ffprobe.ProbeResult) bool {
+	if rule.Match != "" {
+		ok, err := filepath.Match(strings.ToLower(rule.Match), strings.ToLower(filepath.Base(path)))
+		if err != nil || !ok {
+			return false
+		}
+	}
+	if !rule.NeedsProbe() {
+		return true
+	}
+	if probe == nil {
+		return false
+	}
+
+	video := probe.PrimaryVideoStream()
+	if rule.AudioOnly {
+		return video == nil && len(probe.GetAudioStreams()) > 0
+	}
+	if video == nil {
+		return false
+	}
+	if rule.VideoCodec != "" && !strings.EqualFold(video.CodecName, rule.VideoCodec) {
+		return false
+	}
+	if video.Height < rule.MinHeight || (rule.MaxHeight > 0 && video.Height > rule.MaxHeight) {
+		return false
+	}
+	switch rule.HDR {
+	case "yes":
+		return video.IsHDR()
+	case "no":
+		return !video.IsHDR()
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"encoder/config"
+	"encoder/ffprobe"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTracker_WaitsForStableFiles(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Now()}
+	tracker := NewTracker(10 * time.Second)
+	tracker.now = clock.now
+
+	movie := filepath.Join(dir, "movie.mkv")
+	writeFile(t, movie, "part")
+	writeFile(t, filepath.Join(dir, ".movie2.mkv.Xyz12"), "rsync temp")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not media")
+
+	scan := func() []string {
+		t.Helper()
+		ready, err := tracker.Scan(dir)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return ready
+	}
+
+	if ready := scan(); len(ready) != 0 {
+		t.Errorf("Expected nothing ready on first sight, got %v", ready)
+	}
+
+	// Still being written: the size changes, so the settle time restarts
+	clock.advance(8 * time.Second)
+	writeFile(t, movie, "partial")
+	if ready := scan(); len(ready) != 0 {
+		t.Errorf("Expected a growing file not to be ready, got %v", ready)
+	}
+	clock.advance(8 * time.Second)
+	if ready := scan(); len(ready) != 0 {
+		t.Errorf("Expected the settle time to restart after a change, got %v", ready)
+	}
+
+	clock.advance(2 * time.Second)
+	if ready := scan(); !reflect.DeepEqual(ready, []string{movie}) {
+		t.Errorf("Expected %s to be ready, got %v", movie, ready)
+	}
+
+	// A file that leaves the directory is forgotten
+	os.Remove(movie)
+	scan()
+	if len(tracker.files) != 0 {
+		t.Errorf("Expected removed files to be forgotten, got %v", tracker.files)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	hdr4k := &ffprobe.ProbeResult{Streams: []ffprobe.Stream{
+		{CodecType: "video", CodecName: "hevc", Height: 2160, ColorTransfer: "smpte2084"},
+		{CodecType: "audio", CodecName: "eac3"},
+	}}
+	dvd := &ffprobe.ProbeResult{Streams: []ffprobe.Stream{
+		{CodecType: "video", CodecName: "mpeg2video", Height: 576},
+		{CodecType: "audio", CodecName: "ac3"},
+	}}
+	music := &ffprobe.ProbeResult{Streams: []ffprobe.Stream{
+		{CodecType: "audio", CodecName: "flac"},
+		{CodecType: "video", CodecName: "mjpeg", Disposition: ffprobe.Disposition{AttachedPic: 1}},
+	}}
+
+	rules := []config.WatchRule{
+		{Match: "*.MOV", Profile: "prores"},
+		{MinHeight: 2160, HDR: "yes", Profile: "uhd-hdr"},
+		{VideoCodec: "MPEG2VIDEO", MaxHeight: 576, Profile: "dvd"},
+		{AudioOnly: true, Profile: "music"},
+		{Match: "*.mkv", Profile: "default"},
+	}
+
+	tests := []struct {
+		path  string
+		probe *ffprobe.ProbeResult
+		want  string
+	}{
+		{"/in/clip.mov", dvd, "prores"},
+		{"/in/film.mkv", hdr4k, "uhd-hdr"},
+		{"/in/disc.mkv", dvd, "dvd"},
+		{"/in/album.flac", music, "music"},
+		{"/in/film.mkv", nil, "default"}, // Probe failed: only name rules match
+		{"/in/film.mp4", nil, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if rule := Match(rules, tt.path, tt.probe); rule != nil {
+			got = rule.Profile
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if !NeedsProbe(rules) || NeedsProbe(rules[:1]) {
+		t.Error("Expected only the stream rules to need a probe")
+	}
+}
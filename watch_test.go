@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoder/config"
+	"encoder/jobs"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoveFile_Collisions(t *testing.T) {
+	src := t.TempDir()
+	done := filepath.Join(t.TempDir(), "done")
+
+	want := []string{"movie.mkv", "movie-1.mkv", "movie-2.mkv"}
+	for i, name := range want {
+		path := filepath.Join(src, "movie.mkv")
+		writeFile(t, path, name)
+		got, err := moveFile(path, done)
+		if err != nil {
+			t.Fatalf("moveFile() #%d error = %v", i+1, err)
+		}
+		if got != filepath.Join(done, name) {
+			t.Errorf("moveFile() #%d = %s, want %s", i+1, got, filepath.Join(done, name))
+		}
+		if data, _ := os.ReadFile(got); string(data) != name {
+			t.Errorf("%s holds %q, want %q", got, data, name)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s to be moved away, got %v", path, err)
+		}
+	}
+}
+
+func TestMoveFile_CrossDevice(t *testing.T) {
+	t.Cleanup(func() { rename = os.Rename })
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+
+	src := filepath.Join(t.TempDir(), "movie.mkv")
+	writeFile(t, src, "frames")
+	done := t.TempDir()
+	writeFile(t, filepath.Join(done, "movie.mkv"), "earlier")
+
+	got, err := moveFile(src, done)
+	if err != nil {
+		t.Fatalf("moveFile() error = %v", err)
+	}
+	if got != filepath.Join(done, "movie-1.mkv") {
+		t.Errorf("moveFile() = %s, want movie-1.mkv in %s", got, done)
+	}
+	if data, _ := os.ReadFile(got); string(data) != "frames" {
+		t.Errorf("Copy holds %q, want %q", data, "frames")
+	}
+	if data, _ := os.ReadFile(filepath.Join(done, "movie.mkv")); string(data) != "earlier" {
+		t.Errorf("Existing file was overwritten: %q", data)
+	}
+	if _, err := os.Stat(src); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the source to be removed after copying, got %v", err)
+	}
+}
+
+func TestWatchDaemon_Enqueue(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "movie.mkv")
+	writeFile(t, input, "frames")
+
+	d, err := newWatchDaemon("", config.WatchConfig{Dir: dir, PollInterval: 1, OutputTemplate: "{dir}/{name}_enc.mkv"})
+	if err != nil {
+		t.Fatalf("newWatchDaemon() error = %v", err)
+	}
+	count := func() int { return len(d.store.List()) }
+
+	d.enqueue()
+	if count() != 1 {
+		t.Fatalf("Expected 1 job after the first scan, got %d", count())
+	}
+	job := d.store.List()[0]
+	if job.Input != input || job.Output != filepath.Join(dir, "movie_enc.mkv") {
+		t.Errorf("Input, Output = %s, %s", job.Input, job.Output)
+	}
+
+	// The queued input is not queued again
+	d.enqueue()
+	if count() != 1 {
+		t.Errorf("Expected a queued input not to be queued again, got %d jobs", count())
+	}
+
+	// Nor is an output written into the watched directory
+	writeFile(t, job.Output, "encoded")
+	d.enqueue()
+	if count() != 1 {
+		t.Errorf("Expected the output not to be queued, got %d jobs", count())
+	}
+
+	// A finished job whose source stays in place keeps it known
+	if err := d.store.Finish(job.ID, errors.New("encode failed")); err != nil {
+		t.Fatal(err)
+	}
+	d.enqueue()
+	if count() != 1 {
+		t.Errorf("Expected a finished input that was not moved not to be queued, got %d jobs", count())
+	}
+
+	// Once moved away, a file dropped again under the same name is new
+	if err := d.store.Update(job.ID, func(job *jobs.Job) { job.MovedTo = filepath.Join(dir, "failed", "movie.mkv") }); err != nil {
+		t.Fatal(err)
+	}
+	d.enqueue()
+	if count() != 2 {
+		t.Errorf("Expected a re-dropped input to be queued, got %d jobs", count())
+	}
+}
@@ -0,0 +1,423 @@
+// Package workdir manages per-job working directories.
+//
+// Every encoding job gets its own directory (segments, audio and video chunks,
+// cache manifests) under a shared root. The directory name is derived from the
+// input and output paths, so re-running the same job reuses its cached work
+// while two different jobs writing into the same output folder never share
+// state. A lock file guards each directory against concurrent jobs.
+package workdir
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRootName is the directory created next to the output file when
+	// no explicit work root is configured.
+	DefaultRootName = "tmp"
+
+	// LockFileName is the lock file created inside a work directory while a job runs.
+	LockFileName = ".lock"
+
+	// InfoFileName holds the job metadata used by List and GC.
+	InfoFileName = ".workdir.json"
+)
+
+// ErrLocked is returned by Acquire when another live job holds the lock.
+var ErrLocked = errors.New("work directory is locked by another job")
+
+// Info describes the job that owns a work directory.
+type Info struct {
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Lock describes the process holding a work directory lock.
+type Lock struct {
+	PID        int       `json:"pid"`
+	Hostname   string    `json:"hostname"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// WorkDir is an acquired (locked) work directory.
+type WorkDir struct {
+	Path string
+	Info Info
+
+	lock *os.File // holds the flock until Release
+}
+
+// DefaultRoot returns the default work root for an output path: tmp/ next to the output file.
+func DefaultRoot(outputPath string) string {
+	return filepath.Join(filepath.Dir(outputPath), DefaultRootName)
+}
+
+// Name derives a stable, unique directory name for a job.
+//
+// The name combines the input file stem (for readability) with a short hash
+// of the absolute input and output paths (for uniqueness), e.g. "movie-3f9a1c0b27de".
+func Name(inputPath, outputPath string) string {
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		absInput = inputPath
+	}
+	absOutput, err := filepath.Abs(outputPath)
+	if err != nil {
+		absOutput = outputPath
+	}
+
+	sum := sha256.Sum256([]byte(absInput + "\x00" + absOutput))
+	return fmt.Sprintf("%s-%s", sanitize(stem(inputPath)), hex.EncodeToString(sum[:])[:12])
+}
+
+// Resolve returns the work directory for a job under the given root.
+// An empty root selects DefaultRoot(outputPath).
+func Resolve(root, inputPath, outputPath string) string {
+	if root == "" {
+		root = DefaultRoot(outputPath)
+	}
+	return filepath.Join(root, Name(inputPath, outputPath))
+}
+
+// Acquire creates the work directory if needed and takes its lock.
+//
+// A lock left behind by a process that is no longer running on this host is
+// taken over. Returns ErrLocked if a live job (or another host) holds it.
+func Acquire(path, inputPath, outputPath string) (*WorkDir, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory %s: %w", path, err)
+	}
+
+	lock, err := takeLock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	info, err := readInfo(path)
+	if err != nil {
+		info = &Info{Input: inputPath, Output: outputPath, CreatedAt: now}
+	}
+	info.UpdatedAt = now
+
+	if err := writeJSON(filepath.Join(path, InfoFileName), info); err != nil {
+		os.Remove(filepath.Join(path, LockFileName))
+		lock.Close()
+		return nil, fmt.Errorf("failed to write work directory info: %w", err)
+	}
+
+	return &WorkDir{Path: path, Info: *info, lock: lock}, nil
+}
+
+// Release refreshes the work directory timestamp and removes its lock.
+func (w *WorkDir) Release() error {
+	// The lock file is removed while the flock is still held, so a job
+	// waiting on it notices the unlink and opens a fresh lock file
+	defer w.lock.Close()
+
+	w.Info.UpdatedAt = time.Now()
+	if err := writeJSON(filepath.Join(w.Path, InfoFileName), &w.Info); err != nil {
+		return fmt.Errorf("failed to update work directory info: %w", err)
+	}
+
+	if err := os.Remove(filepath.Join(w.Path, LockFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// takeLock locks the work directory with flock(2) on its lock file and records
+// the owning process in it. The returned file must stay open while the job runs.
+//
+// The kernel drops the flock when its holder exits, so a lock file left behind
+// by a crashed job is simply locked again; a lock file that is still held is
+// never modified or removed, whatever its content.
+func takeLock(path string) (*os.File, error) {
+	lockPath := filepath.Join(path, LockFileName)
+	hostname, _ := os.Hostname()
+
+	var f *os.File
+	for f == nil {
+		file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			file.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, lockedError(path)
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+
+		// Release unlinks the lock file while still holding it; if that happened
+		// between our open and flock, we locked a file nobody else will see
+		if sameFile(file, lockPath) {
+			f = file
+		} else {
+			file.Close()
+		}
+	}
+
+	// A lock left by another host cannot be checked (flock does not reach
+	// across hosts on shared storage) and is treated as live
+	if existing, err := readLock(path); err == nil && existing.Hostname != hostname {
+		f.Close()
+		return nil, lockedError(path)
+	}
+
+	lock := Lock{PID: os.Getpid(), Hostname: hostname, AcquiredAt: time.Now()}
+	if err := writeLock(f, lock); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	// Confirm the lock file on disk is the one we hold and names us
+	written, err := readLock(path)
+	if err != nil || written.PID != lock.PID || written.Hostname != lock.Hostname {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s (lock file changed while it was taken)", ErrLocked, path)
+	}
+
+	return f, nil
+}
+
+// writeLock replaces the content of an open lock file.
+func writeLock(f *os.File, lock Lock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// lockedError describes the job holding the lock of path, as far as it is known.
+func lockedError(path string) error {
+	existing, err := readLock(path)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	return fmt.Errorf("%w: %s (pid %d on %s since %s)", ErrLocked, path,
+		existing.PID, existing.Hostname, existing.AcquiredAt.Format(time.RFC3339))
+}
+
+// sameFile reports whether the open file f is still linked at path.
+func sameFile(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
+
+// isHeld reports whether the lock of a work directory is currently held.
+// Locks recorded by another host cannot be checked and are treated as held.
+func isHeld(path string, lock *Lock) bool {
+	if lock != nil {
+		if hostname, _ := os.Hostname(); lock.Hostname != hostname {
+			return true
+		}
+	}
+
+	f, err := os.Open(filepath.Join(path, LockFileName))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return errors.Is(err, syscall.EWOULDBLOCK)
+	}
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+// Entry describes a work directory found under a root.
+type Entry struct {
+	Path    string
+	Info    *Info // nil if the info file is missing or unreadable
+	Lock    *Lock // nil if there is no readable lock file
+	Active  bool  // true if a job holds the lock
+	Size    int64 // total size of all files in bytes
+	LastUse time.Time
+}
+
+// List returns all work directories under root, most recently used first.
+// A missing root yields an empty list.
+func List(root string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read work root %s: %w", root, err)
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		if !de.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(root, de.Name())
+		info, infoErr := readInfo(path)
+		lock, lockErr := readLock(path)
+
+		// Only directories created by this package are work directories
+		if infoErr != nil && lockErr != nil {
+			continue
+		}
+
+		entry := Entry{Path: path, Size: dirSize(path)}
+		if infoErr == nil {
+			entry.Info = info
+			entry.LastUse = info.UpdatedAt
+		} else if fi, err := de.Info(); err == nil {
+			entry.LastUse = fi.ModTime()
+		}
+		if lockErr == nil {
+			entry.Lock = lock
+		}
+		entry.Active = isHeld(path, entry.Lock)
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUse.After(entries[j].LastUse)
+	})
+
+	return entries, nil
+}
+
+// GC removes work directories under root that are not in use and were last
+// used more than olderThan ago. With dryRun set nothing is deleted.
+// Returns the entries that were (or would be) removed.
+func GC(root string, olderThan time.Duration, dryRun bool) ([]Entry, error) {
+	entries, err := List(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var removed []Entry
+	for _, entry := range entries {
+		if entry.Active || entry.LastUse.After(cutoff) {
+			continue
+		}
+
+		if !dryRun {
+			if err := os.RemoveAll(entry.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+		}
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// readInfo loads the job metadata of a work directory.
+func readInfo(path string) (*Info, error) {
+	data, err := os.ReadFile(filepath.Join(path, InfoFileName))
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse work directory info: %w", err)
+	}
+	return &info, nil
+}
+
+// readLock loads the lock file of a work directory.
+func readLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(path, LockFileName))
+	if err != nil {
+		return nil, err
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+	return &lock, nil
+}
+
+// writeJSON writes v as indented JSON via a temp file and rename.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// dirSize sums the sizes of all regular files below path.
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// stem returns the file name without directory and extension.
+func stem(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// sanitize keeps a file-name-safe, bounded version of s.
+func sanitize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := strings.Trim(b.String(), "._")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	if name == "" {
+		name = "job"
+	}
+	return name
+}
@@ -0,0 +1,293 @@
+package workdir
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestName_StableAndUnique(t *testing.T) {
+	a := Name("/media/movie.mkv", "/out/movie.mkv")
+	b := Name("/media/movie.mkv", "/out/movie.mkv")
+	if a != b {
+		t.Errorf("Expected stable name, got %q and %q", a, b)
+	}
+
+	if !strings.HasPrefix(a, "movie-") {
+		t.Errorf("Expected name to start with input stem, got %q", a)
+	}
+
+	// Same output folder, different output file -> different work dir
+	c := Name("/media/movie.mkv", "/out/movie_720p.mkv")
+	if a == c {
+		t.Error("Expected different names for different outputs")
+	}
+
+	// Different input, same output -> different work dir
+	d := Name("/media/other.mkv", "/out/movie.mkv")
+	if a == d {
+		t.Error("Expected different names for different inputs")
+	}
+}
+
+func TestName_SanitizesStem(t *testing.T) {
+	name := Name("/media/My Movie (2020).mkv", "/out/x.mkv")
+	if strings.ContainsAny(name, " ()") {
+		t.Errorf("Expected sanitized name, got %q", name)
+	}
+}
+
+func TestResolve_DefaultRoot(t *testing.T) {
+	got := Resolve("", "/media/movie.mkv", "/out/final.mkv")
+	if filepath.Dir(got) != filepath.Join("/out", DefaultRootName) {
+		t.Errorf("Expected work dir under /out/tmp, got %s", got)
+	}
+
+	got = Resolve("/scratch", "/media/movie.mkv", "/out/final.mkv")
+	if filepath.Dir(got) != "/scratch" {
+		t.Errorf("Expected work dir under /scratch, got %s", got)
+	}
+}
+
+func TestAcquire_LocksDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job")
+
+	wd, err := Acquire(path, "in.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, LockFileName)); err != nil {
+		t.Errorf("Expected lock file to exist: %v", err)
+	}
+
+	// Second acquire by a live process (ourselves) must fail
+	_, err = Acquire(path, "in.mkv", "out.mkv")
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+
+	if err := wd.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, LockFileName)); !os.IsNotExist(err) {
+		t.Error("Expected lock file to be removed after release")
+	}
+
+	// Acquire again after release
+	wd2, err := Acquire(path, "in.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Re-acquire failed: %v", err)
+	}
+	if !wd2.Info.CreatedAt.Equal(wd.Info.CreatedAt) {
+		t.Error("Expected CreatedAt to be preserved across acquisitions")
+	}
+	wd2.Release()
+}
+
+func TestAcquire_TakesOverStaleLock(t *testing.T) {
+	path := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	// PID that cannot exist on this host
+	writeLockFile(t, path, Lock{PID: 1 << 30, Hostname: hostname, AcquiredAt: time.Now()})
+
+	wd, err := Acquire(path, "in.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Expected stale lock to be taken over, got %v", err)
+	}
+	wd.Release()
+}
+
+func TestAcquire_RespectsForeignHostLock(t *testing.T) {
+	path := t.TempDir()
+	writeLockFile(t, path, Lock{PID: 1 << 30, Hostname: "some-other-host", AcquiredAt: time.Now()})
+
+	if _, err := Acquire(path, "in.mkv", "out.mkv"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for lock held on another host, got %v", err)
+	}
+}
+
+func TestAcquire_KeepsHeldUnparsableLock(t *testing.T) {
+	path := t.TempDir()
+	lockPath := filepath.Join(path, LockFileName)
+
+	// A job that has locked the file but not yet written its details
+	holder, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	defer holder.Close()
+	if err := syscall.Flock(int(holder.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatalf("Failed to flock: %v", err)
+	}
+
+	if _, err := Acquire(path, "in.mkv", "out.mkv"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for held lock, got %v", err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Held lock file must not be removed: %v", err)
+	}
+
+	entries, err := List(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, e := range entries {
+		if e.Path == path && !e.Active {
+			t.Error("Expected held lock to be reported as active")
+		}
+	}
+}
+
+func TestAcquire_Concurrent(t *testing.T) {
+	path := t.TempDir()
+
+	const jobs = 8
+	var wg sync.WaitGroup
+	acquired := make(chan *WorkDir, jobs)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if wd, err := Acquire(path, "in.mkv", "out.mkv"); err == nil {
+				acquired <- wd
+			}
+		}()
+	}
+	wg.Wait()
+	close(acquired)
+
+	if len(acquired) != 1 {
+		t.Fatalf("Expected exactly one job to acquire the lock, got %d", len(acquired))
+	}
+	wd := <-acquired
+
+	lock, err := readLock(path)
+	if err != nil {
+		t.Fatalf("Failed to read lock: %v", err)
+	}
+	if lock.PID != os.Getpid() {
+		t.Errorf("Expected lock to name pid %d, got %d", os.Getpid(), lock.PID)
+	}
+	wd.Release()
+}
+
+func TestListAndGC(t *testing.T) {
+	root := t.TempDir()
+
+	// Old, unlocked job
+	oldDir := filepath.Join(root, Name("old.mkv", "out.mkv"))
+	wd, err := Acquire(oldDir, "old.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	wd.Release()
+	setUpdatedAt(t, oldDir, time.Now().Add(-48*time.Hour))
+	os.WriteFile(filepath.Join(oldDir, "chunk.mkv"), make([]byte, 100), 0644)
+
+	// Recent, unlocked job
+	recentDir := filepath.Join(root, Name("recent.mkv", "out.mkv"))
+	wd, err = Acquire(recentDir, "recent.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	wd.Release()
+
+	// Old but active job
+	activeDir := filepath.Join(root, Name("active.mkv", "out.mkv"))
+	active, err := Acquire(activeDir, "active.mkv", "out.mkv")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer active.Release()
+	setUpdatedAt(t, activeDir, time.Now().Add(-48*time.Hour))
+
+	// Unrelated directory must be ignored
+	os.MkdirAll(filepath.Join(root, "not-a-workdir"), 0755)
+
+	entries, err := List(root)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 work dirs, got %d", len(entries))
+	}
+
+	for _, e := range entries {
+		if e.Path == oldDir && e.Size < 100 {
+			t.Errorf("Expected size >= 100 for old dir, got %d", e.Size)
+		}
+		if e.Path == activeDir && !e.Active {
+			t.Error("Expected active dir to be reported as active")
+		}
+	}
+
+	// Dry run removes nothing
+	removed, err := GC(root, 24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("GC dry run failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Path != oldDir {
+		t.Fatalf("Expected dry run to select only the old dir, got %+v", removed)
+	}
+	if _, err := os.Stat(oldDir); err != nil {
+		t.Error("Dry run should not delete directories")
+	}
+
+	removed, err = GC(root, 24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Fatalf("Expected 1 removed dir, got %d", len(removed))
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Error("Expected old dir to be removed")
+	}
+	if _, err := os.Stat(recentDir); err != nil {
+		t.Error("Recent dir should be kept")
+	}
+	if _, err := os.Stat(activeDir); err != nil {
+		t.Error("Active dir should be kept")
+	}
+}
+
+func TestList_MissingRoot(t *testing.T) {
+	entries, err := List(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Expected no error for missing root, got %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries, got %d", len(entries))
+	}
+}
+
+// Helper functions
+
+func writeLockFile(t *testing.T, dir string, lock Lock) {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		t.Fatalf("Failed to marshal lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LockFileName), data, 0644); err != nil {
+		t.Fatalf("Failed to write lock: %v", err)
+	}
+}
+
+func setUpdatedAt(t *testing.T, dir string, when time.Time) {
+	info, err := readInfo(dir)
+	if err != nil {
+		t.Fatalf("Failed to read info: %v", err)
+	}
+	info.UpdatedAt = when
+	if err := writeJSON(filepath.Join(dir, InfoFileName), info); err != nil {
+		t.Fatalf("Failed to write info: %v", err)
+	}
+}
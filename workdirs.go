@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoder/config"
+	"encoder/workdir"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runSubcommand dispatches "encoder <name> ..." invocations and returns the exit code.
+func runSubcommand(name string, args []string) int {
+	switch name {
+	case "workdirs":
+		return runWorkdirsCommand(args)
+	case "profiles":
+		return runProfilesCommand(args)
+	case "batch":
+		return runBatchCommand(args)
+	case "watch":
+		return runWatchCommand(args)
+	case "serve":
+		return runServeCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown command: %s (run 'encoder -h' for usage)\n", name)
+		return 2
+	}
+}
+
+// runWorkdirsCommand lists or garbage-collects per-job work directories.
+//
+//	encoder workdirs list [-root DIR]
+//	encoder workdirs gc [-root DIR] [-older-than 168h] [-dry-run]
+func runWorkdirsCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "gc") {
+		fmt.Fprintln(os.Stderr, "usage: encoder workdirs list|gc [-root DIR] [-older-than DURATION] [-dry-run]")
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("workdirs "+action, flag.ContinueOnError)
+	root := fs.String("root", "", "Work root to inspect (default: work_dir from config file)")
+	olderThan := fs.Duration("older-than", 7*24*time.Hour, "Only remove work directories unused for at least this long")
+	dryRun := fs.Bool("dry-run", false, "Show what would be removed without deleting anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *root == "" {
+		*root = defaultWorkRoot()
+	}
+	if *root == "" {
+		// Without work_dir every job keeps its work directories in tmp/ next
+		// to its own output, so there is no single root to look at
+		fmt.Fprintln(os.Stderr, "❌ No work root: pass -root DIR (the tmp/ directory next to the outputs) or set work_dir in the config file")
+		return 2
+	}
+
+	if action == "list" {
+		entries, err := workdir.List(*root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No work directories in %s\n", *root)
+			return 0
+		}
+
+		fmt.Printf("Work directories in %s:\n\n", *root)
+		for _, e := range entries {
+			state := "idle"
+			if e.Active && e.Lock != nil {
+				state = fmt.Sprintf("locked (pid %d on %s)", e.Lock.PID, e.Lock.Hostname)
+			} else if e.Active {
+				state = "locked"
+			} else if e.Lock != nil {
+				state = "stale lock"
+			}
+			fmt.Printf("  %s\n", e.Path)
+			if e.Info != nil {
+				fmt.Printf("    Input:     %s\n", e.Info.Input)
+				fmt.Printf("    Output:    %s\n", e.Info.Output)
+			}
+			fmt.Printf("    Size:      %.2f MB\n", float64(e.Size)/(1024*1024))
+			fmt.Printf("    Last used: %s (%s ago)\n", e.LastUse.Format(time.RFC3339), time.Since(e.LastUse).Round(time.Minute))
+			fmt.Printf("    State:     %s\n", state)
+		}
+		return 0
+	}
+
+	removed, err := workdir.GC(*root, *olderThan, *dryRun)
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+		fmt.Printf("  %s %s (%.2f MB)\n", verb, e.Path, float64(e.Size)/(1024*1024))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s %d work directories, %.2f MB\n", verb, len(removed), float64(freed)/(1024*1024))
+	return 0
+}
+
+// defaultWorkRoot returns work_dir from the discovered config file, or ""
+// if none is set.
+func defaultWorkRoot() string {
+	if path := config.FindConfigFile(); path != "" {
+		if cfg, err := config.LoadConfigFile(path); err == nil {
+			return cfg.WorkDir
+		}
+	}
+	return ""
+}